@@ -0,0 +1,89 @@
+// Package aferobackend implements a versionfs.Backend over an afero.Fs, so that versionfs
+// can run over afero.MemMapFs, afero's filtered filesystems, and the rest of the afero
+// ecosystem. It is a separate module to keep the core versionfs module free of afero.
+package aferobackend
+
+import (
+	"errors"
+	"github.com/sperano/versionfs"
+	"github.com/spf13/afero"
+	"io"
+	"io/fs"
+)
+
+// NewWithAfero creates a VersionFS instance storing its files in an afero filesystem, under the root path.
+//
+// Example:
+//
+//	vfs := aferobackend.NewWithAfero(afero.NewMemMapFs(), "/data")
+func NewWithAfero(fsys afero.Fs, root string) *versionfs.VersionFS {
+	v := versionfs.New(root)
+	v.Backend = Backend{Fs: fsys}
+	return v
+}
+
+// Backend is a versionfs.Backend storing files in an afero.Fs.
+type Backend struct {
+	Fs afero.Fs
+}
+
+// ReadFile implements versionfs.Backend with afero.ReadFile.
+func (b Backend) ReadFile(name string) ([]byte, error) {
+	return afero.ReadFile(b.Fs, name)
+}
+
+// WriteFile implements versionfs.Backend with afero.WriteFile.
+func (b Backend) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	return afero.WriteFile(b.Fs, name, data, perm)
+}
+
+// Open implements versionfs.Backend, the returned file implements io.Seeker.
+func (b Backend) Open(name string) (fs.File, error) {
+	f, err := b.Fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return file{File: f}, nil
+}
+
+// file is an afero.File reading io.EOF past its end, like an os.File.
+// afero.MemMapFs returns io.ErrUnexpectedEOF instead.
+type file struct {
+	afero.File
+}
+
+func (f file) Read(p []byte) (int, error) {
+	n, err := f.File.Read(p)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// Remove implements versionfs.Backend.
+func (b Backend) Remove(name string) error {
+	return b.Fs.Remove(name)
+}
+
+// ReadDir implements versionfs.Backend with afero.ReadDir.
+func (b Backend) ReadDir(name string) ([]fs.DirEntry, error) {
+	infos, err := afero.ReadDir(b.Fs, name)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]fs.DirEntry, len(infos))
+	for i, info := range infos {
+		entries[i] = fs.FileInfoToDirEntry(info)
+	}
+	return entries, nil
+}
+
+// Stat implements versionfs.Backend.
+func (b Backend) Stat(name string) (fs.FileInfo, error) {
+	return b.Fs.Stat(name)
+}
+
+// MkdirAll implements versionfs.Backend.
+func (b Backend) MkdirAll(name string, perm fs.FileMode) error {
+	return b.Fs.MkdirAll(name, perm)
+}
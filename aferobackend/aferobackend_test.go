@@ -0,0 +1,50 @@
+package aferobackend
+
+import (
+	"errors"
+	"github.com/sperano/versionfs"
+	"github.com/sperano/versionfs/versionfstest"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"io/fs"
+	"regexp"
+	"testing"
+)
+
+func TestConformance_MemMapFs(t *testing.T) {
+	t.Parallel()
+	versionfstest.TestConformance(t, func(t *testing.T) *versionfs.VersionFS {
+		return NewWithAfero(afero.NewMemMapFs(), "/data")
+	})
+}
+
+func TestConformance_OsFs(t *testing.T) {
+	t.Parallel()
+	versionfstest.TestConformance(t, func(t *testing.T) *versionfs.VersionFS {
+		return NewWithAfero(afero.NewOsFs(), t.TempDir())
+	})
+}
+
+type fileLeague struct{}
+
+func (fileLeague) Dir() string  { return "2023/league" }
+func (fileLeague) Name() string { return "league" }
+func (fileLeague) Ext() string  { return "json" }
+
+func TestNewWithAfero_RegexpFs(t *testing.T) {
+	t.Parallel()
+	// RegexpFs can't create files, the tree is populated through its source
+	mem := afero.NewMemMapFs()
+	assert.Nil(t, afero.WriteFile(mem, "/data/2023/league/league.json.20231019140523", []byte("filtered"), 0644))
+	assert.Nil(t, afero.WriteFile(mem, "/data/2023/league/league.json.bak", []byte("hidden"), 0644))
+	vfs := NewWithAfero(afero.NewRegexpFs(mem, regexp.MustCompile(`\.json\.\d{14}$`)), "/data")
+	versions, err := vfs.Versions(fileLeague{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(versions))
+	data, err := vfs.Read(fileLeague{}, versions[0])
+	assert.Nil(t, err)
+	assert.Equal(t, "filtered", string(data))
+	// files hidden by the filter don't exist
+	_, err = vfs.Backend.ReadFile("/data/2023/league/league.json.bak")
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+}
@@ -0,0 +1,18 @@
+module github.com/sperano/versionfs/aferobackend
+
+go 1.24
+
+replace github.com/sperano/versionfs => ../
+
+require (
+	github.com/sperano/versionfs v0.0.0
+	github.com/spf13/afero v1.15.0
+	github.com/stretchr/testify v1.8.4
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/text v0.28.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
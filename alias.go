@@ -0,0 +1,226 @@
+package versionfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	path_ "path"
+	"sort"
+)
+
+// Alias is a legacy name and extension under which versions of a file type are still stored,
+// typically because the type was renamed after versions were written.
+type Alias struct {
+	// Name is the legacy base filename, without extension or timestamp.
+	Name string
+	// Ext is the legacy extension, without the leading dot.
+	Ext string
+}
+
+// file returns a File describing the alias in the given directory.
+func (a Alias) file(dir string) File {
+	return aliasFile{dir: dir, alias: a}
+}
+
+// aliasFile is the File used to match and access versions stored under an alias.
+type aliasFile struct {
+	dir   string
+	alias Alias
+}
+
+func (f aliasFile) Dir() string  { return f.dir }
+func (f aliasFile) Name() string { return f.alias.Name }
+func (f aliasFile) Ext() string  { return f.alias.Ext }
+
+// ListedVersion is a version returned by the alias-aware listing methods.
+type ListedVersion struct {
+	// Timestamp is the version of the file.
+	Timestamp Timestamp
+	// Alias is the legacy name the version is stored under, or nil for the current name.
+	Alias *Alias
+}
+
+// RegisterAlias registers a legacy name and extension for a file type.
+// Find, Versions, and DetectType additionally match versions stored under the alias,
+// and Read and Remove fall back to the alias when the current name doesn't exist.
+// Write always uses the current name.
+//
+// The file type of a file is the one it declares by implementing TypedFile, or else the one
+// New created its Go type for, so that a file built directly without implementing TypedFile
+// has no aliases, and neither has a Go type New created for several file types.
+//
+// Example:
+//
+//	vfs.RegisterAlias(RosterFileType, "players", "json")
+func (v *VersionFS) RegisterAlias(ftype FileType, oldName, oldExt string) {
+	v.logger().Debugf("Registering alias %s.%s for file type %s", oldName, oldExt, v.TypeName(ftype))
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.aliases[ftype] = append(v.aliases[ftype], Alias{Name: oldName, Ext: oldExt})
+}
+
+// aliasesOf returns the aliases registered for the file type of a file, as found by typeOf.
+func (v *VersionFS) aliasesOf(file File) []Alias {
+	v.mu.RLock()
+	empty := len(v.aliases) == 0
+	v.mu.RUnlock()
+	if empty {
+		return nil
+	}
+	ftype, ok := v.typeOf(file)
+	if !ok {
+		return nil
+	}
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.aliases[ftype]
+}
+
+// ListVersions returns all versions of a file like Versions, sorted newest first,
+// marking the versions that are stored under an alias.
+//
+// Example:
+//
+//	versions, err := vfs.ListVersions(file)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	for _, lv := range versions {
+//	    if lv.Alias != nil {
+//	        fmt.Printf("Version %s still uses %s.%s\n", lv.Timestamp, lv.Alias.Name, lv.Alias.Ext)
+//	    }
+//	}
+func (v *VersionFS) ListVersions(file File) ([]ListedVersion, error) {
+	return v.listVersions(context.Background(), file)
+}
+
+// listVersions implements ListVersions, checking ctx between directory entries.
+func (v *VersionFS) listVersions(ctx context.Context, file File) ([]ListedVersion, error) {
+	versions, err := v.versions(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+	return v.withAliases(ctx, file.Dir(), file, versions)
+}
+
+// ListFind searches a directory for all files matching the given file type like Find,
+// sorted newest first, marking the versions that are stored under an alias.
+func (v *VersionFS) ListFind(dir string, file File) ([]ListedVersion, error) {
+	ctx := context.Background()
+	found, err := v.findAppend(ctx, nil, dir, file)
+	if err != nil {
+		return nil, err
+	}
+	return v.withAliases(ctx, dir, file, found)
+}
+
+// withAliases marks the given versions as current and merges the versions stored under
+// the aliases of the file in dir. Timestamps already listed under the current name are not repeated.
+func (v *VersionFS) withAliases(ctx context.Context, dir string, file File, versions []Timestamp) ([]ListedVersion, error) {
+	listed := make([]ListedVersion, 0, len(versions))
+	seen := make(map[Timestamp]bool, len(versions))
+	for _, ts := range versions {
+		listed = append(listed, ListedVersion{Timestamp: ts})
+		seen[ts] = true
+	}
+	aliases := v.aliasesOf(file)
+	for i := range aliases {
+		found, err := v.findAppend(ctx, nil, dir, aliases[i].file(dir))
+		if err != nil {
+			return nil, err
+		}
+		for _, ts := range found {
+			if !seen[ts] {
+				listed = append(listed, ListedVersion{Timestamp: ts, Alias: &aliases[i]})
+				seen[ts] = true
+			}
+		}
+	}
+	if len(aliases) > 0 {
+		sort.SliceStable(listed, func(i, j int) bool {
+			return listed[i].Timestamp.time.After(listed[j].Timestamp.time)
+		})
+	}
+	return listed, nil
+}
+
+// resolvePath returns the relative path of a version, falling back to the first alias
+// under which the version exists when it doesn't exist under the current name. When Sharded
+// is set, the version is looked for in its shard first.
+func (v *VersionFS) resolvePath(file File, ts Timestamp) string {
+	filepath := v.formatPath(file, ts)
+	if v.Sharded {
+		shard := ShardPath(file, ts)
+		if _, err := v.backend().Stat(path_.Join(v.RootPath, shard)); !errors.Is(err, fs.ErrNotExist) {
+			return shard
+		}
+	}
+	aliases := v.aliasesOf(file)
+	if len(aliases) == 0 {
+		return filepath
+	}
+	if _, err := v.backend().Stat(path_.Join(v.RootPath, filepath)); !errors.Is(err, fs.ErrNotExist) {
+		return filepath
+	}
+	for _, alias := range aliases {
+		aliasPath := v.formatPath(alias.file(file.Dir()), ts)
+		if _, err := v.backend().Stat(path_.Join(v.RootPath, aliasPath)); err == nil {
+			return aliasPath
+		}
+	}
+	return filepath
+}
+
+// resolvedPaths returns the path of a version like resolvePath, and the path joined to
+// the root, in a single allocation with the default layout and without aliases.
+func (v *VersionFS) resolvedPaths(file File, ts Timestamp) (name, full string) {
+	if v.Sharded || v.Scheme != nil || len(v.aliasesOf(file)) > 0 {
+		name = v.resolvePath(file, ts)
+		return name, path_.Join(v.RootPath, name)
+	}
+	return joinPath(v.RootPath, file, ts)
+}
+
+// DetectType classifies a filename as one of the registered file types and extracts its timestamp.
+// Each candidate file is checked with Detect under its current name, then the filename is checked
+// against the aliases of every registered file type. Candidates whose type was never created with
+// New are ignored.
+// The returned FileType is always the canonical type, even when matched through an alias.
+//
+// Example:
+//
+//	ftype, ts, err := vfs.DetectType("players.json.20231019140523", vfs.New(RosterFileType, 2023, 12, "2023-10-19"))
+//	if err != nil {
+//	    fmt.Println("Unknown file")
+//	}
+func (v *VersionFS) DetectType(filename string, candidates ...File) (FileType, Timestamp, error) {
+	for _, file := range candidates {
+		ftype, ok := v.typeOf(file)
+		if !ok {
+			continue
+		}
+		if ts, err := v.Detect(filename, file); err == nil {
+			return ftype, ts, nil
+		}
+	}
+	v.mu.RLock()
+	ftypes := make([]FileType, 0, len(v.aliases))
+	for ftype := range v.aliases {
+		ftypes = append(ftypes, ftype)
+	}
+	aliases := make(map[FileType][]Alias, len(v.aliases))
+	for ftype, a := range v.aliases {
+		aliases[ftype] = a
+	}
+	v.mu.RUnlock()
+	sort.Slice(ftypes, func(i, j int) bool { return ftypes[i] < ftypes[j] })
+	for _, ftype := range ftypes {
+		for _, alias := range aliases[ftype] {
+			if ts, err := v.Detect(filename, alias.file("")); err == nil {
+				return ftype, ts, nil
+			}
+		}
+	}
+	return 0, Timestamp{}, fmt.Errorf("filename %q does not match any registered file type", filename)
+}
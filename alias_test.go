@@ -0,0 +1,176 @@
+package versionfs
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path"
+	"testing"
+)
+
+// write raw files in the league directory, bypassing Write so we control the names
+func writeLeagueFiles(t *testing.T, vfs *VersionFS, names ...string) {
+	t.Helper()
+	if err := vfs.MkdirAll("2023/league", 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range names {
+		if err := os.WriteFile(path.Join(vfs.RootPath, "2023/league", name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestVersionFS_RegisterAlias_Versions(t *testing.T) {
+	t.Parallel()
+	dir, vfs := newTmpVersionFS(t)
+	defer func() { _ = os.RemoveAll(dir) }()
+	writeLeagueFiles(t, vfs,
+		"league.txt.20211125011947",
+		"players.json.20211218030527",
+		"players.json.20211125011946",
+		"players.txt.20211201000000",
+	)
+	file := vfs.New(LeagueFileType, 2023)
+	versions, err := vfs.Versions(file)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(versions))
+
+	vfs.RegisterAlias(LeagueFileType, "players", "json")
+	versions, err = vfs.Versions(file)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(versions))
+	assert.Equal(t, "20211218030527", versions[0].String())
+	assert.Equal(t, "20211125011947", versions[1].String())
+	assert.Equal(t, "20211125011946", versions[2].String())
+
+	timestamps, err := vfs.Find("2023/league", file)
+	assert.Nil(t, err)
+	assert.Equal(t, versions, timestamps)
+
+	listed, err := vfs.ListVersions(file)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(listed))
+	assert.Equal(t, &Alias{Name: "players", Ext: "json"}, listed[0].Alias)
+	assert.Nil(t, listed[1].Alias)
+	assert.Equal(t, &Alias{Name: "players", Ext: "json"}, listed[2].Alias)
+
+	matched, rejected, err := vfs.DetectDir("2023/league", file)
+	assert.Nil(t, err)
+	assert.Equal(t, versions, matched)
+	assert.Equal(t, []string{`filename "players.txt.20211201000000" does not match file name "league"`}, rejected)
+
+	listed, err = vfs.ListFind("2023/league", file)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(listed))
+	assert.Nil(t, listed[1].Alias)
+}
+
+// Read and Remove find versions stored under an alias, Write uses the current name
+func TestVersionFS_RegisterAlias_ReadWriteRemove(t *testing.T) {
+	t.Parallel()
+	dir, vfs := newTmpVersionFS(t)
+	defer func() { _ = os.RemoveAll(dir) }()
+	writeLeagueFiles(t, vfs, "players.json.20211218030527")
+	file := vfs.New(LeagueFileType, 2023)
+	vfs.RegisterAlias(LeagueFileType, "players", "json")
+	ts, _ := NewTimestamp("20211218030527")
+
+	data, err := vfs.Read(file, ts)
+	assert.Nil(t, err)
+	assert.Equal(t, "players.json.20211218030527", string(data))
+
+	newTs, err := vfs.Write(file, []byte("new"))
+	assert.Nil(t, err)
+	exists, err := vfs.PathExists(Path(file, newTs))
+	assert.Nil(t, err)
+	assert.True(t, exists)
+
+	assert.Nil(t, vfs.Remove(file, ts))
+	exists, err = vfs.PathExists("2023/league/players.json.20211218030527")
+	assert.Nil(t, err)
+	assert.False(t, exists)
+}
+
+func TestVersionFS_RegisterAlias_Clone(t *testing.T) {
+	t.Parallel()
+	vfs := newTestVersionFS()
+	vfs.New(LeagueFileType, 2023)
+	clone := vfs.Clone(vfs.RootPath)
+	clone.RegisterAlias(LeagueFileType, "players", "json")
+	assert.Equal(t, 0, len(vfs.aliases[LeagueFileType]))
+	assert.Equal(t, 1, len(clone.aliases[LeagueFileType]))
+}
+
+// declaredLeague is a league file declaring its file type.
+type declaredLeague struct {
+	fileLeague
+}
+
+func (declaredLeague) FileType() FileType { return LeagueFileType }
+
+func TestVersionFS_RegisterAlias_TypedFile(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	putVersion(t, vfs, fileLeague{season: 2023}, "20211125011947", "current")
+	putVersion(t, vfs, aliasFile{dir: "2023/league", alias: Alias{Name: "players", Ext: "json"}}, "20211125011946", "legacy")
+	vfs.RegisterAlias(LeagueFileType, "players", "json")
+
+	// a file built without New has the aliases of the file type it declares
+	versions, err := vfs.Versions(declaredLeague{fileLeague{season: 2023}})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"20211125011947", "20211125011946"}, timestampStrings(versions))
+	versions, err = vfs.Versions(fileLeague{season: 2023})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"20211125011947"}, timestampStrings(versions))
+}
+
+// A Go type created by New for several file types has the aliases of none, whatever the order.
+func TestVersionFS_RegisterAlias_SharedGoType(t *testing.T) {
+	t.Parallel()
+	for _, order := range [][]FileType{{LeagueFileType, RosterFileType}, {RosterFileType, LeagueFileType}} {
+		vfs := NewMemory()
+		for _, ftype := range []FileType{LeagueFileType, RosterFileType} {
+			vfs.RegisterFileType(ftype, func(args ...any) File {
+				return fileLeague{season: args[0].(int)}
+			})
+		}
+		putVersion(t, vfs, fileLeague{season: 2023}, "20211125011947", "current")
+		putVersion(t, vfs, aliasFile{dir: "2023/league", alias: Alias{Name: "players", Ext: "json"}}, "20211125011946", "legacy")
+		vfs.RegisterAlias(RosterFileType, "players", "json")
+		for _, ftype := range order {
+			vfs.New(ftype, 2023)
+		}
+		versions, err := vfs.Versions(fileLeague{season: 2023})
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"20211125011947"}, timestampStrings(versions), "order %v", order)
+		_, ok := vfs.typeOf(fileLeague{season: 2023})
+		assert.False(t, ok)
+	}
+}
+
+func TestVersionFS_DetectType(t *testing.T) {
+	t.Parallel()
+	vfs := newTestVersionFS()
+	league := vfs.New(LeagueFileType, 2023)
+	roster := vfs.New(RosterFileType, 2023, 12, "2023-10-19")
+	vfs.RegisterAlias(RosterFileType, "players", "json")
+
+	ftype, ts, err := vfs.DetectType("league.txt.20211125011947", roster, league)
+	assert.Nil(t, err)
+	assert.Equal(t, LeagueFileType, ftype)
+	assert.Equal(t, "20211125011947", ts.String())
+
+	ftype, ts, err = vfs.DetectType("roster-12-2023-10-19.json.20211125011947", league, roster)
+	assert.Nil(t, err)
+	assert.Equal(t, RosterFileType, ftype)
+	assert.Equal(t, "20211125011947", ts.String())
+
+	ftype, ts, err = vfs.DetectType("players.json.20211125011946")
+	assert.Nil(t, err)
+	assert.Equal(t, RosterFileType, ftype)
+	assert.Equal(t, "20211125011946", ts.String())
+
+	_, _, err = vfs.DetectType("players.txt.20211125011946", league, roster)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "does not match any registered file type")
+}
@@ -0,0 +1,221 @@
+package versionfs
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	path_ "path"
+	"strings"
+)
+
+// ExportOptions configures ExportTar.
+type ExportOptions struct {
+	// Gzip compresses the archive, to write a .tar.gz.
+	Gzip bool
+	// LatestOnly exports only the latest version of each file, identified by its directory,
+	// name, and extension.
+	LatestOnly bool
+}
+
+// exportedVersion is a version found by ExportTar.
+type exportedVersion struct {
+	dir, name, ext string
+	ts             Timestamp
+	size           int64
+}
+
+func (e exportedVersion) path() string {
+	return path_.Join(e.dir, e.name+"."+e.ext+"."+e.ts.String())
+}
+
+// ExportTar writes every versioned file under dirPrefix to w as a tar archive, for support
+// bundles and cold backups. The entries are named by their path relative to the root and
+// their modification time is the version timestamp. Files that don't have the
+// name.ext.timestamp format are skipped. The file contents are streamed, not loaded in memory.
+//
+// Example:
+//
+//	f, err := os.Create("2023.tar.gz")
+//	...
+//	err = vfs.ExportTar(f, "2023", versionfs.ExportOptions{Gzip: true})
+func (v *VersionFS) ExportTar(w io.Writer, dirPrefix string, opts ExportOptions) error {
+	var versions []exportedVersion
+	latest := make(map[string]int)
+	err := v.WalkVersions(dirPrefix, func(dir, name, ext string, ts Timestamp, info os.FileInfo) error {
+		version := exportedVersion{dir: dir, name: name, ext: ext, ts: ts, size: info.Size()}
+		if !opts.LatestOnly {
+			versions = append(versions, version)
+			return nil
+		}
+		key := path_.Join(dir, name+"."+ext)
+		if i, ok := latest[key]; !ok {
+			latest[key] = len(versions)
+			versions = append(versions, version)
+		} else if ts.time.After(versions[i].ts.time) {
+			versions[i] = version
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if opts.Gzip {
+		gz := gzip.NewWriter(w)
+		if err := v.writeTar(gz, versions); err != nil {
+			_ = gz.Close()
+			return err
+		}
+		return gz.Close()
+	}
+	return v.writeTar(w, versions)
+}
+
+// writeTar writes versions to w as a tar archive.
+func (v *VersionFS) writeTar(w io.Writer, versions []exportedVersion) error {
+	tw := tar.NewWriter(w)
+	for _, version := range versions {
+		if err := v.writeTarEntry(tw, version); err != nil {
+			_ = tw.Close()
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// writeTarEntry writes a version to tw.
+func (v *VersionFS) writeTarEntry(tw *tar.Writer, version exportedVersion) error {
+	f, err := v.backend().Open(path_.Join(v.RootPath, version.path()))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     version.path(),
+		Size:     version.size,
+		Mode:     0644,
+		ModTime:  version.ts.Time(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// ImportOptions configures ImportTar.
+type ImportOptions struct {
+	// Overwrite replaces the existing versions with the ones of the archive, instead of skipping them.
+	Overwrite bool
+}
+
+// ImportReport is the outcome of ImportTar, the paths are relative to the root.
+type ImportReport struct {
+	// Imported are the versions that didn't exist.
+	Imported []string
+	// Overwritten are the existing versions replaced with Overwrite.
+	Overwritten []string
+	// Skipped are the existing versions kept without Overwrite.
+	Skipped []string
+	// Rejected are the entries of the archive that aren't versions, they are not written.
+	Rejected []ImportRejection
+}
+
+// ImportRejection is an entry of the archive rejected by ImportTar.
+type ImportRejection struct {
+	// Name is the name of the entry.
+	Name string
+	// Err is the reason it was rejected.
+	Err error
+}
+
+// ImportTar reads a tar archive, gzip-compressed or not, and writes the versions it contains
+// into the tree with their timestamps unchanged, to restore an archive written by ExportTar.
+// Every entry must be a regular file named dir/name.ext.timestamp relative to the root,
+// the other entries are rejected and reported, except for directories, which are ignored.
+// Existing versions are skipped, or replaced if Overwrite is set. Each version is staged like
+// PublishTo, so that readers never see it half-written. Returns the report of the entries
+// processed so far with the first error reading the archive or writing a version.
+//
+// Example:
+//
+//	f, err := os.Open("2023.tar.gz")
+//	...
+//	report, err := vfs.ImportTar(f, versionfs.ImportOptions{})
+//	for _, rejected := range report.Rejected {
+//	    fmt.Printf("%s: %s\n", rejected.Name, rejected.Err)
+//	}
+func (v *VersionFS) ImportTar(r io.Reader, opts ImportOptions) (ImportReport, error) {
+	var report ImportReport
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return report, err
+		}
+		defer func() { _ = gz.Close() }()
+		r = gz
+	} else {
+		r = br
+	}
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return report, nil
+		}
+		if err != nil {
+			return report, err
+		}
+		if header.Typeflag == tar.TypeDir {
+			continue
+		}
+		if err := validateImport(header); err != nil {
+			report.Rejected = append(report.Rejected, ImportRejection{Name: header.Name, Err: err})
+			continue
+		}
+		if err := v.importEntry(&report, header.Name, header.Size, tr, opts); err != nil {
+			return report, err
+		}
+	}
+}
+
+// validateImport returns why an entry of an archive can't be imported, nil if it can.
+func validateImport(header *tar.Header) error {
+	if header.Typeflag != tar.TypeReg {
+		return errors.New("not a regular file")
+	}
+	name := header.Name
+	if path_.IsAbs(name) || path_.Clean(name) != name || name == ".." || strings.HasPrefix(name, "../") {
+		return fmt.Errorf("path %q is not relative to the root", name)
+	}
+	_, _, _, err := ParseFilename(path_.Base(name))
+	return err
+}
+
+// importEntry writes a version read from an archive at name, relative to the root.
+func (v *VersionFS) importEntry(report *ImportReport, name string, size int64, r io.Reader, opts ImportOptions) error {
+	_, err := v.backend().Stat(path_.Join(v.RootPath, name))
+	exists := err == nil
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if exists && !opts.Overwrite {
+		report.Skipped = append(report.Skipped, name)
+		return nil
+	}
+	if err := v.writeAt(name, size, r); err != nil {
+		return err
+	}
+	if exists {
+		report.Overwritten = append(report.Overwritten, name)
+	} else {
+		report.Imported = append(report.Imported, name)
+	}
+	return nil
+}
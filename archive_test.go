@@ -0,0 +1,190 @@
+package versionfs
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+// readTar returns the content of the entries of a tar archive by name, and their modification times.
+func readTar(t *testing.T, r io.Reader) (map[string]string, map[string]time.Time) {
+	t.Helper()
+	contents := make(map[string]string)
+	mtimes := make(map[string]time.Time)
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return contents, mtimes
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents[header.Name] = string(data)
+		mtimes[header.Name] = header.ModTime
+	}
+}
+
+// newExportVersionFS returns an instance with versions in 2023 and 2024.
+func newExportVersionFS(t *testing.T) *VersionFS {
+	vfs := NewMemory()
+	putVersion(t, vfs, fileLeague{season: 2023}, "20231019140523", "first")
+	putVersion(t, vfs, fileLeague{season: 2023}, "20231020140523", "second")
+	putVersion(t, vfs, fileRoster{season: 2023, teamID: 12, date: "2023-10-19"}, "20231019140523", "roster")
+	putVersion(t, vfs, fileLeague{season: 2024}, "20241019140523", "next")
+	putRaw(t, vfs, "2023/league/README", "not a version")
+	return vfs
+}
+
+func TestVersionFS_ExportTar(t *testing.T) {
+	t.Parallel()
+	vfs := newExportVersionFS(t)
+	var buf bytes.Buffer
+	assert.Nil(t, vfs.ExportTar(&buf, "2023", ExportOptions{}))
+	contents, mtimes := readTar(t, &buf)
+	assert.Equal(t, map[string]string{
+		"2023/league/league.txt.20231019140523":                        "first",
+		"2023/league/league.txt.20231020140523":                        "second",
+		"2023/roster/team-12/roster-12-2023-10-19.json.20231019140523": "roster",
+	}, contents)
+	assert.True(t, time.Date(2023, time.October, 20, 14, 5, 23, 0, time.Local).Equal(mtimes["2023/league/league.txt.20231020140523"]))
+}
+
+func TestVersionFS_ExportTar_GzipLatestOnly(t *testing.T) {
+	t.Parallel()
+	vfs := newExportVersionFS(t)
+	var buf bytes.Buffer
+	assert.Nil(t, vfs.ExportTar(&buf, "", ExportOptions{Gzip: true, LatestOnly: true}))
+	gz, err := gzip.NewReader(&buf)
+	assert.Nil(t, err)
+	contents, _ := readTar(t, gz)
+	assert.Equal(t, map[string]string{
+		"2023/league/league.txt.20231020140523":                        "second",
+		"2023/roster/team-12/roster-12-2023-10-19.json.20231019140523": "roster",
+		"2024/league/league.txt.20241019140523":                        "next",
+	}, contents)
+}
+
+func TestVersionFS_ExportTar_MissingDir(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	assert.Nil(t, NewMemory().ExportTar(&buf, "2023", ExportOptions{}))
+	contents, _ := readTar(t, &buf)
+	assert.Empty(t, contents)
+}
+
+// treeContents returns the content of every versioned file of vfs by path.
+func treeContents(t *testing.T, vfs *VersionFS) map[string]string {
+	t.Helper()
+	contents := make(map[string]string)
+	err := vfs.WalkVersions("", func(dir, name, ext string, ts Timestamp, info os.FileInfo) error {
+		filename := path.Join(dir, name+"."+ext+"."+ts.String())
+		data, err := vfs.Backend.ReadFile(path.Join(vfs.RootPath, filename))
+		contents[filename] = string(data)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return contents
+}
+
+func TestVersionFS_ImportTar_RoundTrip(t *testing.T) {
+	t.Parallel()
+	src := newExportVersionFS(t)
+	putVersion(t, src, fileLeague{season: 2023}, "20231021", "daily\x00binary\xff")
+	for _, gzipped := range []bool{false, true} {
+		var buf bytes.Buffer
+		assert.Nil(t, src.ExportTar(&buf, "", ExportOptions{Gzip: gzipped}))
+		dst := NewMemory()
+		report, err := dst.ImportTar(&buf, ImportOptions{})
+		assert.Nil(t, err)
+		assert.Len(t, report.Imported, 5)
+		assert.Equal(t, treeContents(t, src), treeContents(t, dst))
+	}
+}
+
+// tarEntry is an entry written by writeTestTar.
+type tarEntry struct {
+	name     string
+	typeflag byte
+	data     string
+}
+
+// writeTestTar writes a tar archive with entries.
+func writeTestTar(t *testing.T, entries ...tarEntry) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, entry := range entries {
+		header := &tar.Header{Name: entry.name, Typeflag: entry.typeflag, Mode: 0644, Size: int64(len(entry.data))}
+		if entry.typeflag != tar.TypeReg {
+			header.Size = 0
+			header.Linkname = "target"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(entry.data)); entry.typeflag == tar.TypeReg && err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestVersionFS_ImportTar_Report(t *testing.T) {
+	t.Parallel()
+	archive := func() *bytes.Buffer {
+		return writeTestTar(t,
+			tarEntry{name: "2023/league", typeflag: tar.TypeDir},
+			tarEntry{name: "2023/league/league.txt.20231019140523", typeflag: tar.TypeReg, data: "archived"},
+			tarEntry{name: "2023/league/league.txt.20231020140523", typeflag: tar.TypeReg, data: "new"},
+			tarEntry{name: "2023/league/README", typeflag: tar.TypeReg, data: "readme"},
+			tarEntry{name: "../league.txt.20231019140523", typeflag: tar.TypeReg, data: "escape"},
+			tarEntry{name: "2023/league/link.txt.20231019140523", typeflag: tar.TypeSymlink},
+		)
+	}
+	vfs := NewMemory()
+	file := fileLeague{season: 2023}
+	putVersion(t, vfs, file, "20231019140523", "current")
+	report, err := vfs.ImportTar(archive(), ImportOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"2023/league/league.txt.20231020140523"}, report.Imported)
+	assert.Equal(t, []string{"2023/league/league.txt.20231019140523"}, report.Skipped)
+	assert.Empty(t, report.Overwritten)
+	var rejected []string
+	for _, rejection := range report.Rejected {
+		rejected = append(rejected, rejection.Name+": "+rejection.Err.Error())
+	}
+	assert.Equal(t, []string{
+		`2023/league/README: filename "README" has invalid format, expected name.ext.timestamp`,
+		`../league.txt.20231019140523: path "../league.txt.20231019140523" is not relative to the root`,
+		`2023/league/link.txt.20231019140523: not a regular file`,
+	}, rejected)
+	assert.Equal(t, map[string]string{
+		"2023/league/league.txt.20231019140523": "current",
+		"2023/league/league.txt.20231020140523": "new",
+	}, treeContents(t, vfs))
+
+	report, err = vfs.ImportTar(archive(), ImportOptions{Overwrite: true})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"2023/league/league.txt.20231019140523", "2023/league/league.txt.20231020140523"}, report.Overwritten)
+	assert.Empty(t, report.Imported)
+	ts, _ := NewTimestamp("20231019140523")
+	data, err := vfs.Read(file, ts)
+	assert.Nil(t, err)
+	assert.Equal(t, "archived", string(data))
+}
@@ -0,0 +1,128 @@
+package versionfs
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// Backend is the storage a VersionFS reads and writes versions from.
+// Names are slash-separated paths, already joined with the VersionFS root path.
+// Errors for missing files and directories must satisfy errors.Is(err, fs.ErrNotExist).
+type Backend interface {
+	// ReadFile reads the whole content of a file.
+	ReadFile(name string) ([]byte, error)
+	// WriteFile writes data to a file, creating or truncating it.
+	// The parent directory must exist.
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+	// Open opens a file for reading. The file may implement io.Seeker.
+	Open(name string) (fs.File, error)
+	// Remove deletes a file or an empty directory.
+	Remove(name string) error
+	// ReadDir lists the entries of a directory, sorted by filename.
+	ReadDir(name string) ([]fs.DirEntry, error)
+	// Stat returns the information of a file or directory.
+	Stat(name string) (fs.FileInfo, error)
+	// MkdirAll creates a directory and all necessary parent directories.
+	MkdirAll(name string, perm fs.FileMode) error
+}
+
+// SyncBackend is implemented by the backends able to make a write durable before returning.
+// It is used by Write when SyncOnWrite is set. Backends without durable storage, such as the
+// memory backend, don't need to implement it.
+type SyncBackend interface {
+	// WriteFileSync writes data to a file like WriteFile, then flushes the file and its
+	// parent directory to stable storage.
+	WriteFileSync(name string, data []byte, perm fs.FileMode) error
+}
+
+// RenameBackend is implemented by the backends able to write a file from a stream and to
+// rename it atomically. It is used by PublishTo to stage a version in a temporary file before
+// renaming it, so that readers never see it half-written.
+type RenameBackend interface {
+	// Create creates or truncates a file for writing. The parent directory must exist.
+	Create(name string, perm fs.FileMode) (io.WriteCloser, error)
+	// Rename renames a file, replacing newname if it exists.
+	Rename(oldname, newname string) error
+}
+
+// OSBackend is the Backend storing versions in the local filesystem. It is the default.
+// The slash-separated names are converted to the separator of the platform.
+type OSBackend struct{}
+
+// ReadFile implements Backend with os.ReadFile.
+func (OSBackend) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(filepath.FromSlash(name))
+}
+
+// WriteFile implements Backend with os.WriteFile.
+func (OSBackend) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	return os.WriteFile(filepath.FromSlash(name), data, perm)
+}
+
+// WriteFileSync implements SyncBackend: the file is written with os.OpenFile and synced,
+// then its parent directory is synced so the new directory entry survives a power loss.
+// Directories can't be synced on Windows, where only the file is synced.
+func (OSBackend) WriteFileSync(name string, data []byte, perm fs.FileMode) error {
+	f, err := os.OpenFile(filepath.FromSlash(name), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	dir, err := os.Open(filepath.Dir(filepath.FromSlash(name)))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = dir.Close() }()
+	return dir.Sync()
+}
+
+// Create implements RenameBackend with os.OpenFile.
+func (OSBackend) Create(name string, perm fs.FileMode) (io.WriteCloser, error) {
+	return os.OpenFile(filepath.FromSlash(name), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+}
+
+// Rename implements RenameBackend with os.Rename, which is atomic within a filesystem.
+func (OSBackend) Rename(oldname, newname string) error {
+	return os.Rename(filepath.FromSlash(oldname), filepath.FromSlash(newname))
+}
+
+// Open implements Backend with os.Open.
+func (OSBackend) Open(name string) (fs.File, error) {
+	return os.Open(filepath.FromSlash(name))
+}
+
+// Remove implements Backend with os.Remove.
+func (OSBackend) Remove(name string) error {
+	return os.Remove(filepath.FromSlash(name))
+}
+
+// ReadDir implements Backend with os.ReadDir.
+func (OSBackend) ReadDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(filepath.FromSlash(name))
+}
+
+// Stat implements Backend with os.Stat.
+func (OSBackend) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(filepath.FromSlash(name))
+}
+
+// MkdirAll implements Backend with os.MkdirAll.
+func (OSBackend) MkdirAll(name string, perm fs.FileMode) error {
+	return os.MkdirAll(filepath.FromSlash(name), perm)
+}
@@ -0,0 +1,164 @@
+package versionfs
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	path_ "path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// versionsCache holds the versions listed by Versions when VersionsCacheTTL is set, keyed
+// by file. It is not shared with other instances.
+type versionsCache struct {
+	mu sync.Mutex
+	// gen is incremented by every invalidation, so that a listing started before one isn't stored.
+	gen     uint64
+	entries map[versionsKey]cachedVersions
+}
+
+// versionsKey identifies the versions of a file in the cache.
+type versionsKey struct {
+	dir, name, ext string
+}
+
+// cachedVersions is a listing of the cache.
+type cachedVersions struct {
+	versions []Timestamp
+	listed   time.Time
+}
+
+// cachedVersions implements versions with the cache of the instance: a listing is reused
+// until it is VersionsCacheTTL old, or a write or removal of the instance in its directory.
+func (v *VersionFS) cachedVersions(ctx context.Context, file File) ([]Timestamp, error) {
+	key := versionsKey{dir: path_.Clean(slashed(file.Dir())), name: file.Name(), ext: file.Ext()}
+	c := v.cache
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	gen := c.gen
+	c.mu.Unlock()
+	if ok && time.Since(entry.listed) < v.VersionsCacheTTL {
+		v.metrics().IncCounter(MetricCacheHits, 1, "root", v.RootPath)
+		return slices.Clone(entry.versions), nil
+	}
+	v.metrics().IncCounter(MetricCacheMisses, 1, "root", v.RootPath)
+	listed := time.Now()
+	versions, err := v.readVersions(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gen == gen {
+		if c.entries == nil {
+			c.entries = make(map[versionsKey]cachedVersions)
+		}
+		c.entries[key] = cachedVersions{versions: slices.Clone(versions), listed: listed}
+	}
+	return versions, nil
+}
+
+// invalidate drops the cached listings of the directories holding name, relative to the
+// root, or under it, and the cached contents of name or under it, after name was written,
+// removed, or renamed.
+func (v *VersionFS) invalidate(name string) {
+	name = path_.Clean(slashed(name))
+	if v.readCache != nil {
+		v.readCache.invalidate(name)
+	}
+	if v.cache == nil {
+		return
+	}
+	c := v.cache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	for key := range c.entries {
+		if key.dir == "." || key.dir == name || strings.HasPrefix(name, key.dir+"/") || strings.HasPrefix(key.dir, name+"/") {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// readCache holds the contents returned by Read when ReadCacheBytes is set, keyed by their
+// path relative to the root, and evicts the least recently used ones beyond ReadCacheBytes.
+// It is not shared with other instances.
+type readCache struct {
+	mu sync.Mutex
+	// gen is incremented by every invalidation, so that a read started before one isn't stored.
+	gen uint64
+	// lru holds the *cachedContent, the most recently used first.
+	lru     list.List
+	entries map[string]*list.Element
+	bytes   int64
+}
+
+// cachedContent is a content of the read cache.
+type cachedContent struct {
+	name string
+	data []byte
+}
+
+// cachedRead returns a copy of the content of name, relative to the root, from the cache
+// of Read, and the generation to pass to storeRead on a miss.
+func (v *VersionFS) cachedRead(name string) ([]byte, uint64, bool) {
+	c := v.readCache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[name]
+	if !ok {
+		v.metrics().IncCounter(MetricReadCacheMisses, 1, "root", v.RootPath)
+		return nil, c.gen, false
+	}
+	c.lru.MoveToFront(elem)
+	v.metrics().IncCounter(MetricReadCacheHits, 1, "root", v.RootPath)
+	return bytes.Clone(elem.Value.(*cachedContent).data), c.gen, true
+}
+
+// storeRead caches a copy of data, the content of name read at generation gen, evicting the
+// least recently used contents beyond ReadCacheBytes. Contents larger than it aren't cached.
+func (v *VersionFS) storeRead(name string, data []byte, gen uint64) {
+	size := int64(len(data))
+	if size > v.ReadCacheBytes {
+		return
+	}
+	c := v.readCache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gen != gen {
+		return
+	}
+	if _, ok := c.entries[name]; ok {
+		return
+	}
+	if c.entries == nil {
+		c.entries = make(map[string]*list.Element)
+	}
+	c.entries[name] = c.lru.PushFront(&cachedContent{name: name, data: bytes.Clone(data)})
+	c.bytes += size
+	for c.bytes > v.ReadCacheBytes {
+		c.remove(c.lru.Back())
+	}
+}
+
+// invalidate drops the contents of name or under it.
+func (c *readCache) invalidate(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	for key, elem := range c.entries {
+		if key == name || strings.HasPrefix(key, name+"/") {
+			c.remove(elem)
+		}
+	}
+}
+
+// remove drops a content of the cache. The caller must hold the lock.
+func (c *readCache) remove(elem *list.Element) {
+	content := c.lru.Remove(elem).(*cachedContent)
+	delete(c.entries, content.name)
+	c.bytes -= int64(len(content.data))
+}
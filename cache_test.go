@@ -0,0 +1,237 @@
+package versionfs
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newCachedVersionFS returns a memory instance caching the listings for ttl, with its metrics.
+func newCachedVersionFS(ttl time.Duration) (*VersionFS, *MemoryMetrics) {
+	vfs := NewMemory()
+	metrics := &MemoryMetrics{}
+	vfs.Metrics = metrics
+	vfs.VersionsCacheTTL = ttl
+	return vfs, metrics
+}
+
+func TestVersionFS_VersionsCache(t *testing.T) {
+	t.Parallel()
+	vfs, metrics := newCachedVersionFS(time.Hour)
+	file := fileLeague{season: 2023}
+	putVersion(t, vfs, file, "20231019140523", "first")
+	counts := func() (int64, int64) {
+		return metrics.Counter(MetricCacheHits, "root", vfs.RootPath), metrics.Counter(MetricCacheMisses, "root", vfs.RootPath)
+	}
+
+	versions, err := vfs.Versions(file)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"20231019140523"}, timestampStrings(versions))
+	versions[0] = Timestamp{} // the cached listing is a copy
+	versions, err = vfs.Versions(file)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"20231019140523"}, timestampStrings(versions))
+	hits, misses := counts()
+	assert.Equal(t, int64(1), hits)
+	assert.Equal(t, int64(1), misses)
+
+	// a write of the instance is visible on the next call
+	ts, err := vfs.Write(file, []byte("second"))
+	assert.Nil(t, err)
+	latest, err := vfs.LastVersion(file)
+	assert.Nil(t, err)
+	assert.Equal(t, ts.String(), latest.String())
+	versions, err = vfs.Versions(file)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{ts.String(), "20231019140523"}, timestampStrings(versions))
+	hits, misses = counts()
+	assert.Equal(t, int64(2), hits)
+	assert.Equal(t, int64(2), misses)
+
+	// and so are its removals
+	assert.Nil(t, vfs.Remove(file, ts))
+	versions, err = vfs.Versions(file)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"20231019140523"}, timestampStrings(versions))
+	putVersion(t, vfs, file, "20231018140523", "older")
+	removed, err := vfs.Prune(file, RetentionPolicy{KeepLast: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"20231018140523"}, timestampStrings(removed))
+	versions, err = vfs.Versions(file)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"20231019140523"}, timestampStrings(versions))
+
+	// the writes of other files keep the listing
+	_, err = vfs.Write(fileLeague{season: 2024}, []byte("other"))
+	assert.Nil(t, err)
+	hitsBefore, _ := counts()
+	_, err = vfs.Versions(file)
+	assert.Nil(t, err)
+	hits, _ = counts()
+	assert.Equal(t, hitsBefore+1, hits)
+}
+
+func TestVersionFS_VersionsCache_ExternalWrites(t *testing.T) {
+	t.Parallel()
+	vfs, _ := newCachedVersionFS(time.Hour)
+	other := vfs.WithRoot(vfs.RootPath)
+	file := fileLeague{season: 2023}
+	putVersion(t, vfs, file, "20231019140523", "first")
+	_, err := vfs.Versions(file)
+	assert.Nil(t, err)
+	_, err = other.Write(file, []byte("external"))
+	assert.Nil(t, err)
+
+	// the writes of other instances are only seen once the listing expires
+	versions, err := vfs.Versions(file)
+	assert.Nil(t, err)
+	assert.Len(t, versions, 1)
+	vfs.VersionsCacheTTL = time.Nanosecond
+	versions, err = vfs.Versions(file)
+	assert.Nil(t, err)
+	assert.Len(t, versions, 2)
+}
+
+func TestVersionFS_VersionsCache_Concurrent(t *testing.T) {
+	t.Parallel()
+	vfs, _ := newCachedVersionFS(time.Hour)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		file := aliasFile{dir: "shared", alias: Alias{Name: fmt.Sprintf("file%d", i), Ext: "txt"}}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 1; j <= 5; j++ {
+				_, err := vfs.Write(file, []byte("data"))
+				assert.Nil(t, err)
+				versions, err := vfs.Versions(file)
+				assert.Nil(t, err)
+				assert.Len(t, versions, j)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// newReadCachedVersionFS returns a memory instance caching up to max bytes of contents, with its metrics.
+func newReadCachedVersionFS(max int64) (*VersionFS, *MemoryMetrics) {
+	vfs := NewMemory()
+	metrics := &MemoryMetrics{}
+	vfs.Metrics = metrics
+	vfs.ReadCacheBytes = max
+	return vfs, metrics
+}
+
+func TestVersionFS_ReadCache(t *testing.T) {
+	t.Parallel()
+	vfs, metrics := newReadCachedVersionFS(1 << 10)
+	file := fileLeague{season: 2023}
+	ts := putVersion(t, vfs, file, "20231019140523", "first")
+	counts := func() (int64, int64) {
+		return metrics.Counter(MetricReadCacheHits, "root", vfs.RootPath), metrics.Counter(MetricReadCacheMisses, "root", vfs.RootPath)
+	}
+
+	data, err := vfs.Read(file, ts)
+	assert.Nil(t, err)
+	assert.Equal(t, "first", string(data))
+	data[0] = 'F' // the cached content is a copy
+	data, err = vfs.Read(file, ts)
+	assert.Nil(t, err)
+	assert.Equal(t, "first", string(data))
+	data[0] = 'F' // and so are the contents returned from it
+	data, err = vfs.Read(file, ts)
+	assert.Nil(t, err)
+	assert.Equal(t, "first", string(data))
+	hits, misses := counts()
+	assert.Equal(t, int64(2), hits)
+	assert.Equal(t, int64(1), misses)
+
+	// a removal of the instance drops the content
+	assert.Nil(t, vfs.Remove(file, ts))
+	_, err = vfs.Read(file, ts)
+	assert.ErrorIs(t, err, ErrVersionNotFound)
+
+	// and so does a write replacing a version within the same period
+	daily := dailyLeague{file}
+	ts, err = vfs.Write(daily, []byte("morning"))
+	assert.Nil(t, err)
+	data, err = vfs.Read(daily, ts)
+	assert.Nil(t, err)
+	assert.Equal(t, "morning", string(data))
+	replaced, err := vfs.Write(daily, []byte("evening"))
+	assert.Nil(t, err)
+	assert.Equal(t, ts.String(), replaced.String())
+	data, err = vfs.Read(daily, ts)
+	assert.Nil(t, err)
+	assert.Equal(t, "evening", string(data))
+
+	// the clones don't share the cache
+	clone := vfs.WithRoot(vfs.RootPath)
+	_, err = clone.Read(daily, ts)
+	assert.Nil(t, err)
+	_, misses = counts()
+	assert.Equal(t, int64(5), misses)
+}
+
+func TestVersionFS_ReadCache_Eviction(t *testing.T) {
+	t.Parallel()
+	vfs, metrics := newReadCachedVersionFS(10)
+	file := fileLeague{season: 2023}
+	first := putVersion(t, vfs, file, "20231017140523", "aaaa")
+	second := putVersion(t, vfs, file, "20231018140523", "bbbb")
+	third := putVersion(t, vfs, file, "20231019140523", "cccc")
+	large := putVersion(t, vfs, file, "20231020140523", "larger than the cache")
+	read := func(ts Timestamp) bool {
+		t.Helper()
+		hits := metrics.Counter(MetricReadCacheHits, "root", vfs.RootPath)
+		_, err := vfs.Read(file, ts)
+		assert.Nil(t, err)
+		return metrics.Counter(MetricReadCacheHits, "root", vfs.RootPath) > hits
+	}
+
+	assert.False(t, read(first))
+	assert.False(t, read(second))
+	assert.True(t, read(first))
+	// the third content evicts the least recently used one, the second
+	assert.False(t, read(third))
+	assert.True(t, read(first))
+	assert.True(t, read(third))
+	assert.False(t, read(second))
+	// the contents larger than the cache aren't cached, and don't evict the others
+	assert.False(t, read(large))
+	assert.False(t, read(large))
+	assert.True(t, read(second))
+	assert.True(t, read(third))
+	assert.Equal(t, int64(8), vfs.readCache.bytes)
+}
+
+func TestVersionFS_ReadCache_Concurrent(t *testing.T) {
+	t.Parallel()
+	vfs, _ := newReadCachedVersionFS(64)
+	var timestamps []Timestamp
+	for i := 0; i < 10; i++ {
+		timestamps = append(timestamps, putVersion(t, vfs, fileLeague{season: 2023}, fmt.Sprintf("202310191405%02d", i), fmt.Sprintf("content %02d", i)))
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				k := (i + j) % len(timestamps)
+				data, err := vfs.Read(fileLeague{season: 2023}, timestamps[k])
+				assert.Nil(t, err)
+				assert.Equal(t, fmt.Sprintf("content %02d", k), string(data))
+				data[0] = 'X'
+				if j%50 == 0 {
+					_, err := vfs.Write(fileLeague{season: 2024 + i}, []byte("other"))
+					assert.Nil(t, err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.LessOrEqual(t, vfs.readCache.bytes, int64(64))
+}
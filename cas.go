@@ -0,0 +1,582 @@
+package versionfs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	path_ "path"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BlobDirName is the name of the directory of the blobs of a CASBackend created by EnableCAS,
+// at the root. It starts with a dot, so that it is skipped with IgnoreDotfiles.
+const BlobDirName = ".blobs"
+
+// casPointerPrefix starts the content of the pointer files written by a CASBackend,
+// followed by the hex-encoded SHA-256 of the blob and a newline.
+const casPointerPrefix = "versionfs-blob sha256:"
+
+// casPointerSize is the size of a pointer file.
+const casPointerSize = len(casPointerPrefix) + sha256.Size*2 + 1
+
+// casRefsSuffix is appended to the name of a blob for the file holding its reference count.
+const casRefsSuffix = ".refs"
+
+// CASBackend is a Backend storing contents once, for data duplicated across files and not
+// only across the versions of a file. Each content is stored as a blob named by its SHA-256
+// in BlobDir, and the files are small pointers to their blob. Reading, opening, listing,
+// and stating a pointer transparently gives the blob's content and size, so the VersionFS
+// APIs behave the same as with the wrapped backend, and files written before the backend
+// was wrapped are read as they are.
+//
+// Each blob has a reference count, stored next to it, that is decremented when a pointer is
+// removed or replaced, and the blob is deleted with the last one. The counts are kept
+// consistent within a process; Compact repairs them and removes the orphaned blobs left by
+// interrupted operations or other processes.
+type CASBackend struct {
+	// Backend is the wrapped storage of the pointers and the blobs.
+	Backend Backend
+	// BlobDir is the directory of the blobs, a name like the ones given to the backend,
+	// already joined with the root.
+	BlobDir string
+	// mu serializes the updates of the pointers and the reference counts.
+	mu sync.Mutex
+	// tmp numbers the temporary blobs of the streamed writes.
+	tmp atomic.Int64
+}
+
+// NewCASBackend creates a CASBackend storing its blobs in blobDir of backend.
+func NewCASBackend(backend Backend, blobDir string) *CASBackend {
+	return &CASBackend{Backend: backend, BlobDir: blobDir}
+}
+
+// rootedBackend is implemented by the backends tied to the root of the instance they were
+// created for, so that Clone recreates them for the root of the clone.
+type rootedBackend interface {
+	// rebase returns the backend to use under newRoot instead of oldRoot.
+	rebase(oldRoot, newRoot string) Backend
+}
+
+// rebase implements rootedBackend: a BlobDir under oldRoot, as set by EnableCAS, is moved
+// under newRoot in a new CASBackend, with its own reference counts. A BlobDir out of oldRoot
+// stays shared.
+func (b *CASBackend) rebase(oldRoot, newRoot string) Backend {
+	dir, root := path_.Clean(b.BlobDir), path_.Clean(oldRoot)
+	var rel string
+	switch {
+	case root == "." && !path_.IsAbs(dir) && dir != ".." && !strings.HasPrefix(dir, "../"):
+		rel = dir
+	case strings.HasPrefix(dir, root+"/"):
+		rel = dir[len(root)+1:]
+	default:
+		return b
+	}
+	backend := b.Backend
+	if rb, ok := backend.(rootedBackend); ok {
+		backend = rb.rebase(oldRoot, newRoot)
+	}
+	return NewCASBackend(backend, path_.Join(newRoot, rel))
+}
+
+// EnableCAS makes the instance store its files content-addressed, wrapping its Backend in a
+// CASBackend with its blobs in BlobDirName at the root. Views created afterwards with WithRoot
+// and Sub share the blobs, while Clone gives the clone its own blobs under its root. Call it
+// before the instance is used.
+//
+// Example:
+//
+//	vfs := versionfs.New("./data")
+//	vfs.EnableCAS()
+func (v *VersionFS) EnableCAS() {
+	v.Backend = NewCASBackend(v.Backend, path_.Join(v.RootPath, BlobDirName))
+}
+
+// Compact recounts the references to the blobs of a CASBackend from the pointers under the
+// root, repairs the reference counts, and removes the blobs without references, returning
+// how many were removed. Every root sharing the blobs must be under this root, or their blobs
+// are removed. Returns an error wrapping errors.ErrUnsupported if the Backend isn't a CASBackend.
+//
+// Example:
+//
+//	removed, err := vfs.Compact()
+func (v *VersionFS) Compact() (int, error) {
+	b, ok := v.Backend.(*CASBackend)
+	if !ok {
+		return 0, fmt.Errorf("compact: %w", errors.ErrUnsupported)
+	}
+	if err := v.checkWritable("compact", "."); err != nil {
+		return 0, err
+	}
+	v.logger().Debugf("Compacting the blobs of %s", v.RootPath)
+	return b.compact(v.RootPath)
+}
+
+// inBlobDir reports whether name is the blob directory or in it, where files are stored as is.
+func (b *CASBackend) inBlobDir(name string) bool {
+	dir := path_.Clean(b.BlobDir)
+	name = path_.Clean(name)
+	return name == dir || strings.HasPrefix(name, dir+"/")
+}
+
+// blobPath returns the name of the blob of a SHA-256.
+func (b *CASBackend) blobPath(sum string) string {
+	return path_.Join(b.BlobDir, sum)
+}
+
+// parsePointer returns the SHA-256 of the blob data points to, or false if it isn't a pointer.
+func parsePointer(data []byte) (string, bool) {
+	if len(data) != casPointerSize || !bytes.HasPrefix(data, []byte(casPointerPrefix)) || data[len(data)-1] != '\n' {
+		return "", false
+	}
+	sum := string(data[len(casPointerPrefix) : len(data)-1])
+	if _, err := hex.DecodeString(sum); err != nil {
+		return "", false
+	}
+	return sum, true
+}
+
+// pointerOf returns the SHA-256 of the blob the file name points to, or false if it
+// isn't a pointer. info is the information of the file.
+func (b *CASBackend) pointerOf(name string, info fs.FileInfo) (string, bool, error) {
+	if !info.Mode().IsRegular() || info.Size() != int64(casPointerSize) || b.inBlobDir(name) {
+		return "", false, nil
+	}
+	data, err := b.Backend.ReadFile(name)
+	if err != nil {
+		return "", false, err
+	}
+	sum, ok := parsePointer(data)
+	return sum, ok, nil
+}
+
+// pointerAt returns the SHA-256 of the blob the file name points to, or false if it
+// doesn't exist or isn't a pointer.
+func (b *CASBackend) pointerAt(name string) (string, bool, error) {
+	info, err := b.Backend.Stat(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", false, nil
+	} else if err != nil {
+		return "", false, err
+	}
+	return b.pointerOf(name, info)
+}
+
+// ReadFile implements Backend, reading the blob of a pointer.
+func (b *CASBackend) ReadFile(name string) ([]byte, error) {
+	data, err := b.Backend.ReadFile(name)
+	if err != nil || b.inBlobDir(name) {
+		return data, err
+	}
+	if sum, ok := parsePointer(data); ok {
+		return b.Backend.ReadFile(b.blobPath(sum))
+	}
+	return data, nil
+}
+
+// WriteFile implements Backend, storing data as a blob if it isn't stored yet and writing
+// a pointer to it.
+func (b *CASBackend) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	return b.store(name, data, perm, b.Backend.WriteFile)
+}
+
+// WriteFileSync implements SyncBackend, syncing the blob and the pointer when the wrapped
+// backend implements SyncBackend.
+func (b *CASBackend) WriteFileSync(name string, data []byte, perm fs.FileMode) error {
+	write := b.Backend.WriteFile
+	if sb, ok := b.Backend.(SyncBackend); ok {
+		write = sb.WriteFileSync
+	}
+	return b.store(name, data, perm, write)
+}
+
+// store stores data as a blob and writes a pointer to it at name with write.
+func (b *CASBackend) store(name string, data []byte, perm fs.FileMode, write func(string, []byte, fs.FileMode) error) error {
+	if b.inBlobDir(name) {
+		return write(name, data, perm)
+	}
+	h := sha256.Sum256(data)
+	sum := hex.EncodeToString(h[:])
+	if _, err := b.Backend.Stat(path_.Dir(name)); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, err := b.Backend.Stat(b.blobPath(sum)); errors.Is(err, fs.ErrNotExist) {
+		if err := b.Backend.MkdirAll(b.BlobDir, 0755); err != nil {
+			return err
+		}
+		if err := write(b.blobPath(sum), data, perm); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	return b.point(name, sum, perm, write)
+}
+
+// point writes a pointer to the blob sum at name, counting the reference and releasing the
+// blob of the pointer it replaces. b.mu must be held.
+func (b *CASBackend) point(name, sum string, perm fs.FileMode, write func(string, []byte, fs.FileMode) error) error {
+	replaced, hadPointer, err := b.pointerAt(name)
+	if err != nil {
+		return err
+	}
+	if err := b.addRef(sum, 1); err != nil {
+		return err
+	}
+	if err := write(name, []byte(casPointerPrefix+sum+"\n"), perm); err != nil {
+		_ = b.addRef(sum, -1)
+		return err
+	}
+	if hadPointer {
+		return b.addRef(replaced, -1)
+	}
+	return nil
+}
+
+// addRef adds delta to the reference count of the blob sum, removing the blob when no
+// reference is left. b.mu must be held.
+func (b *CASBackend) addRef(sum string, delta int) error {
+	refsPath := b.blobPath(sum) + casRefsSuffix
+	refs := 0
+	data, err := b.Backend.ReadFile(refsPath)
+	if err == nil {
+		if refs, err = strconv.Atoi(strings.TrimSpace(string(data))); err != nil {
+			return fmt.Errorf("invalid reference count of blob %s: %w", sum, err)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	refs += delta
+	if refs > 0 {
+		return b.Backend.WriteFile(refsPath, []byte(strconv.Itoa(refs)+"\n"), 0644)
+	}
+	if err := b.Backend.Remove(b.blobPath(sum)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := b.Backend.Remove(refsPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Create implements RenameBackend. The content is streamed to a temporary blob while it is
+// hashed when the wrapped backend implements RenameBackend, and buffered otherwise; the
+// blob and the pointer are stored when the writer is closed.
+func (b *CASBackend) Create(name string, perm fs.FileMode) (io.WriteCloser, error) {
+	if b.inBlobDir(name) {
+		return b.create(name, perm)
+	}
+	if _, err := b.Backend.Stat(path_.Dir(name)); err != nil {
+		return nil, err
+	}
+	rb, ok := b.Backend.(RenameBackend)
+	if !ok {
+		return &casBuffer{backend: b, name: name, perm: perm}, nil
+	}
+	if err := b.Backend.MkdirAll(b.BlobDir, 0755); err != nil {
+		return nil, err
+	}
+	tmp := path_.Join(b.BlobDir, fmt.Sprintf(".%d.%d.tmp", os.Getpid(), b.tmp.Add(1)))
+	out, err := rb.Create(tmp, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &casWriter{backend: b, rb: rb, out: out, hash: sha256.New(), tmp: tmp, name: name, perm: perm}, nil
+}
+
+// create creates name in the wrapped backend, buffering the content if it isn't a RenameBackend.
+func (b *CASBackend) create(name string, perm fs.FileMode) (io.WriteCloser, error) {
+	if rb, ok := b.Backend.(RenameBackend); ok {
+		return rb.Create(name, perm)
+	}
+	return &casBuffer{backend: b, name: name, perm: perm}, nil
+}
+
+// casBuffer is a writer storing its content with WriteFile when it is closed.
+type casBuffer struct {
+	bytes.Buffer
+	backend *CASBackend
+	name    string
+	perm    fs.FileMode
+}
+
+func (w *casBuffer) Close() error {
+	return w.backend.WriteFile(w.name, w.Bytes(), w.perm)
+}
+
+// casWriter is a writer streaming to a temporary blob, renamed to its SHA-256 when it is closed.
+type casWriter struct {
+	backend *CASBackend
+	rb      RenameBackend
+	out     io.WriteCloser
+	hash    hash.Hash
+	tmp     string
+	name    string
+	perm    fs.FileMode
+}
+
+func (w *casWriter) Write(p []byte) (int, error) {
+	n, err := w.out.Write(p)
+	w.hash.Write(p[:n])
+	return n, err
+}
+
+// Sync syncs the temporary blob when the writer of the wrapped backend supports it.
+func (w *casWriter) Sync() error {
+	if syncer, ok := w.out.(interface{ Sync() error }); ok {
+		return syncer.Sync()
+	}
+	return nil
+}
+
+func (w *casWriter) Close() error {
+	if err := w.out.Close(); err != nil {
+		_ = w.backend.Backend.Remove(w.tmp)
+		return err
+	}
+	b := w.backend
+	sum := hex.EncodeToString(w.hash.Sum(nil))
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, err := b.Backend.Stat(b.blobPath(sum)); err == nil {
+		if err := b.Backend.Remove(w.tmp); err != nil {
+			return err
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		_ = b.Backend.Remove(w.tmp)
+		return err
+	} else if err := w.rb.Rename(w.tmp, b.blobPath(sum)); err != nil {
+		_ = b.Backend.Remove(w.tmp)
+		return err
+	}
+	return b.point(w.name, sum, w.perm, b.Backend.WriteFile)
+}
+
+// Rename implements RenameBackend, renaming the pointer and releasing the blob of the
+// pointer it replaces.
+func (b *CASBackend) Rename(oldname, newname string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	replaced, hadPointer, err := b.pointerAt(newname)
+	if err != nil {
+		return err
+	}
+	if rb, ok := b.Backend.(RenameBackend); ok {
+		err = rb.Rename(oldname, newname)
+	} else {
+		err = b.moveRaw(oldname, newname)
+	}
+	if err != nil || !hadPointer {
+		return err
+	}
+	return b.addRef(replaced, -1)
+}
+
+// moveRaw moves the content of oldname to newname without renaming it.
+func (b *CASBackend) moveRaw(oldname, newname string) error {
+	data, err := b.Backend.ReadFile(oldname)
+	if err != nil {
+		return err
+	}
+	if err := b.Backend.WriteFile(newname, data, 0644); err != nil {
+		return err
+	}
+	return b.Backend.Remove(oldname)
+}
+
+// Open implements Backend, opening the blob of a pointer.
+func (b *CASBackend) Open(name string) (fs.File, error) {
+	sum, ok, err := b.pointerAt(name)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return b.Backend.Open(b.blobPath(sum))
+	}
+	return b.Backend.Open(name)
+}
+
+// Remove implements Backend, releasing the blob of a pointer.
+func (b *CASBackend) Remove(name string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	sum, ok, err := b.pointerAt(name)
+	if err != nil {
+		return err
+	}
+	if err := b.Backend.Remove(name); err != nil {
+		return err
+	}
+	if ok {
+		return b.addRef(sum, -1)
+	}
+	return nil
+}
+
+// ReadDir implements Backend, the information of the pointers giving the size of their blob.
+func (b *CASBackend) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := b.Backend.ReadDir(name)
+	if err != nil || b.inBlobDir(name) {
+		return entries, err
+	}
+	for i, entry := range entries {
+		if !entry.IsDir() {
+			entries[i] = casEntry{DirEntry: entry, backend: b, name: path_.Join(name, entry.Name())}
+		}
+	}
+	return entries, nil
+}
+
+// Stat implements Backend, the information of a pointer giving the size of its blob.
+func (b *CASBackend) Stat(name string) (fs.FileInfo, error) {
+	info, err := b.Backend.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	return b.resolveInfo(name, info)
+}
+
+// resolveInfo returns the information of the file name, with the size of its blob if it is a pointer.
+func (b *CASBackend) resolveInfo(name string, info fs.FileInfo) (fs.FileInfo, error) {
+	sum, ok, err := b.pointerOf(name, info)
+	if err != nil || !ok {
+		return info, err
+	}
+	blob, err := b.Backend.Stat(b.blobPath(sum))
+	if err != nil {
+		return nil, err
+	}
+	return casInfo{FileInfo: info, size: blob.Size()}, nil
+}
+
+// MkdirAll implements Backend.
+func (b *CASBackend) MkdirAll(name string, perm fs.FileMode) error {
+	return b.Backend.MkdirAll(name, perm)
+}
+
+// EvalSymlinks implements SymlinkBackend when the wrapped backend does, and returns name as
+// is otherwise.
+func (b *CASBackend) EvalSymlinks(name string) (string, error) {
+	if sb, ok := b.Backend.(SymlinkBackend); ok {
+		return sb.EvalSymlinks(name)
+	}
+	return name, nil
+}
+
+// LockDir implements LockBackend when the wrapped backend does, and locks nothing otherwise.
+func (b *CASBackend) LockDir(name string, timeout time.Duration) (func(), error) {
+	if lb, ok := b.Backend.(LockBackend); ok {
+		return lb.LockDir(name, timeout)
+	}
+	return func() {}, nil
+}
+
+// casEntry is a fs.DirEntry of a CASBackend, whose information gives the size of the blob.
+type casEntry struct {
+	fs.DirEntry
+	backend *CASBackend
+	name    string
+}
+
+func (e casEntry) Info() (fs.FileInfo, error) {
+	info, err := e.DirEntry.Info()
+	if err != nil {
+		return nil, err
+	}
+	return e.backend.resolveInfo(e.name, info)
+}
+
+// casInfo is the information of a pointer with the size of its blob.
+type casInfo struct {
+	fs.FileInfo
+	size int64
+}
+
+func (i casInfo) Size() int64 { return i.size }
+
+// compact implements Compact for the pointers under root.
+func (b *CASBackend) compact(root string) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	refs := make(map[string]int)
+	if err := b.countRefs(root, refs); err != nil {
+		return 0, err
+	}
+	entries, err := b.Backend.ReadDir(b.BlobDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, entry := range entries {
+		sum := entry.Name()
+		if entry.IsDir() || len(sum) != sha256.Size*2 {
+			// reference counts and temporary blobs
+			continue
+		}
+		if _, err := hex.DecodeString(sum); err != nil {
+			continue
+		}
+		refsPath := b.blobPath(sum) + casRefsSuffix
+		if refs[sum] == 0 {
+			if err := b.Backend.Remove(b.blobPath(sum)); err != nil {
+				return removed, err
+			}
+			if err := b.Backend.Remove(refsPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return removed, err
+			}
+			removed++
+			continue
+		}
+		if err := b.Backend.WriteFile(refsPath, []byte(strconv.Itoa(refs[sum])+"\n"), 0644); err != nil {
+			return removed, err
+		}
+	}
+	return removed, nil
+}
+
+// countRefs counts the pointers to each blob in the tree under dir, skipping the blob directory.
+func (b *CASBackend) countRefs(dir string, refs map[string]int) error {
+	entries, err := b.Backend.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := path_.Join(dir, entry.Name())
+		if b.inBlobDir(name) {
+			continue
+		}
+		if entry.IsDir() {
+			if err := b.countRefs(name, refs); err != nil {
+				return err
+			}
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		sum, ok, err := b.pointerOf(name, info)
+		if err != nil {
+			return err
+		}
+		if ok {
+			refs[sum]++
+		}
+	}
+	return nil
+}
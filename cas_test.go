@@ -0,0 +1,140 @@
+package versionfs
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path"
+	"testing"
+)
+
+// blobNames returns the names of the files in the blob directory of vfs.
+func blobNames(t *testing.T, vfs *VersionFS) []string {
+	t.Helper()
+	b := vfs.Backend.(*CASBackend)
+	entries, err := b.Backend.ReadDir(b.BlobDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	assert.Nil(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func TestCASBackend_Dedup(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	vfs.EnableCAS()
+	league := fileLeague{season: 2023}
+	themes := fileThemes{}
+	first := putVersion(t, vfs, league, "20231018140523", "same")
+	second := putVersion(t, vfs, themes, "20231019140523", "same")
+	blobs := blobNames(t, vfs)
+	assert.Len(t, blobs, 2)
+	refs, err := vfs.Backend.(*CASBackend).Backend.ReadFile(path.Join(BlobDirName, blobs[0]+".refs"))
+	assert.Nil(t, err)
+	assert.Equal(t, "2\n", string(refs))
+
+	// the blob is kept until the last reference is removed
+	assert.Nil(t, vfs.Remove(league, first))
+	assert.Len(t, blobNames(t, vfs), 2)
+	data, err := vfs.Read(themes, second)
+	assert.Nil(t, err)
+	assert.Equal(t, "same", string(data))
+	assert.Nil(t, vfs.Remove(themes, second))
+	assert.Empty(t, blobNames(t, vfs))
+}
+
+func TestCASBackend_Transparent(t *testing.T) {
+	t.Parallel()
+	vfs := New(t.TempDir())
+	vfs.EnableCAS()
+	file := fileLeague{season: 2023}
+	ts := putVersion(t, vfs, file, "20231018140523", "first version")
+
+	data, err := vfs.ReadRange(file, ts, 6, 7)
+	assert.Nil(t, err)
+	assert.Equal(t, "version", string(data))
+	infos, err := vfs.VersionsWithInfo(file)
+	assert.Nil(t, err)
+	assert.Len(t, infos, 1)
+	assert.Equal(t, int64(13), infos[0].Size)
+	info, err := vfs.Backend.Stat(path.Join(vfs.RootPath, Path(file, ts)))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(13), info.Size())
+
+	// streamed writes are staged and stored as blobs too
+	published, err := vfs.PublishTo(file, ts, fileThemes{})
+	assert.Nil(t, err)
+	data, err = vfs.Read(fileThemes{}, published)
+	assert.Nil(t, err)
+	assert.Equal(t, "first version", string(data))
+	assert.Len(t, blobNames(t, vfs), 2)
+	raw, err := os.ReadFile(path.Join(vfs.RootPath, Path(fileThemes{}, published)))
+	assert.Nil(t, err)
+	assert.Contains(t, string(raw), casPointerPrefix)
+
+	// files written before CAS was enabled are read as they are
+	putRaw(t, New(vfs.RootPath), Path(file, published), "plain")
+	data, err = vfs.Read(file, published)
+	assert.Nil(t, err)
+	assert.Equal(t, "plain", string(data))
+}
+
+// A clone stores its blobs under its own root, so that compacting one root keeps the other's.
+func TestVersionFS_Clone_CAS(t *testing.T) {
+	t.Parallel()
+	vfs := New(t.TempDir())
+	vfs.EnableCAS()
+	file := fileLeague{season: 2023}
+	putVersion(t, vfs, file, "20231018140523", "original")
+	clone := vfs.Clone(t.TempDir())
+	assert.Equal(t, path.Join(clone.RootPath, BlobDirName), clone.Backend.(*CASBackend).BlobDir)
+	ts := putVersion(t, clone, file, "20231019140523", "cloned")
+	assert.Len(t, blobNames(t, vfs), 2)
+	assert.Len(t, blobNames(t, clone), 2)
+	removed, err := vfs.Compact()
+	assert.Nil(t, err)
+	assert.Zero(t, removed)
+	data, err := clone.Read(file, ts)
+	assert.Nil(t, err)
+	assert.Equal(t, "cloned", string(data))
+
+	// a blob directory out of the root stays shared
+	shared := NewMemory()
+	shared.Backend = NewCASBackend(shared.Backend, "blobs")
+	shared.RootPath = "root"
+	assert.Same(t, shared.Backend, shared.Clone("other").Backend)
+}
+
+func TestVersionFS_Compact(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	vfs.EnableCAS()
+	b := vfs.Backend.(*CASBackend)
+	file := fileLeague{season: 2023}
+	ts := putVersion(t, vfs, file, "20231018140523", "kept")
+	orphaned := putVersion(t, vfs, file, "20231019140523", "orphaned")
+
+	// a pointer removed behind the back of the backend leaves an orphaned blob
+	assert.Nil(t, b.Backend.Remove(Path(file, orphaned)))
+	assert.Len(t, blobNames(t, vfs), 4)
+	removed, err := vfs.Compact()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, removed)
+	assert.Len(t, blobNames(t, vfs), 2)
+	data, err := vfs.Read(file, ts)
+	assert.Nil(t, err)
+	assert.Equal(t, "kept", string(data))
+
+	// nothing more is removed by the next runs
+	removed, err = vfs.Compact()
+	assert.Nil(t, err)
+	assert.Zero(t, removed)
+
+	_, err = NewMemory().Compact()
+	assert.ErrorIs(t, err, errors.ErrUnsupported)
+}
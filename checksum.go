@@ -0,0 +1,66 @@
+package versionfs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	path_ "path"
+	"strings"
+)
+
+// ChecksumSuffix is appended to the path of a version to name its checksum sidecar.
+const ChecksumSuffix = ".sha256"
+
+// ErrChecksumMismatch is returned when the content of a version doesn't have the expected SHA-256.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// ErrNoChecksum is returned by Read when RequireChecksum is set and a version has no checksum sidecar.
+var ErrNoChecksum = errors.New("no checksum")
+
+// WriteChecksum writes the checksum sidecar of a version, next to it with the ChecksumSuffix
+// appended to its name, so that VerifyOnRead can detect its corruption later. The sidecar
+// has the format of sha256sum, and can be checked with sha256sum -c in the directory of
+// the version. Returns an error wrapping ErrVersionNotFound if the version doesn't exist.
+//
+// Example:
+//
+//	ts, err := vfs.Write(file, data)
+//	if err == nil {
+//	    err = vfs.WriteChecksum(file, ts)
+//	}
+func (v *VersionFS) WriteChecksum(file File, ts Timestamp) error {
+	name := v.resolvePath(file, ts)
+	if err := v.confine("checksum", name); err != nil {
+		return err
+	}
+	if err := v.restrict("checksum", name); err != nil {
+		return err
+	}
+	hash, err := v.hashPath(name)
+	if err != nil {
+		return err
+	}
+	line := hash + "  " + path_.Base(name) + "\n"
+	return v.writeAt(name+ChecksumSuffix, int64(len(line)), strings.NewReader(line))
+}
+
+// verify checks data, the content of the version at name relative to the root, against its checksum sidecar.
+func (v *VersionFS) verify(name string, data []byte) error {
+	sidecar, err := v.backend().ReadFile(path_.Join(v.RootPath, name+ChecksumSuffix))
+	if errors.Is(err, fs.ErrNotExist) {
+		if v.RequireChecksum {
+			return fmt.Errorf("%s: %w", name, ErrNoChecksum)
+		}
+		return nil
+	} else if err != nil {
+		return err
+	}
+	fields := strings.Fields(string(sidecar))
+	sum := sha256.Sum256(data)
+	if len(fields) == 0 || !strings.EqualFold(fields[0], hex.EncodeToString(sum[:])) {
+		return fmt.Errorf("%s: %w", name, ErrChecksumMismatch)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package versionfs
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestVersionFS_WriteChecksum(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	file := fileLeague{season: 2023}
+	ts := putVersion(t, vfs, file, "20231019140523", "data")
+	assert.Nil(t, vfs.WriteChecksum(file, ts))
+	sidecar, err := vfs.Backend.ReadFile("2023/league/league.txt.20231019140523.sha256")
+	assert.Nil(t, err)
+	assert.Equal(t, "3a6eb0790f39ac87c94f3856b2dd2c5d110e6811602261a9a923d3bb23adc8b7  league.txt.20231019140523\n", string(sidecar))
+
+	missing, _ := NewTimestamp("20231020140523")
+	assert.True(t, errors.Is(vfs.WriteChecksum(file, missing), ErrVersionNotFound))
+}
+
+func TestVersionFS_VerifyOnRead(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	vfs.VerifyOnRead = true
+	file := fileLeague{season: 2023}
+	ts := putVersion(t, vfs, file, "20231019140523", "data")
+	other := putVersion(t, vfs, file, "20231020140523", "other")
+	assert.Nil(t, vfs.WriteChecksum(file, ts))
+	data, err := vfs.Read(file, ts)
+	assert.Nil(t, err)
+	assert.Equal(t, "data", string(data))
+
+	// bit-rot
+	putRaw(t, vfs, "2023/league/league.txt.20231019140523", "dada")
+	data, err = vfs.Read(file, ts)
+	assert.True(t, errors.Is(err, ErrChecksumMismatch))
+	assert.Nil(t, data)
+
+	data, err = vfs.Read(file, other)
+	assert.Nil(t, err)
+	assert.Equal(t, "other", string(data))
+	vfs.RequireChecksum = true
+	_, err = vfs.Read(file, other)
+	assert.True(t, errors.Is(err, ErrNoChecksum))
+
+	vfs.VerifyOnRead = false
+	data, err = vfs.Read(file, ts)
+	assert.Nil(t, err)
+	assert.Equal(t, "dada", string(data))
+}
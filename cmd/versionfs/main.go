@@ -0,0 +1,382 @@
+// Command versionfs inspects and manages versioned trees from the command line. It works on
+// the naming convention alone, dir/name.ext.timestamp, without registering file types.
+//
+// Usage:
+//
+//	versionfs [-root dir] [-json] <command> [flags] [args]
+//
+// The commands are:
+//
+//	ls [dir]                      list the files of dir with their number of versions
+//	versions <dir/name.ext>       list the versions of a file, newest first
+//	cat [-ts ts] <dir/name.ext>   print a version of a file, the latest by default
+//	write <dir/name.ext>          write stdin to a new version of a file
+//	rm -ts ts <dir/name.ext>      remove a version of a file
+//	prune -keep n <dir/name.ext>  remove the versions of a file but the n newest
+//	check [dir]                   verify the versions under dir against their checksum sidecars
+//
+// It exits with status 1 when a command fails, and 2 when it is misused.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/sperano/versionfs"
+)
+
+// errUsage is returned by the commands when they are misused, the exit status is then 2.
+var errUsage = errors.New("usage")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// cli is the state shared by the commands.
+type cli struct {
+	vfs    *versionfs.VersionFS
+	json   bool
+	stdin  io.Reader
+	stdout io.Writer
+}
+
+// run runs the command of args and returns the exit status.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("versionfs", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	root := flags.String("root", ".", "root directory of the tree")
+	asJSON := flags.Bool("json", false, "print the results as JSON")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: versionfs [-root dir] [-json] ls|versions|cat|write|rm|prune|check [flags] [args]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+	c := &cli{vfs: versionfs.New(*root), json: *asJSON, stdin: stdin, stdout: stdout}
+	commands := map[string]func([]string) error{
+		"ls":       c.ls,
+		"versions": c.versions,
+		"cat":      c.cat,
+		"write":    c.write,
+		"rm":       c.rm,
+		"prune":    c.prune,
+		"check":    c.check,
+	}
+	command, ok := commands[flags.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "versionfs: unknown command %q\n", flags.Arg(0))
+		flags.Usage()
+		return 2
+	}
+	if err := command(flags.Args()[1:]); errors.Is(err, errUsage) {
+		fmt.Fprintf(stderr, "versionfs %s: %s\n", flags.Arg(0), err)
+		return 2
+	} else if err != nil {
+		fmt.Fprintf(stderr, "versionfs %s: %s\n", flags.Arg(0), err)
+		return 1
+	}
+	return 0
+}
+
+// file is a File named by a path following the naming convention, dir/name.ext.
+type file struct {
+	dir, name, ext string
+}
+
+func (f file) Dir() string  { return f.dir }
+func (f file) Name() string { return f.name }
+func (f file) Ext() string  { return f.ext }
+
+// parseFile returns the file named by p, dir/name.ext, where name can't contain dots,
+// like ParseFilename.
+func parseFile(p string) (file, error) {
+	p = path.Clean(p)
+	base := path.Base(p)
+	dot := strings.IndexByte(base, '.')
+	if dot <= 0 || dot == len(base)-1 {
+		return file{}, fmt.Errorf("%w: invalid file %q, expected dir/name.ext", errUsage, p)
+	}
+	return file{dir: path.Dir(p), name: base[:dot], ext: base[dot+1:]}, nil
+}
+
+// parseArgs parses the flags of a command and returns its positional arguments, failing
+// if there aren't between min and max of them.
+func parseArgs(flags *flag.FlagSet, args []string, min, max int) ([]string, error) {
+	flags.SetOutput(io.Discard)
+	if err := flags.Parse(args); err != nil {
+		return nil, fmt.Errorf("%w: %s", errUsage, err)
+	}
+	if flags.NArg() < min || flags.NArg() > max {
+		return nil, fmt.Errorf("%w: expected %d to %d arguments, got %d", errUsage, min, max, flags.NArg())
+	}
+	return flags.Args(), nil
+}
+
+// fileArg parses the flags of a command taking a single file argument, and returns the file.
+func fileArg(flags *flag.FlagSet, args []string) (file, error) {
+	rest, err := parseArgs(flags, args, 1, 1)
+	if err != nil {
+		return file{}, err
+	}
+	return parseFile(rest[0])
+}
+
+// print prints v as JSON with -json, and the lines returned by text otherwise.
+func (c *cli) print(v any, text func() []string) error {
+	if c.json {
+		enc := json.NewEncoder(c.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	for _, line := range text() {
+		if _, err := fmt.Fprintln(c.stdout, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listedFile is a file listed by ls.
+type listedFile struct {
+	File     string              `json:"file"`
+	Versions int                 `json:"versions"`
+	Latest   versionfs.Timestamp `json:"latest"`
+}
+
+func (c *cli) ls(args []string) error {
+	rest, err := parseArgs(flag.NewFlagSet("ls", flag.ContinueOnError), args, 0, 1)
+	if err != nil {
+		return err
+	}
+	dir := "."
+	if len(rest) == 1 {
+		dir = path.Clean(rest[0])
+	}
+	if !fs.ValidPath(dir) {
+		return fmt.Errorf("%w: invalid directory %q", errUsage, dir)
+	}
+	entries, err := c.vfs.Backend.ReadDir(path.Join(c.vfs.RootPath, dir))
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]*listedFile)
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		name, ext, ts, err := versionfs.ParseFilename(entry.Name())
+		if err != nil {
+			continue
+		}
+		f := file{dir: dir, name: name, ext: ext}
+		key := versionfs.BasePath(f)
+		listed, ok := byName[key]
+		if !ok {
+			listed = &listedFile{File: key}
+			byName[key] = listed
+		}
+		listed.Versions++
+		if ts.Time().After(listed.Latest.Time()) {
+			listed.Latest = ts
+		}
+	}
+	files := make([]listedFile, 0, len(byName))
+	for _, listed := range byName {
+		files = append(files, *listed)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].File < files[j].File })
+	return c.print(files, func() []string {
+		lines := make([]string, len(files))
+		for i, f := range files {
+			lines[i] = fmt.Sprintf("%s\t%d\t%s", f.File, f.Versions, f.Latest)
+		}
+		return lines
+	})
+}
+
+func (c *cli) versions(args []string) error {
+	f, err := fileArg(flag.NewFlagSet("versions", flag.ContinueOnError), args)
+	if err != nil {
+		return err
+	}
+	infos, err := c.vfs.VersionsWithInfo(f)
+	if err != nil {
+		return err
+	}
+	if infos == nil {
+		infos = []versionfs.VersionInfo{}
+	}
+	return c.print(infos, func() []string {
+		lines := make([]string, len(infos))
+		for i, info := range infos {
+			lines[i] = fmt.Sprintf("%s\t%d", info.Timestamp, info.Size)
+		}
+		return lines
+	})
+}
+
+// timestampFlag parses the -ts flag, the zero Timestamp when it isn't set.
+func timestampFlag(s string) (versionfs.Timestamp, error) {
+	if s == "" {
+		return versionfs.Timestamp{}, nil
+	}
+	ts, err := versionfs.NewTimestamp(s)
+	if err != nil {
+		return versionfs.Timestamp{}, fmt.Errorf("%w: invalid timestamp %q", errUsage, s)
+	}
+	return ts, nil
+}
+
+func (c *cli) cat(args []string) error {
+	flags := flag.NewFlagSet("cat", flag.ContinueOnError)
+	tsFlag := flags.String("ts", "", "timestamp of the version, the latest by default")
+	f, err := fileArg(flags, args)
+	if err != nil {
+		return err
+	}
+	ts, err := timestampFlag(*tsFlag)
+	if err != nil {
+		return err
+	}
+	if ts.IsZero() {
+		if ts, err = c.vfs.LastVersion(f); err != nil {
+			return err
+		}
+	}
+	data, err := c.vfs.Read(f, ts)
+	if err != nil {
+		return err
+	}
+	_, err = c.stdout.Write(data)
+	return err
+}
+
+func (c *cli) write(args []string) error {
+	f, err := fileArg(flag.NewFlagSet("write", flag.ContinueOnError), args)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(c.stdin)
+	if err != nil {
+		return err
+	}
+	ts, err := c.vfs.Write(f, data)
+	if err != nil {
+		return err
+	}
+	return c.print(map[string]versionfs.Timestamp{"timestamp": ts}, func() []string {
+		return []string{ts.String()}
+	})
+}
+
+func (c *cli) rm(args []string) error {
+	flags := flag.NewFlagSet("rm", flag.ContinueOnError)
+	tsFlag := flags.String("ts", "", "timestamp of the version to remove")
+	f, err := fileArg(flags, args)
+	if err != nil {
+		return err
+	}
+	ts, err := timestampFlag(*tsFlag)
+	if err != nil {
+		return err
+	} else if ts.IsZero() {
+		return fmt.Errorf("%w: -ts is required", errUsage)
+	}
+	if err := c.vfs.Remove(f, ts); err != nil {
+		return err
+	}
+	return c.print(map[string]versionfs.Timestamp{"removed": ts}, func() []string {
+		return []string{ts.String()}
+	})
+}
+
+func (c *cli) prune(args []string) error {
+	flags := flag.NewFlagSet("prune", flag.ContinueOnError)
+	keep := flags.Int("keep", 0, "number of newest versions to keep")
+	f, err := fileArg(flags, args)
+	if err != nil {
+		return err
+	}
+	if *keep < 1 {
+		return fmt.Errorf("%w: -keep must be at least 1", errUsage)
+	}
+	removed, err := c.vfs.Prune(f, versionfs.RetentionPolicy{KeepLast: *keep})
+	if err != nil {
+		return err
+	}
+	if removed == nil {
+		removed = []versionfs.Timestamp{}
+	}
+	return c.print(map[string][]versionfs.Timestamp{"removed": removed}, func() []string {
+		lines := make([]string, len(removed))
+		for i, ts := range removed {
+			lines[i] = ts.String()
+		}
+		return lines
+	})
+}
+
+// checkError is a version failing check.
+type checkError struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// checkReport is the outcome of check.
+type checkReport struct {
+	Checked int          `json:"checked"`
+	Errors  []checkError `json:"errors"`
+}
+
+// errCheckFailed is returned by check when some versions fail.
+var errCheckFailed = errors.New("check failed")
+
+func (c *cli) check(args []string) error {
+	rest, err := parseArgs(flag.NewFlagSet("check", flag.ContinueOnError), args, 0, 1)
+	if err != nil {
+		return err
+	}
+	dir := ""
+	if len(rest) == 1 {
+		dir = rest[0]
+	}
+	c.vfs.VerifyOnRead = true
+	report := checkReport{Errors: []checkError{}}
+	err = c.vfs.WalkVersions(dir, func(dir, name, ext string, ts versionfs.Timestamp, info fs.FileInfo) error {
+		f := file{dir: dir, name: name, ext: ext}
+		report.Checked++
+		if _, err := c.vfs.Read(f, ts); err != nil {
+			report.Errors = append(report.Errors, checkError{Path: versionfs.Path(f, ts), Error: err.Error()})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := c.print(report, func() []string {
+		var lines []string
+		for _, e := range report.Errors {
+			lines = append(lines, fmt.Sprintf("%s: %s", e.Path, e.Error))
+		}
+		return append(lines, fmt.Sprintf("%d versions checked, %d errors", report.Checked, len(report.Errors)))
+	}); err != nil {
+		return err
+	}
+	if len(report.Errors) > 0 {
+		return fmt.Errorf("%w: %d of %d versions", errCheckFailed, len(report.Errors), report.Checked)
+	}
+	return nil
+}
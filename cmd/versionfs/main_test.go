@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// binary is the path of the versionfs binary built by TestMain.
+var binary string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "versionfs-cli")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	binary = filepath.Join(dir, "versionfs")
+	if out, err := exec.Command("go", "build", "-o", binary, ".").CombinedOutput(); err != nil {
+		fmt.Fprintf(os.Stderr, "building versionfs: %s\n%s", err, out)
+		os.Exit(1)
+	}
+	code := m.Run()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// runCLI runs the binary on the tree at root with stdin, and returns its output and exit status.
+func runCLI(t *testing.T, root, stdin string, args ...string) (string, string, int) {
+	t.Helper()
+	cmd := exec.Command(binary, append([]string{"-root", root}, args...)...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return stdout.String(), stderr.String(), exitErr.ExitCode()
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	return stdout.String(), stderr.String(), 0
+}
+
+// putVersion stores a version with a known timestamp under root.
+func putVersion(t *testing.T, root, name, data string) {
+	t.Helper()
+	p := filepath.Join(root, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCLI_WriteCat(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	stdout, stderr, code := runCLI(t, root, "hello", "-json", "write", "2023/league/league.txt")
+	assert.Equal(t, 0, code, stderr)
+	var written struct{ Timestamp string }
+	assert.Nil(t, json.Unmarshal([]byte(stdout), &written))
+	_, err := os.Stat(filepath.Join(root, "2023", "league", "league.txt."+written.Timestamp))
+	assert.Nil(t, err)
+
+	putVersion(t, root, "2023/league/league.txt.20211125011947", "old")
+	stdout, _, code = runCLI(t, root, "", "cat", "2023/league/league.txt")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "hello", stdout)
+	stdout, _, code = runCLI(t, root, "", "cat", "-ts", "20211125011947", "2023/league/league.txt")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "old", stdout)
+
+	_, stderr, code = runCLI(t, root, "", "cat", "-ts", "20201125011947", "2023/league/league.txt")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "version not found")
+}
+
+func TestCLI_LsVersions(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	putVersion(t, root, "2023/league/league.txt.20211125011946", "1")
+	putVersion(t, root, "2023/league/league.txt.20211218030527", "333")
+	putVersion(t, root, "2023/league/themes.csv.gz.20211125011947", "2")
+	putVersion(t, root, "2023/league/notes.txt", "not a version")
+	putVersion(t, root, "2023/league/nested/league.txt.20211125011948", "nested")
+
+	stdout, _, code := runCLI(t, root, "", "ls", "2023/league")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "2023/league/league.txt\t2\t20211218030527\n2023/league/themes.csv.gz\t1\t20211125011947\n", stdout)
+
+	stdout, _, code = runCLI(t, root, "", "-json", "ls", "2023/league")
+	assert.Equal(t, 0, code)
+	var files []struct {
+		File     string
+		Versions int
+		Latest   string
+	}
+	assert.Nil(t, json.Unmarshal([]byte(stdout), &files))
+	assert.Len(t, files, 2)
+	assert.Equal(t, "2023/league/themes.csv.gz", files[1].File)
+
+	stdout, _, code = runCLI(t, root, "", "versions", "2023/league/league.txt")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "20211218030527\t3\n20211125011946\t1\n", stdout)
+	stdout, _, code = runCLI(t, root, "", "-json", "versions", "2023/league/missing.txt")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "[]\n", stdout)
+}
+
+func TestCLI_RmPrune(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	putVersion(t, root, "2023/league/league.txt.20211125011946", "1")
+	putVersion(t, root, "2023/league/league.txt.20211125011947", "2")
+	putVersion(t, root, "2023/league/league.txt.20211218030527", "3")
+	putVersion(t, root, "2023/league/league.txt.20211218030528", "4")
+
+	stdout, _, code := runCLI(t, root, "", "rm", "-ts", "20211218030528", "2023/league/league.txt")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "20211218030528\n", stdout)
+	_, _, code = runCLI(t, root, "", "rm", "-ts", "20211218030528", "2023/league/league.txt")
+	assert.Equal(t, 1, code)
+
+	stdout, _, code = runCLI(t, root, "", "-json", "prune", "-keep", "1", "2023/league/league.txt")
+	assert.Equal(t, 0, code)
+	var pruned struct{ Removed []string }
+	assert.Nil(t, json.Unmarshal([]byte(stdout), &pruned))
+	assert.Equal(t, []string{"20211125011947", "20211125011946"}, pruned.Removed)
+	stdout, _, code = runCLI(t, root, "", "versions", "2023/league/league.txt")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "20211218030527\t1\n", stdout)
+}
+
+func TestCLI_Check(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	putVersion(t, root, "2023/league/league.txt.20211125011946", "1")
+	putVersion(t, root, "2023/league/league.txt.20211125011947", "2")
+	putVersion(t, root, "2023/league/league.txt.20211125011947.sha256",
+		"6b86b273ff34fce19d6b804eff5a3f5747ada4eaa22f1d49c01e52ddb7875b4b  league.txt.20211125011947\n")
+
+	stdout, _, code := runCLI(t, root, "", "check")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stdout, "2023/league/league.txt.20211125011947: ")
+	assert.Contains(t, stdout, "2 versions checked, 1 errors")
+
+	putVersion(t, root, "2023/league/league.txt.20211125011947", "1")
+	stdout, _, code = runCLI(t, root, "", "-json", "check", "2023")
+	assert.Equal(t, 0, code)
+	assert.JSONEq(t, `{"checked": 2, "errors": []}`, stdout)
+}
+
+func TestCLI_Usage(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	for _, args := range [][]string{
+		{},
+		{"unknown"},
+		{"cat"},
+		{"cat", "2023/league/league"},
+		{"cat", "-ts", "yesterday", "2023/league/league.txt"},
+		{"rm", "2023/league/league.txt"},
+		{"prune", "2023/league/league.txt"},
+		{"ls", "../outside"},
+	} {
+		_, stderr, code := runCLI(t, root, "", args...)
+		assert.Equal(t, 2, code, "%v", args)
+		assert.NotEmpty(t, stderr, "%v", args)
+	}
+}
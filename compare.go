@@ -0,0 +1,88 @@
+package versionfs
+
+import (
+	"bytes"
+	"errors"
+	path_ "path"
+)
+
+// LatestEqual reports whether the latest versions of a file are identical in this instance
+// and in other, for example two roots of a blue/green deployment. The sizes are compared
+// first, then the contents. It returns false without error if either side has no versions,
+// while storage failures are returned as errors. Nothing is modified.
+//
+// Example:
+//
+//	same, err := blue.LatestEqual(green, file)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	if !same {
+//	    fmt.Println("green is not ready")
+//	}
+func (v *VersionFS) LatestEqual(other *VersionFS, file File) (bool, error) {
+	ts, err := v.localLastVersion(file)
+	if errors.Is(err, ErrNoVersions) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	otherTs, err := other.localLastVersion(file)
+	if errors.Is(err, ErrNoVersions) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	info, err := v.backend().Stat(path_.Join(v.RootPath, v.resolvePath(file, ts)))
+	if err != nil {
+		return false, versionNotFound(err)
+	}
+	otherInfo, err := other.backend().Stat(path_.Join(other.RootPath, other.resolvePath(file, otherTs)))
+	if err != nil {
+		return false, versionNotFound(err)
+	}
+	if info.Size() != otherInfo.Size() {
+		return false, nil
+	}
+	data, err := v.Read(file, ts)
+	if err != nil {
+		return false, err
+	}
+	otherData, err := other.Read(file, otherTs)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(data, otherData), nil
+}
+
+// LatestMatches reports whether the latest version of a file contains exactly data, for
+// idempotent publishers checking whether a write is needed. The size is compared first,
+// then the contents. It returns false without error if the file has no versions, and
+// nothing is modified.
+//
+// Example:
+//
+//	same, err := vfs.LatestMatches(file, data)
+//	if err == nil && !same {
+//	    _, err = vfs.Write(file, data)
+//	}
+func (v *VersionFS) LatestMatches(file File, data []byte) (bool, error) {
+	ts, err := v.localLastVersion(file)
+	if errors.Is(err, ErrNoVersions) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	info, err := v.backend().Stat(path_.Join(v.RootPath, v.resolvePath(file, ts)))
+	if err != nil {
+		return false, versionNotFound(err)
+	}
+	if info.Size() != int64(len(data)) {
+		return false, nil
+	}
+	latest, err := v.Read(file, ts)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(latest, data), nil
+}
@@ -0,0 +1,84 @@
+package versionfs
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io/fs"
+	"testing"
+)
+
+func TestVersionFS_LatestEqual(t *testing.T) {
+	t.Parallel()
+	blue, green := NewMemory(), NewMemory()
+	file := fileLeague{season: 2023}
+	equal, err := blue.LatestEqual(green, file)
+	assert.Nil(t, err)
+	assert.False(t, equal)
+
+	putVersion(t, blue, file, "20211125011947", "old")
+	putVersion(t, blue, file, "20231019140523", "same")
+	equal, err = blue.LatestEqual(green, file)
+	assert.Nil(t, err)
+	assert.False(t, equal)
+	equal, err = green.LatestEqual(blue, file)
+	assert.Nil(t, err)
+	assert.False(t, equal)
+
+	// the timestamps may differ, only the contents are compared
+	putVersion(t, green, file, "20231019150000", "same")
+	equal, err = blue.LatestEqual(green, file)
+	assert.Nil(t, err)
+	assert.True(t, equal)
+
+	putVersion(t, green, file, "20231019160000", "diff")
+	equal, err = blue.LatestEqual(green, file)
+	assert.Nil(t, err)
+	assert.False(t, equal)
+	putVersion(t, green, file, "20231019170000", "different size")
+	equal, err = blue.LatestEqual(green, file)
+	assert.Nil(t, err)
+	assert.False(t, equal)
+}
+
+// failingBackend is a MemoryBackend whose directory listings fail.
+type failingBackend struct {
+	*MemoryBackend
+}
+
+func (failingBackend) ReadDir(name string) ([]fs.DirEntry, error) {
+	return nil, fs.ErrPermission
+}
+
+func TestVersionFS_LatestEqual_Error(t *testing.T) {
+	t.Parallel()
+	blue, green := NewMemory(), NewMemory()
+	file := fileLeague{season: 2023}
+	putVersion(t, blue, file, "20231019140523", "same")
+	putVersion(t, green, file, "20231019140523", "same")
+	green.Backend = failingBackend{green.Backend.(*MemoryBackend)}
+	_, err := blue.LatestEqual(green, file)
+	assert.True(t, errors.Is(err, fs.ErrPermission))
+	_, err = green.LatestEqual(blue, file)
+	assert.True(t, errors.Is(err, fs.ErrPermission))
+}
+
+func TestVersionFS_LatestMatches(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	file := fileLeague{season: 2023}
+	same, err := vfs.LatestMatches(file, []byte("same"))
+	assert.Nil(t, err)
+	assert.False(t, same)
+
+	putVersion(t, vfs, file, "20211125011947", "old")
+	putVersion(t, vfs, file, "20231019140523", "same")
+	same, err = vfs.LatestMatches(file, []byte("same"))
+	assert.Nil(t, err)
+	assert.True(t, same)
+	same, err = vfs.LatestMatches(file, []byte("diff"))
+	assert.Nil(t, err)
+	assert.False(t, same)
+	same, err = vfs.LatestMatches(file, []byte("old"))
+	assert.Nil(t, err)
+	assert.False(t, same)
+}
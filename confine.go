@@ -0,0 +1,170 @@
+package versionfs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	path_ "path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ErrOutsideRoot is returned when the path of an operation resolves outside the root: a
+// directory or name containing "..", an absolute directory, or, with RestrictToRoot, a
+// symbolic link in the tree pointing out of it. Operations on such paths fail without
+// touching the storage.
+var ErrOutsideRoot = errors.New("path outside root")
+
+// ErrPathEscapesRoot is returned, when RestrictToRoot is set, when the path of an operation
+// resolves outside the root through symbolic links, or when Write and Read can't resolve it.
+// It wraps ErrOutsideRoot.
+var ErrPathEscapesRoot = fmt.Errorf("path escapes root through symbolic links: %w", ErrOutsideRoot)
+
+// SymlinkBackend is implemented by the backends where files and directories may be symbolic
+// links, such as the local filesystem, so that the operations are confined to the root even
+// when a link inside the tree points out of it.
+type SymlinkBackend interface {
+	// EvalSymlinks returns the path of an existing file or directory after the evaluation of
+	// the symbolic links it contains.
+	EvalSymlinks(name string) (string, error)
+}
+
+// EvalSymlinks implements SymlinkBackend with filepath.EvalSymlinks.
+func (OSBackend) EvalSymlinks(name string) (string, error) {
+	return filepath.EvalSymlinks(filepath.FromSlash(name))
+}
+
+// confine fails with an *fs.PathError wrapping ErrOutsideRoot if name, relative to the root,
+// is absolute or goes up out of the root. With RestrictToRoot, it also fails with one wrapping
+// ErrPathEscapesRoot if the nearest existing parent of name, or name itself if it exists,
+// resolves outside the root through symbolic links, or can't be resolved, such as a loop of
+// links or a directory that can't be read. Since every operation checks its paths with
+// it, it also fails with one wrapping ErrShardedScheme if the layout options conflict.
+func (v *VersionFS) confine(op, name string) error {
+	if err := v.checkLayout(); err != nil {
+		return &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	cleaned := path_.Clean(slashed(name))
+	if path_.IsAbs(cleaned) || filepath.IsAbs(name) || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return &fs.PathError{Op: op, Path: name, Err: ErrOutsideRoot}
+	}
+	if !v.RestrictToRoot {
+		return nil
+	}
+	sb, ok := v.Backend.(SymlinkBackend)
+	if !ok {
+		return nil
+	}
+	evalSymlinks := v.evalSymlinks(sb)
+	escapes := &fs.PathError{Op: op, Path: name, Err: ErrPathEscapesRoot}
+	// a missing root is left to the operation to report
+	root, err := v.resolveRoot(sb)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if errors.Is(err, ErrTimeout) {
+		return err
+	} else if err != nil {
+		return escapes
+	}
+	target := path_.Join(v.RootPath, cleaned)
+	var resolved string
+	for {
+		if resolved, err = evalSymlinks(target); err == nil {
+			break
+		} else if errors.Is(err, ErrTimeout) {
+			return err
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return escapes
+		}
+		target = path_.Dir(target)
+	}
+	if !within(root, resolved) {
+		return escapes
+	}
+	return nil
+}
+
+// restrict implements RestrictToRoot: it fails with an *fs.PathError wrapping ErrPathEscapesRoot
+// if name, relative to the root, or its directory if name doesn't exist, resolves outside the
+// root through symbolic links or can't be resolved. A missing root or directory is left to the
+// operation to report.
+func (v *VersionFS) restrict(op, name string) error {
+	if !v.RestrictToRoot {
+		return nil
+	}
+	sb, ok := v.Backend.(SymlinkBackend)
+	if !ok {
+		return nil
+	}
+	evalSymlinks := v.evalSymlinks(sb)
+	escapes := &fs.PathError{Op: op, Path: name, Err: ErrPathEscapesRoot}
+	root, err := v.resolveRoot(sb)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if errors.Is(err, ErrTimeout) {
+		return err
+	} else if err != nil {
+		return escapes
+	}
+	target := path_.Join(v.RootPath, path_.Clean(slashed(name)))
+	resolved, err := evalSymlinks(target)
+	if errors.Is(err, fs.ErrNotExist) {
+		resolved, err = evalSymlinks(path_.Dir(target))
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+	}
+	if errors.Is(err, ErrTimeout) {
+		return err
+	} else if err != nil || !within(root, resolved) {
+		return escapes
+	}
+	return nil
+}
+
+// resolvedRoot caches the root resolved through symbolic links by confine and restrict, keyed
+// by RootPath since it may be set after the instance is created.
+type resolvedRoot struct {
+	mu       sync.Mutex
+	root     string
+	resolved string
+}
+
+// resolveRoot returns RootPath resolved through the symbolic links of sb. The root is
+// resolved once and cached; failures aren't cached, so that a root created later is resolved.
+func (v *VersionFS) resolveRoot(sb SymlinkBackend) (string, error) {
+	if v.root != nil {
+		v.root.mu.Lock()
+		root, resolved := v.root.root, v.root.resolved
+		v.root.mu.Unlock()
+		if resolved != "" && root == v.RootPath {
+			return resolved, nil
+		}
+	}
+	resolved, err := v.evalSymlinks(sb)(path_.Clean(v.RootPath))
+	if err != nil {
+		return "", err
+	}
+	if v.root != nil {
+		v.root.mu.Lock()
+		v.root.root, v.root.resolved = v.RootPath, resolved
+		v.root.mu.Unlock()
+	}
+	return resolved, nil
+}
+
+// evalSymlinks returns a function calling the EvalSymlinks of sb with the timeout and retries of v.
+func (v *VersionFS) evalSymlinks(sb SymlinkBackend) func(string) (string, error) {
+	return func(name string) (string, error) {
+		return callBackend(v, "lstat", name, func() (string, error) {
+			return sb.EvalSymlinks(name)
+		})
+	}
+}
+
+// within reports whether the resolved path is root or under it.
+func within(root, resolved string) bool {
+	rel, err := filepath.Rel(root, resolved)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,222 @@
+package versionfs
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// assertOutsideRoot runs the operations confined to the root on file and dir, and checks that they all fail with ErrOutsideRoot.
+func assertOutsideRoot(t *testing.T, vfs *VersionFS, file File, dir string) {
+	t.Helper()
+	ts, _ := NewTimestamp("20231019140523")
+	_, err := vfs.Write(file, []byte("data"))
+	assert.True(t, errors.Is(err, ErrOutsideRoot), "write: %v", err)
+	_, err = vfs.Read(file, ts)
+	assert.True(t, errors.Is(err, ErrOutsideRoot), "read: %v", err)
+	_, err = vfs.ReadRange(file, ts, 0, -1)
+	assert.True(t, errors.Is(err, ErrOutsideRoot), "read range: %v", err)
+	err = vfs.Remove(file, ts)
+	assert.True(t, errors.Is(err, ErrOutsideRoot), "remove: %v", err)
+	_, err = vfs.Versions(file)
+	assert.True(t, errors.Is(err, ErrOutsideRoot), "versions: %v", err)
+	_, err = vfs.Find(dir, file)
+	assert.True(t, errors.Is(err, ErrOutsideRoot), "find: %v", err)
+	err = vfs.MkdirAll(dir, 0755)
+	assert.True(t, errors.Is(err, ErrOutsideRoot), "mkdir: %v", err)
+	_, err = vfs.PathExists(dir)
+	assert.True(t, errors.Is(err, ErrOutsideRoot), "path exists: %v", err)
+	_, err = vfs.FindAnyExt(dir, file.Name())
+	assert.True(t, errors.Is(err, ErrOutsideRoot), "find any ext: %v", err)
+	_, _, err = vfs.DetectDir(dir, file)
+	assert.True(t, errors.Is(err, ErrOutsideRoot), "detect dir: %v", err)
+	err = vfs.WalkVersions(dir, func(string, string, string, Timestamp, os.FileInfo) error { return nil })
+	assert.True(t, errors.Is(err, ErrOutsideRoot), "walk: %v", err)
+	_, err = vfs.CountVersionsRecursive(dir)
+	assert.True(t, errors.Is(err, ErrOutsideRoot), "count: %v", err)
+	_, err = vfs.Prune(file, RetentionPolicy{KeepLast: 1})
+	assert.True(t, errors.Is(err, ErrOutsideRoot), "prune: %v", err)
+	_, err = vfs.PruneDir(dir, file, RetentionPolicy{KeepLast: 1})
+	assert.True(t, errors.Is(err, ErrOutsideRoot), "prune dir: %v", err)
+	_, err = vfs.PruneDirPrefix(dir, file.Name(), RetentionPolicy{KeepLast: 1})
+	assert.True(t, errors.Is(err, ErrOutsideRoot), "prune dir prefix: %v", err)
+	_, err = vfs.ChangeExt(dir, file.Name(), file.Ext(), "bak")
+	assert.True(t, errors.Is(err, ErrOutsideRoot), "change ext: %v", err)
+	_, err = vfs.Version(file, ts).WriteTo(io.Discard)
+	assert.True(t, errors.Is(err, ErrOutsideRoot), "write to: %v", err)
+	inside := fileLeague{season: 2023}
+	_, err = vfs.PublishTo(file, ts, inside)
+	assert.True(t, errors.Is(err, ErrOutsideRoot), "publish from: %v", err)
+	_, err = vfs.PublishTo(inside, ts, file)
+	assert.True(t, errors.Is(err, ErrOutsideRoot), "publish to: %v", err)
+	err = vfs.WriteChecksum(file, ts)
+	assert.True(t, errors.Is(err, ErrOutsideRoot), "write checksum: %v", err)
+}
+
+func TestVersionFS_OutsideRoot_Traversal(t *testing.T) {
+	t.Parallel()
+	parent := t.TempDir()
+	vfs := New(filepath.Join(parent, "root"))
+	assert.Nil(t, os.Mkdir(vfs.RootPath, 0755))
+	assertOutsideRoot(t, vfs, aliasFile{dir: "../escaped", alias: Alias{Name: "league", Ext: "txt"}}, "../escaped")
+	assertOutsideRoot(t, vfs, aliasFile{dir: "2023/../../escaped", alias: Alias{Name: "league", Ext: "txt"}}, "2023/../..")
+	_, err := os.Stat(filepath.Join(parent, "escaped"))
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+
+	// the memory backend is confined too
+	assertOutsideRoot(t, NewMemory(), aliasFile{dir: "..", alias: Alias{Name: "league", Ext: "txt"}}, "..")
+}
+
+// The streaming and checksum operations don't touch the files of an existing directory
+// next to the root, even without CreateDirs.
+func TestVersionFS_OutsideRoot_Existing(t *testing.T) {
+	t.Parallel()
+	parent := t.TempDir()
+	vfs := New(filepath.Join(parent, "root"))
+	vfs.CreateDirs = false
+	inside := fileLeague{season: 2023}
+	ts := putVersion(t, vfs, inside, "20231019140523", "inside")
+	assert.Nil(t, os.Mkdir(filepath.Join(parent, "x"), 0755))
+	assert.Nil(t, os.WriteFile(filepath.Join(parent, "x", "pwn.txt.20231019140523"), []byte("outside"), 0644))
+	outside := aliasFile{dir: "../x", alias: Alias{Name: "pwn", Ext: "txt"}}
+
+	var buf bytes.Buffer
+	_, err := vfs.Version(outside, ts).WriteTo(&buf)
+	assert.ErrorIs(t, err, ErrOutsideRoot)
+	assert.Empty(t, buf.String())
+	_, err = vfs.PublishTo(outside, ts, inside)
+	assert.ErrorIs(t, err, ErrOutsideRoot)
+	_, err = vfs.PublishTo(inside, ts, outside)
+	assert.ErrorIs(t, err, ErrOutsideRoot)
+	assert.ErrorIs(t, vfs.WriteChecksum(outside, ts), ErrOutsideRoot)
+	entries, err := os.ReadDir(filepath.Join(parent, "x"))
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1)
+	versions, err := vfs.Versions(inside)
+	assert.Nil(t, err)
+	assert.Len(t, versions, 1)
+}
+
+func TestVersionFS_OutsideRoot_Absolute(t *testing.T) {
+	t.Parallel()
+	vfs := New(t.TempDir())
+	assertOutsideRoot(t, vfs, aliasFile{dir: "/etc", alias: Alias{Name: "league", Ext: "txt"}}, "/etc")
+}
+
+func TestVersionFS_OutsideRoot_Symlink(t *testing.T) {
+	t.Parallel()
+	outside := t.TempDir()
+	vfs := New(t.TempDir())
+	vfs.RestrictToRoot = true
+	if err := os.Symlink(outside, filepath.Join(vfs.RootPath, "2023")); err != nil {
+		t.Skipf("symbolic links not supported: %s", err)
+	}
+	assertOutsideRoot(t, vfs, aliasFile{dir: "2023/league", alias: Alias{Name: "league", Ext: "txt"}}, "2023/league")
+	entries, err := os.ReadDir(outside)
+	assert.Nil(t, err)
+	assert.Empty(t, entries)
+
+	// links within the root are followed
+	assert.Nil(t, os.Mkdir(filepath.Join(vfs.RootPath, "2024"), 0755))
+	assert.Nil(t, os.Symlink("2024", filepath.Join(vfs.RootPath, "current")))
+	file := aliasFile{dir: "current", alias: Alias{Name: "league", Ext: "txt"}}
+	ts, err := vfs.Write(file, []byte("data"))
+	assert.Nil(t, err)
+	data, err := vfs.Read(file, ts)
+	assert.Nil(t, err)
+	assert.Equal(t, "data", string(data))
+}
+
+func TestVersionFS_RestrictToRoot(t *testing.T) {
+	t.Parallel()
+	outside := t.TempDir()
+	vfs := New(t.TempDir())
+	vfs.RestrictToRoot = true
+	if err := os.Symlink(outside, filepath.Join(vfs.RootPath, "2023")); err != nil {
+		t.Skipf("symbolic links not supported: %s", err)
+	}
+	ts, _ := NewTimestamp("20231019140523")
+	file := aliasFile{dir: "2023/league", alias: Alias{Name: "league", Ext: "txt"}}
+	_, err := vfs.Write(file, []byte("data"))
+	assert.ErrorIs(t, err, ErrPathEscapesRoot)
+	assert.ErrorIs(t, err, ErrOutsideRoot)
+	_, err = vfs.Read(file, ts)
+	assert.ErrorIs(t, err, ErrPathEscapesRoot)
+
+	// paths that can't be resolved are refused too
+	assert.Nil(t, os.Symlink("loop", filepath.Join(vfs.RootPath, "loop")))
+	loop := aliasFile{dir: "loop", alias: Alias{Name: "league", Ext: "txt"}}
+	_, err = vfs.Read(loop, ts)
+	assert.ErrorIs(t, err, ErrPathEscapesRoot)
+	_, err = vfs.Versions(loop)
+	assert.ErrorIs(t, err, ErrPathEscapesRoot)
+	_, err = vfs.PathExists("loop/league")
+	assert.ErrorIs(t, err, ErrPathEscapesRoot)
+	vfs.RestrictToRoot = false
+	_, err = vfs.Read(loop, ts)
+	assert.NotErrorIs(t, err, ErrPathEscapesRoot)
+	vfs.RestrictToRoot = true
+
+	// links within the root and missing versions are unaffected
+	assert.Nil(t, os.Mkdir(filepath.Join(vfs.RootPath, "2024"), 0755))
+	assert.Nil(t, os.Symlink("2024", filepath.Join(vfs.RootPath, "current")))
+	current := aliasFile{dir: "current/league", alias: Alias{Name: "league", Ext: "txt"}}
+	written, err := vfs.Write(current, []byte("data"))
+	assert.Nil(t, err)
+	data, err := vfs.Read(current, written)
+	assert.Nil(t, err)
+	assert.Equal(t, "data", string(data))
+	_, err = vfs.Read(current, ts)
+	assert.ErrorIs(t, err, ErrVersionNotFound)
+	_, err = vfs.Read(aliasFile{dir: "2025", alias: Alias{Name: "league", Ext: "txt"}}, ts)
+	assert.ErrorIs(t, err, ErrVersionNotFound)
+}
+
+// TestVersionFS_confine_Allocs checks that confining a path doesn't touch the storage nor
+// allocate without RestrictToRoot, since every Read and listing does it.
+func TestVersionFS_confine_Allocs(t *testing.T) {
+	vfs := New(t.TempDir())
+	assert.Zero(t, testing.AllocsPerRun(100, func() {
+		_ = vfs.confine("read", "2023/league/league.txt.20231019140523")
+	}))
+}
+
+// countingSymlinkBackend is an OSBackend counting the calls to EvalSymlinks.
+type countingSymlinkBackend struct {
+	OSBackend
+	calls map[string]int
+}
+
+func (b *countingSymlinkBackend) EvalSymlinks(name string) (string, error) {
+	b.calls[name]++
+	return b.OSBackend.EvalSymlinks(name)
+}
+
+func TestVersionFS_RestrictToRoot_RootResolvedOnce(t *testing.T) {
+	t.Parallel()
+	backend := &countingSymlinkBackend{calls: make(map[string]int)}
+	vfs := New(t.TempDir())
+	vfs.Backend = backend
+	vfs.RestrictToRoot = true
+	file := fileLeague{season: 2023}
+	ts, err := vfs.Write(file, []byte("data"))
+	assert.Nil(t, err)
+	clear(backend.calls)
+	for i := 0; i < 3; i++ {
+		_, err = vfs.Read(file, ts)
+		assert.Nil(t, err)
+	}
+	assert.Zero(t, backend.calls[vfs.RootPath])
+
+	// the root is resolved again when it changes
+	vfs.RootPath = t.TempDir()
+	root, err := vfs.resolveRoot(backend)
+	assert.Nil(t, err)
+	expected, err := filepath.EvalSymlinks(vfs.RootPath)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, root)
+}
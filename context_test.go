@@ -0,0 +1,92 @@
+package versionfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+	"time"
+)
+
+// newLargeVersionFS creates 50 seasons of 20 versions each.
+func newLargeVersionFS(t *testing.T) *VersionFS {
+	vfs := newTypedVersionFS()
+	for season := 2000; season < 2050; season++ {
+		for i := 0; i < 20; i++ {
+			putVersion(t, vfs, fileLeague{season: season}, fmt.Sprintf("2021112501%04d", i), "data")
+		}
+	}
+	return vfs
+}
+
+func TestVersionFS_WalkVersionsCtx_Cancel(t *testing.T) {
+	t.Parallel()
+	vfs := newLargeVersionFS(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	calls := 0
+	err := vfs.WalkVersionsCtx(ctx, "", func(dir, name, ext string, ts Timestamp, info os.FileInfo) error {
+		calls++
+		if calls == 10 {
+			cancel()
+		}
+		return nil
+	})
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, 10, calls)
+}
+
+func TestVersionFS_Ctx_Done(t *testing.T) {
+	t.Parallel()
+	vfs := newLargeVersionFS(t)
+	file := fileLeague{season: 2023}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := vfs.VersionsCtx(ctx, file)
+	assert.True(t, errors.Is(err, context.Canceled))
+	_, err = vfs.FindCtx(ctx, file.Dir(), file)
+	assert.True(t, errors.Is(err, context.Canceled))
+	ts, _ := NewTimestamp("20211125010000")
+	_, err = vfs.ReadCtx(ctx, file, ts)
+	assert.True(t, errors.Is(err, context.Canceled))
+	_, err = vfs.WriteCtx(ctx, fileLeague{season: 2099}, []byte("data"))
+	assert.True(t, errors.Is(err, context.Canceled))
+	exists, err := vfs.PathExists("2099")
+	assert.Nil(t, err)
+	assert.False(t, exists)
+
+	deadline, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	err = vfs.WalkVersionsCtx(deadline, "", func(dir, name, ext string, ts Timestamp, info os.FileInfo) error {
+		return nil
+	})
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	// live contexts behave like the plain methods
+	versions, err := vfs.VersionsCtx(context.Background(), file)
+	assert.Nil(t, err)
+	assert.Equal(t, 20, len(versions))
+	data, err := vfs.ReadCtx(context.Background(), file, ts)
+	assert.Nil(t, err)
+	assert.Equal(t, "data", string(data))
+}
+
+func TestVersionFS_VersionsCtx_Aliases(t *testing.T) {
+	t.Parallel()
+	vfs := newTypedVersionFS()
+	file := vfs.New(LeagueFileType, 2023)
+	vfs.RegisterAlias(LeagueFileType, "ligue", "txt")
+	putRaw(t, vfs, "2023/league/ligue.txt.20211125011947", "old")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := vfs.VersionsCtx(ctx, file)
+	assert.True(t, errors.Is(err, context.Canceled))
+	_, err = vfs.FindCtx(ctx, file.Dir(), file)
+	assert.True(t, errors.Is(err, context.Canceled))
+	versions, err := vfs.VersionsCtx(context.Background(), file)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"20211125011947"}, timestampStrings(versions))
+}
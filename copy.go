@@ -0,0 +1,68 @@
+package versionfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	path_ "path"
+)
+
+// ErrVersionConflict is returned when a version already exists in the destination with a different size.
+var ErrVersionConflict = errors.New("version differs in destination")
+
+// CopyOptions configures CopyTreeWith.
+type CopyOptions struct {
+	// Force replaces the versions of the destination that differ from the source,
+	// instead of failing with ErrVersionConflict.
+	Force bool
+}
+
+// CopyTree copies every versioned file under dirPrefix, the whole tree if empty, to the same
+// path in the root of dst, for migrations and tiered storage. It returns the number of
+// versions copied and the number of bytes written. Versions already in dst with the same
+// size are skipped, and if a version exists in dst with a different size, nothing is copied
+// and an error wrapping ErrVersionConflict is returned. Files that don't have the
+// name.ext.timestamp format are ignored.
+//
+// Example:
+//
+//	copied, bytes, err := hot.CopyTree(cold, "2022")
+func (v *VersionFS) CopyTree(dst *VersionFS, dirPrefix string) (int, int64, error) {
+	return v.CopyTreeWith(dst, dirPrefix, CopyOptions{})
+}
+
+// CopyTreeWith works like CopyTree, with options.
+func (v *VersionFS) CopyTreeWith(dst *VersionFS, dirPrefix string, opts CopyOptions) (int, int64, error) {
+	paths, sizes, err := listTree(context.Background(), v, dirPrefix)
+	if err != nil {
+		return 0, 0, err
+	}
+	var missing []string
+	for _, name := range paths {
+		info, err := dst.backend().Stat(path_.Join(dst.RootPath, name))
+		if errors.Is(err, fs.ErrNotExist) {
+			missing = append(missing, name)
+			continue
+		} else if err != nil {
+			return 0, 0, err
+		}
+		if info.Size() == sizes[name] {
+			continue
+		}
+		if !opts.Force {
+			return 0, 0, fmt.Errorf("%s: %w", name, ErrVersionConflict)
+		}
+		missing = append(missing, name)
+	}
+	var copied int
+	var written int64
+	for _, name := range missing {
+		if err := syncVersion(v, dst, name, sizes[name], false); err != nil {
+			return copied, written, err
+		}
+		copied++
+		written += sizes[name]
+	}
+	return copied, written, nil
+}
@@ -0,0 +1,52 @@
+package versionfs
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestVersionFS_CopyTree(t *testing.T) {
+	t.Parallel()
+	src := newExportVersionFS(t)
+	dst := NewMemory()
+	putVersion(t, dst, fileLeague{season: 2023}, "20231019140523", "FIRST")
+	copied, written, err := src.CopyTree(dst, "2023")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, copied)
+	assert.Equal(t, int64(12), written)
+	roster := fileRoster{season: 2023, teamID: 12, date: "2023-10-19"}
+	ts, err := dst.LastVersion(roster)
+	assert.Nil(t, err)
+	data, err := dst.Read(roster, ts)
+	assert.Nil(t, err)
+	assert.Equal(t, "roster", string(data))
+	exists, _ := dst.PathExists("2023/league/README")
+	assert.False(t, exists)
+	exists, _ = dst.PathExists("2024")
+	assert.False(t, exists)
+
+	copied, written, err = src.CopyTree(dst, "")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, copied)
+	assert.Equal(t, int64(4), written)
+}
+
+func TestVersionFS_CopyTree_Conflict(t *testing.T) {
+	t.Parallel()
+	src := newExportVersionFS(t)
+	dst := NewMemory()
+	ts := putVersion(t, dst, fileLeague{season: 2023}, "20231020140523", "changed")
+	_, _, err := src.CopyTree(dst, "")
+	assert.True(t, errors.Is(err, ErrVersionConflict))
+	exists, _ := dst.PathExists("2024")
+	assert.False(t, exists)
+
+	copied, written, err := src.CopyTreeWith(dst, "", CopyOptions{Force: true})
+	assert.Nil(t, err)
+	assert.Equal(t, 4, copied)
+	assert.Equal(t, int64(21), written)
+	data, err := dst.Read(fileLeague{season: 2023}, ts)
+	assert.Nil(t, err)
+	assert.Equal(t, "second", string(data))
+}
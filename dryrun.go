@@ -0,0 +1,72 @@
+package versionfs
+
+import (
+	"sync"
+)
+
+// OpType is the type of an operation, planned by a dry-run instance or traced by a Tracer.
+type OpType string
+
+// The operations recorded by a dry-run instance.
+const (
+	OpWrite  OpType = "write"
+	OpRemove OpType = "remove"
+	OpMkdir  OpType = "mkdir"
+	OpRename OpType = "rename"
+)
+
+// The other operations traced by a Tracer.
+const (
+	OpRead      OpType = "read"
+	OpReadRange OpType = "read_range"
+	OpWriteTo   OpType = "write_to"
+	OpVersions  OpType = "versions"
+	OpFind      OpType = "find"
+	OpWalk      OpType = "walk"
+)
+
+// PlannedOp is an operation a dry-run instance would have performed.
+type PlannedOp struct {
+	// Op is the type of the operation.
+	Op OpType
+	// Path is the path of the file or directory, relative to the root.
+	Path string
+	// From is the previous path of a renamed file, relative to the root, empty for other operations.
+	From string
+	// Size is the number of bytes that would have been written, zero for other operations.
+	Size int64
+}
+
+// plan is the log of the operations planned in dry-run mode.
+type plan struct {
+	mu  sync.Mutex
+	ops []PlannedOp
+}
+
+func (p *plan) record(op PlannedOp) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ops = append(p.ops, op)
+}
+
+// Operations returns the operations planned since the last ResetOperations while DryRun was set, in order.
+//
+// Example:
+//
+//	vfs.DryRun = true
+//	runMigration(vfs)
+//	for _, op := range vfs.Operations() {
+//	    fmt.Printf("%s %s (%d bytes)\n", op.Op, op.Path, op.Size)
+//	}
+func (v *VersionFS) Operations() []PlannedOp {
+	v.plan.mu.Lock()
+	defer v.plan.mu.Unlock()
+	return append([]PlannedOp(nil), v.plan.ops...)
+}
+
+// ResetOperations clears the log of planned operations.
+func (v *VersionFS) ResetOperations() {
+	v.plan.mu.Lock()
+	defer v.plan.mu.Unlock()
+	v.plan.ops = nil
+}
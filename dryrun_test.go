@@ -0,0 +1,55 @@
+package versionfs
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestVersionFS_DryRun(t *testing.T) {
+	t.Parallel()
+	dir, vfs := newTmpVersionFS(t)
+	defer func() { _ = os.RemoveAll(dir) }()
+	file := vfs.New(LeagueFileType, 2023)
+	existing, err := vfs.Write(file, []byte("existing"))
+	assert.Nil(t, err)
+	vfs.DryRun = true
+
+	ts, err := vfs.Write(vfs.New(LeagueFileType, 2024), []byte("planned"))
+	assert.Nil(t, err)
+	assert.False(t, ts.Time().IsZero())
+	assert.Nil(t, vfs.Remove(file, existing))
+	assert.Nil(t, vfs.MkdirAll("2025/league", 0755))
+	missing, _ := NewTimestamp("20211125011947")
+	assert.True(t, errors.Is(vfs.Remove(file, missing), ErrVersionNotFound))
+
+	assert.Equal(t, []PlannedOp{
+		{Op: OpWrite, Path: "2024/league/league.txt." + ts.String(), Size: 7},
+		{Op: OpRemove, Path: "2023/league/league.txt." + existing.String()},
+		{Op: OpMkdir, Path: "2025/league"},
+	}, vfs.Operations())
+
+	// nothing was written or removed, reads are unaffected
+	entries, err := os.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entries))
+	data, err := vfs.Read(file, existing)
+	assert.Nil(t, err)
+	assert.Equal(t, "existing", string(data))
+
+	vfs.ResetOperations()
+	assert.Empty(t, vfs.Operations())
+}
+
+func TestVersionFS_DryRun_Clone(t *testing.T) {
+	t.Parallel()
+	vfs := newTypedVersionFS()
+	vfs.DryRun = true
+	view := vfs.WithRoot("view")
+	clone := vfs.Clone("clone")
+	assert.Nil(t, view.MkdirAll("a", 0755))
+	assert.Nil(t, clone.MkdirAll("b", 0755))
+	assert.Equal(t, []PlannedOp{{Op: OpMkdir, Path: "a"}}, vfs.Operations())
+	assert.Equal(t, []PlannedOp{{Op: OpMkdir, Path: "b"}}, clone.Operations())
+}
@@ -0,0 +1,60 @@
+package versionfs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	path_ "path"
+)
+
+// DuplicateGroups finds the versions of a file having identical content, to report the
+// space wasted by redundant versions. It returns the timestamps grouped by the hex-encoded
+// SHA-256 of their content, newest first, keeping only the groups with more than one version.
+// Each version is read once and hashed while streaming, so versions are never fully loaded in memory.
+//
+// Example:
+//
+//	groups, err := vfs.DuplicateGroups(file)
+//	for hash, versions := range groups {
+//	    fmt.Printf("%s: %d identical versions\n", hash, len(versions))
+//	}
+func (v *VersionFS) DuplicateGroups(file File) (map[string][]Timestamp, error) {
+	v.logger().Debugf("Finding duplicate versions of file %s/%s.%s", file.Dir(), file.Name(), file.Ext())
+	versions, err := v.Versions(file)
+	if err != nil {
+		return nil, err
+	}
+	groups := make(map[string][]Timestamp)
+	for _, ts := range versions {
+		hash, err := v.hashVersion(file, ts)
+		if err != nil {
+			return nil, err
+		}
+		groups[hash] = append(groups[hash], ts)
+	}
+	for hash, group := range groups {
+		if len(group) < 2 {
+			delete(groups, hash)
+		}
+	}
+	return groups, nil
+}
+
+// hashVersion returns the hex-encoded SHA-256 of a version's content.
+func (v *VersionFS) hashVersion(file File, ts Timestamp) (string, error) {
+	return v.hashPath(v.resolvePath(file, ts))
+}
+
+// hashPath returns the hex-encoded SHA-256 of the content of a version at name, relative to the root.
+func (v *VersionFS) hashPath(name string) (string, error) {
+	f, err := v.backend().Open(path_.Join(v.RootPath, name))
+	if err != nil {
+		return "", versionNotFound(err)
+	}
+	defer func() { _ = f.Close() }()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
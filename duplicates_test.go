@@ -0,0 +1,35 @@
+package versionfs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestVersionFS_DuplicateGroups(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	file := fileLeague{season: 2023}
+	ts1 := putVersion(t, vfs, file, "20211125011947", "same")
+	putVersion(t, vfs, file, "20221125011947", "unique")
+	ts3 := putVersion(t, vfs, file, "20231125011947", "same")
+	groups, err := vfs.DuplicateGroups(file)
+	assert.Nil(t, err)
+	sum := sha256.Sum256([]byte("same"))
+	assert.Equal(t, map[string][]Timestamp{hex.EncodeToString(sum[:]): {ts3, ts1}}, groups)
+}
+
+func TestVersionFS_DuplicateGroups_NoDuplicates(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	file := fileLeague{season: 2023}
+	groups, err := vfs.DuplicateGroups(file)
+	assert.Nil(t, err)
+	assert.Empty(t, groups)
+	putVersion(t, vfs, file, "20211125011947", "a")
+	putVersion(t, vfs, file, "20221125011947", "b")
+	groups, err = vfs.DuplicateGroups(file)
+	assert.Nil(t, err)
+	assert.Empty(t, groups)
+}
@@ -0,0 +1,63 @@
+package versionfs
+
+import (
+	"bytes"
+	"context"
+)
+
+// SetFallback sets the secondary instance consulted when this one misses, e.g. a small hot
+// root on a local disk backed by the full history on a network mount. Read falls back to the
+// secondary when the version doesn't exist in this root, and Versions merges the versions of
+// both roots, deduplicated by timestamp and sorted newest first, and so do the methods built
+// on it such as LastVersion, PreviousVersion, and VersionAt. With CopyThrough, the versions
+// read from the secondary are also written to this root. Write, Remove, Reset, Find,
+// OpenConcat, LatestEqual, LatestMatches, and the Prune APIs only use this root. The
+// secondary may have a fallback itself. Passing nil removes the fallback.
+//
+// Like the other options, the fallback must be set before the instance is used concurrently.
+// Clone and WithRoot don't keep it, while Sub sets the same subdirectory of the secondary as
+// the fallback of the view.
+//
+// Example:
+//
+//	hot := versionfs.New("/ssd/data")
+//	hot.SetFallback(versionfs.New("/mnt/archive/data"))
+//	hot.CopyThrough = true
+func (v *VersionFS) SetFallback(secondary *VersionFS) {
+	v.fallback = secondary
+}
+
+// readFallback reads a version missing in this root from the fallback, copying it to this
+// root with CopyThrough. A failed copy is logged and doesn't fail the read.
+func (v *VersionFS) readFallback(ctx context.Context, file File, ts Timestamp) ([]byte, error) {
+	v.logger().Debugf("Reading file %s/%s.%s.%s from fallback %s", file.Dir(), file.Name(), file.Ext(), ts, v.fallback.RootPath)
+	data, err := v.fallback.ReadCtx(ctx, file, ts)
+	if err != nil || !v.CopyThrough {
+		return data, err
+	}
+	if err := v.writeAt(Path(file, ts), int64(len(data)), bytes.NewReader(data)); err != nil {
+		v.logger().Warnf("copying %s from fallback %s: %s", Path(file, ts), v.fallback.RootPath, err)
+	}
+	return data, nil
+}
+
+// withFallback merges the versions of a file in the fallback into versions, the versions of
+// this root, sorted newest first.
+func (v *VersionFS) withFallback(ctx context.Context, file File, versions []Timestamp) ([]Timestamp, error) {
+	secondary, err := v.fallback.VersionsCtx(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(versions))
+	for _, ts := range versions {
+		seen[ts.String()] = true
+	}
+	for _, ts := range secondary {
+		if !seen[ts.String()] {
+			versions = append(versions, ts)
+			seen[ts.String()] = true
+		}
+	}
+	sortNewestFirst(versions)
+	return versions, nil
+}
@@ -0,0 +1,128 @@
+package versionfs
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestVersionFS_SetFallback(t *testing.T) {
+	t.Parallel()
+	hot := NewMemory()
+	cold := NewMemory()
+	hot.SetFallback(cold)
+	file := fileLeague{season: 2023}
+	oldest := putVersion(t, cold, file, "20231017140523", "oldest")
+	both := putVersion(t, cold, file, "20231018140523", "cold copy")
+	putVersion(t, hot, file, "20231018140523", "hot copy")
+	newest := putVersion(t, hot, file, "20231019140523", "newest")
+
+	// the versions of both roots are merged
+	versions, err := hot.Versions(file)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"20231019140523", "20231018140523", "20231017140523"}, timestampStrings(versions))
+	latest, err := hot.LastVersion(file)
+	assert.Nil(t, err)
+	assert.Equal(t, newest, latest)
+
+	// the primary is read first
+	data, err := hot.Read(file, both)
+	assert.Nil(t, err)
+	assert.Equal(t, "hot copy", string(data))
+	data, err = hot.Read(file, oldest)
+	assert.Nil(t, err)
+	assert.Equal(t, "oldest", string(data))
+	missing, _ := NewTimestamp("20231016140523")
+	_, err = hot.Read(file, missing)
+	assert.ErrorIs(t, err, ErrVersionNotFound)
+
+	// without copy-through, the primary is unchanged
+	exists, err := hot.PathExists(Path(file, oldest))
+	assert.Nil(t, err)
+	assert.False(t, exists)
+
+	// writes only go to the primary
+	ts, err := hot.Write(file, []byte("written"))
+	assert.Nil(t, err)
+	exists, err = cold.PathExists(Path(file, ts))
+	assert.Nil(t, err)
+	assert.False(t, exists)
+
+	// the fallback can be removed
+	hot.SetFallback(nil)
+	_, err = hot.Read(file, oldest)
+	assert.ErrorIs(t, err, ErrVersionNotFound)
+}
+
+func TestVersionFS_SetFallback_CopyThrough(t *testing.T) {
+	t.Parallel()
+	hot := New(t.TempDir())
+	cold := NewMemory()
+	hot.SetFallback(cold)
+	hot.CopyThrough = true
+	file := fileLeague{season: 2023}
+	ts := putVersion(t, cold, file, "20231018140523", "cold")
+	data, err := hot.Read(file, ts)
+	assert.Nil(t, err)
+	assert.Equal(t, "cold", string(data))
+	exists, err := hot.PathExists(Path(file, ts))
+	assert.Nil(t, err)
+	assert.True(t, exists)
+
+	// the copy is served by the primary from now on
+	hot.SetFallback(nil)
+	data, err = hot.Read(file, ts)
+	assert.Nil(t, err)
+	assert.Equal(t, "cold", string(data))
+
+	// a failed copy doesn't fail the read
+	readOnly := NewMemory()
+	readOnly.ReadOnly = true
+	readOnly.CopyThrough = true
+	readOnly.SetFallback(cold)
+	data, err = readOnly.Read(file, ts)
+	assert.Nil(t, err)
+	assert.Equal(t, "cold", string(data))
+}
+
+func TestVersionFS_SetFallback_LocalOperations(t *testing.T) {
+	t.Parallel()
+	hot := NewMemory()
+	cold := NewMemory()
+	hot.SetFallback(cold)
+	file := fileLeague{season: 2023}
+	putVersion(t, cold, file, "20231017140523", "cold")
+	putVersion(t, hot, file, "20231018140523", "first")
+	putVersion(t, hot, file, "20231019140523", "second")
+
+	// pruning and resetting only remove the versions of the primary
+	removed, err := hot.Prune(file, RetentionPolicy{KeepLast: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"20231018140523"}, timestampStrings(removed))
+	_, err = hot.Reset(file, []byte("reset"))
+	assert.Nil(t, err)
+	versions, err := cold.Versions(file)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"20231017140523"}, timestampStrings(versions))
+	versions, err = hot.Versions(file)
+	assert.Nil(t, err)
+	assert.Len(t, versions, 2)
+}
+
+func TestVersionFS_SetFallback_Views(t *testing.T) {
+	t.Parallel()
+	hot := NewMemory()
+	cold := NewMemory()
+	hot.SetFallback(cold)
+	file := fileLeague{season: 2023}
+	ts := putVersion(t, cold, file, "20231018140523", "cold")
+	sub, err := hot.Sub("2023")
+	assert.Nil(t, err)
+	data, err := sub.Read(aliasFile{dir: "league", alias: Alias{Name: "league", Ext: "txt"}}, ts)
+	assert.Nil(t, err)
+	assert.Equal(t, "cold", string(data))
+
+	for _, view := range []*VersionFS{hot.WithRoot(hot.RootPath), hot.Clone(hot.RootPath)} {
+		_, err := view.Read(file, ts)
+		assert.ErrorIs(t, err, ErrVersionNotFound)
+	}
+}
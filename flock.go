@@ -0,0 +1,82 @@
+package versionfs
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	path_ "path"
+	"path/filepath"
+	"time"
+)
+
+// LockFileName is the name of the lock file created in the directories locked by ProcessLocks.
+const LockFileName = ".versionfs-lock"
+
+// lockPollInterval is how often a busy directory lock is retried.
+const lockPollInterval = 10 * time.Millisecond
+
+// ErrLockTimeout is returned when a directory lock can't be taken within LockTimeout.
+var ErrLockTimeout = errors.New("lock timeout")
+
+// LockBackend is implemented by the backends able to take advisory locks shared between
+// processes. It is used by Write and the Prune APIs when ProcessLocks is set.
+type LockBackend interface {
+	// LockDir takes an exclusive lock on the directory name, waiting up to timeout, or
+	// without limit if it is zero, and returns the function releasing it. Returns an
+	// error wrapping ErrLockTimeout if the lock is still held by then.
+	LockDir(name string, timeout time.Duration) (unlock func(), err error)
+}
+
+// LockDir implements LockBackend with flock on the LockFileName file of the directory,
+// created if needed. The lock is released if the process dies. Returns an error wrapping
+// errors.ErrUnsupported on the platforms without flock, such as Windows, without creating
+// the file.
+func (OSBackend) LockDir(name string, timeout time.Duration) (func(), error) {
+	if !haveFlock {
+		return nil, &fs.PathError{Op: "lock", Path: name, Err: errors.ErrUnsupported}
+	}
+	f, err := os.OpenFile(filepath.Join(filepath.FromSlash(name), LockFileName), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		locked, err := tryFlock(f)
+		if err != nil {
+			_ = f.Close()
+			return nil, &fs.PathError{Op: "lock", Path: name, Err: err}
+		}
+		if locked {
+			return func() {
+				_ = funlock(f)
+				_ = f.Close()
+			}, nil
+		}
+		if timeout > 0 && time.Now().After(deadline) {
+			_ = f.Close()
+			return nil, &fs.PathError{Op: "lock", Path: name, Err: ErrLockTimeout}
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// lockDir takes the lock of dir shared between processes when ProcessLocks is set and the
+// backend implements LockBackend, and returns the function releasing it. A directory that
+// doesn't exist has nothing to protect and isn't locked, and neither are the directories of
+// a read-only or dry-run instance, which doesn't modify them. When the backend can't lock
+// on this platform, the directory is locked within the process only.
+func (v *VersionFS) lockDir(dir string) (func(), error) {
+	lb, ok := v.Backend.(LockBackend)
+	if !v.ProcessLocks || !ok || v.ReadOnly || v.DryRun {
+		return func() {}, nil
+	}
+	unlock, err := lb.LockDir(path_.Join(v.RootPath, slashed(dir)), v.LockTimeout)
+	if errors.Is(err, fs.ErrNotExist) {
+		return func() {}, nil
+	} else if errors.Is(err, errors.ErrUnsupported) {
+		mu := &v.locks.dirs[v.dirStripe(dir)]
+		mu.Lock()
+		return mu.Unlock, nil
+	}
+	return unlock, err
+}
@@ -0,0 +1,21 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package versionfs
+
+import (
+	"errors"
+	"os"
+)
+
+// haveFlock reports whether the platform has flock.
+const haveFlock = false
+
+// tryFlock fails with errors.ErrUnsupported, the platform has no flock.
+func tryFlock(f *os.File) (bool, error) {
+	return false, errors.ErrUnsupported
+}
+
+// funlock does nothing, the platform has no flock.
+func funlock(f *os.File) error {
+	return nil
+}
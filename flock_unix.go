@@ -0,0 +1,26 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package versionfs
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// haveFlock reports whether the platform has flock.
+const haveFlock = true
+
+// tryFlock takes an exclusive flock on f without blocking, it returns false if it is held elsewhere.
+func tryFlock(f *os.File) (bool, error) {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// funlock releases the flock on f.
+func funlock(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
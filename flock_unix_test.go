@@ -0,0 +1,83 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package versionfs
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"testing"
+	"time"
+)
+
+// TestHelperLockProcess is run as a child process by TestVersionFS_ProcessLocks: it locks
+// the directory given in the environment and holds the lock until its stdin is closed.
+func TestHelperLockProcess(t *testing.T) {
+	dir := os.Getenv("VERSIONFS_LOCK_DIR")
+	if dir == "" {
+		t.Skip("helper process")
+	}
+	unlock, err := OSBackend{}.LockDir(dir, time.Second)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Println("locked")
+	_, _ = bufio.NewReader(os.Stdin).ReadString('\n')
+	unlock()
+	os.Exit(0)
+}
+
+func TestVersionFS_ProcessLocks(t *testing.T) {
+	t.Parallel()
+	dir, vfs := newTmpVersionFS(t)
+	defer func() { _ = os.RemoveAll(dir) }()
+	vfs.ProcessLocks = true
+	vfs.LockTimeout = 100 * time.Millisecond
+	file := vfs.New(LeagueFileType, 2023)
+	_, err := vfs.Write(file, []byte("before"))
+	assert.Nil(t, err)
+
+	child := exec.Command(os.Args[0], "-test.run=^TestHelperLockProcess$")
+	child.Env = append(os.Environ(), "VERSIONFS_LOCK_DIR="+path.Join(dir, file.Dir()))
+	stdin, err := child.StdinPipe()
+	assert.Nil(t, err)
+	stdout, err := child.StdoutPipe()
+	assert.Nil(t, err)
+	assert.Nil(t, child.Start())
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	assert.Nil(t, err)
+	assert.Equal(t, "locked\n", line)
+
+	// the child holds the lock
+	_, err = vfs.Write(file, []byte("during"))
+	assert.ErrorIs(t, err, ErrLockTimeout)
+	var pathErr *fs.PathError
+	assert.ErrorAs(t, err, &pathErr)
+	assert.Equal(t, path.Join(dir, file.Dir()), pathErr.Path)
+	_, err = vfs.Prune(file, RetentionPolicy{KeepLast: 1})
+	assert.ErrorIs(t, err, ErrLockTimeout)
+
+	// the lock is free once the child is done
+	assert.Nil(t, stdin.Close())
+	assert.Nil(t, child.Wait())
+	_, err = vfs.Write(file, []byte("after"))
+	assert.Nil(t, err)
+	versions, err := vfs.Versions(file)
+	assert.Nil(t, err)
+	assert.Len(t, versions, 2)
+}
+
+func TestVersionFS_ProcessLocks_MissingDir(t *testing.T) {
+	t.Parallel()
+	dir, vfs := newTmpVersionFS(t)
+	defer func() { _ = os.RemoveAll(dir) }()
+	vfs.ProcessLocks = true
+	removed, err := vfs.PruneDir("2023/league", vfs.New(LeagueFileType, 2023), RetentionPolicy{KeepLast: 1})
+	assert.Nil(t, err)
+	assert.Empty(t, removed)
+}
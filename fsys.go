@@ -0,0 +1,80 @@
+package versionfs
+
+import (
+	"errors"
+	"io/fs"
+	path_ "path"
+	"strings"
+)
+
+// ErrReadOnly is returned by the operations that would modify a read-only instance,
+// created by NewFromFS or with the ReadOnly option.
+// It is wrapped in an *fs.PathError holding the attempted path.
+var ErrReadOnly = errors.New("read-only file system")
+
+// NewFromFS creates a read-only VersionFS instance reading its files from fsys,
+// for example reference datasets embedded with go:embed. Timestamps are parsed
+// from the filenames exactly as on disk. Write, Remove, and MkdirAll return an
+// error wrapping ErrReadOnly.
+//
+// Example:
+//
+//	//go:embed data
+//	var data embed.FS
+//
+//	sub, _ := fs.Sub(data, "data")
+//	vfs := versionfs.NewFromFS(sub)
+func NewFromFS(fsys fs.FS) *VersionFS {
+	v := New("")
+	v.Backend = FSBackend{FS: fsys}
+	return v
+}
+
+// FSBackend is a read-only Backend reading files from an fs.FS.
+type FSBackend struct {
+	FS fs.FS
+}
+
+// clean converts a name to the unrooted form expected by fs.FS.
+func (b FSBackend) clean(name string) string {
+	name = strings.TrimPrefix(path_.Clean("/"+name), "/")
+	if name == "" {
+		return "."
+	}
+	return name
+}
+
+// ReadFile implements Backend with fs.ReadFile.
+func (b FSBackend) ReadFile(name string) ([]byte, error) {
+	return fs.ReadFile(b.FS, b.clean(name))
+}
+
+// WriteFile implements Backend, it always returns an error wrapping ErrReadOnly.
+func (b FSBackend) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	return &fs.PathError{Op: "write", Path: name, Err: ErrReadOnly}
+}
+
+// Open implements Backend with fsys.Open.
+func (b FSBackend) Open(name string) (fs.File, error) {
+	return b.FS.Open(b.clean(name))
+}
+
+// Remove implements Backend, it always returns an error wrapping ErrReadOnly.
+func (b FSBackend) Remove(name string) error {
+	return &fs.PathError{Op: "remove", Path: name, Err: ErrReadOnly}
+}
+
+// ReadDir implements Backend with fs.ReadDir.
+func (b FSBackend) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(b.FS, b.clean(name))
+}
+
+// Stat implements Backend with fs.Stat.
+func (b FSBackend) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(b.FS, b.clean(name))
+}
+
+// MkdirAll implements Backend, it always returns an error wrapping ErrReadOnly.
+func (b FSBackend) MkdirAll(name string, perm fs.FileMode) error {
+	return &fs.PathError{Op: "mkdir", Path: name, Err: ErrReadOnly}
+}
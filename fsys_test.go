@@ -0,0 +1,68 @@
+package versionfs
+
+import (
+	"embed"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io/fs"
+	"testing"
+)
+
+//go:embed test-data
+var embeddedTestData embed.FS
+
+func newEmbeddedVersionFS(t *testing.T) *VersionFS {
+	t.Helper()
+	sub, err := fs.Sub(embeddedTestData, "test-data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vfs := NewFromFS(sub)
+	vfs.RegisterFileType(LeagueFileType, func(args ...any) File {
+		return fileLeague{season: args[0].(int)}
+	})
+	return vfs
+}
+
+func TestNewFromFS_Read(t *testing.T) {
+	t.Parallel()
+	vfs := newEmbeddedVersionFS(t)
+	file := vfs.New(LeagueFileType, 2023)
+	versions, err := vfs.Versions(file)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"20211218030527", "20211125011947", "20211125011946"}, timestampStrings(versions))
+	timestamps, err := vfs.Find("2023/league", file)
+	assert.Nil(t, err)
+	assert.Equal(t, versions, timestamps)
+	data, err := vfs.Read(file, versions[1])
+	assert.Nil(t, err)
+	assert.Equal(t, "hello world 2\n", string(data))
+	data, err = vfs.ReadRange(file, versions[1], 6, 5)
+	assert.Nil(t, err)
+	assert.Equal(t, "world", string(data))
+	exists, err := vfs.PathExists("2023/league")
+	assert.Nil(t, err)
+	assert.True(t, exists)
+	versions, err = vfs.Versions(vfs.New(LeagueFileType, 2024))
+	assert.Nil(t, err)
+	assert.Equal(t, []Timestamp{}, versions)
+	ts, _ := NewTimestamp("20000101000000")
+	_, err = vfs.Read(file, ts)
+	assert.True(t, errors.Is(err, ErrVersionNotFound))
+}
+
+func TestNewFromFS_ReadOnly(t *testing.T) {
+	t.Parallel()
+	vfs := newEmbeddedVersionFS(t)
+	file := vfs.New(LeagueFileType, 2023)
+	_, err := vfs.Write(file, []byte("data"))
+	assert.True(t, errors.Is(err, ErrReadOnly))
+	ts, _ := NewTimestamp("20211125011947")
+	err = vfs.Remove(file, ts)
+	assert.True(t, errors.Is(err, ErrReadOnly))
+	assert.Equal(t, "remove 2023/league/league.txt.20211125011947: read-only file system", err.Error())
+	err = vfs.MkdirAll("2024", 0755)
+	assert.True(t, errors.Is(err, ErrReadOnly))
+	_, err = vfs.Read(file, ts)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,127 @@
+package versionfs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"mime"
+	"net/http"
+	path_ "path"
+	"strings"
+)
+
+// Handler returns a read-only http.Handler serving the versions of the tree, to let
+// teammates fetch a file without an ad-hoc server. The request path names a logical
+// file, relative to the root and without timestamp:
+//
+//   - GET /{dir}/{name}.{ext} serves the latest version
+//   - GET /{dir}/{name}.{ext}?ts=20231019140523 serves a specific version
+//   - GET /{dir}/{name}.{ext}/versions lists the versions, newest first, as a JSON array
+//     of VersionInfo
+//
+// The Content-Type of a version is inferred from its extension, and its modification time
+// is its timestamp, so conditional and range requests are supported. Unknown files and
+// versions are answered with 404 Not Found, invalid paths and timestamps with 400 Bad Request,
+// paths out of the root, including through symbolic links with RestrictToRoot, with 403
+// Forbidden, and methods other than GET and HEAD with 405 Method Not Allowed.
+//
+// Example:
+//
+//	http.Handle("/files/", http.StripPrefix("/files", versionfs.Handler(vfs)))
+func Handler(vfs *VersionFS) http.Handler {
+	return handler{v: vfs}
+}
+
+// handler is the http.Handler returned by Handler.
+type handler struct {
+	v *VersionFS
+}
+
+// ServeHTTP implements http.Handler.
+func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	name := strings.TrimPrefix(slashed(r.URL.Path), "/")
+	name, listing := strings.CutSuffix(name, "/versions")
+	if !fs.ValidPath(name) || name == "." {
+		http.Error(w, "invalid path", http.StatusBadRequest)
+		return
+	}
+	base := path_.Base(name)
+	dot := strings.IndexByte(base, '.')
+	if dot <= 0 || dot == len(base)-1 {
+		http.NotFound(w, r)
+		return
+	}
+	file := Alias{Name: base[:dot], Ext: base[dot+1:]}.file(path_.Dir(name))
+	if listing {
+		h.serveVersions(w, file)
+		return
+	}
+	if tsParam := r.URL.Query().Get("ts"); tsParam != "" {
+		ts, err := NewTimestamp(tsParam)
+		if err != nil {
+			http.Error(w, "invalid timestamp", http.StatusBadRequest)
+			return
+		}
+		h.serveVersion(w, r, file, ts)
+		return
+	}
+	versions, err := h.v.Find(file.Dir(), file)
+	if err != nil {
+		h.serveError(w, err)
+		return
+	}
+	if len(versions) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+	h.serveVersion(w, r, file, versions[0])
+}
+
+// serveVersion serves the content of a version.
+func (h handler) serveVersion(w http.ResponseWriter, r *http.Request, file File, ts Timestamp) {
+	data, err := h.v.Read(file, ts)
+	if err != nil {
+		h.serveError(w, err)
+		return
+	}
+	contentType := mime.TypeByExtension(path_.Ext("." + file.Ext()))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	http.ServeContent(w, r, file.Name()+"."+file.Ext(), ts.Time(), bytes.NewReader(data))
+}
+
+// serveVersions serves the list of the versions of a file.
+func (h handler) serveVersions(w http.ResponseWriter, file File) {
+	versions, err := h.v.VersionsWithInfo(file)
+	if err != nil {
+		h.serveError(w, err)
+		return
+	}
+	if len(versions) == 0 {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(versions)
+}
+
+// serveError answers with the status matching err.
+func (h handler) serveError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrVersionNotFound), errors.Is(err, fs.ErrNotExist):
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	case errors.Is(err, ErrOutsideRoot):
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+	default:
+		h.v.logger().Warnf("serving versions: %s", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
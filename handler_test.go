@@ -0,0 +1,135 @@
+package versionfs
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func serve(h http.Handler, method, target string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+	return rec
+}
+
+func TestHandler_Latest(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	file := fileLeague{season: 2023}
+	putVersion(t, vfs, file, "20231018140523", "first")
+	putVersion(t, vfs, file, "20231019140523", "second")
+	h := Handler(vfs)
+
+	rec := serve(h, http.MethodGet, "/2023/league/league.txt")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "second", rec.Body.String())
+	assert.Equal(t, "text/plain; charset=utf-8", rec.Header().Get("Content-Type"))
+	lastModified := time.Date(2023, time.October, 19, 14, 5, 23, 0, time.Local).UTC().Format(http.TimeFormat)
+	assert.Equal(t, lastModified, rec.Header().Get("Last-Modified"))
+
+	rec = serve(h, http.MethodHead, "/2023/league/league.txt")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "6", rec.Header().Get("Content-Length"))
+
+	rec = serve(h, http.MethodGet, "/2024/league/league.txt")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = serve(h, http.MethodGet, "/2023/league/league.json")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = serve(h, http.MethodGet, "/2023/league")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestHandler_Timestamp(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	file := fileThemes{}
+	putVersion(t, vfs, file, "20231018140523", "first")
+	putVersion(t, vfs, file, "20231019140523", "second")
+	h := Handler(vfs)
+	target := "/" + path.Join(file.Dir(), file.Name()+"."+file.Ext())
+
+	rec := serve(h, http.MethodGet, target+"?ts=20231018140523")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "first", rec.Body.String())
+	assert.Equal(t, "application/gzip", rec.Header().Get("Content-Type"))
+
+	rec = serve(h, http.MethodGet, target+"?ts=20231017140523")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = serve(h, http.MethodGet, target+"?ts=yesterday")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestHandler_Versions(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	file := fileLeague{season: 2023}
+	putVersion(t, vfs, file, "20231018140523", "first")
+	putVersion(t, vfs, file, "20231019140523", "second")
+	h := Handler(vfs)
+
+	rec := serve(h, http.MethodGet, "/2023/league/league.txt/versions")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var list []struct {
+		Timestamp string `json:"timestamp"`
+		Size      int64  `json:"size"`
+	}
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	assert.Len(t, list, 2)
+	assert.Equal(t, "20231019140523", list[0].Timestamp)
+	assert.Equal(t, int64(6), list[0].Size)
+	assert.Equal(t, "20231018140523", list[1].Timestamp)
+	assert.Equal(t, int64(5), list[1].Size)
+
+	rec = serve(h, http.MethodGet, "/2024/league/league.txt/versions")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestHandler_ReadOnly(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	putVersion(t, vfs, fileLeague{season: 2023}, "20231019140523", "data")
+	for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodDelete} {
+		rec := serve(Handler(vfs), method, "/2023/league/league.txt")
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code, method)
+		assert.Equal(t, "GET, HEAD", rec.Header().Get("Allow"), method)
+	}
+	versions, err := vfs.Versions(fileLeague{season: 2023})
+	assert.Nil(t, err)
+	assert.Len(t, versions, 1)
+}
+
+func TestHandler_Traversal(t *testing.T) {
+	t.Parallel()
+	parent := t.TempDir()
+	putRaw(t, New(parent), "secret/league.txt.20231019140523", "secret")
+	vfs := New(path.Join(parent, "root"))
+	putVersion(t, vfs, fileLeague{season: 2023}, "20231019140523", "data")
+	h := Handler(vfs)
+	for _, target := range []string{
+		"/../secret/league.txt",
+		"/2023/../../secret/league.txt",
+		"/%2e%2e/secret/league.txt",
+		"/..%2fsecret/league.txt",
+		"/..%5csecret/league.txt",
+		"/../secret/league.txt/versions",
+		"//secret/league.txt",
+	} {
+		rec := serve(h, http.MethodGet, target)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, target)
+		assert.NotContains(t, rec.Body.String(), "secret", target)
+	}
+
+	// a link out of the root is refused with RestrictToRoot
+	vfs.RestrictToRoot = true
+	assert.Nil(t, os.Symlink(path.Join(parent, "secret"), path.Join(parent, "root", "link")))
+	rec := serve(h, http.MethodGet, "/link/league.txt")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	rec = serve(h, http.MethodGet, "/link/league.txt/versions")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
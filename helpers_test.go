@@ -0,0 +1,48 @@
+package versionfs
+
+import (
+	"path"
+	"testing"
+	"time"
+)
+
+// The conformance tests shared by every storage backend are in the versionfstest package,
+// these helpers store test data through any backend.
+
+// putVersion stores a version with a known timestamp through the backend of vfs.
+func putVersion(t *testing.T, vfs *VersionFS, file File, ts string, data string) Timestamp {
+	t.Helper()
+	timestamp, err := NewTimestamp(ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	putRaw(t, vfs, path.Join(file.Dir(), file.Name()+"."+file.Ext()+"."+ts), data)
+	return timestamp
+}
+
+// putRaw stores a file at a path relative to the root through the backend of vfs.
+func putRaw(t *testing.T, vfs *VersionFS, name string, data string) {
+	t.Helper()
+	if err := vfs.Backend.MkdirAll(path.Join(vfs.RootPath, path.Dir(name)), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := vfs.Backend.WriteFile(path.Join(vfs.RootPath, name), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// setLocal sets time.Local to a fixed zone offset seconds east of UTC until the end of the
+// test, as if the process ran with TZ set. The test must not be parallel.
+func setLocal(t *testing.T, name string, offset int) {
+	saved := time.Local
+	time.Local = time.FixedZone(name, offset)
+	t.Cleanup(func() { time.Local = saved })
+}
+
+func timestampStrings(timestamps []Timestamp) []string {
+	strs := make([]string, len(timestamps))
+	for i, ts := range timestamps {
+		strs[i] = ts.String()
+	}
+	return strs
+}
@@ -0,0 +1,173 @@
+package versionfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	path_ "path"
+	"sort"
+	"time"
+)
+
+// VersionInfo describes a version with the information of its file, as listed by
+// VersionsWithInfo and served by Handler.
+type VersionInfo struct {
+	// Timestamp is the timestamp of the version, embedded in its filename.
+	Timestamp Timestamp `json:"timestamp"`
+	// Size is the size of the version in bytes.
+	Size int64 `json:"size"`
+	// ModTime is the modification time of the file in the storage.
+	ModTime time.Time `json:"modTime"`
+}
+
+// VersionsWithInfo returns the versions of a file like Versions, sorted newest first, with
+// their size and modification time, from a single listing of the directory instead of one
+// Stat per version. Only the versions stored under the current name of the file are listed,
+// matching its name and extension exactly like Find. Returns an empty slice if the directory
+// doesn't exist.
+//
+// Example:
+//
+//	versions, err := vfs.VersionsWithInfo(file)
+//	for _, info := range versions {
+//	    fmt.Printf("%s: %d bytes\n", info.Timestamp, info.Size)
+//	}
+func (v *VersionFS) VersionsWithInfo(file File) ([]VersionInfo, error) {
+	info := newOpInfo(OpVersions, file)
+	end := v.instrument(context.Background(), &info)
+	versions, err := v.versionsWithInfo(file)
+	end(err)
+	return versions, err
+}
+
+// versionsWithInfo implements VersionsWithInfo.
+func (v *VersionFS) versionsWithInfo(file File) ([]VersionInfo, error) {
+	dir := slashed(file.Dir())
+	if err := v.confine("versions", dir); err != nil {
+		return nil, err
+	}
+	entries, err := v.backend().ReadDir(path_.Join(v.RootPath, dir))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return []VersionInfo{}, nil
+		}
+		return nil, err
+	}
+	versions := []VersionInfo{}
+	for _, entry := range entries {
+		if entry.IsDir() || v.hidden(entry.Name()) {
+			continue
+		}
+		ts, ok := v.matchVersion(dir, entry.Name(), file)
+		if !ok {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		if v.SkipEmpty && info.Size() == 0 {
+			continue
+		}
+		versions = append(versions, VersionInfo{Timestamp: ts, Size: info.Size(), ModTime: info.ModTime()})
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].Timestamp.time.After(versions[j].Timestamp.time)
+	})
+	return versions, nil
+}
+
+// VersionsByModTime returns the versions of a file whose modification time in the storage is
+// within [start, end), sorted newest first by modification time, to find the versions that
+// actually landed in a window when the clock that named them drifted. It filters the listing
+// of VersionsWithInfo, ignoring the timestamps embedded in the filenames.
+//
+// Example:
+//
+//	landed, err := vfs.VersionsByModTime(file, time.Now().Add(-time.Hour), time.Now())
+func (v *VersionFS) VersionsByModTime(file File, start, end time.Time) ([]VersionInfo, error) {
+	versions, err := v.VersionsWithInfo(file)
+	if err != nil {
+		return nil, err
+	}
+	matching := versions[:0]
+	for _, info := range versions {
+		if !info.ModTime.Before(start) && info.ModTime.Before(end) {
+			matching = append(matching, info)
+		}
+	}
+	sort.SliceStable(matching, func(i, j int) bool {
+		return matching[i].ModTime.After(matching[j].ModTime)
+	})
+	return matching, nil
+}
+
+// LatestContentInfo returns the content of the latest version of a file with its information,
+// for example to serve it with its Content-Length and Last-Modified headers. The version is
+// opened once, then stated and read through the same handle, so that the information describes
+// the content returned even if the version is replaced in between; Size is the length of the
+// content. Returns ErrNoVersions if the file has no versions.
+//
+// Example:
+//
+//	data, info, err := vfs.LatestContentInfo(file)
+//	if errors.Is(err, versionfs.ErrNoVersions) {
+//	    fmt.Println("No versions yet")
+//	} else if err != nil {
+//	    log.Fatal(err)
+//	}
+//	w.Header().Set("Last-Modified", info.ModTime.UTC().Format(http.TimeFormat))
+func (v *VersionFS) LatestContentInfo(file File) ([]byte, VersionInfo, error) {
+	ts, err := v.LastVersion(file)
+	if err != nil {
+		return nil, VersionInfo{}, err
+	}
+	info := newOpInfo(OpRead, file)
+	info.Timestamp = ts
+	end := v.instrument(context.Background(), &info)
+	data, vinfo, err := v.contentInfo(file, ts)
+	info.Bytes = int64(len(data))
+	end(err)
+	if err != nil {
+		return nil, VersionInfo{}, err
+	}
+	return data, vinfo, nil
+}
+
+// contentInfo reads a version with its information for LatestContentInfo, from the fallback
+// when it doesn't exist in this root.
+func (v *VersionFS) contentInfo(file File, ts Timestamp) ([]byte, VersionInfo, error) {
+	v.logger().Debugf("Reading file %s/%s.%s.%s with its info", file.Dir(), file.Name(), file.Ext(), ts)
+	name, filepath := v.resolvedPaths(file, ts)
+	if err := v.confine("read", name); err != nil {
+		return nil, VersionInfo{}, err
+	}
+	if err := v.restrict("read", name); err != nil {
+		return nil, VersionInfo{}, err
+	}
+	f, err := v.backend().Open(filepath)
+	if err != nil {
+		err = versionNotFound(err)
+		if v.fallback != nil && errors.Is(err, ErrVersionNotFound) {
+			return v.fallback.contentInfo(file, ts)
+		}
+		return nil, VersionInfo{}, err
+	}
+	defer func() { _ = f.Close() }()
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, VersionInfo{}, err
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, stat.Size()+bytes.MinRead))
+	if _, err := buf.ReadFrom(f); err != nil {
+		return nil, VersionInfo{}, err
+	}
+	data := buf.Bytes()
+	if v.VerifyOnRead {
+		if err := v.verify(name, data); err != nil {
+			return nil, VersionInfo{}, err
+		}
+	}
+	return data, VersionInfo{Timestamp: ts, Size: int64(len(data)), ModTime: stat.ModTime()}, nil
+}
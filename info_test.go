@@ -0,0 +1,93 @@
+package versionfs
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestVersionFS_VersionsWithInfo(t *testing.T) {
+	t.Parallel()
+	vfs := New(t.TempDir())
+	file := fileLeague{season: 2023}
+	first := putVersion(t, vfs, file, "20231018140523", "first")
+	second := putVersion(t, vfs, file, "20231019140523", "second")
+	putRaw(t, vfs, "2023/league/league.json.20231020140523", "other extension")
+	landed := time.Date(2023, 10, 19, 14, 5, 30, 0, time.UTC)
+	assert.Nil(t, os.Chtimes(path.Join(vfs.RootPath, Path(file, second)), landed, landed))
+
+	versions, err := vfs.VersionsWithInfo(file)
+	assert.Nil(t, err)
+	assert.Len(t, versions, 2)
+	assert.Equal(t, second.String(), versions[0].Timestamp.String())
+	assert.Equal(t, int64(6), versions[0].Size)
+	assert.True(t, landed.Equal(versions[0].ModTime))
+	assert.Equal(t, first.String(), versions[1].Timestamp.String())
+	assert.Equal(t, int64(5), versions[1].Size)
+
+	versions, err = vfs.VersionsWithInfo(fileLeague{season: 2024})
+	assert.Nil(t, err)
+	assert.NotNil(t, versions)
+	assert.Empty(t, versions)
+}
+
+func TestVersionFS_VersionsByModTime(t *testing.T) {
+	t.Parallel()
+	vfs := New(t.TempDir())
+	file := fileLeague{season: 2023}
+	// the clock that named the versions drifted: the newest timestamp landed first
+	base := time.Date(2023, 10, 20, 12, 0, 0, 0, time.UTC)
+	for i, ts := range []string{"20231019140523", "20231018140523", "20231017140523"} {
+		version := putVersion(t, vfs, file, ts, "data")
+		mtime := base.Add(time.Duration(i) * time.Hour)
+		assert.Nil(t, os.Chtimes(path.Join(vfs.RootPath, Path(file, version)), mtime, mtime))
+	}
+
+	versions, err := vfs.VersionsByModTime(file, base, base.Add(2*time.Hour))
+	assert.Nil(t, err)
+	assert.Len(t, versions, 2)
+	assert.Equal(t, "20231018140523", versions[0].Timestamp.String())
+	assert.Equal(t, "20231019140523", versions[1].Timestamp.String())
+
+	versions, err = vfs.VersionsByModTime(file, base.Add(time.Minute), base.Add(3*time.Hour))
+	assert.Nil(t, err)
+	assert.Len(t, versions, 2)
+	assert.Equal(t, "20231017140523", versions[0].Timestamp.String())
+	assert.Equal(t, "20231018140523", versions[1].Timestamp.String())
+
+	versions, err = vfs.VersionsByModTime(file, base.Add(-time.Hour), base)
+	assert.Nil(t, err)
+	assert.Empty(t, versions)
+}
+
+func TestVersionFS_LatestContentInfo(t *testing.T) {
+	t.Parallel()
+	vfs := New(t.TempDir())
+	file := fileLeague{season: 2023}
+	_, _, err := vfs.LatestContentInfo(file)
+	assert.Equal(t, ErrNoVersions, err)
+
+	putVersion(t, vfs, file, "20231018140523", "first")
+	second := putVersion(t, vfs, file, "20231019140523", "second")
+	landed := time.Date(2023, 10, 19, 14, 5, 30, 0, time.UTC)
+	assert.Nil(t, os.Chtimes(path.Join(vfs.RootPath, Path(file, second)), landed, landed))
+
+	data, info, err := vfs.LatestContentInfo(file)
+	assert.Nil(t, err)
+	assert.Equal(t, "second", string(data))
+	assert.Equal(t, second.String(), info.Timestamp.String())
+	assert.Equal(t, int64(6), info.Size)
+	assert.True(t, landed.Equal(info.ModTime))
+
+	// the latest version may only exist in the fallback
+	fallback := NewMemory()
+	vfs.SetFallback(fallback)
+	newer := putVersion(t, fallback, file, "20231020140523", "in the fallback")
+	data, info, err = vfs.LatestContentInfo(file)
+	assert.Nil(t, err)
+	assert.Equal(t, "in the fallback", string(data))
+	assert.Equal(t, newer.String(), info.Timestamp.String())
+	assert.Equal(t, int64(15), info.Size)
+}
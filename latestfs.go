@@ -0,0 +1,216 @@
+package versionfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	path_ "path"
+	"sort"
+	"time"
+)
+
+// FS returns a read-only fs.FS view of the tree where every logical file, named
+// without its timestamp, resolves to its latest version: Open("2023/league/league.json")
+// opens the newest "2023/league/league.json.<timestamp>". ReadDir lists the logical files
+// and the subdirectories, hiding the timestamped names and the unversioned files.
+//
+// Each Open call resolves the latest version at that moment: an opened file keeps
+// reading the version it resolved even if newer versions are written afterwards.
+//
+// Example:
+//
+//	http.Handle("/data/", http.StripPrefix("/data/", http.FileServer(http.FS(vfs.FS()))))
+func (v *VersionFS) FS() fs.FS {
+	return latestFS{v: v}
+}
+
+// FSAt returns a read-only fs.FS view like FS, pinned at a point in time: every logical file
+// resolves to its version effective at that instant, as with VersionAt, the timestamps being
+// interpreted in the local time zone. Files without a version by then return fs.ErrNotExist
+// and are hidden from ReadDir.
+//
+// Example:
+//
+//	report := vfs.FSAt(time.Date(2023, 10, 1, 0, 0, 0, 0, time.Local))
+//	data, err := fs.ReadFile(report, "2023/league/league.json")
+func (v *VersionFS) FSAt(at time.Time) fs.FS {
+	return latestFS{v: v, at: &at}
+}
+
+// latestFS is the fs.FS returned by FS and FSAt.
+type latestFS struct {
+	v  *VersionFS
+	at *time.Time // versions after at are ignored when set
+}
+
+// logicalVersion is the resolved version of a logical file in a directory.
+type logicalVersion struct {
+	name string
+	ts   Timestamp
+	info fs.FileInfo
+}
+
+// Open implements fs.FS.
+func (l latestFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	full := path_.Join(l.v.RootPath, name)
+	if info, err := l.v.backend().Stat(full); err == nil && info.IsDir() {
+		entries, err := l.ReadDir(name)
+		if err != nil {
+			return nil, err
+		}
+		return &latestDir{info: renamedInfo{FileInfo: info, name: path_.Base(name)}, entries: entries}, nil
+	}
+	if name == "." {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	logical, err := l.resolve(path_.Dir(name))
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	for _, lv := range logical {
+		if lv.name == path_.Base(name) {
+			f, err := l.v.backend().Open(path_.Join(l.v.RootPath, path_.Dir(name), lv.info.Name()))
+			if err != nil {
+				return nil, err
+			}
+			return &latestFile{File: f, info: renamedInfo{FileInfo: lv.info, name: lv.name}}, nil
+		}
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadDir implements fs.ReadDirFS, listing the subdirectories and logical files of a directory.
+func (l latestFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	entries, err := l.v.backend().ReadDir(path_.Join(l.v.RootPath, name))
+	if err != nil {
+		return nil, err
+	}
+	logical, err := l.logical(entries)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	var result []fs.DirEntry
+	for _, entry := range entries {
+		if entry.IsDir() {
+			result = append(result, entry)
+		}
+	}
+	for _, lv := range logical {
+		result = append(result, fs.FileInfoToDirEntry(renamedInfo{FileInfo: lv.info, name: lv.name}))
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name() < result[j].Name() })
+	return result, nil
+}
+
+// resolve returns the logical files of a directory with their latest version.
+func (l latestFS) resolve(dir string) ([]logicalVersion, error) {
+	entries, err := l.v.backend().ReadDir(path_.Join(l.v.RootPath, dir))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fs.ErrNotExist
+		}
+		return nil, err
+	}
+	return l.logical(entries)
+}
+
+// logical groups the versioned files among entries by logical name, keeping the latest version of each.
+func (l latestFS) logical(entries []fs.DirEntry) ([]logicalVersion, error) {
+	latest := make(map[string]logicalVersion)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name, ext, ts, err := ParseFilename(entry.Name())
+		if err != nil {
+			continue
+		}
+		if l.at != nil && ts.time.After(*l.at) {
+			continue
+		}
+		logicalName := name + "." + ext
+		if current, ok := latest[logicalName]; ok && !ts.time.After(current.ts.time) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		latest[logicalName] = logicalVersion{name: logicalName, ts: ts, info: info}
+	}
+	logical := make([]logicalVersion, 0, len(latest))
+	for _, lv := range latest {
+		logical = append(logical, lv)
+	}
+	sort.Slice(logical, func(i, j int) bool { return logical[i].name < logical[j].name })
+	return logical, nil
+}
+
+// renamedInfo is a fs.FileInfo reporting another name, the logical name of a version.
+type renamedInfo struct {
+	fs.FileInfo
+	name string
+}
+
+func (i renamedInfo) Name() string { return i.name }
+
+// latestFile is a version opened through a logical name.
+type latestFile struct {
+	fs.File
+	info fs.FileInfo
+}
+
+func (f *latestFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+// Seek implements io.Seeker when the underlying file does, as required by http.FileServer.
+func (f *latestFile) Seek(offset int64, whence int) (int64, error) {
+	seeker, ok := f.File.(io.Seeker)
+	if !ok {
+		return 0, errors.New("seek not supported")
+	}
+	return seeker.Seek(offset, whence)
+}
+
+// latestDir is a directory opened through FS.
+type latestDir struct {
+	info    fs.FileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *latestDir) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *latestDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.Name(), Err: errors.New("is a directory")}
+}
+
+func (d *latestDir) Close() error {
+	return nil
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (d *latestDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	remaining := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return remaining, nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(remaining) {
+		n = len(remaining)
+	}
+	d.offset += n
+	return remaining[:n], nil
+}
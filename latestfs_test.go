@@ -0,0 +1,165 @@
+package versionfs
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func newLatestVersionFS(t *testing.T) *VersionFS {
+	t.Helper()
+	vfs := NewMemory()
+	putRaw(t, vfs, "2023/league/league.json.20211125011947", `{"v":1}`)
+	putRaw(t, vfs, "2023/league/league.json.20231019140523", `{"v":2}`)
+	putRaw(t, vfs, "2023/league/league.csv.20211125011947", "v,1")
+	putRaw(t, vfs, "2023/league/notes.txt", "unversioned")
+	putRaw(t, vfs, "2023/roster/team-12/roster-12.json.20231019140523", `{"team":12}`)
+	return vfs
+}
+
+func TestVersionFS_FS_Open(t *testing.T) {
+	t.Parallel()
+	fsys := newLatestVersionFS(t).FS()
+	data, err := fs.ReadFile(fsys, "2023/league/league.json")
+	assert.Nil(t, err)
+	assert.Equal(t, `{"v":2}`, string(data))
+	info, err := fs.Stat(fsys, "2023/league/league.json")
+	assert.Nil(t, err)
+	assert.Equal(t, "league.json", info.Name())
+	assert.Equal(t, int64(7), info.Size())
+	// timestamped and unversioned names are hidden
+	_, err = fsys.Open("2023/league/league.json.20231019140523")
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+	_, err = fsys.Open("2023/league/notes.txt")
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+	_, err = fsys.Open("2024/league/league.json")
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+	_, err = fsys.Open("../league.json")
+	assert.True(t, errors.Is(err, fs.ErrInvalid))
+}
+
+func TestVersionFS_FS_ReadDir(t *testing.T) {
+	t.Parallel()
+	fsys := newLatestVersionFS(t).FS()
+	entries, err := fs.ReadDir(fsys, "2023/league")
+	assert.Nil(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.Equal(t, []string{"league.csv", "league.json"}, names)
+	entries, err = fs.ReadDir(fsys, "2023")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(entries))
+	assert.True(t, entries[0].IsDir())
+	assert.Equal(t, "league", entries[0].Name())
+	// paging through an opened directory
+	f, err := fsys.Open("2023/league")
+	assert.Nil(t, err)
+	dir := f.(fs.ReadDirFile)
+	entries, err = dir.ReadDir(1)
+	assert.Nil(t, err)
+	assert.Equal(t, "league.csv", entries[0].Name())
+	entries, err = dir.ReadDir(5)
+	assert.Nil(t, err)
+	assert.Equal(t, "league.json", entries[0].Name())
+	_, err = dir.ReadDir(1)
+	assert.Equal(t, io.EOF, err)
+	assert.Nil(t, f.Close())
+}
+
+func TestVersionFS_FS_TestFS(t *testing.T) {
+	t.Parallel()
+	fsys := newLatestVersionFS(t).FS()
+	assert.Nil(t, fstest.TestFS(fsys, "2023/league/league.json", "2023/league/league.csv", "2023/roster/team-12/roster-12.json"))
+}
+
+func TestVersionFS_FS_FileServer(t *testing.T) {
+	t.Parallel()
+	vfs := newLatestVersionFS(t)
+	server := httptest.NewServer(http.FileServer(http.FS(vfs.FS())))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/2023/league/league.json")
+	assert.Nil(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	assert.Equal(t, `{"v":2}`, string(body))
+
+	// a newer version is served on the next request
+	putRaw(t, vfs, "2023/league/league.json.20241019140523", `{"v":3}`)
+	resp, err = http.Get(server.URL + "/2023/league/league.json")
+	assert.Nil(t, err)
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, `{"v":3}`, string(body))
+
+	resp, err = http.Get(server.URL + "/2023/league/")
+	assert.Nil(t, err)
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Contains(t, string(body), `href="league.json"`)
+	assert.NotContains(t, string(body), "20231019140523")
+
+	resp, err = http.Get(server.URL + "/2023/league/missing.json")
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestVersionFS_FSAt(t *testing.T) {
+	t.Parallel()
+	vfs := newLatestVersionFS(t)
+	before := vfs.FSAt(time.Date(2022, 1, 1, 0, 0, 0, 0, time.Local))
+	after := vfs.FSAt(time.Date(2023, 12, 31, 0, 0, 0, 0, time.Local))
+
+	data, err := fs.ReadFile(before, "2023/league/league.json")
+	assert.Nil(t, err)
+	assert.Equal(t, `{"v":1}`, string(data))
+	data, err = fs.ReadFile(after, "2023/league/league.json")
+	assert.Nil(t, err)
+	assert.Equal(t, `{"v":2}`, string(data))
+
+	// the roster didn't exist yet
+	_, err = before.Open("2023/roster/team-12/roster-12.json")
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+	entries, err := fs.ReadDir(before, "2023/roster/team-12")
+	assert.Nil(t, err)
+	assert.Empty(t, entries)
+	entries, err = fs.ReadDir(after, "2023/roster/team-12")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entries))
+
+	// the cutoff is inclusive
+	exact := vfs.FSAt(time.Date(2023, 10, 19, 14, 5, 23, 0, time.Local))
+	data, err = fs.ReadFile(exact, "2023/league/league.json")
+	assert.Nil(t, err)
+	assert.Equal(t, `{"v":2}`, string(data))
+}
+
+// A version just written is effective now, whatever the offset of the local time zone.
+func TestVersionFS_FSAt_Now(t *testing.T) {
+	for _, zone := range []struct {
+		name   string
+		offset int
+	}{{"JST", 9 * 3600}, {"EDT", -4 * 3600}} {
+		t.Run(zone.name, func(t *testing.T) {
+			setLocal(t, zone.name, zone.offset)
+			vfs := New(t.TempDir())
+			_, err := vfs.Write(fileLeague{season: 2023}, []byte("now"))
+			assert.Nil(t, err)
+			data, err := fs.ReadFile(vfs.FSAt(time.Now()), "2023/league/league.txt")
+			assert.Nil(t, err)
+			assert.Equal(t, "now", string(data))
+		})
+	}
+}
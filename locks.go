@@ -0,0 +1,63 @@
+package versionfs
+
+import (
+	"hash/fnv"
+	path_ "path"
+	"sync"
+)
+
+// lockStripes is the number of mutexes the file locks are striped over.
+const lockStripes = 64
+
+// fileLocks are the per-file locks of an instance, striped by the hash of the file's
+// root, directory, and name so that unrelated files rarely contend.
+type fileLocks struct {
+	// writes serializes Write on the same file.
+	writes [lockStripes]sync.Mutex
+	// callers serializes the sequences guarded by LockFile.
+	callers [lockStripes]sync.Mutex
+	// dirs serializes the directory locks of ProcessLocks on the platforms without them.
+	dirs [lockStripes]sync.Mutex
+}
+
+// stripe returns the index of the mutexes guarding a file.
+func (v *VersionFS) stripe(file File) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(path_.Join(v.RootPath, file.Dir(), file.Name())))
+	return int(h.Sum32() % lockStripes)
+}
+
+// dirStripe returns the index of the mutex guarding a directory.
+func (v *VersionFS) dirStripe(dir string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(path_.Join(v.RootPath, slashed(dir))))
+	return int(h.Sum32() % lockStripes)
+}
+
+// lockWrite locks the writes of a file and returns the function unlocking them.
+func (v *VersionFS) lockWrite(file File) func() {
+	mu := &v.locks.writes[v.stripe(file)]
+	mu.Lock()
+	return mu.Unlock
+}
+
+// LockFile locks a file for a read-modify-write sequence and returns the function
+// unlocking it. Other callers of LockFile on the same file, identified by its directory
+// and name, wait until it is unlocked, while files with other names can be locked in
+// parallel. The lock is held within the process only, and Write, Read, and the other
+// operations don't take it, so that they can be called while holding it: every writer
+// of the sequence has to use LockFile. Locks are shared with the instances created by
+// WithRoot and Clone.
+//
+// Example:
+//
+//	unlock := vfs.LockFile(file)
+//	defer unlock()
+//	latest, err := vfs.LastVersion(file)
+//	...
+//	_, err = vfs.Write(file, updated)
+func (v *VersionFS) LockFile(file File) (unlock func()) {
+	mu := &v.locks.callers[v.stripe(file)]
+	mu.Lock()
+	return mu.Unlock
+}
@@ -0,0 +1,156 @@
+package versionfs
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io/fs"
+	"os"
+	"path"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestVersionFS_Write_Concurrent(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	file := fileLeague{season: 2023}
+	// colliding writes wait for the next second
+	const writers = 3
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := vfs.Write(file, []byte(strconv.Itoa(i)))
+			assert.Nil(t, err)
+		}(i)
+	}
+	wg.Wait()
+	versions, err := vfs.Versions(file)
+	assert.Nil(t, err)
+	assert.Len(t, versions, writers)
+}
+
+// A write within the same second as an existing version waits for the next second,
+// instead of dating the version in the future.
+func TestVersionFS_Write_NotInFuture(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	file := fileLeague{season: 2023}
+	var last Timestamp
+	for i := 0; i < 3; i++ {
+		ts, err := vfs.Write(file, []byte(strconv.Itoa(i)))
+		assert.Nil(t, err)
+		assert.False(t, ts.Time().After(time.Now()), ts.String())
+		last = ts
+	}
+	ts, err := vfs.VersionAt(file, time.Now())
+	assert.Nil(t, err)
+	assert.Equal(t, last.String(), ts.String())
+	age, err := vfs.LatestAge(file)
+	assert.Nil(t, err)
+	assert.GreaterOrEqual(t, age, time.Duration(0))
+}
+
+func TestVersionFS_LockFile(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	file := fileLeague{season: 2023}
+	_, err := vfs.Write(file, []byte("0"))
+	assert.Nil(t, err)
+	const increments = 3
+	var wg sync.WaitGroup
+	for i := 0; i < increments; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock := vfs.LockFile(file)
+			defer unlock()
+			latest, err := vfs.LastVersion(file)
+			assert.Nil(t, err)
+			data, err := vfs.Read(file, latest)
+			assert.Nil(t, err)
+			n, _ := strconv.Atoi(string(data))
+			_, err = vfs.Write(file, []byte(strconv.Itoa(n+1)))
+			assert.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+	latest, err := vfs.LastVersion(file)
+	assert.Nil(t, err)
+	data, err := vfs.Read(file, latest)
+	assert.Nil(t, err)
+	assert.Equal(t, strconv.Itoa(increments), string(data))
+
+	// other files are locked independently
+	unlock := vfs.LockFile(file)
+	defer unlock()
+	vfs.LockFile(fileLeague{season: 2024})()
+}
+
+// Read-only and dry-run instances don't create lock files.
+func TestVersionFS_ProcessLocks_ReadOnly(t *testing.T) {
+	t.Parallel()
+	vfs := New(t.TempDir())
+	file := fileLeague{season: 2023}
+	putVersion(t, vfs, file, "20231018140523", "first")
+	putVersion(t, vfs, file, "20231019140523", "second")
+	vfs.ProcessLocks = true
+	lockFile := path.Join(vfs.RootPath, file.Dir(), LockFileName)
+
+	vfs.DryRun = true
+	removed, err := vfs.Prune(file, RetentionPolicy{KeepLast: 1})
+	assert.Nil(t, err)
+	assert.Len(t, removed, 1)
+	_, err = os.Stat(lockFile)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	vfs.DryRun = false
+	vfs.ReadOnly = true
+	_, err = vfs.Prune(file, RetentionPolicy{KeepLast: 1})
+	assert.ErrorIs(t, err, ErrReadOnly)
+	_, err = os.Stat(lockFile)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+// unsupportedLockBackend is a MemoryBackend that can't lock directories, like the local
+// filesystem on the platforms without flock.
+type unsupportedLockBackend struct {
+	*MemoryBackend
+}
+
+func (unsupportedLockBackend) LockDir(name string, timeout time.Duration) (func(), error) {
+	return nil, &fs.PathError{Op: "lock", Path: name, Err: errors.ErrUnsupported}
+}
+
+// Without locks shared between processes, the directories are locked within the process.
+func TestVersionFS_ProcessLocks_Unsupported(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	vfs.Backend = unsupportedLockBackend{vfs.Backend.(*MemoryBackend)}
+	vfs.ProcessLocks = true
+	file := fileLeague{season: 2023}
+	putVersion(t, vfs, file, "20231018140523", "first")
+	_, err := vfs.Write(file, []byte("second"))
+	assert.Nil(t, err)
+
+	unlock, err := vfs.lockDir(file.Dir())
+	assert.Nil(t, err)
+	pruned := make(chan error)
+	go func() {
+		_, err := vfs.Prune(file, RetentionPolicy{KeepLast: 1})
+		pruned <- err
+	}()
+	select {
+	case <-pruned:
+		t.Fatal("Prune didn't wait for the lock of the directory")
+	case <-time.After(20 * time.Millisecond):
+	}
+	unlock()
+	assert.Nil(t, <-pruned)
+	versions, err := vfs.Versions(file)
+	assert.Nil(t, err)
+	assert.Len(t, versions, 1)
+}
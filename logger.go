@@ -0,0 +1,57 @@
+package versionfs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// Logger receives the debug and warning messages of a VersionFS instance,
+// such as the unexpected files skipped while listing versions.
+type Logger interface {
+	Debugf(format string, args ...any)
+	Warnf(format string, args ...any)
+}
+
+// NopLogger is the Logger discarding every message, the default.
+type NopLogger struct{}
+
+// Debugf implements Logger, it does nothing.
+func (NopLogger) Debugf(format string, args ...any) {}
+
+// Warnf implements Logger, it does nothing.
+func (NopLogger) Warnf(format string, args ...any) {}
+
+// SlogLogger is the Logger writing to a *slog.Logger.
+//
+// Example:
+//
+//	vfs.Logger = versionfs.SlogLogger{Logger: slog.Default()}
+type SlogLogger struct {
+	Logger *slog.Logger
+}
+
+// Debugf implements Logger at the slog.LevelDebug level.
+func (l SlogLogger) Debugf(format string, args ...any) {
+	l.logf(slog.LevelDebug, format, args...)
+}
+
+// Warnf implements Logger at the slog.LevelWarn level.
+func (l SlogLogger) Warnf(format string, args ...any) {
+	l.logf(slog.LevelWarn, format, args...)
+}
+
+func (l SlogLogger) logf(level slog.Level, format string, args ...any) {
+	ctx := context.Background()
+	if l.Logger.Enabled(ctx, level) {
+		l.Logger.Log(ctx, level, fmt.Sprintf(format, args...))
+	}
+}
+
+// logger returns the Logger of the instance, discarding messages if none is set.
+func (v *VersionFS) logger() Logger {
+	if v.Logger == nil {
+		return NopLogger{}
+	}
+	return v.Logger
+}
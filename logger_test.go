@@ -0,0 +1,57 @@
+package versionfs
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"testing"
+)
+
+// recordingLogger is a Logger keeping every message.
+type recordingLogger struct {
+	messages []string
+}
+
+func (l *recordingLogger) Debugf(format string, args ...any) {
+	l.messages = append(l.messages, "debug: "+fmt.Sprintf(format, args...))
+}
+
+func (l *recordingLogger) Warnf(format string, args ...any) {
+	l.messages = append(l.messages, "warn: "+fmt.Sprintf(format, args...))
+}
+
+func TestVersionFS_Logger(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	logger := &recordingLogger{}
+	vfs.Logger = logger
+	file := fileLeague{season: 2023}
+	putRaw(t, vfs, "2023/league/league.txt.notatimestamp", "bad")
+	_, err := vfs.Find("2023/league", file)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"warn: unexpected timestamp for file: 2023/league/league.txt.notatimestamp"}, logger.messages)
+
+	// a nil Logger discards the messages
+	vfs.Logger = nil
+	_, err = vfs.Find("2023/league", file)
+	assert.Nil(t, err)
+}
+
+func TestSlogLogger(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	handler := slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelWarn,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	logger := SlogLogger{Logger: slog.New(handler)}
+	logger.Debugf("hidden %d", 1)
+	logger.Warnf("unexpected file: %s", "2023/league/league")
+	assert.Equal(t, "level=WARN msg=\"unexpected file: 2023/league/league\"\n", buf.String())
+}
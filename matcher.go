@@ -0,0 +1,113 @@
+package versionfs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Matcher matches the filenames of the versions of a file in the default layout,
+// name.ext.timestamp, the way Detect, Find, and Versions do. Compile it once with
+// CompileMatcher to match many filenames, such as the entries of a directory walked by
+// the caller.
+//
+// Example:
+//
+//	m := versionfs.CompileMatcher(file)
+//	for _, entry := range entries {
+//	    if ts, ok := m.Match(entry.Name()); ok {
+//	        fmt.Println("version", ts)
+//	    }
+//	}
+type Matcher struct {
+	name, ext string
+	// foldExt compares the extensions case-insensitively, like CaseInsensitiveExt.
+	foldExt bool
+}
+
+// mismatch is the reason a filename doesn't match a Matcher.
+type mismatch int
+
+const (
+	matched mismatch = iota
+	wrongName
+	noDotAfterName
+	noExt
+	wrongExt
+	badTimestamp
+)
+
+// CompileMatcher returns the Matcher of the versions of file. The extensions are compared
+// exactly, like a VersionFS without CaseInsensitiveExt; a VersionFS with a Scheme parses
+// other filenames, use its Detect method.
+func CompileMatcher(file File) Matcher {
+	return Matcher{name: file.Name(), ext: file.Ext()}
+}
+
+// matcher returns the Matcher of file, comparing the extensions like matchExt.
+func (v *VersionFS) matcher(file File) Matcher {
+	return Matcher{name: file.Name(), ext: file.Ext(), foldExt: v.CaseInsensitiveExt}
+}
+
+// Match returns the timestamp of basename if it is a version of the file, without
+// allocating.
+func (m Matcher) Match(basename string) (Timestamp, bool) {
+	ts, reason, _ := m.match(basename)
+	return ts, reason == matched
+}
+
+// MatchError works like Match, but returns an error describing why basename isn't a
+// version of the file, the error of Detect.
+func (m Matcher) MatchError(basename string) (Timestamp, error) {
+	ts, reason, err := m.match(basename)
+	switch reason {
+	case wrongName:
+		return Timestamp{}, fmt.Errorf("filename %q does not match file name %q", basename, m.name)
+	case noDotAfterName:
+		return Timestamp{}, fmt.Errorf("filename %q has invalid format, expected dot after name", basename)
+	case noExt:
+		return Timestamp{}, fmt.Errorf("filename %q has invalid format, expected ext.timestamp", basename)
+	case wrongExt:
+		actualExt := basename[len(m.name)+1 : strings.LastIndexByte(basename, '.')]
+		return Timestamp{}, fmt.Errorf("filename %q has extension %q but expected %q", basename, actualExt, m.ext)
+	case badTimestamp:
+		return Timestamp{}, fmt.Errorf("filename %q has invalid timestamp: %w", basename, err)
+	}
+	return ts, nil
+}
+
+// match implements Match and MatchError, returning why basename doesn't match, with the
+// error of its timestamp.
+func (m Matcher) match(basename string) (Timestamp, mismatch, error) {
+	if !strings.HasPrefix(basename, m.name) {
+		return Timestamp{}, wrongName, nil
+	}
+	// Expected format: name.ext.timestamp or name.ext1.ext2.timestamp
+	rest := basename[len(m.name):]
+	if len(rest) == 0 || rest[0] != '.' {
+		return Timestamp{}, noDotAfterName, nil
+	}
+	rest = rest[1:]
+	last := strings.LastIndexByte(rest, '.')
+	if last < 0 {
+		return Timestamp{}, noExt, nil
+	}
+	// Everything before the last dot is the extension, possibly multi-part like csv.gz
+	if ext := rest[:last]; ext != m.ext && !(m.foldExt && strings.EqualFold(ext, m.ext)) {
+		return Timestamp{}, wrongExt, nil
+	}
+	ts, err := NewTimestamp(rest[last+1:])
+	if err != nil {
+		return Timestamp{}, badTimestamp, err
+	}
+	return ts, matched, nil
+}
+
+// matchName returns the timestamp of filename, an entry of dir, if m matches it, warning
+// about the versions whose timestamp doesn't parse.
+func (v *VersionFS) matchName(m Matcher, dir, filename string) (Timestamp, bool) {
+	ts, reason, _ := m.match(filename)
+	if reason == badTimestamp {
+		v.logger().Warnf("unexpected timestamp for file: %s/%s", dir, filename)
+	}
+	return ts, reason == matched
+}
@@ -0,0 +1,88 @@
+package versionfs
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"path"
+	"testing"
+)
+
+func TestMatcher(t *testing.T) {
+	t.Parallel()
+	m := CompileMatcher(fileThemes{})
+	ts, ok := m.Match("themes.csv.gz.20231019140523")
+	assert.True(t, ok)
+	assert.Equal(t, "20231019140523", ts.String())
+	for filename, reason := range map[string]string{
+		"players.csv.gz.20231019140523":   `filename "players.csv.gz.20231019140523" does not match file name "themes"`,
+		"themes_csv.gz.20231019140523":    `filename "themes_csv.gz.20231019140523" has invalid format, expected dot after name`,
+		"themes.20231019140523":           `filename "themes.20231019140523" has invalid format, expected ext.timestamp`,
+		"themes.csv.20231019140523":       `filename "themes.csv.20231019140523" has extension "csv" but expected "csv.gz"`,
+		"themes.CSV.GZ.20231019140523":    `filename "themes.CSV.GZ.20231019140523" has extension "CSV.GZ" but expected "csv.gz"`,
+		"themes.csv.gz.2023-10-19":        `filename "themes.csv.gz.2023-10-19" has invalid timestamp`,
+		"themes.csv.gz.20231019140523.sh": `filename "themes.csv.gz.20231019140523.sh" has extension "csv.gz.20231019140523" but expected "csv.gz"`,
+	} {
+		_, ok := m.Match(filename)
+		assert.False(t, ok, filename)
+		_, err := m.MatchError(filename)
+		if assert.NotNil(t, err, filename) {
+			assert.Contains(t, err.Error(), reason)
+		}
+	}
+}
+
+func TestMatcher_Allocs(t *testing.T) {
+	var file File = fileThemes{}
+	m := CompileMatcher(file)
+	for _, filename := range []string{
+		"themes.csv.gz.20231019140523",
+		"themes.json.20231019140523",
+		"roster.json.20231019140523",
+	} {
+		assert.Zero(t, testing.AllocsPerRun(100, func() {
+			m.Match(filename)
+		}), filename)
+	}
+}
+
+// TestMatcher_Agrees checks that Detect, Find, Versions, and Match accept the same versions,
+// on random filenames sharing a directory.
+func TestMatcher_Agrees(t *testing.T) {
+	t.Parallel()
+	rng := rand.New(rand.NewSource(1))
+	names := []string{"league", "leagues", "league_", "lea", ""}
+	exts := []string{"txt", "TXT", "json", "txt.gz", "", "tx"}
+	timestamps := []string{"20231019140523", "2023101914", "20231019", "2023-10-19", "", "20231019140523.1"}
+	file := fileLeague{season: 2023}
+	for _, caseInsensitive := range []bool{false, true} {
+		vfs := NewMemory()
+		vfs.Logger = NopLogger{}
+		vfs.CaseInsensitiveExt = caseInsensitive
+		m := vfs.matcher(file)
+		want := map[string]bool{}
+		for i := 0; i < 200; i++ {
+			filename := names[rng.Intn(len(names))] + "." + exts[rng.Intn(len(exts))] + "." + timestamps[rng.Intn(len(timestamps))]
+			putRaw(t, vfs, path.Join(file.Dir(), filename), "data")
+			ts, ok := m.Match(filename)
+			_, err := m.MatchError(filename)
+			assert.Equal(t, ok, err == nil, filename)
+			detected, err := vfs.Detect(filename, file)
+			assert.Equal(t, ok, err == nil, filename)
+			if ok {
+				assert.Equal(t, ts.String(), detected.String(), filename)
+				want[ts.String()] = true
+			}
+		}
+		versions, err := vfs.Versions(file)
+		assert.Nil(t, err)
+		found, err := vfs.Find(file.Dir(), file)
+		assert.Nil(t, err)
+		for _, listed := range [][]Timestamp{versions, found} {
+			got := map[string]bool{}
+			for _, ts := range listed {
+				got[ts.String()] = true
+			}
+			assert.Equal(t, want, got)
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package versionfs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	path_ "path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NewMemory creates a new VersionFS instance storing its files in memory.
+// It behaves like an instance created with New, which makes it convenient for
+// unit tests of code built on versionfs, without temporary directories.
+//
+// Example:
+//
+//	vfs := versionfs.NewMemory()
+//	vfs.RegisterFileType(LeagueFileType, func(args ...any) versionfs.File {
+//	    return LeagueFile{season: args[0].(int)}
+//	})
+func NewMemory() *VersionFS {
+	v := New("")
+	v.Backend = NewMemoryBackend()
+	return v
+}
+
+// MemoryBackend is a Backend storing files and directories in memory.
+// It is safe for concurrent use.
+type MemoryBackend struct {
+	mu    sync.RWMutex
+	files map[string]*memoryFile
+	dirs  map[string]time.Time
+}
+
+// memoryFile is the content of a file stored by MemoryBackend.
+type memoryFile struct {
+	data    []byte
+	perm    fs.FileMode
+	modTime time.Time
+}
+
+// NewMemoryBackend creates an empty MemoryBackend.
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		files: make(map[string]*memoryFile),
+		dirs:  make(map[string]time.Time),
+	}
+}
+
+// clean normalizes a name, the root directory is ".".
+func (m *MemoryBackend) clean(name string) string {
+	name = path_.Clean(name)
+	if name == "/" {
+		return "."
+	}
+	return strings.TrimPrefix(name, "/")
+}
+
+// isDir reports whether a cleaned name is an existing directory. The caller must hold the lock.
+func (m *MemoryBackend) isDir(name string) bool {
+	if name == "." {
+		return true
+	}
+	_, ok := m.dirs[name]
+	return ok
+}
+
+// ReadFile implements Backend.
+func (m *MemoryBackend) ReadFile(name string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	f, ok := m.files[m.clean(name)]
+	if !ok {
+		if m.isDir(m.clean(name)) {
+			return nil, &fs.PathError{Op: "read", Path: name, Err: errors.New("is a directory")}
+		}
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return bytes.Clone(f.data), nil
+}
+
+// WriteFile implements Backend.
+func (m *MemoryBackend) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cleaned := m.clean(name)
+	if m.isDir(cleaned) {
+		return &fs.PathError{Op: "open", Path: name, Err: errors.New("is a directory")}
+	}
+	if !m.isDir(path_.Dir(cleaned)) {
+		return &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	m.files[cleaned] = &memoryFile{data: bytes.Clone(data), perm: perm, modTime: time.Now()}
+	return nil
+}
+
+// Create implements RenameBackend. The file is stored when the writer is closed.
+func (m *MemoryBackend) Create(name string, perm fs.FileMode) (io.WriteCloser, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	cleaned := m.clean(name)
+	if m.isDir(cleaned) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errors.New("is a directory")}
+	}
+	if !m.isDir(path_.Dir(cleaned)) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return &memoryWriter{backend: m, name: name, perm: perm}, nil
+}
+
+// Rename implements RenameBackend. A directory is renamed with its content, and can't
+// replace an existing file or directory.
+func (m *MemoryBackend) Rename(oldname, newname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	oldCleaned, newCleaned := m.clean(oldname), m.clean(newname)
+	if _, ok := m.dirs[oldCleaned]; ok {
+		return m.renameDir(oldname, newname, oldCleaned, newCleaned)
+	}
+	f, ok := m.files[oldCleaned]
+	if !ok {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: fs.ErrNotExist}
+	}
+	if m.isDir(newCleaned) || !m.isDir(path_.Dir(newCleaned)) {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: fs.ErrInvalid}
+	}
+	delete(m.files, oldCleaned)
+	m.files[newCleaned] = f
+	return nil
+}
+
+// renameDir renames the directory oldCleaned and its content to newCleaned. The caller must hold the lock.
+func (m *MemoryBackend) renameDir(oldname, newname, oldCleaned, newCleaned string) error {
+	if _, ok := m.stat(newCleaned); ok {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: fs.ErrExist}
+	}
+	if !m.isDir(path_.Dir(newCleaned)) || strings.HasPrefix(newCleaned, oldCleaned+"/") {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: fs.ErrInvalid}
+	}
+	moved := func(name string) (string, bool) {
+		if name == oldCleaned {
+			return newCleaned, true
+		} else if rest, ok := strings.CutPrefix(name, oldCleaned+"/"); ok {
+			return newCleaned + "/" + rest, true
+		}
+		return "", false
+	}
+	for name, f := range m.files {
+		if to, ok := moved(name); ok {
+			delete(m.files, name)
+			m.files[to] = f
+		}
+	}
+	for name, modTime := range m.dirs {
+		if to, ok := moved(name); ok {
+			delete(m.dirs, name)
+			m.dirs[to] = modTime
+		}
+	}
+	return nil
+}
+
+// Open implements Backend. The returned file implements io.Seeker.
+func (m *MemoryBackend) Open(name string) (fs.File, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	cleaned := m.clean(name)
+	f, ok := m.files[cleaned]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return &memoryReader{Reader: bytes.NewReader(f.data), info: m.fileInfo(cleaned, f)}, nil
+}
+
+// Remove implements Backend.
+func (m *MemoryBackend) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cleaned := m.clean(name)
+	if _, ok := m.files[cleaned]; ok {
+		delete(m.files, cleaned)
+		return nil
+	}
+	if _, ok := m.dirs[cleaned]; ok {
+		if len(m.children(cleaned)) > 0 {
+			return &fs.PathError{Op: "remove", Path: name, Err: errors.New("directory not empty")}
+		}
+		delete(m.dirs, cleaned)
+		return nil
+	}
+	return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadDir implements Backend.
+func (m *MemoryBackend) ReadDir(name string) ([]fs.DirEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	cleaned := m.clean(name)
+	if !m.isDir(cleaned) {
+		if _, ok := m.files[cleaned]; ok {
+			return nil, &fs.PathError{Op: "readdirent", Path: name, Err: errors.New("not a directory")}
+		}
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	children := m.children(cleaned)
+	entries := make([]fs.DirEntry, 0, len(children))
+	for _, child := range children {
+		info, _ := m.stat(child)
+		entries = append(entries, fs.FileInfoToDirEntry(info))
+	}
+	return entries, nil
+}
+
+// children returns the sorted names of the direct children of a directory. The caller must hold the lock.
+func (m *MemoryBackend) children(dir string) []string {
+	var children []string
+	for name := range m.files {
+		if path_.Dir(name) == dir {
+			children = append(children, name)
+		}
+	}
+	for name := range m.dirs {
+		if path_.Dir(name) == dir {
+			children = append(children, name)
+		}
+	}
+	sort.Strings(children)
+	return children
+}
+
+// Stat implements Backend.
+func (m *MemoryBackend) Stat(name string) (fs.FileInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	info, ok := m.stat(m.clean(name))
+	if !ok {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return info, nil
+}
+
+// stat returns the information of a cleaned name. The caller must hold the lock.
+func (m *MemoryBackend) stat(name string) (fs.FileInfo, bool) {
+	if f, ok := m.files[name]; ok {
+		return m.fileInfo(name, f), true
+	}
+	if name == "." {
+		return memoryInfo{name: ".", mode: fs.ModeDir | 0755}, true
+	}
+	if modTime, ok := m.dirs[name]; ok {
+		return memoryInfo{name: path_.Base(name), mode: fs.ModeDir | 0755, modTime: modTime}, true
+	}
+	return nil, false
+}
+
+func (m *MemoryBackend) fileInfo(name string, f *memoryFile) fs.FileInfo {
+	return memoryInfo{name: path_.Base(name), size: int64(len(f.data)), mode: f.perm, modTime: f.modTime}
+}
+
+// MkdirAll implements Backend.
+func (m *MemoryBackend) MkdirAll(name string, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cleaned := m.clean(name)
+	var missing []string
+	for dir := cleaned; !m.isDir(dir); dir = path_.Dir(dir) {
+		if _, ok := m.files[dir]; ok {
+			return &fs.PathError{Op: "mkdir", Path: dir, Err: errors.New("not a directory")}
+		}
+		missing = append(missing, dir)
+	}
+	now := time.Now()
+	for _, dir := range missing {
+		m.dirs[dir] = now
+	}
+	return nil
+}
+
+// memoryInfo implements fs.FileInfo for MemoryBackend.
+type memoryInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (i memoryInfo) Name() string       { return i.name }
+func (i memoryInfo) Size() int64        { return i.size }
+func (i memoryInfo) Mode() fs.FileMode  { return i.mode }
+func (i memoryInfo) ModTime() time.Time { return i.modTime }
+func (i memoryInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i memoryInfo) Sys() any           { return nil }
+
+// memoryReader is a file opened for reading from MemoryBackend.
+type memoryReader struct {
+	*bytes.Reader
+	info fs.FileInfo
+}
+
+func (r *memoryReader) Stat() (fs.FileInfo, error) { return r.info, nil }
+func (r *memoryReader) Close() error               { return nil }
+
+// memoryWriter is a file created for writing in MemoryBackend.
+type memoryWriter struct {
+	bytes.Buffer
+	backend *MemoryBackend
+	name    string
+	perm    fs.FileMode
+}
+
+func (w *memoryWriter) Close() error { return w.backend.WriteFile(w.name, w.Bytes(), w.perm) }
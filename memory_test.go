@@ -0,0 +1,64 @@
+package versionfs
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/fs"
+	"testing"
+)
+
+func TestMemoryBackend_WriteFile_MissingDir(t *testing.T) {
+	t.Parallel()
+	m := NewMemoryBackend()
+	err := m.WriteFile("2023/league/league.txt.20211125011947", []byte("data"), 0644)
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+	assert.Nil(t, m.MkdirAll("2023/league", 0755))
+	assert.Nil(t, m.WriteFile("2023/league/league.txt.20211125011947", []byte("data"), 0644))
+	// a file can't be used as a directory
+	err = m.MkdirAll("2023/league/league.txt.20211125011947/sub", 0755)
+	assert.NotNil(t, err)
+}
+
+func TestMemoryBackend_ReadDir(t *testing.T) {
+	t.Parallel()
+	m := NewMemoryBackend()
+	assert.Nil(t, m.MkdirAll("/data/2023/league", 0755))
+	assert.Nil(t, m.MkdirAll("/data/2023/roster", 0755))
+	assert.Nil(t, m.WriteFile("/data/2023/b.txt", []byte("b"), 0644))
+	entries, err := m.ReadDir("/data/2023/")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(entries))
+	assert.Equal(t, "b.txt", entries[0].Name())
+	assert.False(t, entries[0].IsDir())
+	assert.Equal(t, "league", entries[1].Name())
+	assert.True(t, entries[1].IsDir())
+	assert.Equal(t, "roster", entries[2].Name())
+	_, err = m.ReadDir("/data/2024")
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+	// non-empty directories can't be removed
+	assert.NotNil(t, m.Remove("/data/2023"))
+	assert.Nil(t, m.Remove("/data/2023/league"))
+}
+
+// the content is copied, mutating the written or read slices doesn't change the stored file
+func TestMemoryBackend_Isolation(t *testing.T) {
+	t.Parallel()
+	m := NewMemoryBackend()
+	data := []byte("data")
+	assert.Nil(t, m.WriteFile("a.txt", data, 0644))
+	data[0] = 'x'
+	read, err := m.ReadFile("a.txt")
+	assert.Nil(t, err)
+	assert.Equal(t, "data", string(read))
+	read[0] = 'x'
+	f, err := m.Open("a.txt")
+	assert.Nil(t, err)
+	content, err := io.ReadAll(f)
+	assert.Nil(t, err)
+	assert.Equal(t, "data", string(content))
+	info, err := f.Stat()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), info.Size())
+	assert.Nil(t, f.Close())
+}
@@ -0,0 +1,154 @@
+package versionfs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics receives the counters and latencies of a VersionFS instance, for example to
+// export them to Prometheus. Labels are key-value pairs, such as "op", "write", "root", "./data".
+type Metrics interface {
+	// IncCounter adds delta to the counter name with labels.
+	IncCounter(name string, delta int64, labels ...string)
+	// ObserveDuration records a duration of name with labels.
+	ObserveDuration(name string, d time.Duration, labels ...string)
+}
+
+// The metrics recorded for Write, Read, ReadRange, Remove, Versions, Find, WalkVersions,
+// and VersionRef.WriteTo, and their Ctx variants, with the "op" and "root" labels.
+const (
+	// MetricOperations counts the operations.
+	MetricOperations = "versionfs_operations_total"
+	// MetricErrors counts the operations that failed.
+	MetricErrors = "versionfs_errors_total"
+	// MetricOperationDuration records the latency of the operations.
+	MetricOperationDuration = "versionfs_operation_duration"
+)
+
+// The other metrics, with the "root" label.
+const (
+	// MetricBytesWritten counts the bytes written by Write.
+	MetricBytesWritten = "versionfs_written_bytes_total"
+	// MetricBytesRead counts the bytes returned by Read and ReadRange, and streamed by VersionRef.WriteTo.
+	MetricBytesRead = "versionfs_read_bytes_total"
+	// MetricPrunedVersions counts the versions removed by Prune, PruneDir, and PruneDirPrefix.
+	MetricPrunedVersions = "versionfs_pruned_versions_total"
+	// MetricCacheHits counts the listings of Versions served by the cache of VersionsCacheTTL.
+	MetricCacheHits = "versionfs_cache_hits_total"
+	// MetricCacheMisses counts the listings of Versions read from the storage with VersionsCacheTTL set.
+	MetricCacheMisses = "versionfs_cache_misses_total"
+	// MetricReadCacheHits counts the contents returned by Read from the cache of ReadCacheBytes.
+	MetricReadCacheHits = "versionfs_read_cache_hits_total"
+	// MetricReadCacheMisses counts the contents read from the storage by Read with ReadCacheBytes set.
+	MetricReadCacheMisses = "versionfs_read_cache_misses_total"
+)
+
+// NopMetrics is the Metrics discarding everything, the default.
+type NopMetrics struct{}
+
+// IncCounter implements Metrics, it does nothing.
+func (NopMetrics) IncCounter(name string, delta int64, labels ...string) {}
+
+// ObserveDuration implements Metrics, it does nothing.
+func (NopMetrics) ObserveDuration(name string, d time.Duration, labels ...string) {}
+
+// MemoryMetrics is the Metrics keeping the counters and durations in memory, for tests.
+// The zero value is ready to use.
+//
+// Example:
+//
+//	metrics := &versionfs.MemoryMetrics{}
+//	vfs.Metrics = metrics
+//	_, _ = vfs.Write(file, data)
+//	fmt.Println(metrics.Counter(versionfs.MetricOperations, "op", "write", "root", vfs.RootPath)) // 1
+type MemoryMetrics struct {
+	mu        sync.Mutex
+	counters  map[string]int64
+	durations map[string][]time.Duration
+}
+
+// IncCounter implements Metrics.
+func (m *MemoryMetrics) IncCounter(name string, delta int64, labels ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.counters == nil {
+		m.counters = make(map[string]int64)
+	}
+	m.counters[metricKey(name, labels)] += delta
+}
+
+// ObserveDuration implements Metrics.
+func (m *MemoryMetrics) ObserveDuration(name string, d time.Duration, labels ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.durations == nil {
+		m.durations = make(map[string][]time.Duration)
+	}
+	key := metricKey(name, labels)
+	m.durations[key] = append(m.durations[key], d)
+}
+
+// Counter returns the value of the counter name with labels.
+func (m *MemoryMetrics) Counter(name string, labels ...string) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.counters[metricKey(name, labels)]
+}
+
+// Counters returns every counter, keyed like `versionfs_operations_total{op="write",root="./data"}`
+// with the labels sorted by key.
+func (m *MemoryMetrics) Counters() map[string]int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	counters := make(map[string]int64, len(m.counters))
+	for key, value := range m.counters {
+		counters[key] = value
+	}
+	return counters
+}
+
+// Durations returns the durations recorded for name with labels, in order.
+func (m *MemoryMetrics) Durations(name string, labels ...string) []time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]time.Duration(nil), m.durations[metricKey(name, labels)]...)
+}
+
+// metricKey formats a metric name with its labels sorted by key.
+func metricKey(name string, labels []string) string {
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", labels[i], labels[i+1]))
+	}
+	sort.Strings(pairs)
+	return name + "{" + strings.Join(pairs, ",") + "}"
+}
+
+// metrics returns the Metrics of the instance, discarding everything if none is set.
+func (v *VersionFS) metrics() Metrics {
+	if v.Metrics == nil {
+		return NopMetrics{}
+	}
+	return v.Metrics
+}
+
+// recordOp records an operation that took d and ended with err.
+func (v *VersionFS) recordOp(info *OpInfo, d time.Duration, err error) {
+	m := v.metrics()
+	labels := []string{"op", string(info.Op), "root", v.RootPath}
+	m.IncCounter(MetricOperations, 1, labels...)
+	m.ObserveDuration(MetricOperationDuration, d, labels...)
+	if err != nil {
+		m.IncCounter(MetricErrors, 1, labels...)
+		return
+	}
+	switch info.Op {
+	case OpWrite:
+		m.IncCounter(MetricBytesWritten, info.Bytes, "root", v.RootPath)
+	case OpRead, OpReadRange, OpWriteTo:
+		m.IncCounter(MetricBytesRead, info.Bytes, "root", v.RootPath)
+	}
+}
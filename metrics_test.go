@@ -0,0 +1,53 @@
+package versionfs
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestVersionFS_Metrics(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	vfs.RootPath = "data"
+	metrics := &MemoryMetrics{}
+	vfs.Metrics = metrics
+	file := fileLeague{season: 2023}
+	putVersion(t, vfs, file, "20231019140523", "first")
+	putVersion(t, vfs, file, "20231020140523", "second")
+	ts, err := vfs.Write(file, []byte("hello"))
+	assert.Nil(t, err)
+	_, err = vfs.Read(file, ts)
+	assert.Nil(t, err)
+	_, err = vfs.ReadRange(file, ts, 0, 2)
+	assert.Nil(t, err)
+	_, err = vfs.Find("2023/league", file)
+	assert.Nil(t, err)
+	removed, err := vfs.Prune(file, RetentionPolicy{KeepLast: 1})
+	assert.Nil(t, err)
+	assert.Len(t, removed, 2)
+	_, err = vfs.Read(file, removed[0])
+	assert.ErrorIs(t, err, ErrVersionNotFound)
+
+	assert.Equal(t, map[string]int64{
+		`versionfs_operations_total{op="write",root="data"}`:      1,
+		`versionfs_operations_total{op="read",root="data"}`:       2,
+		`versionfs_operations_total{op="read_range",root="data"}`: 1,
+		`versionfs_operations_total{op="find",root="data"}`:       1,
+		`versionfs_operations_total{op="versions",root="data"}`:   1,
+		`versionfs_operations_total{op="remove",root="data"}`:     2,
+		`versionfs_errors_total{op="read",root="data"}`:           1,
+		`versionfs_written_bytes_total{root="data"}`:              5,
+		`versionfs_read_bytes_total{root="data"}`:                 7,
+		`versionfs_pruned_versions_total{root="data"}`:            2,
+	}, metrics.Counters())
+	assert.Equal(t, int64(2), metrics.Counter(MetricOperations, "root", "data", "op", "remove"))
+	assert.Len(t, metrics.Durations(MetricOperationDuration, "op", "read", "root", "data"), 2)
+}
+
+func TestVersionFS_Metrics_Nil(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	vfs.Metrics = nil
+	_, err := vfs.Write(fileLeague{season: 2023}, []byte("hello"))
+	assert.Nil(t, err)
+}
@@ -0,0 +1,136 @@
+package versionfs
+
+import (
+	"context"
+	"errors"
+	path_ "path"
+	"sync"
+)
+
+// MmapData is the content of a version opened by OpenMmap. Close it once done with the
+// content: the memory returned by Bytes is only valid until then.
+type MmapData interface {
+	// Bytes returns the content of the version. It must not be modified, and must not be
+	// used after Close, nor any slice of it.
+	Bytes() []byte
+	// Close releases the content. Closing more than once does nothing.
+	Close() error
+}
+
+// MmapBackend is implemented by the backends able to map files in memory. It is used by
+// OpenMmap.
+type MmapBackend interface {
+	// Mmap maps the file name read-only in memory. Returns an error wrapping
+	// errors.ErrUnsupported if the platform can't, OpenMmap then reads the file.
+	Mmap(name string) (MmapData, error)
+}
+
+// OpenMmap returns the content of a version like Read, mapped in memory rather than read
+// when the backend implements MmapBackend, as the OSBackend does on Unix. The pages are only
+// loaded when accessed, and aren't duplicated in the memory of the process, for large
+// versions that are parsed into another structure right away. The versions are read
+// otherwise, on other platforms and backends, and from the fallback.
+//
+// The content aliases the file: it is read-only, and writing to it crashes the program.
+// It must not be used after Close, which unmaps it, including the slices and strings built
+// on it without a copy. A version removed while mapped stays readable until Close, but a
+// version rewritten in place, by a Write within the same period of its resolution, changes
+// under the mapping, and accessing the content past its new size crashes the program.
+//
+// Example:
+//
+//	data, err := vfs.OpenMmap(file, timestamp)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer data.Close()
+//	index, err := parseIndex(data.Bytes()) // copies what it keeps
+func (v *VersionFS) OpenMmap(file File, ts Timestamp) (MmapData, error) {
+	info := newOpInfo(OpRead, file)
+	info.Timestamp = ts
+	end := v.instrument(context.Background(), &info)
+	data, err := v.openMmap(file, ts)
+	if v.fallback != nil && errors.Is(err, ErrVersionNotFound) {
+		var read []byte
+		if read, err = v.readFallback(context.Background(), file, ts); err == nil {
+			data = readData(read)
+		}
+	}
+	if err == nil {
+		info.Bytes = int64(len(data.Bytes()))
+	}
+	end(err)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// openMmap implements OpenMmap without the fallback.
+func (v *VersionFS) openMmap(file File, ts Timestamp) (MmapData, error) {
+	mb, ok := v.Backend.(MmapBackend)
+	if !ok {
+		data, err := v.read(context.Background(), file, ts)
+		return readData(data), err
+	}
+	v.logger().Debugf("Mapping file %s/%s.%s.%s", file.Dir(), file.Name(), file.Ext(), ts)
+	name := v.resolvePath(file, ts)
+	if err := v.confine("read", name); err != nil {
+		return nil, err
+	}
+	if err := v.restrict("read", name); err != nil {
+		return nil, err
+	}
+	data, err := mb.Mmap(path_.Join(v.RootPath, name))
+	if errors.Is(err, errors.ErrUnsupported) {
+		read, err := v.read(context.Background(), file, ts)
+		return readData(read), err
+	} else if err != nil {
+		return nil, versionNotFound(err)
+	}
+	if v.VerifyOnRead {
+		if err := v.verify(name, data.Bytes()); err != nil {
+			_ = data.Close()
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// readData is the MmapData of the versions read rather than mapped.
+type readData []byte
+
+// Bytes implements MmapData.
+func (d readData) Bytes() []byte {
+	return d
+}
+
+// Close implements MmapData, it does nothing.
+func (readData) Close() error {
+	return nil
+}
+
+// mappedData is the MmapData of the versions mapped by the OSBackend.
+type mappedData struct {
+	mu   sync.Mutex
+	data []byte
+}
+
+// Bytes implements MmapData, it returns nil once closed.
+func (m *mappedData) Bytes() []byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.data
+}
+
+// Close implements MmapData, it unmaps the content.
+func (m *mappedData) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.data == nil {
+		return nil
+	}
+	err := munmap(m.data)
+	m.data = nil
+	return err
+}
@@ -0,0 +1,19 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package versionfs
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// Mmap implements MmapBackend, it fails with errors.ErrUnsupported: OpenMmap reads the
+// versions on this platform.
+func (OSBackend) Mmap(name string) (MmapData, error) {
+	return nil, &fs.PathError{Op: "mmap", Path: name, Err: errors.ErrUnsupported}
+}
+
+// munmap does nothing, nothing is mapped on this platform.
+func munmap(data []byte) error {
+	return nil
+}
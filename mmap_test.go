@@ -0,0 +1,116 @@
+package versionfs
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestVersionFS_OpenMmap(t *testing.T) {
+	t.Parallel()
+	dir, vfs := newTmpVersionFS(t)
+	defer func() { _ = os.RemoveAll(dir) }()
+	vfs.VerifyOnRead = true
+	file := fileLeague{season: 2023}
+	ts, err := vfs.Write(file, []byte("mapped"))
+	assert.Nil(t, err)
+	assert.Nil(t, vfs.WriteChecksum(file, ts))
+
+	data, err := vfs.OpenMmap(file, ts)
+	assert.Nil(t, err)
+	assert.Equal(t, "mapped", string(data.Bytes()))
+	assert.Nil(t, data.Close())
+	assert.Nil(t, data.Close())
+
+	// bit-rot
+	putRaw(t, vfs, Path(file, ts), "mopped")
+	_, err = vfs.OpenMmap(file, ts)
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+
+	// the empty versions can't be mapped
+	vfs.VerifyOnRead = false
+	empty := putVersion(t, vfs, file, "20231019140523", "")
+	data, err = vfs.OpenMmap(file, empty)
+	assert.Nil(t, err)
+	assert.Empty(t, data.Bytes())
+	assert.Nil(t, data.Close())
+
+	missing, err := NewTimestamp("20231020140523")
+	assert.Nil(t, err)
+	_, err = vfs.OpenMmap(file, missing)
+	assert.ErrorIs(t, err, ErrVersionNotFound)
+}
+
+func TestVersionFS_OpenMmap_Read(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	fallback := NewMemory()
+	vfs.SetFallback(fallback)
+	file := fileLeague{season: 2023}
+	ts := putVersion(t, vfs, file, "20231019140523", "in memory")
+	old := putVersion(t, fallback, file, "20221019140523", "in the fallback")
+
+	// the backends not implementing MmapBackend are read
+	data, err := vfs.OpenMmap(file, ts)
+	assert.Nil(t, err)
+	assert.Equal(t, "in memory", string(data.Bytes()))
+	assert.Nil(t, data.Close())
+	data, err = vfs.OpenMmap(file, old)
+	assert.Nil(t, err)
+	assert.Equal(t, "in the fallback", string(data.Bytes()))
+	assert.Nil(t, data.Close())
+	missing, err := NewTimestamp("20231020140523")
+	assert.Nil(t, err)
+	_, err = vfs.OpenMmap(file, missing)
+	assert.ErrorIs(t, err, ErrVersionNotFound)
+}
+
+// touched keeps the result of BenchmarkOpenMmap, so that its reads aren't optimized away.
+var touched byte
+
+// BenchmarkOpenMmap compares Read and OpenMmap on a version of 1 GB, reading a byte of every
+// page as a parser would. Skipped with -short.
+func BenchmarkOpenMmap(b *testing.B) {
+	if testing.Short() {
+		b.Skip("writes a version of 1 GB")
+	}
+	dir, vfs := newTmpVersionFS(b)
+	defer func() { _ = os.RemoveAll(dir) }()
+	file := fileLeague{season: 2023}
+	ts, err := vfs.Write(file, bytes.Repeat([]byte("0123456789abcdef"), 1<<26))
+	if err != nil {
+		b.Fatal(err)
+	}
+	var sum byte
+	touch := func(data []byte) {
+		for i := 0; i < len(data); i += 4096 {
+			sum += data[i]
+		}
+	}
+
+	b.Run("Read", func(b *testing.B) {
+		b.SetBytes(1 << 30)
+		for i := 0; i < b.N; i++ {
+			data, err := vfs.Read(file, ts)
+			if err != nil {
+				b.Fatal(err)
+			}
+			touch(data)
+		}
+	})
+	b.Run("OpenMmap", func(b *testing.B) {
+		b.SetBytes(1 << 30)
+		for i := 0; i < b.N; i++ {
+			data, err := vfs.OpenMmap(file, ts)
+			if err != nil {
+				b.Fatal(err)
+			}
+			touch(data.Bytes())
+			if err := data.Close(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	touched = sum
+}
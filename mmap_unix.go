@@ -0,0 +1,42 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package versionfs
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// Mmap implements MmapBackend with a read-only shared mapping of the file. Empty files,
+// which can't be mapped, are returned as an empty content.
+func (OSBackend) Mmap(name string) (MmapData, error) {
+	f, err := os.Open(filepath.FromSlash(name))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := info.Size()
+	if size == 0 {
+		return readData(nil), nil
+	}
+	if int64(int(size)) != size {
+		return nil, &fs.PathError{Op: "mmap", Path: name, Err: errors.New("file too large")}
+	}
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, &fs.PathError{Op: "mmap", Path: name, Err: err}
+	}
+	return &mappedData{data: data}, nil
+}
+
+// munmap unmaps data, mapped by Mmap.
+func munmap(data []byte) error {
+	return syscall.Munmap(data)
+}
@@ -0,0 +1,27 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package versionfs
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestVersionFS_OpenMmap_Mapped(t *testing.T) {
+	t.Parallel()
+	dir, vfs := newTmpVersionFS(t)
+	defer func() { _ = os.RemoveAll(dir) }()
+	file := fileLeague{season: 2023}
+	ts, err := vfs.Write(file, []byte("mapped"))
+	assert.Nil(t, err)
+
+	data, err := vfs.OpenMmap(file, ts)
+	assert.Nil(t, err)
+	assert.IsType(t, &mappedData{}, data)
+	// a version removed while mapped stays readable until Close
+	assert.Nil(t, vfs.Remove(file, ts))
+	assert.Equal(t, "mapped", string(data.Bytes()))
+	assert.Nil(t, data.Close())
+	assert.Nil(t, data.Bytes())
+}
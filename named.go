@@ -0,0 +1,61 @@
+package versionfs
+
+import (
+	"fmt"
+)
+
+// RegisterNamed registers a constructor function for a file type identified by a string,
+// for systems where file types come from configuration rather than Go constants.
+// The string registry coexists with the FileType registry.
+//
+// Example:
+//
+//	vfs.RegisterNamed("league", func(args ...any) versionfs.File {
+//	    return LeagueFile{season: args[0].(int)}
+//	})
+func (v *VersionFS) RegisterNamed(name string, constructor Constructor) {
+	v.RegisterNamedE(name, func(args ...any) (File, error) {
+		return constructor(args...), nil
+	})
+}
+
+// RegisterNamedE registers a constructor function that can fail for a file type identified by a string.
+func (v *VersionFS) RegisterNamedE(name string, constructor ConstructorE) {
+	v.logger().Debugf("Registering file type %q", name)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.named[name] = constructor
+}
+
+// NewNamed creates a new File instance using the constructor registered with RegisterNamed.
+// If no constructor was registered under that name, the FileType registered with that
+// name by RegisterFileType is used. Returns an error wrapping ErrNotRegistered if neither exists,
+// or the error returned by the constructor.
+//
+// Example:
+//
+//	file, err := vfs.NewNamed(cfg.Type, 2023)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+func (v *VersionFS) NewNamed(name string, args ...any) (File, error) {
+	v.mu.RLock()
+	c, ok := v.named[name]
+	if !ok {
+		for ftype, typeName := range v.names {
+			if typeName == name {
+				v.mu.RUnlock()
+				return v.NewE(ftype, args...)
+			}
+		}
+	}
+	v.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("file type %q %w", name, ErrNotRegistered)
+	}
+	file, err := c(args...)
+	if err != nil {
+		return nil, fmt.Errorf("file type %q: %w", name, err)
+	}
+	return file, nil
+}
@@ -0,0 +1,52 @@
+package versionfs
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestVersionFS_NewNamed(t *testing.T) {
+	t.Parallel()
+	vfs := newTestVersionFS()
+	vfs.RegisterNamed("league", func(args ...any) File {
+		return fileLeague{season: args[0].(int)}
+	})
+	vfs.RegisterNamedE("themes", func(args ...any) (File, error) {
+		if len(args) > 0 {
+			return nil, fmt.Errorf("unexpected arguments")
+		}
+		return fileThemes{}, nil
+	})
+	file, err := vfs.NewNamed("league", 2023)
+	assert.Nil(t, err)
+	assert.Equal(t, fileLeague{season: 2023}, file)
+	file, err = vfs.NewNamed("themes")
+	assert.Nil(t, err)
+	assert.Equal(t, fileThemes{}, file)
+	_, err = vfs.NewNamed("themes", 1)
+	assert.Equal(t, `file type "themes": unexpected arguments`, err.Error())
+	_, err = vfs.NewNamed("missing")
+	assert.True(t, errors.Is(err, ErrNotRegistered))
+	assert.Equal(t, `file type "missing" not registered`, err.Error())
+	// the string registry is copied by Clone
+	clone := vfs.Clone(vfs.RootPath)
+	clone.RegisterNamed("other", func(args ...any) File { return fileThemes{} })
+	_, err = clone.NewNamed("league", 2023)
+	assert.Nil(t, err)
+	_, err = vfs.NewNamed("other")
+	assert.True(t, errors.Is(err, ErrNotRegistered))
+}
+
+// file types registered with a name can be created by that name
+func TestVersionFS_NewNamed_FileType(t *testing.T) {
+	t.Parallel()
+	vfs := newTestVersionFS()
+	vfs.RegisterFileType(RosterFileType, func(args ...any) File {
+		return fileRoster{season: args[0].(int), teamID: args[1].(int), date: args[2].(string)}
+	}, "roster")
+	file, err := vfs.NewNamed("roster", 2023, 3, "2023-10-19")
+	assert.Nil(t, err)
+	assert.Equal(t, "2023/roster/team-3", file.Dir())
+}
@@ -0,0 +1,59 @@
+// Package otelversionfs implements a versionfs.Tracer creating OpenTelemetry spans.
+// It is a separate module so that the core versionfs module doesn't depend on OpenTelemetry.
+package otelversionfs
+
+import (
+	"context"
+	"github.com/sperano/versionfs"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// The attributes set on the spans.
+const (
+	AttrOp        = attribute.Key("versionfs.op")
+	AttrDir       = attribute.Key("versionfs.dir")
+	AttrName      = attribute.Key("versionfs.name")
+	AttrExt       = attribute.Key("versionfs.ext")
+	AttrTimestamp = attribute.Key("versionfs.timestamp")
+	AttrBytes     = attribute.Key("versionfs.bytes")
+)
+
+// New returns a versionfs.Tracer creating a span named "versionfs.<op>" with tracer for
+// every operation, as a child of the span of the context given to the Ctx variants.
+// The span records the error the operation fails with.
+//
+// Example:
+//
+//	vfs.Tracer = otelversionfs.New(otel.Tracer("github.com/sperano/versionfs"))
+func New(tracer trace.Tracer) versionfs.Tracer {
+	return adapter{tracer: tracer}
+}
+
+type adapter struct {
+	tracer trace.Tracer
+}
+
+func (a adapter) Start(ctx context.Context, info *versionfs.OpInfo) func(err error) {
+	_, span := a.tracer.Start(ctx, "versionfs."+string(info.Op), trace.WithAttributes(
+		AttrOp.String(string(info.Op)),
+		AttrDir.String(info.Dir),
+	))
+	return func(err error) {
+		if info.Name != "" {
+			span.SetAttributes(AttrName.String(info.Name), AttrExt.String(info.Ext))
+		}
+		if !info.Timestamp.Time().IsZero() {
+			span.SetAttributes(AttrTimestamp.String(info.Timestamp.String()))
+		}
+		if info.Bytes > 0 {
+			span.SetAttributes(AttrBytes.Int64(info.Bytes))
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
@@ -0,0 +1,56 @@
+package otelversionfs
+
+import (
+	"context"
+	"errors"
+	"github.com/sperano/versionfs"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"testing"
+)
+
+type fileLeague struct{}
+
+func (fileLeague) Dir() string  { return "2023/league" }
+func (fileLeague) Name() string { return "league" }
+func (fileLeague) Ext() string  { return "json" }
+
+func TestNew(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	vfs := versionfs.NewMemory()
+	vfs.Tracer = New(provider.Tracer("versionfs"))
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "handler")
+	ts, err := vfs.WriteCtx(ctx, fileLeague{}, []byte("hello"))
+	assert.Nil(t, err)
+	parent.End()
+	assert.Nil(t, vfs.Remove(fileLeague{}, ts))
+	_, err = vfs.Read(fileLeague{}, ts)
+	assert.True(t, errors.Is(err, versionfs.ErrVersionNotFound))
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 4)
+	write := spans[0]
+	assert.Equal(t, "versionfs.write", write.Name)
+	assert.Equal(t, parent.SpanContext().SpanID(), write.Parent.SpanID())
+	assert.ElementsMatch(t, []attribute.KeyValue{
+		AttrOp.String("write"),
+		AttrDir.String("2023/league"),
+		AttrName.String("league"),
+		AttrExt.String("json"),
+		AttrTimestamp.String(ts.String()),
+		AttrBytes.Int64(5),
+	}, write.Attributes)
+	assert.Equal(t, codes.Unset, write.Status.Code)
+
+	assert.Equal(t, "versionfs.remove", spans[2].Name)
+	read := spans[3]
+	assert.Equal(t, "versionfs.read", read.Name)
+	assert.Equal(t, codes.Error, read.Status.Code)
+	assert.Len(t, read.Events, 1)
+	assert.Equal(t, "exception", read.Events[0].Name)
+}
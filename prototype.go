@@ -0,0 +1,51 @@
+package versionfs
+
+import (
+	"errors"
+	"fmt"
+	path_ "path"
+)
+
+// ErrNoMatch is returned by FileFor when no registered prototype matches a file.
+var ErrNoMatch = errors.New("no matching file")
+
+// RegisterPrototype registers files that FileFor can return, so that generic tooling
+// walking the tree can obtain a concrete File from a path.
+//
+// Example:
+//
+//	vfs.RegisterPrototype(vfs.New(LeagueFileType, 2023), vfs.New(LeagueFileType, 2024))
+func (v *VersionFS) RegisterPrototype(files ...File) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.prototypes = append(v.prototypes, files...)
+}
+
+// FileFor returns the registered prototype stored in dir under filename, with the timestamp
+// of the version. Each prototype is checked with Detect against the filename, and its Dir must
+// be the same directory. Returns an error wrapping ErrNoMatch if no prototype matches.
+//
+// Example:
+//
+//	err := vfs.WalkVersions("", func(dir, name, ext string, ts versionfs.Timestamp, info os.FileInfo) error {
+//	    file, ts, err := vfs.FileFor(dir, info.Name())
+//	    if err != nil {
+//	        return nil
+//	    }
+//	    return vfs.Remove(file, ts)
+//	})
+func (v *VersionFS) FileFor(dir, filename string) (File, Timestamp, error) {
+	v.mu.RLock()
+	prototypes := v.prototypes
+	v.mu.RUnlock()
+	dir = path_.Clean(slashed(dir))
+	for _, prototype := range prototypes {
+		if path_.Clean(slashed(prototype.Dir())) != dir {
+			continue
+		}
+		if ts, err := v.Detect(filename, prototype); err == nil {
+			return prototype, ts, nil
+		}
+	}
+	return nil, Timestamp{}, fmt.Errorf("%s: %w", path_.Join(dir, filename), ErrNoMatch)
+}
@@ -0,0 +1,71 @@
+package versionfs
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestVersionFS_FileFor(t *testing.T) {
+	t.Parallel()
+	vfs := newTypedVersionFS()
+	league2023 := vfs.New(LeagueFileType, 2023)
+	league2024 := vfs.New(LeagueFileType, 2024)
+	roster := vfs.New(RosterFileType, 2023, 12, "2023-10-19")
+	vfs.RegisterPrototype(league2023, league2024)
+	vfs.RegisterPrototype(roster)
+
+	file, ts, err := vfs.FileFor("2024/league", "league.txt.20231019140523")
+	assert.Nil(t, err)
+	assert.Equal(t, league2024, file)
+	assert.Equal(t, "20231019140523", ts.String())
+	file, _, err = vfs.FileFor("2023/roster/team-12/", "roster-12-2023-10-19.json.20231019140523")
+	assert.Nil(t, err)
+	assert.Equal(t, roster, file)
+
+	// wrong directory, wrong extension, and not versioned
+	_, _, err = vfs.FileFor("2025/league", "league.txt.20231019140523")
+	assert.True(t, errors.Is(err, ErrNoMatch))
+	assert.Equal(t, "2025/league/league.txt.20231019140523: no matching file", err.Error())
+	_, _, err = vfs.FileFor("2023/league", "league.json.20231019140523")
+	assert.True(t, errors.Is(err, ErrNoMatch))
+	_, _, err = vfs.FileFor("2023/league", "league.txt")
+	assert.True(t, errors.Is(err, ErrNoMatch))
+}
+
+func TestVersionFS_FileFor_Walk(t *testing.T) {
+	t.Parallel()
+	vfs := newTypedVersionFS()
+	file := vfs.New(LeagueFileType, 2023)
+	vfs.RegisterPrototype(file)
+	putVersion(t, vfs, file, "20211125011947", "1")
+	putVersion(t, vfs, file, "20231019140523", "2")
+	putRaw(t, vfs, "2023/league/other.txt.20211125011947", "unknown")
+	// remove every known version found by a generic walk
+	var unknown []string
+	err := vfs.WalkVersions("", func(dir, name, ext string, ts Timestamp, info os.FileInfo) error {
+		f, ts, err := vfs.FileFor(dir, info.Name())
+		if errors.Is(err, ErrNoMatch) {
+			unknown = append(unknown, info.Name())
+			return nil
+		}
+		return vfs.Remove(f, ts)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"other.txt.20211125011947"}, unknown)
+	versions, err := vfs.Versions(file)
+	assert.Nil(t, err)
+	assert.Empty(t, versions)
+}
+
+func TestVersionFS_RegisterPrototype_Clone(t *testing.T) {
+	t.Parallel()
+	vfs := newTypedVersionFS()
+	clone := vfs.Clone("clone")
+	clone.RegisterPrototype(vfs.New(LeagueFileType, 2023))
+	_, _, err := vfs.FileFor("2023/league", "league.txt.20231019140523")
+	assert.True(t, errors.Is(err, ErrNoMatch))
+	_, _, err = clone.FileFor("2023/league", "league.txt.20231019140523")
+	assert.Nil(t, err)
+}
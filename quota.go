@@ -0,0 +1,145 @@
+package versionfs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	path_ "path"
+	"sync"
+)
+
+// ErrQuotaExceeded is returned when a write would make the size of the files under the root exceed MaxBytes.
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// usage is the number of bytes stored under the root of an instance, scanned on demand and
+// then maintained by the writes and removals of the instance.
+type usage struct {
+	mu sync.Mutex
+	// known is set once bytes has been scanned, it is not maintained before.
+	known bool
+	bytes int64
+}
+
+// Usage returns the total size of the files under the root, in bytes. The first call scans
+// the tree, then Write and the other operations of the instance maintain it incrementally.
+// Files written by other processes or instances are only counted by RecalculateUsage.
+//
+// Example:
+//
+//	used, err := vfs.Usage()
+//	fmt.Printf("%d of %d bytes used\n", used, vfs.MaxBytes)
+func (v *VersionFS) Usage() (int64, error) {
+	v.usage.mu.Lock()
+	defer v.usage.mu.Unlock()
+	if !v.usage.known {
+		if err := v.scanUsage(); err != nil {
+			return 0, err
+		}
+	}
+	return v.usage.bytes, nil
+}
+
+// RecalculateUsage scans the tree again and returns the total size of the files under the
+// root, in bytes, to correct the drift caused by other processes writing to the same root.
+func (v *VersionFS) RecalculateUsage() (int64, error) {
+	v.usage.mu.Lock()
+	defer v.usage.mu.Unlock()
+	if err := v.scanUsage(); err != nil {
+		return 0, err
+	}
+	return v.usage.bytes, nil
+}
+
+// scanUsage sets the usage to the total size of the files under the root. The caller must hold the lock.
+func (v *VersionFS) scanUsage() error {
+	v.logger().Debugf("Scanning the usage of %s", v.RootPath)
+	var total int64
+	var scan func(dir string) error
+	scan = func(dir string) error {
+		entries, err := v.backend().ReadDir(dir)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				if err := scan(path_.Join(dir, entry.Name())); err != nil {
+					return err
+				}
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			total += info.Size()
+		}
+		return nil
+	}
+	if err := scan(v.RootPath); err != nil {
+		return err
+	}
+	v.usage.known = true
+	v.usage.bytes = total
+	return nil
+}
+
+// claim accounts for size bytes about to be written at name, relative to the root, replacing
+// the file at name if it exists. It fails with ErrQuotaExceeded if the usage would exceed
+// MaxBytes, and returns the function to call with the outcome of the write, which releases
+// the bytes if it failed. Nothing is accounted until the usage is known, unless MaxBytes is set.
+func (v *VersionFS) claim(name string, size int64) (func(err error), error) {
+	v.usage.mu.Lock()
+	defer v.usage.mu.Unlock()
+	if !v.usage.known {
+		if v.MaxBytes <= 0 {
+			return func(error) {}, nil
+		}
+		if err := v.scanUsage(); err != nil {
+			return nil, err
+		}
+	}
+	var replaced int64
+	if info, err := v.backend().Stat(path_.Join(v.RootPath, name)); err == nil {
+		replaced = info.Size()
+	}
+	if v.MaxBytes > 0 && v.usage.bytes-replaced+size > v.MaxBytes {
+		return nil, fmt.Errorf("writing %d bytes to %s with %d of %d bytes used: %w", size, name, v.usage.bytes, v.MaxBytes, ErrQuotaExceeded)
+	}
+	v.usage.bytes += size - replaced
+	return func(err error) {
+		if err != nil {
+			v.adjustUsage(replaced - size)
+		}
+	}, nil
+}
+
+// unclaim returns the function to call with the outcome of the removal of the file at name,
+// relative to the root, which releases its bytes if it succeeded.
+func (v *VersionFS) unclaim(name string) func(err error) {
+	v.usage.mu.Lock()
+	defer v.usage.mu.Unlock()
+	if !v.usage.known {
+		return func(error) {}
+	}
+	info, err := v.backend().Stat(path_.Join(v.RootPath, name))
+	if err != nil {
+		return func(error) {}
+	}
+	return func(err error) {
+		if err == nil {
+			v.adjustUsage(-info.Size())
+		}
+	}
+}
+
+// adjustUsage adds delta bytes to the usage, if it is known.
+func (v *VersionFS) adjustUsage(delta int64) {
+	v.usage.mu.Lock()
+	defer v.usage.mu.Unlock()
+	if v.usage.known {
+		v.usage.bytes += delta
+	}
+}
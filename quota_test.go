@@ -0,0 +1,103 @@
+package versionfs
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestVersionFS_Usage(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	used, err := vfs.Usage()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), used)
+
+	file := fileLeague{season: 2023}
+	ts := putVersion(t, vfs, file, "20231019140523", "first")
+	putRaw(t, vfs, "2023/README", "readme")
+	used, err = vfs.Usage()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), used)
+	used, err = vfs.RecalculateUsage()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(11), used)
+
+	_, err = vfs.Write(file, []byte("second"))
+	assert.Nil(t, err)
+	assert.Nil(t, vfs.Remove(file, ts))
+	used, err = vfs.Usage()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(12), used)
+}
+
+func TestVersionFS_MaxBytes(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	vfs.MaxBytes = 10
+	file := fileLeague{season: 2023}
+	old := putVersion(t, vfs, file, "20231019140523", "first")
+	_, err := vfs.Write(file, []byte("second"))
+	assert.True(t, errors.Is(err, ErrQuotaExceeded))
+	assert.True(t, strings.Contains(err.Error(), "5 of 10 bytes used"), err.Error())
+	versions, err := vfs.Versions(file)
+	assert.Nil(t, err)
+	assert.Len(t, versions, 1)
+
+	_, err = vfs.Write(file, []byte("fifth"))
+	assert.Nil(t, err)
+	_, err = vfs.Write(file, []byte("!"))
+	assert.True(t, errors.Is(err, ErrQuotaExceeded))
+
+	removed, err := vfs.Prune(file, RetentionPolicy{KeepLast: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{old.String()}, timestampStrings(removed))
+	used, err := vfs.Usage()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), used)
+	_, err = vfs.Write(file, []byte("!"))
+	assert.Nil(t, err)
+
+	// the version of the same period is replaced at coarser resolutions
+	vfs.Resolution = Day
+	_, err = vfs.Write(file, []byte("1234"))
+	assert.Nil(t, err)
+	_, err = vfs.Write(file, []byte("abcd"))
+	assert.Nil(t, err)
+	used, err = vfs.Usage()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(10), used)
+}
+
+func TestVersionFS_MaxBytes_Race(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	vfs.MaxBytes = 500
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	written := 0
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := vfs.Write(fileRoster{season: 2023, teamID: i, date: "2023-10-19"}, []byte("0123456789"))
+			if err == nil {
+				mu.Lock()
+				written++
+				mu.Unlock()
+			} else if !errors.Is(err, ErrQuotaExceeded) {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, 50, written)
+	used, err := vfs.Usage()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(500), used)
+	used, err = vfs.RecalculateUsage()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(500), used)
+}
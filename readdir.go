@@ -0,0 +1,77 @@
+package versionfs
+
+import "fmt"
+
+// ReadDirContents finds all versions of a file type in a directory like Find, including
+// the versions stored under an alias, and reads each of them, to bulk-load a directory of
+// snapshots. The contents are returned keyed by timestamp. It stops at the first version
+// that can't be read and returns an error wrapping the read error. Returns an empty map
+// if the directory doesn't exist or contains no matching files.
+//
+// Example:
+//
+//	contents, err := vfs.ReadDirContents("2023/league", file)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	for ts, data := range contents {
+//	    fmt.Printf("%s: %d bytes\n", ts, len(data))
+//	}
+func (v *VersionFS) ReadDirContents(dir string, file File) (map[Timestamp][]byte, error) {
+	dir = slashed(dir)
+	listed, err := v.ListFind(dir, file)
+	if err != nil {
+		return nil, err
+	}
+	contents := make(map[Timestamp][]byte, len(listed))
+	for _, lv := range listed {
+		alias := Alias{Name: file.Name(), Ext: file.Ext()}
+		if lv.Alias != nil {
+			alias = *lv.Alias
+		}
+		found := alias.file(dir)
+		data, err := v.Read(found, lv.Timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", Path(found, lv.Timestamp), err)
+		}
+		contents[lv.Timestamp] = data
+	}
+	return contents, nil
+}
+
+// VersionData is a version of a file with its content, as returned by ReadHistoryOrdered.
+type VersionData struct {
+	// Timestamp is the timestamp of the version.
+	Timestamp Timestamp
+	// Data is the content of the version.
+	Data []byte
+}
+
+// ReadHistoryOrdered reads every version of a file, listed by Versions, and returns them
+// newest first with their content, for callers iterating in order, such as rendering a
+// timeline, where the map of ReadDirContents would have to be sorted again. The whole
+// history is held in memory at once: for large histories, iterate over Versions and Read
+// each version instead. It stops at the first version that can't be read and returns an
+// error wrapping the read error. Returns an empty slice if the file has no versions.
+//
+// Example:
+//
+//	history, err := vfs.ReadHistoryOrdered(file)
+//	for _, version := range history {
+//	    fmt.Printf("%s: %d bytes\n", version.Timestamp, len(version.Data))
+//	}
+func (v *VersionFS) ReadHistoryOrdered(file File) ([]VersionData, error) {
+	versions, err := v.Versions(file)
+	if err != nil {
+		return nil, err
+	}
+	history := make([]VersionData, 0, len(versions))
+	for _, ts := range versions {
+		data, err := v.Read(file, ts)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", Path(file, ts), err)
+		}
+		history = append(history, VersionData{Timestamp: ts, Data: data})
+	}
+	return history, nil
+}
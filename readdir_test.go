@@ -0,0 +1,100 @@
+package versionfs
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/fs"
+	"path"
+	"testing"
+)
+
+func TestVersionFS_ReadDirContents(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	file := fileLeague{season: 2023}
+	first := putVersion(t, vfs, file, "20231018140523", "first")
+	second := putVersion(t, vfs, file, "20231019140523", "second")
+	putVersion(t, vfs, fileLeague{season: 2024}, "20231019140523", "other season")
+	putRaw(t, vfs, "2023/league/league.csv.20231019140523", "other extension")
+	contents, err := vfs.ReadDirContents("2023/league", file)
+	assert.Nil(t, err)
+	assert.Equal(t, map[Timestamp][]byte{
+		first:  []byte("first"),
+		second: []byte("second"),
+	}, contents)
+
+	// the directory is the one given, not the file's
+	contents, err = vfs.ReadDirContents("2024/league", file)
+	assert.Nil(t, err)
+	assert.Len(t, contents, 1)
+}
+
+func TestVersionFS_ReadDirContents_Empty(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	contents, err := vfs.ReadDirContents("2023/league", fileLeague{season: 2023})
+	assert.Nil(t, err)
+	assert.NotNil(t, contents)
+	assert.Empty(t, contents)
+}
+
+// readFailingBackend is a MemoryBackend failing to read the files named in denied.
+type readFailingBackend struct {
+	*MemoryBackend
+	denied string
+}
+
+func (b readFailingBackend) ReadFile(name string) ([]byte, error) {
+	if name == b.denied {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	return b.MemoryBackend.ReadFile(name)
+}
+
+func TestVersionFS_ReadDirContents_Unreadable(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	file := fileLeague{season: 2023}
+	putVersion(t, vfs, file, "20231018140523", "first")
+	ts := putVersion(t, vfs, file, "20231019140523", "second")
+	vfs.Backend = readFailingBackend{vfs.Backend.(*MemoryBackend), path.Join(vfs.RootPath, Path(file, ts))}
+	contents, err := vfs.ReadDirContents("2023/league", file)
+	assert.Nil(t, contents)
+	assert.ErrorIs(t, err, fs.ErrPermission)
+	assert.ErrorContains(t, err, Path(file, ts))
+}
+
+func TestVersionFS_ReadHistoryOrdered(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	file := fileLeague{season: 2023}
+	putVersion(t, vfs, file, "20231018140523", "first")
+	putVersion(t, vfs, file, "20231020140523", "third")
+	putVersion(t, vfs, file, "20231019140523", "second")
+	history, err := vfs.ReadHistoryOrdered(file)
+	assert.Nil(t, err)
+	var timestamps, contents []string
+	for _, version := range history {
+		timestamps = append(timestamps, version.Timestamp.String())
+		contents = append(contents, string(version.Data))
+	}
+	assert.Equal(t, []string{"20231020140523", "20231019140523", "20231018140523"}, timestamps)
+	assert.Equal(t, []string{"third", "second", "first"}, contents)
+
+	history, err = vfs.ReadHistoryOrdered(fileLeague{season: 2024})
+	assert.Nil(t, err)
+	assert.NotNil(t, history)
+	assert.Empty(t, history)
+}
+
+func TestVersionFS_ReadHistoryOrdered_Unreadable(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	file := fileLeague{season: 2023}
+	putVersion(t, vfs, file, "20231018140523", "first")
+	ts := putVersion(t, vfs, file, "20231019140523", "second")
+	vfs.Backend = readFailingBackend{vfs.Backend.(*MemoryBackend), path.Join(vfs.RootPath, Path(file, ts))}
+	history, err := vfs.ReadHistoryOrdered(file)
+	assert.Nil(t, history)
+	assert.ErrorIs(t, err, fs.ErrPermission)
+	assert.ErrorContains(t, err, Path(file, ts))
+}
@@ -0,0 +1,217 @@
+package versionfs
+
+import (
+	"errors"
+	"io/fs"
+	path_ "path"
+	"strings"
+)
+
+// ChangeExt renames the versions of the file named name in dir from the extension fromExt
+// to toExt, keeping their timestamps, for example after the storage of a file type moved
+// from "txt" to "json". Multi-part extensions are supported, such as "csv.gz", and files
+// with another extension are left untouched. fromExt is compared like Find does, honoring
+// CaseInsensitiveExt. It returns the number of versions renamed. If a version already
+// exists with the new extension, ChangeExt stops with an error wrapping fs.ErrExist.
+//
+// Example:
+//
+//	renamed, err := vfs.ChangeExt("2023/league", "league", "txt", "json")
+func (v *VersionFS) ChangeExt(dir, name, fromExt, toExt string) (int, error) {
+	v.logger().Debugf("Renaming files %s/%s.%s to %s/%s.%s", dir, name, fromExt, dir, name, toExt)
+	if err := v.confine("rename", slashed(dir)); err != nil {
+		return 0, err
+	}
+	entries, err := v.backend().ReadDir(path_.Join(v.RootPath, dir))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	unlock, err := v.lockDir(dir)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+	renamed := 0
+	for _, entry := range entries {
+		if entry.IsDir() || v.hidden(entry.Name()) || !strings.HasPrefix(entry.Name(), name+".") {
+			continue
+		}
+		rest := entry.Name()[len(name)+1:]
+		last := strings.LastIndexByte(rest, '.')
+		if last <= 0 || !v.matchExt(rest[:last], fromExt) {
+			continue
+		}
+		ts := rest[last+1:]
+		if _, err := NewTimestamp(ts); err != nil {
+			v.logger().Warnf("unexpected timestamp for file: %s/%s", dir, entry.Name())
+			continue
+		}
+		newName := path_.Join(dir, name+"."+toExt+"."+ts)
+		if err := v.renameAt(path_.Join(dir, entry.Name()), newName); err != nil {
+			return renamed, err
+		}
+		renamed++
+	}
+	return renamed, nil
+}
+
+// renameAt renames the file at oldName to newName, relative to the root, failing if newName exists.
+// Backends not implementing RenameBackend copy the content with WriteFile, then remove oldName.
+func (v *VersionFS) renameAt(oldName, newName string) error {
+	if err := v.checkWritable("rename", newName); err != nil {
+		return err
+	}
+	oldPath, newPath := path_.Join(v.RootPath, oldName), path_.Join(v.RootPath, newName)
+	if _, err := v.backend().Stat(newPath); err == nil {
+		return &fs.PathError{Op: "rename", Path: newName, Err: fs.ErrExist}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if v.DryRun {
+		v.plan.record(PlannedOp{Op: OpRename, Path: newName, From: oldName})
+		return nil
+	}
+	defer v.invalidate(newName)
+	defer v.invalidate(oldName)
+	if rb, ok := v.Backend.(RenameBackend); ok {
+		_, err := callBackend(v, "rename", oldPath, func() (struct{}, error) {
+			return struct{}{}, rb.Rename(oldPath, newPath)
+		})
+		return err
+	}
+	data, err := v.backend().ReadFile(oldPath)
+	if err != nil {
+		return err
+	}
+	if err := v.backend().WriteFile(newPath, data, 0644); err != nil {
+		return err
+	}
+	return v.backend().Remove(oldPath)
+}
+
+// MoveDir moves the directory from, relative to the root, with all its versions and
+// subdirectories to to, creating the parent directories of to, e.g. to move 2023/league
+// to archive/2023/league. It fails with an error wrapping fs.ErrExist if to exists. The
+// directory is renamed at once when the backend implements RenameBackend, which is atomic
+// on the local filesystem. When the rename crosses devices, or the backend can't rename,
+// the files are copied, then removed from from: the copy isn't atomic, and a failed copy
+// is removed, leaving from untouched.
+//
+// Example:
+//
+//	err := vfs.MoveDir("2023/league", "archive/2023/league")
+func (v *VersionFS) MoveDir(from, to string) error {
+	v.logger().Debugf("Moving directory %s to %s", from, to)
+	from, to = path_.Clean(slashed(from)), path_.Clean(slashed(to))
+	for _, name := range []string{from, to} {
+		if err := v.confine("movedir", name); err != nil {
+			return err
+		}
+		if name == "." {
+			return &fs.PathError{Op: "movedir", Path: name, Err: fs.ErrInvalid}
+		}
+	}
+	if err := v.checkWritable("movedir", to); err != nil {
+		return err
+	}
+	fromPath, toPath := path_.Join(v.RootPath, from), path_.Join(v.RootPath, to)
+	if info, err := v.backend().Stat(fromPath); err != nil {
+		return err
+	} else if !info.IsDir() {
+		return &fs.PathError{Op: "movedir", Path: from, Err: errors.New("not a directory")}
+	}
+	if _, err := v.backend().Stat(toPath); err == nil {
+		return &fs.PathError{Op: "movedir", Path: to, Err: fs.ErrExist}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if from == to || strings.HasPrefix(to, from+"/") {
+		return &fs.PathError{Op: "movedir", Path: to, Err: fs.ErrInvalid}
+	}
+	if v.DryRun {
+		v.plan.record(PlannedOp{Op: OpRename, Path: to, From: from})
+		return nil
+	}
+	if err := v.MkdirAll(path_.Dir(to), 0755); err != nil {
+		return err
+	}
+	defer v.invalidate(to)
+	defer v.invalidate(from)
+	if rb, ok := v.Backend.(RenameBackend); ok {
+		_, err := callBackend(v, "rename", fromPath, func() (struct{}, error) {
+			return struct{}{}, rb.Rename(fromPath, toPath)
+		})
+		if !crossDevice(err) {
+			return err
+		}
+		v.logger().Debugf("Copying directory %s to %s across devices", from, to)
+	}
+	if err := v.copyDirAt(from, to); err != nil {
+		if removeErr := v.removeDirAt(to); removeErr != nil && !errors.Is(removeErr, fs.ErrNotExist) {
+			v.logger().Warnf("removing the partial copy %s: %s", to, removeErr)
+		}
+		return err
+	}
+	return v.removeDirAt(from)
+}
+
+// crossDevice reports whether err is the failure of a rename across devices.
+func crossDevice(err error) bool {
+	for _, crossDevice := range crossDeviceErrors {
+		if errors.Is(err, crossDevice) {
+			return true
+		}
+	}
+	return false
+}
+
+// copyDirAt copies the directory from and its content to to, relative to the root.
+func (v *VersionFS) copyDirAt(from, to string) error {
+	if err := v.backend().MkdirAll(path_.Join(v.RootPath, to), 0755); err != nil {
+		return err
+	}
+	entries, err := v.backend().ReadDir(path_.Join(v.RootPath, from))
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		src, dst := path_.Join(from, entry.Name()), path_.Join(to, entry.Name())
+		if entry.IsDir() {
+			if err := v.copyDirAt(src, dst); err != nil {
+				return err
+			}
+			continue
+		}
+		data, err := v.backend().ReadFile(path_.Join(v.RootPath, src))
+		if err != nil {
+			return err
+		}
+		if err := v.backend().WriteFile(path_.Join(v.RootPath, dst), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeDirAt removes the directory name and its content, relative to the root.
+func (v *VersionFS) removeDirAt(name string) error {
+	entries, err := v.backend().ReadDir(path_.Join(v.RootPath, name))
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		child := path_.Join(name, entry.Name())
+		if entry.IsDir() {
+			err = v.removeDirAt(child)
+		} else {
+			err = v.backend().Remove(path_.Join(v.RootPath, child))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return v.backend().Remove(path_.Join(v.RootPath, name))
+}
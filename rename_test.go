@@ -0,0 +1,142 @@
+package versionfs
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io/fs"
+	"os"
+	"testing"
+)
+
+func TestVersionFS_ChangeExt(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	putRaw(t, vfs, "2023/league/league.txt.20231019140523", "first")
+	putRaw(t, vfs, "2023/league/league.txt.20231020140523", "second")
+	putRaw(t, vfs, "2023/league/league.csv.gz.20231020140523", "other")
+	putRaw(t, vfs, "2023/league/leagues.txt.20231020140523", "other")
+	putRaw(t, vfs, "2023/league/league.txt", "not a version")
+	renamed, err := vfs.ChangeExt("2023/league", "league", "txt", "json")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, renamed)
+	leagueJSON := aliasFile{dir: "2023/league", alias: Alias{Name: "league", Ext: "json"}}
+	versions, err := vfs.Find("2023/league", leagueJSON)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"20231020140523", "20231019140523"}, timestampStrings(versions))
+	data, err := vfs.Read(leagueJSON, versions[1])
+	assert.Nil(t, err)
+	assert.Equal(t, "first", string(data))
+	for _, name := range []string{"league.csv.gz.20231020140523", "leagues.txt.20231020140523", "league.txt"} {
+		exists, _ := vfs.PathExists("2023/league/" + name)
+		assert.True(t, exists, name)
+	}
+
+	renamed, err = vfs.ChangeExt("2023/league", "league", "csv.gz", "csv.zst")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, renamed)
+	exists, _ := vfs.PathExists("2023/league/league.csv.zst.20231020140523")
+	assert.True(t, exists)
+
+	renamed, err = vfs.ChangeExt("2024/league", "league", "txt", "json")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, renamed)
+}
+
+func TestVersionFS_ChangeExt_Exists(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	putRaw(t, vfs, "2023/league/league.txt.20231019140523", "old")
+	putRaw(t, vfs, "2023/league/league.json.20231019140523", "new")
+	_, err := vfs.ChangeExt("2023/league", "league", "txt", "json")
+	assert.True(t, errors.Is(err, fs.ErrExist))
+	data, err := vfs.Backend.ReadFile("2023/league/league.json.20231019140523")
+	assert.Nil(t, err)
+	assert.Equal(t, "new", string(data))
+}
+
+func TestVersionFS_ChangeExt_DryRun(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	vfs.DryRun = true
+	putRaw(t, vfs, "2023/league/league.txt.20231019140523", "old")
+	renamed, err := vfs.ChangeExt("2023/league", "league", "txt", "json")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, renamed)
+	assert.Equal(t, []PlannedOp{{Op: OpRename, Path: "2023/league/league.json.20231019140523", From: "2023/league/league.txt.20231019140523"}}, vfs.Operations())
+	exists, _ := vfs.PathExists("2023/league/league.txt.20231019140523")
+	assert.True(t, exists)
+}
+
+func TestVersionFS_MoveDir(t *testing.T) {
+	t.Parallel()
+	for name, vfs := range map[string]*VersionFS{"memory": NewMemory(), "os": New(t.TempDir())} {
+		file := fileLeague{season: 2023}
+		ts := putVersion(t, vfs, file, "20231019140523", "league")
+		putRaw(t, vfs, "2023/league/archive/league.txt.20221019140523", "nested")
+		putRaw(t, vfs, "2023/roster/roster.json.20231019140523", "roster")
+		assert.Nil(t, vfs.MoveDir("2023/league", "archive/2023/league"), name)
+
+		moved := aliasFile{dir: "archive/2023/league", alias: Alias{Name: "league", Ext: "txt"}}
+		data, err := vfs.Read(moved, ts)
+		assert.Nil(t, err, name)
+		assert.Equal(t, "league", string(data), name)
+		for path, exists := range map[string]bool{
+			"archive/2023/league/archive/league.txt.20221019140523": true,
+			"2023/roster/roster.json.20231019140523":                true,
+			"2023/league":                                           false,
+		} {
+			found, err := vfs.PathExists(path)
+			assert.Nil(t, err, name)
+			assert.Equal(t, exists, found, "%s %s", name, path)
+		}
+	}
+}
+
+func TestVersionFS_MoveDir_Errors(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	putVersion(t, vfs, fileLeague{season: 2023}, "20231019140523", "league")
+	putVersion(t, vfs, fileLeague{season: 2024}, "20231019140523", "league")
+	assert.ErrorIs(t, vfs.MoveDir("2023/league", "2024/league"), fs.ErrExist)
+	assert.ErrorIs(t, vfs.MoveDir("2022/league", "archive/2022/league"), fs.ErrNotExist)
+	assert.ErrorIs(t, vfs.MoveDir("2023/league", "2023/league/archive"), fs.ErrInvalid)
+	assert.ErrorIs(t, vfs.MoveDir("2023/league", "../league"), ErrOutsideRoot)
+	assert.NotNil(t, vfs.MoveDir("2023/league/league.txt.20231019140523", "archive/league.txt"))
+	exists, err := vfs.PathExists("2023/league/league.txt.20231019140523")
+	assert.Nil(t, err)
+	assert.True(t, exists)
+}
+
+// crossDeviceBackend is a MemoryBackend failing to rename across devices.
+type crossDeviceBackend struct {
+	*MemoryBackend
+}
+
+func (b crossDeviceBackend) Rename(oldname, newname string) error {
+	return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: crossDeviceErrors[0]}
+}
+
+func TestVersionFS_MoveDir_CrossDevice(t *testing.T) {
+	t.Parallel()
+	if len(crossDeviceErrors) == 0 {
+		t.Skip("renames across devices aren't detected on this platform")
+	}
+	vfs := NewMemory()
+	vfs.Backend = crossDeviceBackend{vfs.Backend.(*MemoryBackend)}
+	file := fileLeague{season: 2023}
+	ts := putVersion(t, vfs, file, "20231019140523", "league")
+	putRaw(t, vfs, "2023/league/archive/league.txt.20221019140523", "nested")
+	assert.Nil(t, vfs.MoveDir("2023/league", "archive/2023/league"))
+	data, err := vfs.Read(aliasFile{dir: "archive/2023/league", alias: Alias{Name: "league", Ext: "txt"}}, ts)
+	assert.Nil(t, err)
+	assert.Equal(t, "league", string(data))
+	for path, exists := range map[string]bool{
+		"archive/2023/league/archive/league.txt.20221019140523": true,
+		"2023/league": false,
+		"2023":        true,
+	} {
+		found, err := vfs.PathExists(path)
+		assert.Nil(t, err)
+		assert.Equal(t, exists, found, path)
+	}
+}
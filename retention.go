@@ -0,0 +1,181 @@
+package versionfs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	path_ "path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RetentionPolicy decides which versions of a file are removed by Prune.
+// A version is removed when it exceeds any of the limits that are set.
+// The latest version is never removed.
+type RetentionPolicy struct {
+	// KeepLast is the number of newest versions to keep, zero for no limit.
+	KeepLast int
+	// MaxAge is the age after which versions are removed, zero for no limit.
+	// Like LatestAge, the age is computed from the timestamp, interpreted in the local time zone.
+	MaxAge time.Duration
+}
+
+// expired returns the indices of the versions to remove among versions, sorted newest first.
+func (p RetentionPolicy) expired(versions []Timestamp, now time.Time) []int {
+	var expired []int
+	for i, ts := range versions {
+		if i == 0 {
+			continue
+		}
+		if (p.KeepLast > 0 && i >= p.KeepLast) || (p.MaxAge > 0 && now.Sub(ts.time) > p.MaxAge) {
+			expired = append(expired, i)
+		}
+	}
+	return expired
+}
+
+// Prune removes the versions of a file exceeding the retention policy and returns them, newest first.
+// It stops at the first version that can't be removed.
+//
+// Example:
+//
+//	removed, err := vfs.Prune(file, versionfs.RetentionPolicy{KeepLast: 10, MaxAge: 30 * 24 * time.Hour})
+func (v *VersionFS) Prune(file File, policy RetentionPolicy) ([]Timestamp, error) {
+	if err := v.confine("prune", slashed(file.Dir())); err != nil {
+		return nil, err
+	}
+	unlock, err := v.lockDir(file.Dir())
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	versions, err := v.localVersions(file)
+	if err != nil {
+		return nil, err
+	}
+	return v.prune(versions, policy, func(int) File { return file })
+}
+
+// prune removes the versions exceeding the policy among versions, sorted newest first,
+// the version at index i being stored as fileOf(i).
+func (v *VersionFS) prune(versions []Timestamp, policy RetentionPolicy, fileOf func(i int) File) ([]Timestamp, error) {
+	var removed []Timestamp
+	defer func() {
+		v.metrics().IncCounter(MetricPrunedVersions, int64(len(removed)), "root", v.RootPath)
+	}()
+	for _, i := range policy.expired(versions, time.Now()) {
+		if err := v.Remove(fileOf(i), versions[i]); err != nil {
+			return removed, err
+		}
+		removed = append(removed, versions[i])
+	}
+	return removed, nil
+}
+
+// PruneDir applies the retention policy to every group of versions in dir with the name
+// of file, whatever their extension: "league.json" and "league.json.gz" versions are pruned
+// separately. It returns the removed timestamps keyed by "name.ext", omitting the groups
+// where nothing was removed.
+//
+// Example:
+//
+//	removed, err := vfs.PruneDir("2023/league", file, versionfs.RetentionPolicy{KeepLast: 10})
+func (v *VersionFS) PruneDir(dir string, file File, policy RetentionPolicy) (map[string][]Timestamp, error) {
+	if err := v.confine("prune", slashed(dir)); err != nil {
+		return nil, err
+	}
+	unlock, err := v.lockDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	groups, err := v.findAnyExt(slashed(dir), file.Name())
+	if err != nil {
+		return nil, err
+	}
+	return v.pruneGroups(dir, file.Name(), groups, policy)
+}
+
+// PruneDirPrefix applies the retention policy to every file of dir whose name starts with
+// prefix, for directories with many distinct names, such as one roster per team. Versions are
+// grouped by name and extension, and the removed timestamps are keyed by "name.ext".
+//
+// Example:
+//
+//	removed, err := vfs.PruneDirPrefix("2023/roster", "roster-", versionfs.RetentionPolicy{KeepLast: 3})
+func (v *VersionFS) PruneDirPrefix(dir, prefix string, policy RetentionPolicy) (map[string][]Timestamp, error) {
+	if err := v.confine("prune", slashed(dir)); err != nil {
+		return nil, err
+	}
+	unlock, err := v.lockDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	entries, err := v.backend().ReadDir(path_.Join(v.RootPath, dir))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return map[string][]Timestamp{}, nil
+		}
+		return nil, err
+	}
+	names := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), prefix) {
+			continue
+		}
+		if name, _, _, err := ParseFilename(entry.Name()); err == nil {
+			names[name] = true
+		}
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	result := make(map[string][]Timestamp)
+	for _, name := range sorted {
+		groups, err := v.findAnyExt(slashed(dir), name)
+		if err != nil {
+			return result, err
+		}
+		removed, err := v.pruneGroups(dir, name, groups, policy)
+		for key, timestamps := range removed {
+			result[key] = timestamps
+		}
+		if err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// pruneGroups prunes the versions of name in dir grouped by extension, as returned by
+// findAnyExt. Each version is removed under the extension it is stored with, which differs
+// from the one of its group with CaseInsensitiveExt.
+func (v *VersionFS) pruneGroups(dir, name string, groups map[string][]extVersion, policy RetentionPolicy) (map[string][]Timestamp, error) {
+	exts := make([]string, 0, len(groups))
+	for ext := range groups {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	result := make(map[string][]Timestamp)
+	for _, ext := range exts {
+		group := groups[ext]
+		timestamps := make([]Timestamp, len(group))
+		for i, version := range group {
+			timestamps[i] = version.ts
+		}
+		removed, err := v.prune(timestamps, policy, func(i int) File {
+			return aliasFile{dir: dir, alias: Alias{Name: name, Ext: group[i].ext}}
+		})
+		if len(removed) > 0 {
+			result[name+"."+ext] = removed
+		}
+		if err != nil {
+			return result, fmt.Errorf("pruning %s/%s.%s: %w", dir, name, ext, err)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,142 @@
+package versionfs
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// putAged stores versions of a file that are the given number of days old.
+func putAged(t *testing.T, vfs *VersionFS, file File, days ...int) []Timestamp {
+	var timestamps []Timestamp
+	for _, d := range days {
+		ts := NewFromTime(time.Now().AddDate(0, 0, -d))
+		timestamps = append(timestamps, putVersion(t, vfs, file, ts.String(), fmt.Sprint(d)))
+	}
+	return timestamps
+}
+
+func TestRetentionPolicy_expired(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2023, 10, 19, 0, 0, 0, 0, time.UTC)
+	var versions []Timestamp
+	for d := 0; d < 5; d++ {
+		versions = append(versions, NewFromTime(now.AddDate(0, 0, -d*10)))
+	}
+	assert.Empty(t, RetentionPolicy{}.expired(versions, now))
+	assert.Equal(t, []int{2, 3, 4}, RetentionPolicy{KeepLast: 2}.expired(versions, now))
+	assert.Equal(t, []int{3, 4}, RetentionPolicy{MaxAge: 25 * 24 * time.Hour}.expired(versions, now))
+	assert.Equal(t, []int{2, 3, 4}, RetentionPolicy{KeepLast: 2, MaxAge: 25 * 24 * time.Hour}.expired(versions, now))
+	// the latest version is kept even when too old
+	assert.Equal(t, []int{1, 2, 3}, RetentionPolicy{MaxAge: time.Hour}.expired(versions[1:], now))
+}
+
+func TestVersionFS_Prune(t *testing.T) {
+	t.Parallel()
+	vfs := newTypedVersionFS()
+	file := fileLeague{season: 2023}
+	ts := putAged(t, vfs, file, 1, 10, 20, 40)
+	removed, err := vfs.Prune(file, RetentionPolicy{KeepLast: 3, MaxAge: 15 * 24 * time.Hour})
+	assert.Nil(t, err)
+	assert.Equal(t, timestampStrings(ts[2:]), timestampStrings(removed))
+	versions, err := vfs.Versions(file)
+	assert.Nil(t, err)
+	assert.Equal(t, timestampStrings(ts[:2]), timestampStrings(versions))
+}
+
+func TestVersionFS_PruneDir(t *testing.T) {
+	t.Parallel()
+	vfs := newTypedVersionFS()
+	file := fileLeague{season: 2023}
+	txt := putAged(t, vfs, file, 1, 2, 3)
+	gz := putAged(t, vfs, aliasFile{dir: file.Dir(), alias: Alias{Name: "league", Ext: "txt.gz"}}, 1, 2)
+	putAged(t, vfs, aliasFile{dir: file.Dir(), alias: Alias{Name: "other", Ext: "txt"}}, 1, 2, 3)
+	removed, err := vfs.PruneDir(file.Dir(), file, RetentionPolicy{KeepLast: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(removed))
+	assert.Equal(t, timestampStrings(txt[1:]), timestampStrings(removed["league.txt"]))
+	assert.Equal(t, timestampStrings(gz[1:]), timestampStrings(removed["league.txt.gz"]))
+	groups, err := vfs.FindAnyExt(file.Dir(), "other")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(groups["txt"]))
+
+	removed, err = vfs.PruneDir("missing", file, RetentionPolicy{KeepLast: 1})
+	assert.Nil(t, err)
+	assert.Empty(t, removed)
+}
+
+func TestVersionFS_PruneDir_CaseInsensitiveExt(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	vfs.CaseInsensitiveExt = true
+	file := fileLeague{season: 2023}
+	upper := putVersion(t, vfs, aliasFile{dir: file.Dir(), alias: Alias{Name: "league", Ext: "TXT"}}, "20231017140523", "upper")
+	mixed := putVersion(t, vfs, aliasFile{dir: file.Dir(), alias: Alias{Name: "league", Ext: "Txt"}}, "20231018140523", "mixed")
+	latest := putVersion(t, vfs, file, "20231019140523", "lower")
+	removed, err := vfs.PruneDir(file.Dir(), file, RetentionPolicy{KeepLast: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, timestampStrings([]Timestamp{mixed, upper}), timestampStrings(removed["league.txt"]))
+	groups, err := vfs.FindAnyExt(file.Dir(), "league")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string][]string{"txt": {latest.String()}}, map[string][]string{"txt": timestampStrings(groups["txt"])})
+}
+
+// The versions are named in local time: in a zone behind UTC, MaxAge must not remove them early.
+func TestVersionFS_Prune_LocalZone(t *testing.T) {
+	setLocal(t, "EDT", -4*3600)
+	vfs := NewMemory()
+	file := fileLeague{season: 2023}
+	now := time.Now()
+	putVersion(t, vfs, file, NewFromTime(now.Add(-time.Hour)).String(), "recent")
+	putVersion(t, vfs, file, NewFromTime(now.Add(-10*time.Minute)).String(), "latest")
+	removed, err := vfs.Prune(file, RetentionPolicy{MaxAge: 2 * time.Hour})
+	assert.Nil(t, err)
+	assert.Empty(t, removed)
+}
+
+func TestVersionFS_PruneDirPrefix(t *testing.T) {
+	t.Parallel()
+	vfs := newTypedVersionFS()
+	dir := "2023/roster"
+	roster := func(team int) File {
+		return aliasFile{dir: dir, alias: Alias{Name: fmt.Sprintf("roster-%d", team), Ext: "json"}}
+	}
+	r12 := putAged(t, vfs, roster(12), 1, 2, 3)
+	r13 := putAged(t, vfs, roster(13), 1, 2)
+	putAged(t, vfs, roster(14), 1)
+	putAged(t, vfs, aliasFile{dir: dir, alias: Alias{Name: "index", Ext: "json"}}, 1, 2, 3)
+	putRaw(t, vfs, dir+"/roster-notes.txt", "unversioned")
+	removed, err := vfs.PruneDirPrefix(dir, "roster-", RetentionPolicy{KeepLast: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string][]string{
+		"roster-12.json": timestampStrings(r12[1:]),
+		"roster-13.json": timestampStrings(r13[1:]),
+	}, map[string][]string{
+		"roster-12.json": timestampStrings(removed["roster-12.json"]),
+		"roster-13.json": timestampStrings(removed["roster-13.json"]),
+	})
+	assert.Equal(t, 2, len(removed))
+	groups, err := vfs.FindAnyExt(dir, "index")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(groups["json"]))
+
+	removed, err = vfs.PruneDirPrefix("missing", "roster-", RetentionPolicy{KeepLast: 1})
+	assert.Nil(t, err)
+	assert.Empty(t, removed)
+}
+
+func TestVersionFS_Prune_ReadOnly(t *testing.T) {
+	t.Parallel()
+	vfs := newTypedVersionFS()
+	file := fileLeague{season: 2023}
+	putAged(t, vfs, file, 1, 2, 3)
+	vfs.ReadOnly = true
+	removed, err := vfs.PruneDir(file.Dir(), file, RetentionPolicy{KeepLast: 1})
+	assert.True(t, errors.Is(err, ErrReadOnly))
+	assert.Empty(t, removed)
+	versions, err := vfs.Versions(file)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(versions))
+}
@@ -0,0 +1,92 @@
+package versionfs
+
+import (
+	"errors"
+	"io/fs"
+	"time"
+)
+
+// IsTransient reports whether err is a storage error that may succeed on retry, an I/O
+// error (EIO) or a stale file handle (ESTALE) of a network filesystem. Errors such as
+// fs.ErrNotExist or fs.ErrPermission are not transient.
+func IsTransient(err error) bool {
+	for _, transient := range transientErrors {
+		if errors.Is(err, transient) {
+			return true
+		}
+	}
+	return false
+}
+
+// withRetry calls fn, and calls it again up to MaxRetries times while it fails with a
+// transient error, waiting RetryBackoff before the first retry and twice as long before
+// each of the next ones. The result of the last call is returned.
+func withRetry[T any](v *VersionFS, fn func() (T, error)) (T, error) {
+	value, err := fn()
+	for retry := 0; retry < v.MaxRetries && IsTransient(err); retry++ {
+		wait := v.RetryBackoff << retry
+		v.logger().Debugf("retrying in %s after transient error: %s", wait, err)
+		time.Sleep(wait)
+		value, err = fn()
+	}
+	return value, err
+}
+
+// callBackend calls fn, a call to the Backend that isn't part of the Backend interface,
+// bounded by OpTimeout and retried on transient errors like the calls made through backend().
+func callBackend[T any](v *VersionFS, op, name string, fn func() (T, error)) (T, error) {
+	return withRetry(v, func() (T, error) {
+		return withTimeout(v.OpTimeout, op, name, fn)
+	})
+}
+
+// retryBackend is a Backend retrying the calls of another that fail with a transient error.
+type retryBackend struct {
+	backend Backend
+	v       *VersionFS
+}
+
+func (b retryBackend) ReadFile(name string) ([]byte, error) {
+	return withRetry(b.v, func() ([]byte, error) {
+		return b.backend.ReadFile(name)
+	})
+}
+
+func (b retryBackend) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	_, err := withRetry(b.v, func() (struct{}, error) {
+		return struct{}{}, b.backend.WriteFile(name, data, perm)
+	})
+	return err
+}
+
+func (b retryBackend) Open(name string) (fs.File, error) {
+	return withRetry(b.v, func() (fs.File, error) {
+		return b.backend.Open(name)
+	})
+}
+
+func (b retryBackend) Remove(name string) error {
+	_, err := withRetry(b.v, func() (struct{}, error) {
+		return struct{}{}, b.backend.Remove(name)
+	})
+	return err
+}
+
+func (b retryBackend) ReadDir(name string) ([]fs.DirEntry, error) {
+	return withRetry(b.v, func() ([]fs.DirEntry, error) {
+		return b.backend.ReadDir(name)
+	})
+}
+
+func (b retryBackend) Stat(name string) (fs.FileInfo, error) {
+	return withRetry(b.v, func() (fs.FileInfo, error) {
+		return b.backend.Stat(name)
+	})
+}
+
+func (b retryBackend) MkdirAll(name string, perm fs.FileMode) error {
+	_, err := withRetry(b.v, func() (struct{}, error) {
+		return struct{}{}, b.backend.MkdirAll(name, perm)
+	})
+	return err
+}
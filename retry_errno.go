@@ -0,0 +1,12 @@
+//go:build !plan9
+
+package versionfs
+
+import "syscall"
+
+// transientErrors are the errors classified as transient by IsTransient.
+var transientErrors = []error{syscall.EIO, syscall.ESTALE}
+
+// crossDeviceErrors are the errors of the renames across devices, which MoveDir replaces
+// with a copy.
+var crossDeviceErrors = []error{syscall.EXDEV}
@@ -0,0 +1,11 @@
+//go:build plan9
+
+package versionfs
+
+// transientErrors are the errors classified as transient by IsTransient. Plan 9 reports
+// errors as strings, without errno values to classify, so nothing is retried.
+var transientErrors []error
+
+// crossDeviceErrors are the errors of the renames across devices, which MoveDir replaces
+// with a copy. Plan 9 doesn't classify them.
+var crossDeviceErrors []error
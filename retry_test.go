@@ -0,0 +1,118 @@
+//go:build !plan9
+
+package versionfs
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io/fs"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// flakyBackend is a MemoryBackend whose ReadFile and WriteFile fail with err the first failures times.
+type flakyBackend struct {
+	*MemoryBackend
+	err      error
+	failures int32
+	calls    *atomic.Int32
+}
+
+func newFlakyBackend(memory *MemoryBackend, err error, failures int32) flakyBackend {
+	return flakyBackend{MemoryBackend: memory, err: err, failures: failures, calls: &atomic.Int32{}}
+}
+
+func (b flakyBackend) ReadFile(name string) ([]byte, error) {
+	if b.calls.Add(1) <= b.failures {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: b.err}
+	}
+	return b.MemoryBackend.ReadFile(name)
+}
+
+func (b flakyBackend) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if b.calls.Add(1) <= b.failures {
+		return &fs.PathError{Op: "write", Path: name, Err: b.err}
+	}
+	return b.MemoryBackend.WriteFile(name, data, perm)
+}
+
+func TestIsTransient(t *testing.T) {
+	t.Parallel()
+	assert.True(t, IsTransient(syscall.EIO))
+	assert.True(t, IsTransient(&fs.PathError{Op: "read", Path: "name", Err: syscall.ESTALE}))
+	assert.True(t, IsTransient(fmt.Errorf("reading: %w", syscall.EIO)))
+	assert.False(t, IsTransient(nil))
+	assert.False(t, IsTransient(fs.ErrNotExist))
+	assert.False(t, IsTransient(&fs.PathError{Op: "read", Path: "name", Err: syscall.ENOENT}))
+	assert.False(t, IsTransient(ErrTimeout))
+}
+
+func TestVersionFS_MaxRetries(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	vfs.MaxRetries = 2
+	vfs.RetryBackoff = 10 * time.Millisecond
+	file := fileLeague{season: 2023}
+
+	memory := vfs.Backend.(*MemoryBackend)
+	backend := newFlakyBackend(memory, syscall.ESTALE, 2)
+	vfs.Backend = backend
+	start := time.Now()
+	ts, err := vfs.Write(file, []byte("data"))
+	assert.Nil(t, err)
+	assert.Equal(t, int32(3), backend.calls.Load())
+	// waited 10ms then 20ms
+	assert.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond)
+
+	backend = newFlakyBackend(memory, syscall.EIO, 2)
+	vfs.Backend = backend
+	data, err := vfs.Read(file, ts)
+	assert.Nil(t, err)
+	assert.Equal(t, "data", string(data))
+	assert.Equal(t, int32(3), backend.calls.Load())
+
+	// the error of the last attempt is returned when the retries are exhausted
+	backend = newFlakyBackend(memory, syscall.EIO, 3)
+	vfs.Backend = backend
+	_, err = vfs.Read(file, ts)
+	assert.True(t, errors.Is(err, syscall.EIO))
+	assert.Equal(t, int32(3), backend.calls.Load())
+}
+
+func TestVersionFS_MaxRetries_NotTransient(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	vfs.MaxRetries = 5
+	file := fileLeague{season: 2023}
+	ts := putVersion(t, vfs, file, "20231019140523", "data")
+
+	memory := vfs.Backend.(*MemoryBackend)
+	backend := newFlakyBackend(memory, fs.ErrPermission, 1)
+	vfs.Backend = backend
+	_, err := vfs.Read(file, ts)
+	assert.True(t, errors.Is(err, fs.ErrPermission))
+	assert.Equal(t, int32(1), backend.calls.Load())
+
+	backend = newFlakyBackend(memory, fs.ErrNotExist, 0)
+	vfs.Backend = backend
+	missing, _ := NewTimestamp("20231018140523")
+	_, err = vfs.Read(file, missing)
+	assert.True(t, errors.Is(err, ErrVersionNotFound))
+	assert.Equal(t, int32(1), backend.calls.Load())
+}
+
+func TestVersionFS_MaxRetries_Disabled(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	file := fileLeague{season: 2023}
+	ts := putVersion(t, vfs, file, "20231019140523", "data")
+	memory := vfs.Backend.(*MemoryBackend)
+	backend := newFlakyBackend(memory, syscall.EIO, 1)
+	vfs.Backend = backend
+	_, err := vfs.Read(file, ts)
+	assert.True(t, errors.Is(err, syscall.EIO))
+	assert.Equal(t, int32(1), backend.calls.Load())
+}
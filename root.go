@@ -0,0 +1,68 @@
+package versionfs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ErrInvalidRoot is returned by NewChecked when the root path is empty or isn't a directory.
+var ErrInvalidRoot = errors.New("invalid root")
+
+// RootOptions configures NewChecked.
+type RootOptions struct {
+	// Create creates the root directory and its missing parents, instead of failing when it doesn't exist.
+	Create bool
+	// Perm is the permission of the directories created with Create, 0755 if zero.
+	Perm fs.FileMode
+}
+
+// NewChecked creates a new VersionFS instance like New, after validating the root path: it
+// must not be empty, it is made absolute against the current directory and cleaned, and it
+// must be an existing directory, or is created with RootOptions.Create. It returns an error
+// wrapping ErrInvalidRoot, or the error of the filesystem, so that a misconfigured root is
+// reported when the instance is created instead of by the first write.
+// The root is stored slash-separated, as the paths relative to it.
+//
+// Example:
+//
+//	vfs, err := versionfs.NewChecked(os.Getenv("DATA_DIR"), versionfs.RootOptions{Create: true})
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+func NewChecked(rootPath string, opts RootOptions) (*VersionFS, error) {
+	if rootPath == "" {
+		return nil, fmt.Errorf("empty root path: %w", ErrInvalidRoot)
+	}
+	abs, err := filepath.Abs(rootPath)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(abs)
+	if errors.Is(err, fs.ErrNotExist) && opts.Create {
+		perm := opts.Perm
+		if perm == 0 {
+			perm = 0755
+		}
+		if err := os.MkdirAll(abs, perm); err != nil {
+			return nil, err
+		}
+		info, err = os.Stat(abs)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, &fs.PathError{Op: "root", Path: abs, Err: fmt.Errorf("not a directory: %w", ErrInvalidRoot)}
+	}
+	return New(filepath.ToSlash(abs)), nil
+}
+
+// Root returns the root path of the instance, as normalized by NewChecked, or as given to New.
+// Prefer it to reading RootPath, and WithRoot or Clone to changing it: RootPath must not be
+// modified while operations are in flight.
+func (v *VersionFS) Root() string {
+	return v.RootPath
+}
@@ -0,0 +1,49 @@
+package versionfs
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewChecked(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	vfs, err := NewChecked(dir+"/sub/..", RootOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.ToSlash(dir), vfs.Root())
+	_, err = vfs.Write(fileLeague{season: 2023}, []byte("data"))
+	assert.Nil(t, err)
+
+	// relative paths are resolved against the current directory
+	vfs, err = NewChecked("test-data", RootOptions{})
+	assert.Nil(t, err)
+	wd, _ := os.Getwd()
+	assert.Equal(t, filepath.ToSlash(filepath.Join(wd, "test-data")), vfs.Root())
+}
+
+func TestNewChecked_Create(t *testing.T) {
+	t.Parallel()
+	root := filepath.Join(t.TempDir(), "data", "versions")
+	_, err := NewChecked(root, RootOptions{})
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+	vfs, err := NewChecked(root, RootOptions{Create: true})
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.ToSlash(root), vfs.Root())
+	info, err := os.Stat(root)
+	assert.Nil(t, err)
+	assert.True(t, info.IsDir())
+}
+
+func TestNewChecked_Invalid(t *testing.T) {
+	t.Parallel()
+	_, err := NewChecked("", RootOptions{Create: true})
+	assert.True(t, errors.Is(err, ErrInvalidRoot))
+	file := filepath.Join(t.TempDir(), "file")
+	assert.Nil(t, os.WriteFile(file, nil, 0644))
+	_, err = NewChecked(file, RootOptions{Create: true})
+	assert.True(t, errors.Is(err, ErrInvalidRoot))
+}
@@ -0,0 +1,249 @@
+// Package s3backend implements a versionfs.Backend storing versions in an S3-compatible
+// object store, such as AWS S3 or MinIO. It is a separate module to keep the core
+// versionfs module free of the AWS SDK.
+//
+// Paths map to object keys: the version "2023/league/league.json.20231019140523" under the
+// root path "data" is the object "data/2023/league/league.json.20231019140523".
+//
+// Object stores differ from a filesystem in a few ways:
+//   - Directories are implicit: a directory exists while objects exist under its prefix,
+//     and MkdirAll does nothing.
+//   - There is no rename, each Write is a single PutObject. A version is either fully
+//     stored or absent, but writes are not staged in a temporary file first.
+//   - Listings reflect the consistency of the store. AWS S3 is strongly consistent,
+//     other S3-compatible stores may list a new version only after a delay.
+package s3backend
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	"github.com/sperano/versionfs"
+	"io"
+	"io/fs"
+	path_ "path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Client is the subset of the S3 API used by Backend, implemented by *s3.Client.
+type Client interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+}
+
+// New creates a VersionFS instance storing its files in a bucket, under the root path.
+//
+// Example:
+//
+//	cfg, err := config.LoadDefaultConfig(ctx)
+//	vfs := s3backend.New(s3.NewFromConfig(cfg), "my-bucket", "data")
+func New(client Client, bucket string, rootPath string) *versionfs.VersionFS {
+	v := versionfs.New(rootPath)
+	v.Backend = &Backend{Client: client, Bucket: bucket}
+	return v
+}
+
+// Backend is a versionfs.Backend storing files as objects of a bucket.
+type Backend struct {
+	Client Client
+	Bucket string
+}
+
+// key converts a name to an object key, without leading slash. The root is the empty key.
+func (b *Backend) key(name string) string {
+	return strings.TrimPrefix(path_.Clean("/"+name), "/")
+}
+
+// notFound reports whether err is a missing object error.
+func notFound(err error) bool {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "NoSuchKey", "NotFound":
+			return true
+		}
+	}
+	return false
+}
+
+// pathError wraps err in an *fs.PathError, translating missing objects to fs.ErrNotExist.
+func pathError(op, name string, err error) error {
+	if notFound(err) {
+		err = fs.ErrNotExist
+	}
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+// ReadFile implements versionfs.Backend with GetObject.
+func (b *Backend) ReadFile(name string) ([]byte, error) {
+	f, err := b.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return io.ReadAll(f)
+}
+
+// WriteFile implements versionfs.Backend with a single PutObject. The permissions are ignored.
+func (b *Backend) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	_, err := b.Client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket:        aws.String(b.Bucket),
+		Key:           aws.String(b.key(name)),
+		Body:          bytes.NewReader(data),
+		ContentLength: aws.Int64(int64(len(data))),
+	})
+	if err != nil {
+		return pathError("write", name, err)
+	}
+	return nil
+}
+
+// Open implements versionfs.Backend with GetObject. The object is streamed, the file doesn't implement io.Seeker.
+func (b *Backend) Open(name string) (fs.File, error) {
+	out, err := b.Client.GetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: aws.String(b.Bucket),
+		Key:    aws.String(b.key(name)),
+	})
+	if err != nil {
+		return nil, pathError("open", name, err)
+	}
+	info := objectInfo{name: path_.Base(b.key(name)), size: aws.ToInt64(out.ContentLength), modTime: aws.ToTime(out.LastModified)}
+	return &object{ReadCloser: out.Body, info: info}, nil
+}
+
+// Remove implements versionfs.Backend with DeleteObject. Since deleting a missing object
+// succeeds in S3, the object is checked with HeadObject first. Directories are implicit,
+// removing one does nothing.
+func (b *Backend) Remove(name string) error {
+	info, err := b.Stat(name)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return nil
+	}
+	_, err = b.Client.DeleteObject(context.Background(), &s3.DeleteObjectInput{
+		Bucket: aws.String(b.Bucket),
+		Key:    aws.String(b.key(name)),
+	})
+	if err != nil {
+		return pathError("remove", name, err)
+	}
+	return nil
+}
+
+// ReadDir implements versionfs.Backend with ListObjectsV2, listing the prefix of the
+// directory with the "/" delimiter. A directory without objects doesn't exist.
+func (b *Backend) ReadDir(name string) ([]fs.DirEntry, error) {
+	prefix := b.key(name)
+	if prefix != "" {
+		prefix += "/"
+	}
+	var entries []fs.DirEntry
+	input := &s3.ListObjectsV2Input{
+		Bucket:    aws.String(b.Bucket),
+		Prefix:    aws.String(prefix),
+		Delimiter: aws.String("/"),
+	}
+	for {
+		out, err := b.Client.ListObjectsV2(context.Background(), input)
+		if err != nil {
+			return nil, pathError("readdir", name, err)
+		}
+		for _, p := range out.CommonPrefixes {
+			dirName := strings.TrimSuffix(strings.TrimPrefix(aws.ToString(p.Prefix), prefix), "/")
+			entries = append(entries, fs.FileInfoToDirEntry(objectInfo{name: dirName, dir: true}))
+		}
+		for _, o := range out.Contents {
+			fileName := strings.TrimPrefix(aws.ToString(o.Key), prefix)
+			if fileName == "" {
+				// directory marker created by some tools
+				continue
+			}
+			entries = append(entries, fs.FileInfoToDirEntry(objectInfo{name: fileName, size: aws.ToInt64(o.Size), modTime: aws.ToTime(o.LastModified)}))
+		}
+		if !aws.ToBool(out.IsTruncated) {
+			break
+		}
+		input.ContinuationToken = out.NextContinuationToken
+	}
+	if len(entries) == 0 && prefix != "" {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// Stat implements versionfs.Backend with HeadObject, falling back to listing the prefix for directories.
+func (b *Backend) Stat(name string) (fs.FileInfo, error) {
+	key := b.key(name)
+	if key == "" {
+		return objectInfo{name: ".", dir: true}, nil
+	}
+	out, err := b.Client.HeadObject(context.Background(), &s3.HeadObjectInput{
+		Bucket: aws.String(b.Bucket),
+		Key:    aws.String(key),
+	})
+	if err == nil {
+		return objectInfo{name: path_.Base(key), size: aws.ToInt64(out.ContentLength), modTime: aws.ToTime(out.LastModified)}, nil
+	}
+	if !notFound(err) {
+		return nil, pathError("stat", name, err)
+	}
+	list, err := b.Client.ListObjectsV2(context.Background(), &s3.ListObjectsV2Input{
+		Bucket:  aws.String(b.Bucket),
+		Prefix:  aws.String(key + "/"),
+		MaxKeys: aws.Int32(1),
+	})
+	if err != nil {
+		return nil, pathError("stat", name, err)
+	}
+	if len(list.Contents) == 0 {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return objectInfo{name: path_.Base(key), dir: true}, nil
+}
+
+// MkdirAll implements versionfs.Backend. Directories are implicit in S3, it does nothing.
+func (b *Backend) MkdirAll(name string, perm fs.FileMode) error {
+	return nil
+}
+
+// objectInfo is the fs.FileInfo of an object or an implicit directory.
+type objectInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+}
+
+func (i objectInfo) Name() string       { return i.name }
+func (i objectInfo) Size() int64        { return i.size }
+func (i objectInfo) ModTime() time.Time { return i.modTime }
+func (i objectInfo) IsDir() bool        { return i.dir }
+func (i objectInfo) Sys() any           { return nil }
+
+func (i objectInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0755
+	}
+	return 0644
+}
+
+// object is an object opened for reading.
+type object struct {
+	io.ReadCloser
+	info objectInfo
+}
+
+func (o *object) Stat() (fs.FileInfo, error) {
+	return o.info, nil
+}
@@ -0,0 +1,177 @@
+package s3backend
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/sperano/versionfs"
+	"github.com/sperano/versionfs/versionfstest"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClient is an in-memory Client listing at most pageSize keys per page.
+type fakeClient struct {
+	mu       sync.Mutex
+	objects  map[string][]byte
+	pageSize int
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{objects: make(map[string][]byte), pageSize: 2}
+}
+
+func (c *fakeClient) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, ok := c.objects[aws.ToString(params.Key)]
+	if !ok {
+		return nil, &types.NoSuchKey{}
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(data)), ContentLength: aws.Int64(int64(len(data))), LastModified: aws.Time(time.Now())}, nil
+}
+
+func (c *fakeClient) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	data, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.objects[aws.ToString(params.Key)] = data
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (c *fakeClient) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, ok := c.objects[aws.ToString(params.Key)]
+	if !ok {
+		return nil, &types.NotFound{}
+	}
+	return &s3.HeadObjectOutput{ContentLength: aws.Int64(int64(len(data))), LastModified: aws.Time(time.Now())}, nil
+}
+
+func (c *fakeClient) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.objects, aws.ToString(params.Key))
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func (c *fakeClient) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	prefix, delimiter := aws.ToString(params.Prefix), aws.ToString(params.Delimiter)
+	// collect the sorted keys and common prefixes, as S3 does
+	prefixes := make(map[string]bool)
+	var items []string
+	for key := range c.objects {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		rest := key[len(prefix):]
+		if i := strings.Index(rest, delimiter); delimiter != "" && i >= 0 {
+			key = prefix + rest[:i+1]
+			if prefixes[key] {
+				continue
+			}
+			prefixes[key] = true
+		}
+		items = append(items, key)
+	}
+	sort.Strings(items)
+	start := 0
+	if params.ContinuationToken != nil {
+		fmt.Sscan(aws.ToString(params.ContinuationToken), &start)
+	}
+	limit := c.pageSize
+	if params.MaxKeys != nil && int(*params.MaxKeys) < limit {
+		limit = int(*params.MaxKeys)
+	}
+	out := &s3.ListObjectsV2Output{IsTruncated: aws.Bool(false)}
+	end := start + limit
+	if end < len(items) {
+		out.IsTruncated = aws.Bool(true)
+		out.NextContinuationToken = aws.String(fmt.Sprint(end))
+	} else {
+		end = len(items)
+	}
+	for _, item := range items[start:end] {
+		if prefixes[item] {
+			out.CommonPrefixes = append(out.CommonPrefixes, types.CommonPrefix{Prefix: aws.String(item)})
+		} else {
+			out.Contents = append(out.Contents, types.Object{Key: aws.String(item), Size: aws.Int64(int64(len(c.objects[item]))), LastModified: aws.Time(time.Now())})
+		}
+	}
+	return out, nil
+}
+
+func TestConformance_Fake(t *testing.T) {
+	t.Parallel()
+	versionfstest.TestConformance(t, func(t *testing.T) *versionfs.VersionFS {
+		return New(newFakeClient(), "bucket", "data")
+	})
+}
+
+func TestBackend_ReadDir(t *testing.T) {
+	t.Parallel()
+	client := newFakeClient()
+	b := &Backend{Client: client, Bucket: "bucket"}
+	assert.Nil(t, b.WriteFile("/data/2023/b.txt", []byte("b"), 0644))
+	assert.Nil(t, b.WriteFile("/data/2023/league/a.txt", []byte("a"), 0644))
+	assert.Nil(t, b.WriteFile("/data/2023/roster/r.txt", []byte("r"), 0644))
+	client.objects["data/2023/"] = nil // directory marker
+	entries, err := b.ReadDir("/data/2023/")
+	assert.Nil(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, fmt.Sprintf("%s:%v", entry.Name(), entry.IsDir()))
+	}
+	assert.Equal(t, []string{"b.txt:false", "league:true", "roster:true"}, names)
+	info, err := b.Stat("data/2023/league")
+	assert.Nil(t, err)
+	assert.True(t, info.IsDir())
+	info, err = b.Stat("data/2023/b.txt")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), info.Size())
+	assert.Equal(t, "data/2023/b.txt", b.key("/data/2023/b.txt"))
+}
+
+// TestConformance_MinIO runs the conformance tests against a real S3-compatible store, such as
+// a local MinIO started with:
+//
+//	docker run -p 9000:9000 minio/minio server /data
+//
+// It is skipped unless VERSIONFS_S3_ENDPOINT is set. The bucket (VERSIONFS_S3_BUCKET, "versionfs"
+// by default) must exist, credentials are read from VERSIONFS_S3_ACCESS_KEY and VERSIONFS_S3_SECRET_KEY.
+func TestConformance_MinIO(t *testing.T) {
+	endpoint := os.Getenv("VERSIONFS_S3_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("VERSIONFS_S3_ENDPOINT not set")
+	}
+	bucket := os.Getenv("VERSIONFS_S3_BUCKET")
+	if bucket == "" {
+		bucket = "versionfs"
+	}
+	client := s3.New(s3.Options{
+		BaseEndpoint: aws.String(endpoint),
+		Region:       "us-east-1",
+		UsePathStyle: true,
+		Credentials:  credentials.NewStaticCredentialsProvider(os.Getenv("VERSIONFS_S3_ACCESS_KEY"), os.Getenv("VERSIONFS_S3_SECRET_KEY"), ""),
+	})
+	versionfstest.TestConformance(t, func(t *testing.T) *versionfs.VersionFS {
+		// every subtest gets its own empty root
+		return New(client, bucket, fmt.Sprintf("conformance/%d", time.Now().UnixNano()))
+	})
+}
@@ -0,0 +1,105 @@
+package versionfs
+
+import (
+	"fmt"
+	path_ "path"
+	"strings"
+)
+
+// PathScheme maps the versions of files to their paths in the storage and back, for trees
+// shared with tools expecting another layout than the default dir/name.ext.timestamp.
+type PathScheme interface {
+	// Format returns the path of the version ts of file, relative to the root. The directory
+	// of the path must not depend on ts, since the versions of a file are listed from it.
+	Format(file File, ts Timestamp) string
+	// Parse returns the timestamp of basename, an entry of the directory of the paths
+	// returned by Format, or an error if it isn't the name of a version of file.
+	Parse(basename string, file File) (Timestamp, error)
+}
+
+// FlatScheme is the default PathScheme, dir/name.ext.timestamp, the layout of Path.
+// Unlike the default of a VersionFS, its extensions are always compared case-sensitively.
+type FlatScheme struct{}
+
+// Format implements PathScheme with Path.
+func (FlatScheme) Format(file File, ts Timestamp) string {
+	return Path(file, ts)
+}
+
+// Parse implements PathScheme, matching name.ext.timestamp like Detect.
+func (FlatScheme) Parse(basename string, file File) (Timestamp, error) {
+	return CompileMatcher(file).MatchError(basename)
+}
+
+// DirScheme is a PathScheme storing the versions of a file in a directory named after it,
+// with the timestamp as filename: dir/name/timestamp.ext.
+//
+// Example:
+//
+//	vfs.Scheme = versionfs.DirScheme{}
+//	ts, err := vfs.Write(file, data) // 2023/league/league/20231019140523.txt
+type DirScheme struct{}
+
+// Format implements PathScheme.
+func (DirScheme) Format(file File, ts Timestamp) string {
+	return path_.Join(slashed(file.Dir()), file.Name(), ts.String()+"."+file.Ext())
+}
+
+// Parse implements PathScheme, matching timestamp.ext, where ext may be multi-part.
+func (DirScheme) Parse(basename string, file File) (Timestamp, error) {
+	tsPart, ok := strings.CutSuffix(basename, "."+file.Ext())
+	if !ok {
+		return Timestamp{}, fmt.Errorf("filename %q doesn't have extension %q", basename, file.Ext())
+	}
+	ts, err := NewTimestamp(tsPart)
+	if err != nil {
+		return Timestamp{}, fmt.Errorf("filename %q has invalid timestamp: %w", basename, err)
+	}
+	return ts, nil
+}
+
+// PrefixScheme is a PathScheme putting the timestamp before the name, timestamp.name.ext,
+// for systems expecting that order. Like FlatScheme, its extensions are compared
+// case-sensitively.
+//
+// Example:
+//
+//	vfs.Scheme = versionfs.PrefixScheme{}
+//	ts, err := vfs.Write(file, data) // 2023/league/20231019140523.league.txt
+type PrefixScheme struct{}
+
+// Format implements PathScheme.
+func (PrefixScheme) Format(file File, ts Timestamp) string {
+	return path_.Join(slashed(file.Dir()), ts.String()+"."+file.Name()+"."+file.Ext())
+}
+
+// Parse implements PathScheme, matching timestamp.name.ext, where ext may be multi-part.
+func (PrefixScheme) Parse(basename string, file File) (Timestamp, error) {
+	tsPart, rest, ok := strings.Cut(basename, ".")
+	if !ok || rest != file.Name()+"."+file.Ext() {
+		return Timestamp{}, fmt.Errorf("filename %q doesn't match %q", basename, "timestamp."+file.Name()+"."+file.Ext())
+	}
+	ts, err := NewTimestamp(tsPart)
+	if err != nil {
+		return Timestamp{}, fmt.Errorf("filename %q has invalid timestamp: %w", basename, err)
+	}
+	return ts, nil
+}
+
+// formatPath returns the path of the version ts of file with the Scheme of the instance,
+// relative to the root.
+func (v *VersionFS) formatPath(file File, ts Timestamp) string {
+	if v.Scheme == nil {
+		return Path(file, ts)
+	}
+	return v.Scheme.Format(file, ts)
+}
+
+// versionDir returns the directory listed for the versions of file in dir, relative to
+// the root, with the Scheme of the instance.
+func (v *VersionFS) versionDir(dir string, file File) string {
+	if v.Scheme == nil {
+		return dir
+	}
+	return path_.Dir(v.Scheme.Format(Alias{Name: file.Name(), Ext: file.Ext()}.file(dir), Timestamp{}))
+}
@@ -0,0 +1,143 @@
+package versionfs
+
+import (
+	"github.com/stretchr/testify/assert"
+	"path"
+	"testing"
+)
+
+// testSchemes are the schemes the scheme tests are run against, nil being the default.
+var testSchemes = map[string]PathScheme{
+	"default": nil,
+	"flat":    FlatScheme{},
+	"dir":     DirScheme{},
+	"prefix":  PrefixScheme{},
+}
+
+// putSchemeVersion stores a version with a known timestamp at the path of the scheme of vfs.
+func putSchemeVersion(t *testing.T, vfs *VersionFS, file File, ts string, data string) Timestamp {
+	t.Helper()
+	timestamp, err := NewTimestamp(ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	putRaw(t, vfs, vfs.formatPath(file, timestamp), data)
+	return timestamp
+}
+
+func TestPathScheme(t *testing.T) {
+	t.Parallel()
+	for name, scheme := range testSchemes {
+		scheme := scheme
+		newVFS := func() *VersionFS {
+			vfs := NewMemory()
+			vfs.Scheme = scheme
+			return vfs
+		}
+
+		t.Run(name+"/WriteRead", func(t *testing.T) {
+			vfs := newVFS()
+			file := fileThemes{}
+			ts, err := vfs.Write(file, []byte("hello world"))
+			assert.Nil(t, err)
+			data, err := vfs.Read(file, ts)
+			assert.Nil(t, err)
+			assert.Equal(t, "hello world", string(data))
+			data, err = vfs.ReadRange(file, ts, 6, -1)
+			assert.Nil(t, err)
+			assert.Equal(t, "world", string(data))
+			exists, err := vfs.PathExists(vfs.formatPath(file, ts))
+			assert.Nil(t, err)
+			assert.True(t, exists)
+		})
+
+		t.Run(name+"/VersionsFind", func(t *testing.T) {
+			vfs := newVFS()
+			file := fileLeague{season: 2023}
+			putSchemeVersion(t, vfs, file, "20211125011946", "1")
+			putSchemeVersion(t, vfs, file, "20211218030527", "3")
+			putSchemeVersion(t, vfs, file, "20211125011947", "2")
+			putSchemeVersion(t, vfs, aliasFile{dir: file.Dir(), alias: Alias{Name: "other", Ext: "txt"}}, "20211125011949", "other name")
+			expected := []string{"20211218030527", "20211125011947", "20211125011946"}
+
+			versions, err := vfs.Versions(file)
+			assert.Nil(t, err)
+			assert.Equal(t, expected, timestampStrings(versions))
+			putSchemeVersion(t, vfs, aliasFile{dir: file.Dir(), alias: Alias{Name: "league", Ext: "json"}}, "20211125011948", "wrong extension")
+			found, err := vfs.Find(file.Dir(), file)
+			assert.Nil(t, err)
+			assert.Equal(t, expected, timestampStrings(found))
+			last, err := vfs.LastVersion(file)
+			assert.Nil(t, err)
+			assert.Equal(t, "20211218030527", last.String())
+		})
+
+		t.Run(name+"/MissingDir", func(t *testing.T) {
+			vfs := newVFS()
+			file := fileLeague{season: 2023}
+			versions, err := vfs.Versions(file)
+			assert.Nil(t, err)
+			assert.Empty(t, versions)
+			_, err = vfs.LastVersion(file)
+			assert.Equal(t, ErrNoVersions, err)
+		})
+
+		t.Run(name+"/Remove", func(t *testing.T) {
+			vfs := newVFS()
+			file := fileLeague{season: 2023}
+			ts := putSchemeVersion(t, vfs, file, "20211125011947", "data")
+			assert.Nil(t, vfs.Remove(file, ts))
+			_, err := vfs.Read(file, ts)
+			assert.ErrorIs(t, err, ErrVersionNotFound)
+			assert.ErrorIs(t, vfs.Remove(file, ts), ErrVersionNotFound)
+		})
+
+		t.Run(name+"/Detect", func(t *testing.T) {
+			vfs := newVFS()
+			file := fileThemes{}
+			ts, _ := NewTimestamp("20211125011947")
+			detected, err := vfs.Detect(vfs.formatPath(file, ts), file)
+			assert.Nil(t, err)
+			assert.Equal(t, "20211125011947", detected.String())
+			_, err = vfs.Detect(vfs.formatPath(aliasFile{dir: file.Dir(), alias: Alias{Name: "themes", Ext: "csv"}}, ts), file)
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestDirScheme(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	vfs.Scheme = DirScheme{}
+	ts, err := vfs.Write(fileThemes{}, []byte("data"))
+	assert.Nil(t, err)
+	exists, err := vfs.PathExists(path.Join("catalog/themes", ts.String()+".csv.gz"))
+	assert.Nil(t, err)
+	assert.True(t, exists)
+
+	_, err = DirScheme{}.Parse("20231019140523.csv", fileThemes{})
+	assert.NotNil(t, err)
+	_, err = DirScheme{}.Parse("yesterday.csv.gz", fileThemes{})
+	assert.NotNil(t, err)
+}
+
+func TestPrefixScheme(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	vfs.Scheme = PrefixScheme{}
+	ts, err := vfs.Write(fileThemes{}, []byte("data"))
+	assert.Nil(t, err)
+	exists, err := vfs.PathExists(path.Join("catalog", ts.String()+".themes.csv.gz"))
+	assert.Nil(t, err)
+	assert.True(t, exists)
+
+	for _, basename := range []string{
+		"20231019140523.themes.csv",
+		"20231019140523.themes.csv.gz.bak",
+		"yesterday.themes.csv.gz",
+		"themes.csv.gz.20231019140523",
+	} {
+		_, err = PrefixScheme{}.Parse(basename, fileThemes{})
+		assert.NotNil(t, err, basename)
+	}
+}
@@ -0,0 +1,70 @@
+package versionfs
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fileBackslash is a file whose directory was built with backslashes, as filepath.Join does on Windows.
+type fileBackslash struct{}
+
+func (fileBackslash) Dir() string  { return `2023\league` }
+func (fileBackslash) Name() string { return "league" }
+func (fileBackslash) Ext() string  { return "txt" }
+
+func TestVersionFS_BackslashSeparators(t *testing.T) {
+	t.Parallel()
+	for name, vfs := range map[string]*VersionFS{"memory": NewMemory(), "os": New(t.TempDir())} {
+		ts, err := vfs.Write(fileBackslash{}, []byte("data"))
+		assert.Nil(t, err, name)
+		assert.Equal(t, "2023/league/league.txt."+ts.String(), Path(fileBackslash{}, ts), name)
+
+		// the same versions are found with either separator
+		file := fileLeague{season: 2023}
+		versions, err := vfs.Versions(file)
+		assert.Nil(t, err, name)
+		assert.Equal(t, []string{ts.String()}, timestampStrings(versions), name)
+		versions, err = vfs.Versions(fileBackslash{})
+		assert.Nil(t, err, name)
+		assert.Equal(t, []string{ts.String()}, timestampStrings(versions), name)
+		found, err := vfs.Find(`2023\league`, file)
+		assert.Nil(t, err, name)
+		assert.Equal(t, []string{ts.String()}, timestampStrings(found), name)
+		exists, err := vfs.PathExists(`2023\league`)
+		assert.Nil(t, err, name)
+		assert.True(t, exists, name)
+		data, err := vfs.Read(fileBackslash{}, ts)
+		assert.Nil(t, err, name)
+		assert.Equal(t, "data", string(data), name)
+
+		detected, err := vfs.Detect(`data\2023\league\league.txt.`+ts.String(), file)
+		assert.Nil(t, err, name)
+		assert.Equal(t, ts.String(), detected.String(), name)
+		matched, _, err := vfs.DetectDir(`2023\league`, file)
+		assert.Nil(t, err, name)
+		assert.Equal(t, []string{ts.String()}, timestampStrings(matched), name)
+		assert.Nil(t, vfs.MkdirAll(`2024\league`, 0755), name)
+		exists, err = vfs.PathExists("2024/league")
+		assert.Nil(t, err, name)
+		assert.True(t, exists, name)
+	}
+}
+
+func TestOSBackend_NativeSeparators(t *testing.T) {
+	t.Parallel()
+	vfs := New(t.TempDir())
+	ts, err := vfs.Write(fileLeague{season: 2023}, []byte("data"))
+	assert.Nil(t, err)
+	data, err := os.ReadFile(filepath.Join(vfs.RootPath, "2023", "league", "league.txt."+ts.String()))
+	assert.Nil(t, err)
+	assert.Equal(t, "data", string(data))
+}
+
+func TestVersionFS_BackslashTraversal(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	_, err := vfs.PathExists(`2023\..\..\etc`)
+	assert.ErrorIs(t, err, ErrOutsideRoot)
+}
@@ -0,0 +1,192 @@
+package versionfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	path_ "path"
+	"strconv"
+)
+
+// ErrShardedScheme is returned by the operations of an instance setting Sharded with a Scheme
+// other than FlatScheme: the sharded layout only supports the default layout, and the versions
+// written with both couldn't be listed.
+var ErrShardedScheme = errors.New("sharded layout with a scheme other than FlatScheme")
+
+// checkLayout returns ErrShardedScheme if Sharded is set with a Scheme other than FlatScheme.
+func (v *VersionFS) checkLayout() error {
+	if !v.Sharded || v.Scheme == nil {
+		return nil
+	}
+	if _, ok := v.Scheme.(FlatScheme); ok {
+		return nil
+	}
+	return ErrShardedScheme
+}
+
+// ShardPath returns the path of a version in the sharded layout, relative to the root:
+// dir/name.ext/YYYY/MM/name.ext.timestamp, sharded by the year and month of its timestamp.
+//
+// Example:
+//
+//	ShardPath(file, ts) // "2023/league/league.txt/2023/10/league.txt.20231019140523"
+func ShardPath(file File, ts Timestamp) string {
+	return path_.Join(shardDir(file), ts.time.Format("2006/01"), path_.Base(Path(file, ts)))
+}
+
+// shardDir returns the directory of the shards of a file, relative to the root.
+func shardDir(file File) string {
+	return path_.Join(slashed(file.Dir()), file.Name()+"."+file.Ext())
+}
+
+// versionPath returns the path where Write stores the version ts of file, relative to the root.
+func (v *VersionFS) versionPath(file File, ts Timestamp) string {
+	if v.Sharded {
+		return ShardPath(file, ts)
+	}
+	return v.formatPath(file, ts)
+}
+
+// versionPaths returns the path where Write stores the version ts of file, like versionPath,
+// and the path joined to the root, in a single allocation with the default layout.
+func (v *VersionFS) versionPaths(file File, ts Timestamp) (name, full string) {
+	if v.Sharded || v.Scheme != nil {
+		name = v.versionPath(file, ts)
+		return name, path_.Join(v.RootPath, name)
+	}
+	return joinPath(v.RootPath, file, ts)
+}
+
+// mkShardDir creates the shard directory of the version ts of file. When CreateDirs is
+// disabled, the directory of the file must exist.
+func (v *VersionFS) mkShardDir(file File, ts Timestamp) error {
+	if !v.CreateDirs {
+		_, err := v.backend().Stat(path_.Join(v.RootPath, slashed(file.Dir())))
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("directory %s doesn't exist and CreateDirs is disabled: %w", file.Dir(), err)
+		} else if err != nil {
+			return err
+		}
+	}
+	return v.MkdirAll(path_.Dir(ShardPath(file, ts)), 0755)
+}
+
+// appendShards appends the versions of file stored in the shards under dir to dst, when
+// Sharded is set, skipping the ones already in dst.
+func (v *VersionFS) appendShards(ctx context.Context, dst []Timestamp, dir string, file File) ([]Timestamp, error) {
+	if !v.Sharded {
+		return dst, nil
+	}
+	seen := make(map[string]bool, len(dst))
+	for _, ts := range dst {
+		seen[ts.String()] = true
+	}
+	root := path_.Join(slashed(dir), file.Name()+"."+file.Ext())
+	years, err := v.shardEntries(root, 4)
+	if err != nil {
+		return nil, err
+	}
+	for _, year := range years {
+		months, err := v.shardEntries(path_.Join(root, year), 2)
+		if err != nil {
+			return nil, err
+		}
+		for _, month := range months {
+			shard := path_.Join(root, year, month)
+			entries, err := v.backend().ReadDir(path_.Join(v.RootPath, shard))
+			if err != nil {
+				return nil, err
+			}
+			for _, entry := range entries {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+				if entry.IsDir() || v.hidden(entry.Name()) {
+					continue
+				}
+				if ts, ok := v.matchVersion(shard, entry.Name(), file); ok && !seen[ts.String()] && !v.empty(entry) {
+					dst = append(dst, ts)
+					seen[ts.String()] = true
+				}
+			}
+		}
+	}
+	return dst, nil
+}
+
+// shardEntries returns the names of the subdirectories of dir, relative to the root, made of
+// digits digits, such as the years and months of the shards, or none if dir doesn't exist.
+func (v *VersionFS) shardEntries(dir string, digits int) ([]string, error) {
+	entries, err := v.backend().ReadDir(path_.Join(v.RootPath, dir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err == nil && entry.IsDir() && len(entry.Name()) == digits {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// Reshard moves the versions of file stored flat in its directory to the sharded layout,
+// with their checksum sidecars, and returns the number of versions moved. Set Sharded
+// before, so that the versions are found in their shards once moved; since the versions
+// are found in both layouts, the instance keeps working while they are moved, and an
+// interrupted Reshard is resumed by running it again. If a version already exists in its
+// shard, Reshard stops with an error wrapping fs.ErrExist.
+//
+// Example:
+//
+//	vfs.Sharded = true
+//	moved, err := vfs.Reshard(file)
+func (v *VersionFS) Reshard(file File) (int, error) {
+	v.logger().Debugf("Resharding file %s/%s.%s", file.Dir(), file.Name(), file.Ext())
+	dir := slashed(file.Dir())
+	if err := v.confine("reshard", dir); err != nil {
+		return 0, err
+	}
+	entries, err := v.backend().ReadDir(path_.Join(v.RootPath, dir))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	unlock, err := v.lockDir(file.Dir())
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+	moved := 0
+	for _, entry := range entries {
+		if entry.IsDir() || v.hidden(entry.Name()) {
+			continue
+		}
+		ts, ok := v.matchVersion(dir, entry.Name(), file)
+		if !ok {
+			continue
+		}
+		shard := ShardPath(file, ts)
+		if err := v.MkdirAll(path_.Dir(shard), 0755); err != nil {
+			return moved, err
+		}
+		if err := v.renameAt(path_.Join(dir, entry.Name()), shard); err != nil {
+			return moved, err
+		}
+		sidecar := path_.Join(dir, entry.Name()+ChecksumSuffix)
+		if _, err := v.backend().Stat(path_.Join(v.RootPath, sidecar)); err == nil {
+			if err := v.renameAt(sidecar, shard+ChecksumSuffix); err != nil {
+				return moved, err
+			}
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return moved, err
+		}
+		moved++
+	}
+	return moved, nil
+}
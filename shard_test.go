@@ -0,0 +1,129 @@
+package versionfs
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestShardPath(t *testing.T) {
+	t.Parallel()
+	ts, _ := NewTimestamp("20231019140523")
+	assert.Equal(t, "2023/league/league.txt/2023/10/league.txt.20231019140523", ShardPath(fileLeague{season: 2023}, ts))
+	assert.Equal(t, "catalog/themes.csv.gz/2023/10/themes.csv.gz.20231019140523", ShardPath(fileThemes{}, ts))
+}
+
+func TestVersionFS_Sharded(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	vfs.Sharded = true
+	file := fileLeague{season: 2023}
+	ts, err := vfs.Write(file, []byte("data"))
+	assert.Nil(t, err)
+	exists, err := vfs.PathExists(ShardPath(file, ts))
+	assert.Nil(t, err)
+	assert.True(t, exists)
+	exists, err = vfs.PathExists(Path(file, ts))
+	assert.Nil(t, err)
+	assert.False(t, exists)
+
+	data, err := vfs.Read(file, ts)
+	assert.Nil(t, err)
+	assert.Equal(t, "data", string(data))
+	versions, err := vfs.Versions(file)
+	assert.Nil(t, err)
+	assert.Equal(t, timestampStrings([]Timestamp{ts}), timestampStrings(versions))
+	found, err := vfs.Find(file.Dir(), file)
+	assert.Nil(t, err)
+	assert.Equal(t, timestampStrings([]Timestamp{ts}), timestampStrings(found))
+
+	assert.Nil(t, vfs.Remove(file, ts))
+	versions, err = vfs.Versions(file)
+	assert.Nil(t, err)
+	assert.Empty(t, versions)
+}
+
+// Combining Sharded with a Scheme other than FlatScheme fails every operation, instead of
+// writing versions that can't be listed.
+func TestVersionFS_Sharded_Scheme(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	vfs.Sharded = true
+	vfs.Scheme = DirScheme{}
+	file := fileLeague{season: 2023}
+	ts, _ := NewTimestamp("20231019140523")
+	_, err := vfs.Write(file, []byte("data"))
+	assert.ErrorIs(t, err, ErrShardedScheme)
+	_, err = vfs.Read(file, ts)
+	assert.ErrorIs(t, err, ErrShardedScheme)
+	_, err = vfs.Versions(file)
+	assert.ErrorIs(t, err, ErrShardedScheme)
+	_, err = vfs.LastVersion(file)
+	assert.ErrorIs(t, err, ErrShardedScheme)
+	_, err = vfs.Find(file.Dir(), file)
+	assert.ErrorIs(t, err, ErrShardedScheme)
+	assert.ErrorIs(t, vfs.Remove(file, ts), ErrShardedScheme)
+	_, err = vfs.Reshard(file)
+	assert.ErrorIs(t, err, ErrShardedScheme)
+	entries, err := vfs.Backend.ReadDir("")
+	assert.Nil(t, err)
+	assert.Empty(t, entries)
+
+	// FlatScheme is the default layout
+	vfs.Scheme = FlatScheme{}
+	ts, err = vfs.Write(file, []byte("data"))
+	assert.Nil(t, err)
+	versions, err := vfs.Versions(file)
+	assert.Nil(t, err)
+	assert.Equal(t, timestampStrings([]Timestamp{ts}), timestampStrings(versions))
+}
+
+func TestVersionFS_Reshard(t *testing.T) {
+	t.Parallel()
+	vfs := New(t.TempDir())
+	vfs.VerifyOnRead = true
+	vfs.RequireChecksum = true
+	file := fileLeague{season: 2023}
+	first := putVersion(t, vfs, file, "20230918140523", "first")
+	second := putVersion(t, vfs, file, "20231019140523", "second")
+	assert.Nil(t, vfs.WriteChecksum(file, first))
+	assert.Nil(t, vfs.WriteChecksum(file, second))
+	unchecked := putVersion(t, vfs, file, "20231019140524", "unchecked")
+	putRaw(t, vfs, "2023/league/standings.txt.20231019140523", "other file")
+
+	// the flat and sharded versions are found together while migrating
+	vfs.Sharded = true
+	third, err := vfs.Write(file, []byte("third"))
+	assert.Nil(t, err)
+	assert.Nil(t, vfs.WriteChecksum(file, third))
+	versions, err := vfs.Versions(file)
+	assert.Nil(t, err)
+	assert.Equal(t, timestampStrings([]Timestamp{third, unchecked, second, first}), timestampStrings(versions))
+
+	moved, err := vfs.Reshard(file)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, moved)
+	for _, ts := range []Timestamp{first, second, unchecked} {
+		exists, err := vfs.PathExists(ShardPath(file, ts) + ChecksumSuffix)
+		assert.Nil(t, err)
+		assert.Equal(t, ts.String() != unchecked.String(), exists, ts.String())
+	}
+	versions, err = vfs.Versions(file)
+	assert.Nil(t, err)
+	assert.Equal(t, timestampStrings([]Timestamp{third, unchecked, second, first}), timestampStrings(versions))
+	data, err := vfs.Read(file, first)
+	assert.Nil(t, err)
+	assert.Equal(t, "first", string(data))
+	entries, err := os.ReadDir(path.Join(vfs.RootPath, path.Dir(ShardPath(file, first))))
+	assert.Nil(t, err)
+	assert.Len(t, entries, 2)
+
+	// the other files stay flat, and nothing more is moved by the next runs
+	exists, err := vfs.PathExists("2023/league/standings.txt.20231019140523")
+	assert.Nil(t, err)
+	assert.True(t, exists)
+	moved, err = vfs.Reshard(file)
+	assert.Nil(t, err)
+	assert.Zero(t, moved)
+}
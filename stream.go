@@ -0,0 +1,297 @@
+package versionfs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	path_ "path"
+)
+
+// VersionRef refers to a version of a file, it streams its content as an io.WriterTo.
+type VersionRef struct {
+	vfs  *VersionFS
+	file File
+	ts   Timestamp
+}
+
+// Version returns a reference to a version of a file, to stream it to a writer without
+// loading it in memory, for example to an http.ResponseWriter. The version isn't opened
+// until WriteTo is called.
+//
+// Example:
+//
+//	n, err := vfs.Version(file, ts).WriteTo(w)
+func (v *VersionFS) Version(file File, ts Timestamp) VersionRef {
+	return VersionRef{vfs: v, file: file, ts: ts}
+}
+
+// WriteTo implements io.WriterTo, it copies the content of the version to w and returns
+// the number of bytes written. Returns an error wrapping ErrVersionNotFound if the file
+// doesn't exist, or the error of w.
+func (r VersionRef) WriteTo(w io.Writer) (int64, error) {
+	info := newOpInfo(OpWriteTo, r.file)
+	info.Timestamp = r.ts
+	end := r.vfs.instrument(context.Background(), &info)
+	n, err := r.writeTo(w)
+	info.Bytes = n
+	end(err)
+	return n, err
+}
+
+// writeTo implements WriteTo.
+func (r VersionRef) writeTo(w io.Writer) (int64, error) {
+	v, file, ts := r.vfs, r.file, r.ts
+	v.logger().Debugf("Streaming file %s/%s.%s.%s", file.Dir(), file.Name(), file.Ext(), ts)
+	name := v.resolvePath(file, ts)
+	if err := v.confine("read", name); err != nil {
+		return 0, err
+	}
+	if err := v.restrict("read", name); err != nil {
+		return 0, err
+	}
+	f, err := v.backend().Open(path_.Join(v.RootPath, name))
+	if err != nil {
+		return 0, versionNotFound(err)
+	}
+	defer func() { _ = f.Close() }()
+	return io.Copy(w, f)
+}
+
+// OpenConcat returns a reader chaining the contents of every version of a file, oldest
+// first, to read a log captured as many small versions as one continuous stream. The
+// versions are opened one at a time, when the reader reaches them, and closing the reader
+// closes the version being read. A version removed after OpenConcat is called fails the
+// read with an error wrapping ErrVersionNotFound. The contents are chained as stored: the
+// versions of a gzip-compressed file type form a multi-member gzip stream, which
+// gzip.Reader decompresses as a whole.
+//
+// Example:
+//
+//	r, err := vfs.OpenConcat(file)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer r.Close()
+//	scanner := bufio.NewScanner(r)
+func (v *VersionFS) OpenConcat(file File) (io.ReadCloser, error) {
+	versions, err := v.localVersions(file)
+	if err != nil {
+		return nil, err
+	}
+	c := &concatReader{}
+	readers := make([]io.Reader, len(versions))
+	for i := range versions {
+		// Versions lists the newest first
+		name := path_.Join(v.RootPath, v.resolvePath(file, versions[len(versions)-1-i]))
+		readers[i] = &lazyReader{backend: v.backend(), name: name, concat: c}
+	}
+	c.Reader = io.MultiReader(readers...)
+	return c, nil
+}
+
+// concatReader is the reader returned by OpenConcat.
+type concatReader struct {
+	io.Reader
+	// current is the version being read, nil between versions.
+	current fs.File
+}
+
+// Close closes the version being read.
+func (c *concatReader) Close() error {
+	if c.current == nil {
+		return nil
+	}
+	err := c.current.Close()
+	c.current = nil
+	return err
+}
+
+// lazyReader reads a version, opened by the first Read and closed at its end.
+type lazyReader struct {
+	backend Backend
+	name    string
+	concat  *concatReader
+}
+
+func (r *lazyReader) Read(p []byte) (int, error) {
+	if r.concat.current == nil {
+		f, err := r.backend.Open(r.name)
+		if err != nil {
+			return 0, versionNotFound(err)
+		}
+		r.concat.current = f
+	}
+	n, err := r.concat.current.Read(p)
+	if err == io.EOF {
+		if closeErr := r.concat.Close(); closeErr != nil {
+			return n, closeErr
+		}
+	}
+	return n, err
+}
+
+// PublishTo copies the version ts of src to a new version of dst, for example to publish a
+// staged version to production, and returns the timestamp of the new version. src and dst
+// can be of different file types. The content is streamed to a temporary file in the directory
+// of dst, then renamed, so that readers never see the new version half-written, and the
+// temporary file is removed on failure. Backends not implementing RenameBackend store the
+// content with WriteFile instead. The new version is stored in the layout of Write, and
+// traced and counted as an OpWrite. Returns an error wrapping ErrVersionNotFound if the
+// source version doesn't exist.
+//
+// Example:
+//
+//	ts, err := vfs.PublishTo(stagedFile, stagedTs, productionFile)
+func (v *VersionFS) PublishTo(src File, ts Timestamp, dst File) (Timestamp, error) {
+	info := newOpInfo(OpWrite, dst)
+	end := v.instrument(context.Background(), &info)
+	newTs, err := v.publishTo(src, ts, dst, &info)
+	info.Timestamp = newTs
+	end(err)
+	return newTs, err
+}
+
+// publishTo implements PublishTo, setting the bytes of info to the size of the version.
+func (v *VersionFS) publishTo(src File, ts Timestamp, dst File, info *OpInfo) (Timestamp, error) {
+	v.logger().Debugf("Publishing file %s/%s.%s.%s to %s/%s.%s.?", src.Dir(), src.Name(), src.Ext(), ts, dst.Dir(), dst.Name(), dst.Ext())
+	newTs := v.newTimestamp(dst)
+	if err := v.confine("publish", v.versionPath(dst, newTs)); err != nil {
+		return Timestamp{}, err
+	}
+	if err := v.checkWritable("publish", v.versionPath(dst, newTs)); err != nil {
+		return Timestamp{}, err
+	}
+	srcName := v.resolvePath(src, ts)
+	if err := v.confine("publish", srcName); err != nil {
+		return Timestamp{}, err
+	}
+	if err := v.restrict("publish", srcName); err != nil {
+		return Timestamp{}, err
+	}
+	srcPath := path_.Join(v.RootPath, srcName)
+	stat, err := v.backend().Stat(srcPath)
+	if err != nil {
+		return Timestamp{}, versionNotFound(err)
+	}
+	info.Bytes = stat.Size()
+	if v.DryRun {
+		v.plan.record(PlannedOp{Op: OpWrite, Path: v.versionPath(dst, newTs), Size: stat.Size()})
+		return newTs, nil
+	}
+	if v.CreateDirs {
+		if err := v.MkdirAll(dst.Dir(), 0755); err != nil {
+			return Timestamp{}, err
+		}
+		if v.Scheme != nil {
+			if err := v.MkdirAll(path_.Dir(v.formatPath(dst, newTs)), 0755); err != nil {
+				return Timestamp{}, err
+			}
+		}
+	}
+	newTs, unlock, err := v.reserve(dst, newTs)
+	if err != nil {
+		return Timestamp{}, err
+	}
+	defer unlock()
+	if v.Sharded {
+		if err := v.mkShardDir(dst, newTs); err != nil {
+			return Timestamp{}, err
+		}
+	}
+	name, dstPath := v.versionPaths(dst, newTs)
+	if err := v.restrict("publish", name); err != nil {
+		return Timestamp{}, err
+	}
+	in, err := v.backend().Open(srcPath)
+	if err != nil {
+		return Timestamp{}, versionNotFound(err)
+	}
+	defer func() { _ = in.Close() }()
+	done, err := v.claim(name, stat.Size())
+	if err != nil {
+		return Timestamp{}, err
+	}
+	err = v.stage(in, dstPath)
+	done(err)
+	v.invalidate(name)
+	if err != nil {
+		return Timestamp{}, err
+	}
+	return newTs, nil
+}
+
+// writeAt writes a version of size bytes read from r at name, relative to the root,
+// keeping the timestamp of its name. It is staged, so that readers never see it half-written.
+func (v *VersionFS) writeAt(name string, size int64, r io.Reader) error {
+	if err := v.confine("write", name); err != nil {
+		return err
+	}
+	if err := v.checkWritable("write", name); err != nil {
+		return err
+	}
+	if v.CreateDirs {
+		if err := v.MkdirAll(path_.Dir(name), 0755); err != nil {
+			return err
+		}
+	}
+	if v.DryRun {
+		v.plan.record(PlannedOp{Op: OpWrite, Path: name, Size: size})
+		return nil
+	}
+	if err := v.restrict("write", name); err != nil {
+		return err
+	}
+	done, err := v.claim(name, size)
+	if err != nil {
+		return err
+	}
+	err = v.stage(r, path_.Join(v.RootPath, name))
+	done(err)
+	v.invalidate(name)
+	return err
+}
+
+// stage streams r to a temporary file next to dstPath, then renames it to dstPath, so that
+// readers never see dstPath half-written. Backends not implementing RenameBackend store
+// the content with WriteFile.
+func (v *VersionFS) stage(r io.Reader, dstPath string) (err error) {
+	rb, ok := v.Backend.(RenameBackend)
+	if !ok {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		return v.backend().WriteFile(dstPath, data, 0644)
+	}
+	tmp := path_.Join(path_.Dir(dstPath), fmt.Sprintf(".%s.%d.tmp", path_.Base(dstPath), os.Getpid()))
+	out, err := callBackend(v, "open", tmp, func() (io.WriteCloser, error) {
+		return rb.Create(tmp, 0644)
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = v.backend().Remove(tmp)
+		}
+	}()
+	if _, err = io.Copy(out, r); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if syncer, ok := out.(interface{ Sync() error }); ok && v.SyncOnWrite {
+		if err = syncer.Sync(); err != nil {
+			_ = out.Close()
+			return err
+		}
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+	_, err = callBackend(v, "rename", tmp, func() (struct{}, error) {
+		return struct{}{}, rb.Rename(tmp, dstPath)
+	})
+	return err
+}
@@ -0,0 +1,180 @@
+package versionfs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestVersionRef_WriteTo(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	file := fileLeague{season: 2023}
+	ts := putVersion(t, vfs, file, "20231019140523", "hello world")
+	var buf bytes.Buffer
+	n, err := vfs.Version(file, ts).WriteTo(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(11), n)
+	assert.Equal(t, "hello world", buf.String())
+
+	var _ io.WriterTo = vfs.Version(file, ts)
+}
+
+func TestVersionRef_WriteTo_NotFound(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	ts, _ := NewTimestamp("20231019140523")
+	var buf bytes.Buffer
+	n, err := vfs.Version(fileLeague{season: 2023}, ts).WriteTo(&buf)
+	assert.Zero(t, n)
+	assert.ErrorIs(t, err, ErrVersionNotFound)
+}
+
+func TestVersionFS_PublishTo(t *testing.T) {
+	t.Parallel()
+	dir, vfs := newTmpVersionFS(t)
+	defer func() { _ = os.RemoveAll(dir) }()
+	src := fileLeague{season: 2023}
+	dst := fileLeague{season: 2024}
+	ts := putVersion(t, vfs, src, "20231019140523", "staged")
+	published, err := vfs.PublishTo(src, ts, dst)
+	assert.Nil(t, err)
+	data, err := vfs.Read(dst, published)
+	assert.Nil(t, err)
+	assert.Equal(t, "staged", string(data))
+	entries, err := os.ReadDir(path.Join(dir, dst.Dir()))
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1)
+}
+
+// A published version is stored in the layout of the instance, and traced like a write.
+func TestVersionFS_PublishTo_Layouts(t *testing.T) {
+	t.Parallel()
+	for name, configure := range map[string]func(vfs *VersionFS){
+		"DirScheme": func(vfs *VersionFS) { vfs.Scheme = DirScheme{} },
+		"Sharded":   func(vfs *VersionFS) { vfs.Sharded = true },
+	} {
+		t.Run(name, func(t *testing.T) {
+			vfs := New(t.TempDir())
+			configure(vfs)
+			tracer := &recordingTracer{}
+			vfs.Tracer = tracer
+			src := fileLeague{season: 2023}
+			dst := fileLeague{season: 2024}
+			ts, err := vfs.Write(src, []byte("staged"))
+			assert.Nil(t, err)
+			published, err := vfs.PublishTo(src, ts, dst)
+			assert.Nil(t, err)
+			last, err := vfs.LastVersion(dst)
+			assert.Nil(t, err)
+			assert.Equal(t, published.String(), last.String())
+			data, err := vfs.Read(dst, last)
+			assert.Nil(t, err)
+			assert.Equal(t, "staged", string(data))
+			assert.Equal(t, OpInfo{Op: OpWrite, Dir: "2024/league", Name: "league", Ext: "txt", Timestamp: published, Bytes: 6}, tracer.ops[1].info)
+		})
+	}
+}
+
+func TestVersionFS_PublishTo_NotFound(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	ts, _ := NewTimestamp("20231019140523")
+	_, err := vfs.PublishTo(fileLeague{season: 2023}, ts, fileLeague{season: 2024})
+	assert.ErrorIs(t, err, ErrVersionNotFound)
+}
+
+// renameFailingBackend is a MemoryBackend whose renames fail.
+type renameFailingBackend struct {
+	*MemoryBackend
+}
+
+func (renameFailingBackend) Rename(oldname, newname string) error {
+	return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: fs.ErrPermission}
+}
+
+func TestVersionFS_PublishTo_Cleanup(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	src := fileLeague{season: 2023}
+	dst := fileLeague{season: 2024}
+	ts := putVersion(t, vfs, src, "20231019140523", "staged")
+	vfs.Backend = renameFailingBackend{vfs.Backend.(*MemoryBackend)}
+	_, err := vfs.PublishTo(src, ts, dst)
+	assert.ErrorIs(t, err, fs.ErrPermission)
+	entries, err := vfs.Backend.ReadDir(dst.Dir())
+	assert.Nil(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestVersionFS_OpenConcat(t *testing.T) {
+	t.Parallel()
+	vfs := New(t.TempDir())
+	file := fileLeague{season: 2023}
+	putVersion(t, vfs, file, "20231019140523", "second\n")
+	putVersion(t, vfs, file, "20231018140523", "first\n")
+	putVersion(t, vfs, file, "20231020140523", "third\n")
+	r, err := vfs.OpenConcat(file)
+	assert.Nil(t, err)
+	data, err := io.ReadAll(r)
+	assert.Nil(t, err)
+	assert.Equal(t, "first\nsecond\nthird\n", string(data))
+	assert.Nil(t, r.Close())
+
+	// closing in the middle of a version closes it
+	r, err = vfs.OpenConcat(file)
+	assert.Nil(t, err)
+	buf := make([]byte, 3)
+	_, err = io.ReadFull(r, buf)
+	assert.Nil(t, err)
+	assert.NotNil(t, r.(*concatReader).current)
+	assert.Nil(t, r.Close())
+	assert.Nil(t, r.(*concatReader).current)
+
+	r, err = vfs.OpenConcat(fileLeague{season: 2024})
+	assert.Nil(t, err)
+	data, err = io.ReadAll(r)
+	assert.Nil(t, err)
+	assert.Empty(t, data)
+}
+
+func TestVersionFS_OpenConcat_Removed(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	file := fileLeague{season: 2023}
+	putVersion(t, vfs, file, "20231018140523", "first\n")
+	ts := putVersion(t, vfs, file, "20231019140523", "second\n")
+	r, err := vfs.OpenConcat(file)
+	assert.Nil(t, err)
+	assert.Nil(t, vfs.Remove(file, ts))
+	data, err := io.ReadAll(r)
+	assert.ErrorIs(t, err, ErrVersionNotFound)
+	assert.Equal(t, "first\n", string(data))
+}
+
+func TestVersionFS_OpenConcat_Gzip(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	file := fileThemes{}
+	for i, line := range []string{"first\n", "second\n"} {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		_, _ = gz.Write([]byte(line))
+		assert.Nil(t, gz.Close())
+		putVersion(t, vfs, file, fmt.Sprintf("2023101%d140523", i), buf.String())
+	}
+	r, err := vfs.OpenConcat(file)
+	assert.Nil(t, err)
+	defer func() { _ = r.Close() }()
+	gz, err := gzip.NewReader(r)
+	assert.Nil(t, err)
+	data, err := io.ReadAll(gz)
+	assert.Nil(t, err)
+	assert.Equal(t, "first\nsecond\n", string(data))
+}
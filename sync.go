@@ -0,0 +1,192 @@
+package versionfs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	path_ "path"
+)
+
+// SyncOptions configures Sync.
+type SyncOptions struct {
+	// Delete removes the versions of the destination that don't exist in the source.
+	Delete bool
+	// Verify compares the SHA-256 of the versions existing on both sides with the same size,
+	// updating the ones that differ, and checks the SHA-256 of every version copied.
+	// Without it, only the sizes are compared.
+	Verify bool
+}
+
+// SyncAction is what Sync did to a version of the destination.
+type SyncAction string
+
+// The actions of Sync.
+const (
+	// SyncCopied is a version missing in the destination, copied from the source.
+	SyncCopied SyncAction = "copied"
+	// SyncUpdated is a version of the destination differing from the source, replaced.
+	SyncUpdated SyncAction = "updated"
+	// SyncDeleted is a version of the destination absent from the source, removed with Delete.
+	SyncDeleted SyncAction = "deleted"
+)
+
+// SyncEntry is a version of the destination modified by Sync.
+type SyncEntry struct {
+	// Path is the path of the version, relative to the roots.
+	Path string
+	// Action is what was done to the version.
+	Action SyncAction
+	// Size is the number of bytes copied, zero for deleted versions.
+	Size int64
+}
+
+// SyncReport is the outcome of Sync.
+type SyncReport struct {
+	// Entries are the versions modified, in the order they were processed.
+	Entries []SyncEntry
+	// Unchanged is the number of versions that were already identical.
+	Unchanged int
+	// Bytes is the total number of bytes copied.
+	Bytes int64
+}
+
+// Sync copies the versions under dirPrefix that exist in src but not in dst, matched by
+// their directory, name, extension, and timestamp, to replicate a primary root to a standby.
+// Versions existing on both sides are replaced when their sizes differ, or their SHA-256
+// with Verify, and the versions of dst absent from src are removed with Delete. Files that
+// don't have the name.ext.timestamp format are ignored on both sides.
+//
+// The contents are streamed, and each version is staged like PublishTo, so an interrupted
+// Sync leaves no partial version behind and can be resumed by running it again. It stops
+// with the context error as soon as ctx is done, returning the report of the versions
+// processed so far, as for the other errors.
+//
+// Example:
+//
+//	report, err := versionfs.Sync(ctx, primary, standby, "", versionfs.SyncOptions{Delete: true})
+func Sync(ctx context.Context, src, dst *VersionFS, dirPrefix string, opts SyncOptions) (SyncReport, error) {
+	var report SyncReport
+	srcPaths, srcSizes, err := listTree(ctx, src, dirPrefix)
+	if err != nil {
+		return report, err
+	}
+	dstPaths, dstSizes, err := listTree(ctx, dst, dirPrefix)
+	if err != nil {
+		return report, err
+	}
+	for _, name := range srcPaths {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		size := srcSizes[name]
+		action := SyncCopied
+		if dstSize, ok := dstSizes[name]; ok {
+			same := dstSize == size
+			if same && opts.Verify {
+				if same, err = sameContent(src, dst, name); err != nil {
+					return report, err
+				}
+			}
+			if same {
+				report.Unchanged++
+				continue
+			}
+			action = SyncUpdated
+		}
+		if err := syncVersion(src, dst, name, size, opts.Verify); err != nil {
+			return report, err
+		}
+		report.Entries = append(report.Entries, SyncEntry{Path: name, Action: action, Size: size})
+		report.Bytes += size
+	}
+	if !opts.Delete {
+		return report, nil
+	}
+	for _, name := range dstPaths {
+		if _, ok := srcSizes[name]; ok {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		if err := dst.removeAt(name); err != nil {
+			return report, err
+		}
+		report.Entries = append(report.Entries, SyncEntry{Path: name, Action: SyncDeleted})
+	}
+	return report, nil
+}
+
+// listTree returns the paths of the versions under dirPrefix, relative to the root, in
+// lexical order, with their sizes.
+func listTree(ctx context.Context, v *VersionFS, dirPrefix string) ([]string, map[string]int64, error) {
+	var paths []string
+	sizes := make(map[string]int64)
+	err := v.WalkVersionsCtx(ctx, dirPrefix, func(dir, name, ext string, ts Timestamp, info os.FileInfo) error {
+		p := path_.Join(dir, name+"."+ext+"."+ts.String())
+		paths = append(paths, p)
+		sizes[p] = info.Size()
+		return nil
+	})
+	return paths, sizes, err
+}
+
+// sameContent reports whether the versions at name in src and dst have the same SHA-256.
+func sameContent(src, dst *VersionFS, name string) (bool, error) {
+	srcHash, err := src.hashPath(name)
+	if err != nil {
+		return false, err
+	}
+	dstHash, err := dst.hashPath(name)
+	if err != nil {
+		return false, err
+	}
+	return srcHash == dstHash, nil
+}
+
+// syncVersion streams the version at name from src to dst, checking its SHA-256 if verify is set.
+func syncVersion(src, dst *VersionFS, name string, size int64, verify bool) error {
+	f, err := src.backend().Open(path_.Join(src.RootPath, name))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	h := sha256.New()
+	var r io.Reader = f
+	if verify {
+		r = io.TeeReader(f, h)
+	}
+	if err := dst.writeAt(name, size, r); err != nil {
+		return err
+	}
+	if !verify || dst.DryRun {
+		return nil
+	}
+	dstHash, err := dst.hashPath(name)
+	if err != nil {
+		return err
+	}
+	if dstHash != hex.EncodeToString(h.Sum(nil)) {
+		return fmt.Errorf("%s: %w", name, ErrChecksumMismatch)
+	}
+	return nil
+}
+
+// removeAt removes the version at name, relative to the root.
+func (v *VersionFS) removeAt(name string) error {
+	if err := v.checkWritable("remove", name); err != nil {
+		return err
+	}
+	if v.DryRun {
+		v.plan.record(PlannedOp{Op: OpRemove, Path: name})
+		return nil
+	}
+	done := v.unclaim(name)
+	err := v.backend().Remove(path_.Join(v.RootPath, name))
+	done(err)
+	v.invalidate(name)
+	return err
+}
@@ -0,0 +1,83 @@
+package versionfs
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSync(t *testing.T) {
+	t.Parallel()
+	src := newExportVersionFS(t)
+	dst := NewMemory()
+	putVersion(t, dst, fileLeague{season: 2023}, "20231019140523", "first")
+	ts := putVersion(t, dst, fileLeague{season: 2023}, "20231020140523", "stale")
+	putVersion(t, dst, fileLeague{season: 2023}, "20231018140523", "extra")
+	report, err := Sync(context.Background(), src, dst, "2023", SyncOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []SyncEntry{
+		{Path: "2023/league/league.txt.20231020140523", Action: SyncUpdated, Size: 6},
+		{Path: "2023/roster/team-12/roster-12-2023-10-19.json.20231019140523", Action: SyncCopied, Size: 6},
+	}, report.Entries)
+	assert.Equal(t, 1, report.Unchanged)
+	assert.Equal(t, int64(12), report.Bytes)
+	data, err := dst.Read(fileLeague{season: 2023}, ts)
+	assert.Nil(t, err)
+	assert.Equal(t, "second", string(data))
+	exists, _ := dst.PathExists("2023/league/league.txt.20231018140523")
+	assert.True(t, exists)
+	exists, _ = dst.PathExists("2024")
+	assert.False(t, exists)
+
+	report, err = Sync(context.Background(), src, dst, "2023", SyncOptions{})
+	assert.Nil(t, err)
+	assert.Empty(t, report.Entries)
+	assert.Equal(t, 3, report.Unchanged)
+}
+
+func TestSync_DeleteVerify(t *testing.T) {
+	t.Parallel()
+	src := newExportVersionFS(t)
+	dst := NewMemory()
+	ts := putVersion(t, dst, fileLeague{season: 2023}, "20231019140523", "FIRST")
+	putVersion(t, dst, fileLeague{season: 2023}, "20231018140523", "extra")
+	report, err := Sync(context.Background(), src, dst, "", SyncOptions{Delete: true, Verify: true})
+	assert.Nil(t, err)
+	assert.Equal(t, []SyncEntry{
+		{Path: "2023/league/league.txt.20231019140523", Action: SyncUpdated, Size: 5},
+		{Path: "2023/league/league.txt.20231020140523", Action: SyncCopied, Size: 6},
+		{Path: "2023/roster/team-12/roster-12-2023-10-19.json.20231019140523", Action: SyncCopied, Size: 6},
+		{Path: "2024/league/league.txt.20241019140523", Action: SyncCopied, Size: 4},
+		{Path: "2023/league/league.txt.20231018140523", Action: SyncDeleted},
+	}, report.Entries)
+	data, err := dst.Read(fileLeague{season: 2023}, ts)
+	assert.Nil(t, err)
+	assert.Equal(t, "first", string(data))
+	exists, _ := dst.PathExists("2023/league/league.txt.20231018140523")
+	assert.False(t, exists)
+}
+
+func TestSync_DryRun(t *testing.T) {
+	t.Parallel()
+	src := newExportVersionFS(t)
+	dst := NewMemory()
+	dst.DryRun = true
+	report, err := Sync(context.Background(), src, dst, "2024", SyncOptions{Verify: true})
+	assert.Nil(t, err)
+	assert.Len(t, report.Entries, 1)
+	assert.Equal(t, []PlannedOp{
+		{Op: OpMkdir, Path: "2024/league"},
+		{Op: OpWrite, Path: "2024/league/league.txt.20241019140523", Size: 4},
+	}, dst.Operations())
+	exists, _ := dst.PathExists("2024")
+	assert.False(t, exists)
+}
+
+func TestSync_Canceled(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := Sync(ctx, newExportVersionFS(t), NewMemory(), "", SyncOptions{})
+	assert.True(t, errors.Is(err, context.Canceled))
+}
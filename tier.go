@@ -0,0 +1,103 @@
+package versionfs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	path_ "path"
+	"time"
+)
+
+// ErrNoFallback is returned by ApplyTiering when no fallback root is set with SetFallback.
+var ErrNoFallback = errors.New("no fallback root")
+
+// TierPolicy decides which versions ApplyTiering moves to the fallback root.
+type TierPolicy struct {
+	// HotFor is the age after which versions are moved, zero to never move them.
+	// Like LatestAge, the age is computed from the timestamp, interpreted in the local time zone.
+	HotFor time.Duration
+}
+
+// TierReport is the outcome of ApplyTiering.
+type TierReport struct {
+	// Moved are the paths of the versions moved, relative to the roots, in lexical order.
+	Moved []string
+	// Bytes is the total size of the versions moved.
+	Bytes int64
+}
+
+// ApplyTiering moves the versions under dirPrefix, the whole tree if empty, older than
+// the HotFor of the Tiering policy from this root to the fallback root set with SetFallback,
+// for example from a local disk to a network mount. Each version is copied and staged like
+// with Sync, its SHA-256 is compared with the source, and only then is it removed from this
+// root, so that Read and Versions keep finding every version through the fallback while the
+// versions are moved. Files that don't have the name.ext.timestamp format stay in this root.
+//
+// Running it again moves nothing more, and an interrupted run is resumed by the next one: a
+// version already in the fallback with the same content is only removed from this root, while
+// one with a different content fails with an error wrapping ErrVersionConflict and is kept.
+// It stops at the first version that can't be moved, returning the report of the versions
+// moved so far. Returns ErrNoFallback if no fallback is set.
+//
+// Example:
+//
+//	hot.SetFallback(cold)
+//	hot.Tiering = versionfs.TierPolicy{HotFor: 30 * 24 * time.Hour}
+//	report, err := hot.ApplyTiering("")
+func (v *VersionFS) ApplyTiering(dirPrefix string) (TierReport, error) {
+	var report TierReport
+	if v.fallback == nil {
+		return report, ErrNoFallback
+	}
+	if v.Tiering.HotFor <= 0 {
+		return report, nil
+	}
+	v.logger().Debugf("Moving the versions under %s older than %s to %s", dirPrefix, v.Tiering.HotFor, v.fallback.RootPath)
+	var cold []string
+	sizes := make(map[string]int64)
+	now := time.Now()
+	err := v.WalkVersions(dirPrefix, func(dir, name, ext string, ts Timestamp, info os.FileInfo) error {
+		if now.Sub(ts.time) > v.Tiering.HotFor {
+			p := path_.Join(dir, name+"."+ext+"."+ts.String())
+			cold = append(cold, p)
+			sizes[p] = info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+	for _, name := range cold {
+		if err := v.moveToFallback(name, sizes[name]); err != nil {
+			return report, err
+		}
+		report.Moved = append(report.Moved, name)
+		report.Bytes += sizes[name]
+	}
+	return report, nil
+}
+
+// moveToFallback moves the version at name, relative to the roots, to the fallback root,
+// verifying the copy before removing it from this root.
+func (v *VersionFS) moveToFallback(name string, size int64) error {
+	info, err := v.fallback.backend().Stat(path_.Join(v.fallback.RootPath, name))
+	switch {
+	case err == nil:
+		same := info.Size() == size
+		if same {
+			if same, err = sameContent(v, v.fallback, name); err != nil {
+				return err
+			}
+		}
+		if !same {
+			return fmt.Errorf("%s: %w", name, ErrVersionConflict)
+		}
+	case errors.Is(err, os.ErrNotExist):
+		if err := syncVersion(v, v.fallback, name, size, true); err != nil {
+			return err
+		}
+	default:
+		return err
+	}
+	return v.removeAt(name)
+}
@@ -0,0 +1,111 @@
+package versionfs
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestVersionFS_ApplyTiering(t *testing.T) {
+	t.Parallel()
+	hot := NewMemory()
+	cold := New(t.TempDir())
+	hot.SetFallback(cold)
+	hot.Tiering = TierPolicy{HotFor: 24 * time.Hour}
+	file := fileLeague{season: 2023}
+	first := putVersion(t, hot, file, "20231018140523", "first")
+	second := putVersion(t, hot, file, "20231019140523", "second")
+	putVersion(t, hot, fileLeague{season: 2024}, "20231019140523", "other season")
+	recent, err := hot.Write(file, []byte("recent"))
+	assert.Nil(t, err)
+
+	report, err := hot.ApplyTiering("2023")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{Path(file, first), Path(file, second)}, report.Moved)
+	assert.Equal(t, int64(11), report.Bytes)
+
+	// the moved versions are only in the fallback, and still read through the primary
+	local, err := hot.localVersions(file)
+	assert.Nil(t, err)
+	assert.Equal(t, timestampStrings([]Timestamp{recent}), timestampStrings(local))
+	moved, err := cold.Versions(file)
+	assert.Nil(t, err)
+	assert.Equal(t, timestampStrings([]Timestamp{second, first}), timestampStrings(moved))
+	versions, err := hot.Versions(file)
+	assert.Nil(t, err)
+	assert.Equal(t, timestampStrings([]Timestamp{recent, second, first}), timestampStrings(versions))
+	data, err := hot.Read(file, first)
+	assert.Nil(t, err)
+	assert.Equal(t, "first", string(data))
+
+	// nothing more is moved by the next runs
+	report, err = hot.ApplyTiering("2023")
+	assert.Nil(t, err)
+	assert.Empty(t, report.Moved)
+	assert.Zero(t, report.Bytes)
+
+	// the other directories are moved with the whole tree
+	report, err = hot.ApplyTiering("")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"2024/league/league.txt.20231019140523"}, report.Moved)
+}
+
+// The versions are named in local time: in a zone behind UTC, they must not be moved early.
+func TestVersionFS_ApplyTiering_LocalZone(t *testing.T) {
+	setLocal(t, "EDT", -4*3600)
+	hot := NewMemory()
+	hot.SetFallback(NewMemory())
+	hot.Tiering = TierPolicy{HotFor: 2 * time.Hour}
+	putVersion(t, hot, fileLeague{season: 2023}, NewFromTime(time.Now().Add(-time.Hour)).String(), "recent")
+	report, err := hot.ApplyTiering("")
+	assert.Nil(t, err)
+	assert.Empty(t, report.Moved)
+}
+
+func TestVersionFS_ApplyTiering_Resume(t *testing.T) {
+	t.Parallel()
+	hot := NewMemory()
+	cold := NewMemory()
+	hot.SetFallback(cold)
+	hot.Tiering = TierPolicy{HotFor: time.Hour}
+	file := fileLeague{season: 2023}
+
+	// a version copied by an interrupted run is only removed
+	ts := putVersion(t, hot, file, "20231018140523", "copied")
+	putVersion(t, cold, file, "20231018140523", "copied")
+	report, err := hot.ApplyTiering("")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{Path(file, ts)}, report.Moved)
+	exists, err := hot.PathExists(Path(file, ts))
+	assert.Nil(t, err)
+	assert.False(t, exists)
+
+	// a version differing in the fallback is kept
+	ts = putVersion(t, hot, file, "20231019140523", "hot")
+	putVersion(t, cold, file, "20231019140523", "cold")
+	report, err = hot.ApplyTiering("")
+	assert.ErrorIs(t, err, ErrVersionConflict)
+	assert.Empty(t, report.Moved)
+	data, err := hot.Read(file, ts)
+	assert.Nil(t, err)
+	assert.Equal(t, "hot", string(data))
+}
+
+func TestVersionFS_ApplyTiering_Disabled(t *testing.T) {
+	t.Parallel()
+	hot := NewMemory()
+	file := fileLeague{season: 2023}
+	ts := putVersion(t, hot, file, "20231018140523", "data")
+	hot.Tiering = TierPolicy{HotFor: time.Hour}
+	_, err := hot.ApplyTiering("")
+	assert.ErrorIs(t, err, ErrNoFallback)
+
+	hot.SetFallback(NewMemory())
+	hot.Tiering = TierPolicy{}
+	report, err := hot.ApplyTiering("")
+	assert.Nil(t, err)
+	assert.Empty(t, report.Moved)
+	exists, err := hot.PathExists(Path(file, ts))
+	assert.Nil(t, err)
+	assert.True(t, exists)
+}
@@ -0,0 +1,112 @@
+package versionfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"time"
+)
+
+// ErrTimeout is returned when a storage operation takes longer than OpTimeout.
+var ErrTimeout = errors.New("operation timed out")
+
+// backend returns the Backend of the instance, bounding each of its calls by OpTimeout if it
+// is set, and retrying them on transient errors if MaxRetries is set.
+func (v *VersionFS) backend() Backend {
+	backend := v.Backend
+	if v.OpTimeout > 0 {
+		backend = timeoutBackend{backend: backend, timeout: v.OpTimeout}
+	}
+	if v.MaxRetries > 0 {
+		backend = retryBackend{backend: backend, v: v}
+	}
+	return backend
+}
+
+// withTimeout runs fn in a goroutine and returns its result, or an *fs.PathError wrapping
+// ErrTimeout if it doesn't return within timeout. The goroutine then keeps running until fn
+// returns, and its result is discarded: a value implementing io.Closer, such as the fs.File
+// of an Open, is closed, so that it doesn't leak. Each timed-out call leaves one goroutine
+// behind until the Backend returns. Without timeout, fn is called directly.
+func withTimeout[T any](timeout time.Duration, op, name string, fn func() (T, error)) (T, error) {
+	if timeout <= 0 {
+		return fn()
+	}
+	type result struct {
+		value T
+		err   error
+	}
+	done := make(chan result)
+	abandoned := make(chan struct{})
+	go func() {
+		value, err := fn()
+		select {
+		case done <- result{value, err}:
+		case <-abandoned:
+			if closer, ok := any(value).(io.Closer); ok && err == nil {
+				_ = closer.Close()
+			}
+		}
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-timer.C:
+		close(abandoned)
+		var zero T
+		return zero, &fs.PathError{Op: op, Path: name, Err: ErrTimeout}
+	}
+}
+
+// timeoutBackend is a Backend bounding each call of another by a timeout.
+type timeoutBackend struct {
+	backend Backend
+	timeout time.Duration
+}
+
+func (b timeoutBackend) ReadFile(name string) ([]byte, error) {
+	return withTimeout(b.timeout, "read", name, func() ([]byte, error) {
+		return b.backend.ReadFile(name)
+	})
+}
+
+func (b timeoutBackend) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	_, err := withTimeout(b.timeout, "write", name, func() (struct{}, error) {
+		return struct{}{}, b.backend.WriteFile(name, data, perm)
+	})
+	return err
+}
+
+func (b timeoutBackend) Open(name string) (fs.File, error) {
+	return withTimeout(b.timeout, "open", name, func() (fs.File, error) {
+		return b.backend.Open(name)
+	})
+}
+
+func (b timeoutBackend) Remove(name string) error {
+	_, err := withTimeout(b.timeout, "remove", name, func() (struct{}, error) {
+		return struct{}{}, b.backend.Remove(name)
+	})
+	return err
+}
+
+func (b timeoutBackend) ReadDir(name string) ([]fs.DirEntry, error) {
+	return withTimeout(b.timeout, "readdir", name, func() ([]fs.DirEntry, error) {
+		return b.backend.ReadDir(name)
+	})
+}
+
+func (b timeoutBackend) Stat(name string) (fs.FileInfo, error) {
+	return withTimeout(b.timeout, "stat", name, func() (fs.FileInfo, error) {
+		return b.backend.Stat(name)
+	})
+}
+
+func (b timeoutBackend) MkdirAll(name string, perm fs.FileMode) error {
+	_, err := withTimeout(b.timeout, "mkdir", name, func() (struct{}, error) {
+		return struct{}{}, b.backend.MkdirAll(name, perm)
+	})
+	return err
+}
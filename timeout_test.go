@@ -0,0 +1,109 @@
+package versionfs
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/fs"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stalledBackend is a MemoryBackend whose ReadFile and ReadDir block until release is closed, like a stalled network mount.
+type stalledBackend struct {
+	*MemoryBackend
+	release chan struct{}
+}
+
+func (b stalledBackend) ReadFile(name string) ([]byte, error) {
+	<-b.release
+	return b.MemoryBackend.ReadFile(name)
+}
+
+func (b stalledBackend) ReadDir(name string) ([]fs.DirEntry, error) {
+	<-b.release
+	return b.MemoryBackend.ReadDir(name)
+}
+
+func TestVersionFS_OpTimeout(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	file := fileLeague{season: 2023}
+	ts := putVersion(t, vfs, file, "20231019140523", "data")
+	backend := stalledBackend{MemoryBackend: vfs.Backend.(*MemoryBackend), release: make(chan struct{})}
+	defer close(backend.release)
+	vfs.Backend = backend
+	vfs.OpTimeout = 20 * time.Millisecond
+
+	start := time.Now()
+	_, err := vfs.Read(file, ts)
+	assert.True(t, errors.Is(err, ErrTimeout))
+	var pathErr *fs.PathError
+	assert.True(t, errors.As(err, &pathErr))
+	assert.Equal(t, "2023/league/league.txt.20231019140523", pathErr.Path)
+	_, err = vfs.Versions(file)
+	assert.True(t, errors.Is(err, ErrTimeout))
+	assert.Less(t, time.Since(start), time.Second)
+
+	// the operations that don't stall are unaffected
+	exists, err := vfs.PathExists("2023/league")
+	assert.Nil(t, err)
+	assert.True(t, exists)
+	_, err = vfs.Write(file, []byte("new"))
+	assert.Nil(t, err)
+}
+
+// closeTrackingFile is a file recording whether it was closed.
+type closeTrackingFile struct {
+	fs.File
+	closed *atomic.Bool
+}
+
+func (f closeTrackingFile) Close() error {
+	f.closed.Store(true)
+	return f.File.Close()
+}
+
+// stalledOpenBackend is a MemoryBackend whose Open blocks until release is closed.
+type stalledOpenBackend struct {
+	*MemoryBackend
+	release chan struct{}
+	closed  *atomic.Bool
+}
+
+func (b stalledOpenBackend) Open(name string) (fs.File, error) {
+	<-b.release
+	f, err := b.MemoryBackend.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return closeTrackingFile{File: f, closed: b.closed}, nil
+}
+
+func TestVersionFS_OpTimeout_LateOpen(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	file := fileLeague{season: 2023}
+	ts := putVersion(t, vfs, file, "20231019140523", "data")
+	backend := stalledOpenBackend{MemoryBackend: vfs.Backend.(*MemoryBackend), release: make(chan struct{}), closed: new(atomic.Bool)}
+	vfs.Backend = backend
+	vfs.OpTimeout = 20 * time.Millisecond
+
+	_, err := vfs.Version(file, ts).WriteTo(io.Discard)
+	assert.ErrorIs(t, err, ErrTimeout)
+	assert.False(t, backend.closed.Load())
+	// the file opened once the caller has given up is closed
+	close(backend.release)
+	assert.Eventually(t, backend.closed.Load, time.Second, time.Millisecond)
+}
+
+func TestWithTimeout(t *testing.T) {
+	t.Parallel()
+	value, err := withTimeout(0, "op", "name", func() (int, error) { return 1, nil })
+	assert.Nil(t, err)
+	assert.Equal(t, 1, value)
+	value, err = withTimeout(time.Second, "op", "name", func() (int, error) { return 2, errors.New("failed") })
+	assert.EqualError(t, err, "failed")
+	assert.Equal(t, 2, value)
+}
@@ -1,30 +1,96 @@
 package versionfs
 
 import (
+	"encoding/binary"
+	"fmt"
 	"time"
 )
 
 const (
 	// tsDefaultFormat is the timestamp format used in filenames: YYYYMMDDHHmmss
 	tsDefaultFormat = "20060102150405"
+	// tsMinuteFormat is the filename format at the Minute resolution: YYYYMMDDHHmm
+	tsMinuteFormat = "200601021504"
+	// tsHourFormat is the filename format at the Hour resolution: YYYYMMDDHH
+	tsHourFormat = "2006010215"
+	// tsDayFormat is the filename format at the Day resolution: YYYYMMDD
+	tsDayFormat = "20060102"
 	// tsLongFormat is a human-readable timestamp format: YYYY-MM-DD HH:mm:ss
 	tsLongFormat = "2006-01-02 15:04:05"
 	// tsSimpleDateFormat is a simple date format: YYYY-M-D
 	tsSimpleDateFormat = "2006-1-2"
 )
 
+// Resolution is the precision of the timestamps in filenames.
+type Resolution int
+
+// The resolutions of the timestamps, Second is the default.
+const (
+	Second Resolution = iota // YYYYMMDDHHmmss
+	Minute                   // YYYYMMDDHHmm
+	Hour                     // YYYYMMDDHH
+	Day                      // YYYYMMDD
+)
+
+// format returns the filename format of the resolution.
+func (r Resolution) format() string {
+	switch r {
+	case Minute:
+		return tsMinuteFormat
+	case Hour:
+		return tsHourFormat
+	case Day:
+		return tsDayFormat
+	}
+	return tsDefaultFormat
+}
+
+// ResolutionFile is implemented by the files versioned at a coarser resolution than
+// the Resolution of the instance, such as files that only ever change daily.
+type ResolutionFile interface {
+	File
+	// Resolution returns the resolution of the timestamps generated by Write.
+	Resolution() Resolution
+}
+
 // Timestamp represents a point in time used for file versioning.
 // It wraps a time.Time and provides multiple formatting options.
 type Timestamp struct {
 	time time.Time
+	res  Resolution
 }
 
-// String returns the timestamp in the default format (YYYYMMDDHHmmss).
+// String returns the timestamp in the format of its resolution, YYYYMMDDHHmmss by default.
 // This format is used in filenames.
 //
-// Example: "20231019140523"
+// Example: "20231019140523", or "20231019" at the Day resolution
 func (t Timestamp) String() string {
-	return t.time.Format(tsDefaultFormat)
+	return t.time.Format(t.res.format())
+}
+
+// Resolution returns the resolution of the timestamp, the one of its filename.
+func (t Timestamp) Resolution() Resolution {
+	return t.res
+}
+
+// Truncate returns the timestamp truncated to a resolution, which is formatted accordingly.
+//
+// Example:
+//
+//	ts := versionfs.NewFromTime(time.Now()).Truncate(versionfs.Day) // "20231019"
+func (t Timestamp) Truncate(res Resolution) Timestamp {
+	tm := t.time
+	switch res {
+	case Minute:
+		tm = time.Date(tm.Year(), tm.Month(), tm.Day(), tm.Hour(), tm.Minute(), 0, 0, tm.Location())
+	case Hour:
+		tm = time.Date(tm.Year(), tm.Month(), tm.Day(), tm.Hour(), 0, 0, 0, tm.Location())
+	case Day:
+		tm = time.Date(tm.Year(), tm.Month(), tm.Day(), 0, 0, 0, 0, tm.Location())
+	default:
+		tm = time.Date(tm.Year(), tm.Month(), tm.Day(), tm.Hour(), tm.Minute(), tm.Second(), 0, tm.Location())
+	}
+	return Timestamp{time: tm, res: res}
 }
 
 // LongString returns the timestamp in a human-readable format.
@@ -46,6 +112,66 @@ func (t Timestamp) Time() time.Time {
 	return t.time
 }
 
+// IsZero reports whether the timestamp is the zero value, such as the previous version
+// returned by WriteWithPrevious for a file without versions.
+func (t Timestamp) IsZero() bool {
+	return t.time.IsZero()
+}
+
+// MarshalText implements encoding.TextMarshaler, the timestamp is encoded as in filenames,
+// e.g. as a JSON string.
+func (t Timestamp) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, parsing the formats of NewTimestamp.
+func (t *Timestamp) UnmarshalText(text []byte) error {
+	ts, err := NewTimestamp(string(text))
+	if err != nil {
+		return err
+	}
+	*t = ts
+	return nil
+}
+
+// binaryResolutionShift is the position of the resolution in the binary format, its most
+// significant byte, which the Unix seconds of the years 0 to 9999 don't need.
+const binaryResolutionShift = 56
+
+// MarshalBinary implements encoding.BinaryMarshaler, the timestamp is encoded in 8 bytes,
+// big-endian: its resolution in the first byte, and its Unix seconds in the 7 others. The
+// wall clock of the filename format is encoded as if it were UTC, so that the timestamp
+// decodes to the same filename whatever the time zone of the process. The first byte of the
+// Second resolution is zero, so that the timestamps after 1970 at the Second resolution are
+// encoded as their plain Unix seconds. The fractions of a second aren't encoded.
+func (t Timestamp) MarshalBinary() ([]byte, error) {
+	tm := t.time
+	wall := time.Date(tm.Year(), tm.Month(), tm.Day(), tm.Hour(), tm.Minute(), tm.Second(), 0, time.UTC)
+	seconds := uint64(wall.Unix()) & (1<<binaryResolutionShift - 1)
+	return binary.BigEndian.AppendUint64(nil, uint64(t.res)<<binaryResolutionShift|seconds), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, decoding the format of MarshalBinary
+// to a timestamp in the local time zone, like NewTimestamp, at its encoded resolution. The
+// plain Unix seconds before 1970, whose first byte is 0xff, decode at the Second resolution.
+func (t *Timestamp) UnmarshalBinary(data []byte) error {
+	if len(data) != 8 {
+		return fmt.Errorf("invalid binary timestamp of %d bytes, expected 8", len(data))
+	}
+	value := binary.BigEndian.Uint64(data)
+	res := Resolution(value >> binaryResolutionShift)
+	if res == 0xff {
+		res = Second
+	} else if res > Day {
+		return fmt.Errorf("invalid binary timestamp resolution %d", res)
+	}
+	// sign-extend the 7 bytes of seconds
+	seconds := int64(value<<(64-binaryResolutionShift)) >> (64 - binaryResolutionShift)
+	wall := time.Unix(seconds, 0).UTC()
+	*t = Timestamp{time: time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), 0, time.Local), res: res}
+	return nil
+}
+
 // SimpleDateAsTime returns a time.Time with the date components but time set to midnight.
 // Useful for date-only comparisons.
 func (t Timestamp) SimpleDateAsTime() time.Time {
@@ -53,7 +179,9 @@ func (t Timestamp) SimpleDateAsTime() time.Time {
 		0, 0, 0, 0, t.time.Location())
 }
 
-// NewFromTime creates a Timestamp from a time.Time value.
+// NewFromTime creates a Timestamp from a time.Time value. Its filename is the wall clock of
+// tm in its location, while the names are parsed back in the local time zone, like the ones
+// Write creates from time.Now: pass a local time for the timestamp to keep its instant.
 //
 // Example:
 //
@@ -62,8 +190,14 @@ func NewFromTime(tm time.Time) Timestamp {
 	return Timestamp{time: tm}
 }
 
-// NewTimestamp parses a timestamp string in the default format (YYYYMMDDHHmmss).
-// Returns an error if the string cannot be parsed.
+// NewTimestamp parses a timestamp string in the default format (YYYYMMDDHHmmss), or in
+// the shorter formats of the coarser resolutions (YYYYMMDDHHmm, YYYYMMDDHH, YYYYMMDD).
+// The timestamp has no time zone and is interpreted in the local time zone, the one of the
+// names Write creates. Returns an error if the string cannot be parsed.
+//
+// Earlier versions parsed the timestamp in UTC, which dated the versions written on hosts
+// not running in UTC in the future or the past. To get the UTC instant of a timestamp, parse
+// it with time.Parse and pass the result to NewFromTime.
 //
 // Example:
 //
@@ -72,16 +206,26 @@ func NewFromTime(tm time.Time) Timestamp {
 //	    log.Fatal(err)
 //	}
 func NewTimestamp(tm string) (Timestamp, error) {
-	t, err := time.Parse(tsDefaultFormat, tm)
+	res := Second
+	switch len(tm) {
+	case len(tsMinuteFormat):
+		res = Minute
+	case len(tsHourFormat):
+		res = Hour
+	case len(tsDayFormat):
+		res = Day
+	}
+	t, err := time.ParseInLocation(res.format(), tm, time.Local)
 	if err != nil {
 		return Timestamp{}, err
 	}
-	return Timestamp{t}, nil
+	return Timestamp{time: t, res: res}, nil
 }
 
 // NewTimestampSimple parses a timestamp string in simple date format (YYYY-M-D).
 // Returns an error if the string cannot be parsed.
-// The time component is set to midnight.
+// The time component is set to midnight in the local time zone, like NewTimestamp, which
+// also changed from UTC.
 //
 // Example:
 //
@@ -90,9 +234,9 @@ func NewTimestamp(tm string) (Timestamp, error) {
 //	    log.Fatal(err)
 //	}
 func NewTimestampSimple(tm string) (Timestamp, error) {
-	t, err := time.Parse(tsSimpleDateFormat, tm)
+	t, err := time.ParseInLocation(tsSimpleDateFormat, tm, time.Local)
 	if err != nil {
 		return Timestamp{}, err
 	}
-	return Timestamp{t}, nil
+	return Timestamp{time: t}, nil
 }
@@ -1,6 +1,7 @@
 package versionfs
 
 import (
+	"encoding/json"
 	"github.com/stretchr/testify/assert"
 	"testing"
 	"time"
@@ -145,3 +146,98 @@ func TestTimestamp_NewSimpleErr(t *testing.T) {
 //	date := time.Date(2022, 1, 9, 1, 2, 3, 0, time.UTC)
 //	assert.Equal(t, "2022-1-9", ToYearString(date))
 //}
+
+func TestTimestamp_New_Resolutions(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		str  string
+		res  Resolution
+		long string
+	}{
+		{"20221019140203", Second, "2022-10-19 14:02:03"},
+		{"202210191402", Minute, "2022-10-19 14:02:00"},
+		{"2022101914", Hour, "2022-10-19 14:00:00"},
+		{"20221019", Day, "2022-10-19 00:00:00"},
+	}
+	for _, test := range tests {
+		ts, err := NewTimestamp(test.str)
+		assert.Nil(t, err)
+		assert.Equal(t, test.res, ts.Resolution())
+		assert.Equal(t, test.str, ts.String())
+		assert.Equal(t, test.long, ts.LongString())
+	}
+	_, err := NewTimestamp("202210191")
+	assert.NotNil(t, err)
+}
+
+func TestTimestamp_Truncate(t *testing.T) {
+	t.Parallel()
+	ts := NewFromTime(time.Date(2022, time.October, 19, 14, 2, 3, 500, time.UTC))
+	assert.Equal(t, "20221019140203", ts.Truncate(Second).String())
+	assert.Equal(t, 0, ts.Truncate(Second).Time().Nanosecond())
+	assert.Equal(t, "202210191402", ts.Truncate(Minute).String())
+	assert.Equal(t, "2022101914", ts.Truncate(Hour).String())
+	day := ts.Truncate(Day)
+	assert.Equal(t, "20221019", day.String())
+	assert.Equal(t, Day, day.Resolution())
+	assert.Equal(t, "2022-10-19 00:00:00", day.LongString())
+}
+
+func TestTimestamp_MarshalText(t *testing.T) {
+	t.Parallel()
+	ts, _ := NewTimestamp("20231019140523")
+	data, err := json.Marshal(map[string]Timestamp{"ts": ts})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"ts":"20231019140523"}`, string(data))
+
+	var decoded map[string]Timestamp
+	assert.Nil(t, json.Unmarshal([]byte(`{"ts":"2023101914"}`), &decoded))
+	assert.Equal(t, "2023101914", decoded["ts"].String())
+	assert.Equal(t, Hour, decoded["ts"].Resolution())
+	assert.NotNil(t, json.Unmarshal([]byte(`{"ts":"yesterday"}`), &decoded))
+}
+
+func TestTimestamp_MarshalBinary(t *testing.T) {
+	t.Parallel()
+	ts, _ := NewTimestamp("20231019140523")
+	data, err := ts.MarshalBinary()
+	assert.Nil(t, err)
+	// 1697724323
+	assert.Equal(t, []byte{0, 0, 0, 0, 0x65, 0x31, 0x37, 0xa3}, data)
+
+	var decoded Timestamp
+	assert.Nil(t, decoded.UnmarshalBinary(data))
+	assert.Equal(t, ts.String(), decoded.String())
+	assert.True(t, ts.Time().Equal(decoded.Time()))
+
+	// the filename format is kept whatever the location, to the second
+	local := NewFromTime(time.Date(2023, 10, 19, 14, 5, 23, 999, time.FixedZone("EDT", -4*3600)))
+	data, err = local.MarshalBinary()
+	assert.Nil(t, err)
+	assert.Nil(t, decoded.UnmarshalBinary(data))
+	assert.Equal(t, "20231019140523", decoded.String())
+
+	// every resolution round-trips, before 1970 too
+	for _, s := range []string{"20231019140523", "202310191405", "2023101914", "20231019", "19600101120000", "19600101"} {
+		ts, err := NewTimestamp(s)
+		assert.Nil(t, err)
+		data, err := ts.MarshalBinary()
+		assert.Nil(t, err)
+		var decoded Timestamp
+		assert.Nil(t, decoded.UnmarshalBinary(data), s)
+		assert.Equal(t, s, decoded.String())
+		assert.Equal(t, ts.Resolution(), decoded.Resolution(), s)
+		assert.True(t, ts.Time().Equal(decoded.Time()), s)
+	}
+	day, _ := NewTimestamp("20231019")
+	data, err = day.MarshalBinary()
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{byte(Day), 0, 0, 0, 0x65, 0x30, 0x71, 0x80}, data)
+
+	// plain Unix seconds before 1970 decode at the Second resolution: -1 is 19691231235959
+	assert.Nil(t, decoded.UnmarshalBinary([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}))
+	assert.Equal(t, "19691231235959", decoded.String())
+
+	assert.NotNil(t, decoded.UnmarshalBinary([]byte("20231019140523")))
+	assert.NotNil(t, decoded.UnmarshalBinary([]byte{0x10, 0, 0, 0, 0x65, 0x31, 0x37, 0xa3}))
+}
@@ -0,0 +1,49 @@
+package versionfs
+
+import (
+	"context"
+	"time"
+)
+
+// OpInfo describes an operation traced by a Tracer.
+type OpInfo struct {
+	// Op is the type of the operation.
+	Op OpType
+	// Dir is the directory of the file, or the directory searched by Find and walked by WalkVersions.
+	Dir string
+	// Name and Ext identify the file, they are empty for WalkVersions.
+	Name string
+	Ext  string
+	// Timestamp is the version read, written, or removed. It is zero for listing operations.
+	Timestamp Timestamp
+	// Bytes is the number of bytes read or written.
+	Bytes int64
+}
+
+// Tracer instruments the operations of a VersionFS instance, for example to create
+// OpenTelemetry spans (see the otelversionfs module).
+type Tracer interface {
+	// Start is called when an operation starts, with the context given to the Ctx variants
+	// or context.Background(). It returns the function called when the operation ends, with
+	// its error. The Timestamp and Bytes of info are completed by then.
+	Start(ctx context.Context, info *OpInfo) (end func(err error))
+}
+
+// newOpInfo describes an operation on a file.
+func newOpInfo(op OpType, file File) OpInfo {
+	return OpInfo{Op: op, Dir: file.Dir(), Name: file.Name(), Ext: file.Ext()}
+}
+
+// instrument starts tracing an operation with the Tracer of the instance, if any, and
+// returns the function ending it, which also records the operation in Metrics.
+func (v *VersionFS) instrument(ctx context.Context, info *OpInfo) func(err error) {
+	start := time.Now()
+	end := func(error) {}
+	if v.Tracer != nil {
+		end = v.Tracer.Start(ctx, info)
+	}
+	return func(err error) {
+		end(err)
+		v.recordOp(info, time.Since(start), err)
+	}
+}
@@ -0,0 +1,88 @@
+package versionfs
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+// tracedOp is an operation recorded by recordingTracer, with the error it ended with.
+type tracedOp struct {
+	info OpInfo
+	err  error
+}
+
+// recordingTracer is a Tracer keeping every ended operation.
+type recordingTracer struct {
+	ops []tracedOp
+}
+
+func (r *recordingTracer) Start(ctx context.Context, info *OpInfo) func(err error) {
+	return func(err error) {
+		r.ops = append(r.ops, tracedOp{info: *info, err: err})
+	}
+}
+
+func TestVersionFS_Tracer(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	tracer := &recordingTracer{}
+	vfs.Tracer = tracer
+	file := fileLeague{season: 2023}
+	ts, err := vfs.Write(file, []byte("hello"))
+	assert.Nil(t, err)
+	_, err = vfs.Read(file, ts)
+	assert.Nil(t, err)
+	_, err = vfs.ReadRange(file, ts, 1, 2)
+	assert.Nil(t, err)
+	_, err = vfs.Versions(file)
+	assert.Nil(t, err)
+	_, err = vfs.Find("2023/league", file)
+	assert.Nil(t, err)
+	err = vfs.WalkVersions("", func(string, string, string, Timestamp, os.FileInfo) error { return nil })
+	assert.Nil(t, err)
+	assert.Nil(t, vfs.Remove(file, ts))
+	_, err = vfs.Read(file, ts)
+	assert.ErrorIs(t, err, ErrVersionNotFound)
+
+	fileInfo := func(op OpType, ts Timestamp, bytes int64) OpInfo {
+		return OpInfo{Op: op, Dir: "2023/league", Name: "league", Ext: "txt", Timestamp: ts, Bytes: bytes}
+	}
+	assert.Equal(t, []OpInfo{
+		fileInfo(OpWrite, ts, 5),
+		fileInfo(OpRead, ts, 5),
+		fileInfo(OpReadRange, ts, 2),
+		fileInfo(OpVersions, Timestamp{}, 0),
+		fileInfo(OpFind, Timestamp{}, 0),
+		{Op: OpWalk},
+		fileInfo(OpRemove, ts, 0),
+		fileInfo(OpRead, ts, 0),
+	}, tracedInfos(tracer.ops))
+	for _, op := range tracer.ops[:len(tracer.ops)-1] {
+		assert.Nil(t, op.err)
+	}
+	assert.ErrorIs(t, tracer.ops[len(tracer.ops)-1].err, ErrVersionNotFound)
+}
+
+func TestVersionFS_Tracer_Ctx(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	tracer := &recordingTracer{}
+	vfs.Tracer = tracer
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := vfs.WriteCtx(ctx, fileLeague{season: 2023}, []byte("hello"))
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Len(t, tracer.ops, 1)
+	assert.Equal(t, OpWrite, tracer.ops[0].info.Op)
+	assert.ErrorIs(t, tracer.ops[0].err, context.Canceled)
+}
+
+func tracedInfos(ops []tracedOp) []OpInfo {
+	infos := make([]OpInfo, len(ops))
+	for i, op := range ops {
+		infos[i] = op.info
+	}
+	return infos
+}
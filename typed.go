@@ -0,0 +1,144 @@
+package versionfs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Codec marshals the values stored by a TypedStore.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// JSONCodec is the Codec using encoding/json, the default of TypedStore.
+type JSONCodec struct{}
+
+// Marshal implements Codec with json.Marshal.
+func (JSONCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal implements Codec with json.Unmarshal.
+func (JSONCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// TypedStore reads and writes values of type T as versions of a file type, handling
+// the marshaling. The args of its methods are passed to the constructor registered
+// for the file type.
+type TypedStore[T any] struct {
+	vfs   *VersionFS
+	ftype FileType
+	codec Codec
+}
+
+// NewTypedStore creates a TypedStore for a registered file type. Values are marshaled
+// with the optional codec, JSON by default.
+//
+// Example:
+//
+//	leagues := versionfs.NewTypedStore[League](vfs, LeagueFileType)
+//	ts, err := leagues.Put(League{Name: "Premier League"}, 2023)
+//	league, ts, err := leagues.Latest(2023)
+func NewTypedStore[T any](vfs *VersionFS, ftype FileType, codec ...Codec) *TypedStore[T] {
+	s := &TypedStore[T]{vfs: vfs, ftype: ftype, codec: JSONCodec{}}
+	if len(codec) > 0 {
+		s.codec = codec[0]
+	}
+	return s
+}
+
+// Put marshals a value and writes it as a new version of the file created with args.
+func (s *TypedStore[T]) Put(value T, args ...any) (Timestamp, error) {
+	file, err := s.vfs.NewE(s.ftype, args...)
+	if err != nil {
+		return Timestamp{}, err
+	}
+	data, err := s.codec.Marshal(value)
+	if err != nil {
+		return Timestamp{}, err
+	}
+	return s.vfs.Write(file, data)
+}
+
+// Get reads and unmarshals a version of the file created with args.
+// Returns an error wrapping ErrVersionNotFound if the version doesn't exist.
+func (s *TypedStore[T]) Get(ts Timestamp, args ...any) (T, error) {
+	var value T
+	file, err := s.vfs.NewE(s.ftype, args...)
+	if err != nil {
+		return value, err
+	}
+	data, err := s.vfs.Read(file, ts)
+	if err != nil {
+		return value, err
+	}
+	err = s.codec.Unmarshal(data, &value)
+	return value, err
+}
+
+// Latest reads and unmarshals the most recent version of the file created with args.
+// Returns ErrNoVersions if no versions exist.
+func (s *TypedStore[T]) Latest(args ...any) (T, Timestamp, error) {
+	var value T
+	file, err := s.vfs.NewE(s.ftype, args...)
+	if err != nil {
+		return value, Timestamp{}, err
+	}
+	ts, err := s.vfs.LastVersion(file)
+	if err != nil {
+		return value, Timestamp{}, err
+	}
+	data, err := s.vfs.Read(file, ts)
+	if err != nil {
+		return value, Timestamp{}, err
+	}
+	if err := s.codec.Unmarshal(data, &value); err != nil {
+		return value, Timestamp{}, err
+	}
+	return value, ts, nil
+}
+
+// ErrUnknownSchema is returned by ReadJSONVersioned when dispatch has no target for the schema of a version.
+var ErrUnknownSchema = errors.New("unknown schema")
+
+// ReadJSONVersioned reads a version of a JSON file whose format evolved over time, carrying
+// its version in a top-level "schema" number field. It unmarshals the schema first, then
+// unmarshals the version into the target returned by dispatch for that schema, a pointer
+// such as &LeagueV2{}, and returns it. A version without a schema field has schema 0.
+// Returns an error wrapping ErrUnknownSchema if dispatch returns nil.
+//
+// Example:
+//
+//	ts, err := vfs.LastVersion(file)
+//	league, err := vfs.ReadJSONVersioned(file, ts, func(schema int) any {
+//	    switch schema {
+//	    case 1:
+//	        return &LeagueV1{}
+//	    case 2:
+//	        return &LeagueV2{}
+//	    }
+//	    return nil
+//	})
+func (v *VersionFS) ReadJSONVersioned(file File, ts Timestamp, dispatch func(schema int) any) (any, error) {
+	data, err := v.Read(file, ts)
+	if err != nil {
+		return nil, err
+	}
+	var envelope struct {
+		Schema int `json:"schema"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("reading the schema of %s: %w", Path(file, ts), err)
+	}
+	target := dispatch(envelope.Schema)
+	if target == nil {
+		return nil, fmt.Errorf("%s has schema %d: %w", Path(file, ts), envelope.Schema, ErrUnknownSchema)
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		return nil, fmt.Errorf("unmarshaling %s with schema %d: %w", Path(file, ts), envelope.Schema, err)
+	}
+	return target, nil
+}
@@ -0,0 +1,141 @@
+package versionfs
+
+import (
+	"encoding/xml"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type typedLeague struct {
+	Name  string `json:"name" xml:"name"`
+	Teams int    `json:"teams" xml:"teams"`
+}
+
+func newTypedVersionFS() *VersionFS {
+	vfs := NewMemory()
+	vfs.RegisterFileType(LeagueFileType, func(args ...any) File {
+		return fileLeague{season: args[0].(int)}
+	})
+	vfs.RegisterFileType(RosterFileType, func(args ...any) File {
+		return fileRoster{season: args[0].(int), teamID: args[1].(int), date: args[2].(string)}
+	})
+	return vfs
+}
+
+func TestTypedStore(t *testing.T) {
+	t.Parallel()
+	vfs := newTypedVersionFS()
+	leagues := NewTypedStore[typedLeague](vfs, LeagueFileType)
+	_, _, err := leagues.Latest(2023)
+	assert.Equal(t, ErrNoVersions, err)
+
+	ts, err := leagues.Put(typedLeague{Name: "Premier League", Teams: 20}, 2023)
+	assert.Nil(t, err)
+	data, err := vfs.Read(fileLeague{season: 2023}, ts)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"name":"Premier League","teams":20}`, string(data))
+
+	league, err := leagues.Get(ts, 2023)
+	assert.Nil(t, err)
+	assert.Equal(t, typedLeague{Name: "Premier League", Teams: 20}, league)
+	league, latest, err := leagues.Latest(2023)
+	assert.Nil(t, err)
+	assert.Equal(t, ts.String(), latest.String())
+	assert.Equal(t, "Premier League", league.Name)
+
+	_, err = leagues.Get(ts, 2024)
+	assert.True(t, errors.Is(err, ErrVersionNotFound))
+}
+
+func TestTypedStore_Args(t *testing.T) {
+	t.Parallel()
+	vfs := newTypedVersionFS()
+	rosters := NewTypedStore[[]string](vfs, RosterFileType)
+	ts, err := rosters.Put([]string{"alice", "bob"}, 2023, 12, "2023-10-19")
+	assert.Nil(t, err)
+	exists, err := vfs.PathExists(Path(fileRoster{season: 2023, teamID: 12, date: "2023-10-19"}, ts))
+	assert.Nil(t, err)
+	assert.True(t, exists)
+	roster, err := rosters.Get(ts, 2023, 12, "2023-10-19")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"alice", "bob"}, roster)
+}
+
+func TestTypedStore_NotRegistered(t *testing.T) {
+	t.Parallel()
+	store := NewTypedStore[typedLeague](newTypedVersionFS(), 99)
+	_, err := store.Put(typedLeague{}, 2023)
+	assert.True(t, errors.Is(err, ErrNotRegistered))
+	_, err = store.Get(Timestamp{}, 2023)
+	assert.True(t, errors.Is(err, ErrNotRegistered))
+	_, _, err = store.Latest(2023)
+	assert.True(t, errors.Is(err, ErrNotRegistered))
+}
+
+type xmlCodec struct{}
+
+func (xmlCodec) Marshal(v any) ([]byte, error)      { return xml.Marshal(v) }
+func (xmlCodec) Unmarshal(data []byte, v any) error { return xml.Unmarshal(data, v) }
+
+func TestTypedStore_Codec(t *testing.T) {
+	t.Parallel()
+	vfs := newTypedVersionFS()
+	leagues := NewTypedStore[typedLeague](vfs, LeagueFileType, xmlCodec{})
+	ts, err := leagues.Put(typedLeague{Name: "Serie A", Teams: 20}, 2023)
+	assert.Nil(t, err)
+	data, err := vfs.Read(fileLeague{season: 2023}, ts)
+	assert.Nil(t, err)
+	assert.Equal(t, "<typedLeague><name>Serie A</name><teams>20</teams></typedLeague>", string(data))
+	league, err := leagues.Get(ts, 2023)
+	assert.Nil(t, err)
+	assert.Equal(t, typedLeague{Name: "Serie A", Teams: 20}, league)
+	// content that can't be unmarshaled
+	assert.Nil(t, vfs.Backend.WriteFile(Path(fileLeague{season: 2023}, ts), []byte("not xml"), 0644))
+	_, err = leagues.Get(ts, 2023)
+	assert.NotNil(t, err)
+	_, _, err = leagues.Latest(2023)
+	assert.NotNil(t, err)
+}
+
+type typedLeagueV1 struct {
+	Name string `json:"name"`
+}
+
+type typedLeagueV2 struct {
+	Schema int      `json:"schema"`
+	Name   string   `json:"name"`
+	Teams  []string `json:"teams"`
+}
+
+func TestVersionFS_ReadJSONVersioned(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	file := fileLeague{season: 2023}
+	dispatch := func(schema int) any {
+		switch schema {
+		case 0, 1:
+			return &typedLeagueV1{}
+		case 2:
+			return &typedLeagueV2{}
+		}
+		return nil
+	}
+	v1 := putVersion(t, vfs, file, "20231018140523", `{"name":"Premier League"}`)
+	v2 := putVersion(t, vfs, file, "20231019140523", `{"schema":2,"name":"Premier League","teams":["Arsenal"]}`)
+	v3 := putVersion(t, vfs, file, "20231020140523", `{"schema":3}`)
+	invalid := putVersion(t, vfs, file, "20231021140523", `{"schema":"2"}`)
+
+	league, err := vfs.ReadJSONVersioned(file, v1, dispatch)
+	assert.Nil(t, err)
+	assert.Equal(t, &typedLeagueV1{Name: "Premier League"}, league)
+	league, err = vfs.ReadJSONVersioned(file, v2, dispatch)
+	assert.Nil(t, err)
+	assert.Equal(t, &typedLeagueV2{Schema: 2, Name: "Premier League", Teams: []string{"Arsenal"}}, league)
+	_, err = vfs.ReadJSONVersioned(file, v3, dispatch)
+	assert.ErrorIs(t, err, ErrUnknownSchema)
+	_, err = vfs.ReadJSONVersioned(file, invalid, dispatch)
+	assert.ErrorContains(t, err, "reading the schema of 2023/league/league.txt.20231021140523")
+	_, err = vfs.ReadJSONVersioned(fileLeague{season: 2024}, v1, dispatch)
+	assert.ErrorIs(t, err, ErrVersionNotFound)
+}
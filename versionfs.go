@@ -17,13 +17,18 @@
 package versionfs
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"github.com/rs/zerolog/log"
+	"io"
+	"io/fs"
 	"os"
 	path_ "path"
+	"reflect"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -48,25 +53,265 @@ type File interface {
 	Ext() string
 }
 
+// TypedFile is implemented by the files declaring their FileType, so that what is registered
+// per file type, such as the aliases of RegisterAlias, applies to them whether they were
+// created with New or built directly, and even when several file types share a Go type.
+type TypedFile interface {
+	File
+	// FileType returns the file type of the file.
+	FileType() FileType
+}
+
 // Path constructs the full file path for a given file and timestamp.
 // Returns a path in the format: dir/name.ext.timestamp
 //
 // Example: "2023/league/league.json.20231019140523"
 func Path(file File, version Timestamp) string {
-	return fmt.Sprintf("%s/%s.%s.%s", file.Dir(), file.Name(), file.Ext(), version)
+	var buf [64]byte
+	return string(AppendPath(buf[:0], file, version))
+}
+
+// AppendPath appends the path of a version, as returned by Path, to dst and returns the
+// extended buffer, to build paths in a loop without allocating.
+//
+// Example:
+//
+//	var buf []byte
+//	for _, ts := range versions {
+//	    buf = versionfs.AppendPath(buf[:0], file, ts)
+//	    w.Write(append(buf, '\n'))
+//	}
+func AppendPath(dst []byte, file File, version Timestamp) []byte {
+	start := len(dst)
+	dst = append(dst, file.Dir()...)
+	for i := start; i < len(dst); i++ {
+		if dst[i] == '\\' {
+			dst[i] = '/'
+		}
+	}
+	dst = append(dst, '/')
+	dst = append(dst, file.Name()...)
+	dst = append(dst, '.')
+	dst = append(dst, file.Ext()...)
+	dst = append(dst, '.')
+	return version.time.AppendFormat(dst, version.res.format())
+}
+
+// joinPath returns the Path of a version and the path joined to root, like path.Join,
+// building both in a single allocation when they are clean, the common case.
+func joinPath(root string, file File, version Timestamp) (name, full string) {
+	var buf [128]byte
+	b := buf[:0]
+	if root != "" && root != "." {
+		b = append(append(b, root...), '/')
+	}
+	prefix := len(b)
+	full = string(AppendPath(b, file, version))
+	name = full[prefix:]
+	if path_.Clean(full) != full || root == "." && path_.IsAbs(name) {
+		full = path_.Join(root, name)
+	}
+	return name, full
+}
+
+// BasePath returns the path of a file without its timestamp, the prefix shared by all its versions.
+// Returns a path in the format: dir/name.ext
+// Appending ".*" gives the glob matching the versions, like Find does, for external tools.
+//
+// Example: "2023/league/league.json"
+func BasePath(file File) string {
+	return path_.Join(slashed(file.Dir()), file.Name()+"."+file.Ext())
+}
+
+// slashed returns a relative path with its backslashes replaced by slashes, the separator of
+// the paths relative to the root, so that the paths built with filepath on Windows, or by
+// filepath.Walk, designate the same files on every platform.
+func slashed(name string) string {
+	return strings.ReplaceAll(name, `\`, "/")
 }
 
 // Constructor is a function type for creating File instances.
 // It accepts variadic arguments to support parameterized file types.
 type Constructor func(args ...any) File
 
+// ConstructorE is a function type for creating File instances that can fail,
+// for example when validating its arguments.
+type ConstructorE func(args ...any) (File, error)
+
+// ErrNotRegistered is returned by NewE when the file type has not been registered.
+var ErrNotRegistered = errors.New("not registered")
+
 // VersionFS manages versioned files in a local filesystem.
 // It maintains a root path and a registry of file type constructors.
 type VersionFS struct {
-	// RootPath is the base directory for all file operations.
+	// RootPath is the base directory for all file operations. It is used as given to New,
+	// while NewChecked validates and normalizes it. Don't modify it while operations are in
+	// flight; WithRoot and Clone create instances on other roots.
 	RootPath string
+	// Backend is the storage for the files, the local filesystem by default.
+	Backend Backend
+	// CaseInsensitiveExt makes Detect and Find compare extensions case-insensitively,
+	// so that "league.JSON.20231019140523" matches a file with the "json" extension.
+	// The name is always compared exactly.
+	CaseInsensitiveExt bool
+	// SyncOnWrite makes Write flush the file and its parent directory to stable storage
+	// before returning, so an acknowledged version survives a power loss. It is off by default:
+	// each write then waits for the disk, which is typically orders of magnitude slower.
+	// Only backends implementing SyncBackend are synced, such as the local filesystem.
+	SyncOnWrite bool
+	// CreateDirs makes Write create the directory of the file if it doesn't exist. It is true
+	// by default; disable it when the directory tree is provisioned ahead of time and the
+	// process isn't allowed to create directories.
+	CreateDirs bool
+	// ReadOnly makes every operation that would modify the tree, such as Write, Remove,
+	// and MkdirAll, fail with an error wrapping ErrReadOnly without touching the storage.
+	// Read and list operations are unaffected.
+	ReadOnly bool
+	// DryRun makes Write, Remove, and MkdirAll record the operation they would perform,
+	// returned by Operations, instead of modifying the storage. Write returns the timestamp
+	// the version would have had. Read and list operations are unaffected.
+	DryRun bool
+	// Logger receives the debug and warning messages, they are discarded by default.
+	Logger Logger
+	// Tracer instruments Write, Read, ReadRange, Remove, Versions, Find, WalkVersions, and
+	// VersionRef.WriteTo, and their Ctx variants. Nothing is traced by default.
+	Tracer Tracer
+	// IgnoreDotfiles makes Versions, Find, FindAnyExt, DetectDir, and WalkVersions skip the
+	// entries starting with a dot, such as .DS_Store, the temporary files of PublishTo, and the
+	// lock files of ProcessLocks, without logging them. It is true by default; disable it when
+	// the names of a file type start with a dot.
+	IgnoreDotfiles bool
+	// WalkParallelism is the number of directories WalkVersions, WalkVersionsCtx, and
+	// CountVersionsRecursive read at once, along with the information of their versions, to
+	// walk large trees on a network filesystem faster. The walk function is still never
+	// called concurrently: it is called from the goroutine of the walk, but in no particular
+	// order. Zero or one, the default, walks one directory at a time in lexical order.
+	WalkParallelism int
+	// SkipEmpty makes Versions, Find, FindAnyExt, and the APIs listing versions, such as
+	// LastVersion, HasSome, VersionsWithInfo, and the Prune APIs, ignore the zero-byte versions, such as
+	// the ones left by a writer that crashed, so that they aren't parsed as valid versions.
+	// They can still be read and removed by timestamp, but are never pruned. It is off by
+	// default, as writing empty data legitimately creates empty versions.
+	SkipEmpty bool
+	// ProcessLocks makes Write and the Prune APIs take an advisory lock on the directory of
+	// the files, shared with the other processes using the same root, when the Backend
+	// implements LockBackend, as the local filesystem does on the platforms supporting flock.
+	// On the other platforms, such as Windows, the directories are only locked within the
+	// process. Read-only and dry-run instances don't lock, nor create lock files.
+	ProcessLocks bool
+	// LockTimeout is how long ProcessLocks waits for a directory lock before failing with an
+	// error wrapping ErrLockTimeout. Zero waits without limit.
+	LockTimeout time.Duration
+	// Resolution is the precision of the timestamps generated by Write, Second by default.
+	// A file implementing ResolutionFile overrides it.
+	Resolution Resolution
+	// Metrics receives the operation counters and latencies, they are discarded by default.
+	Metrics Metrics
+	// OpTimeout bounds each call to the Backend, such as a Stat or a ReadDir, when it is not
+	// zero: a call taking longer fails with an error wrapping ErrTimeout, to protect the
+	// callers that can't pass a context from a stalled network filesystem. The call keeps
+	// running in its goroutine until the Backend returns, and a file it opens late is closed.
+	// Every timed-out call leaves one such goroutine, so that a storage hanging for good
+	// holds as many goroutines as the calls made to it meanwhile. The reads and writes of
+	// streamed content are not bounded.
+	OpTimeout time.Duration
+	// MaxRetries is how many times a call to the Backend failing with a transient error, as
+	// classified by IsTransient, is retried, so that reads and writes survive the occasional
+	// EIO or ESTALE of a flaky network mount. Other errors, such as fs.ErrNotExist, are
+	// returned at once. Zero disables the retries. The reads and writes of streamed content
+	// are not retried.
+	MaxRetries int
+	// RetryBackoff is the wait before the first retry of MaxRetries, doubled before each of
+	// the next ones.
+	RetryBackoff time.Duration
+	// MaxBytes is the quota of the root: Write and the other operations adding files fail with
+	// an error wrapping ErrQuotaExceeded instead of making the total size of the files under
+	// the root exceed it. The usage is scanned by the first write, then maintained by the
+	// operations of the instance; see Usage and RecalculateUsage. Zero means no limit.
+	MaxBytes int64
+	// VerifyOnRead makes Read check the content of a version against its checksum sidecar,
+	// written by WriteChecksum, and fail with an error wrapping ErrChecksumMismatch instead of
+	// returning corrupt data. Versions without a sidecar are read normally, unless
+	// RequireChecksum is set. It is off by default, since every read then hashes the content.
+	VerifyOnRead bool
+	// RequireChecksum makes VerifyOnRead fail with an error wrapping ErrNoChecksum when a
+	// version has no checksum sidecar.
+	RequireChecksum bool
+	// RestrictToRoot makes the operations refuse the paths resolving outside the root through
+	// symbolic links, on backends implementing SymlinkBackend, with an error wrapping
+	// ErrPathEscapesRoot: every operation resolves the links of its path when it starts, and
+	// Write and Read check again right before touching the storage, failing as well when the
+	// path can't be resolved. The second check closes the window where a directory is swapped
+	// for a link while Write creates the directories, for multi-tenant trees; it requires the
+	// path to exist: the version for Read, its directory for Write. The root itself is resolved
+	// once. It is off by default, since it resolves the symbolic links of each path.
+	RestrictToRoot bool
+	// Scheme maps the versions to their paths for Write, Read, Remove, Versions, Find, and
+	// Detect, such as DirScheme storing them as dir/name/timestamp.ext. The default, nil, is
+	// the dir/name.ext.timestamp layout of Path, honoring CaseInsensitiveExt. The other APIs,
+	// such as WalkVersions, DetectDir, and Sync, only support the default layout. Combined
+	// with Sharded, only FlatScheme is supported: with another scheme, every operation fails
+	// with an error wrapping ErrShardedScheme.
+	Scheme PathScheme
+	// Sharded makes Write store the versions in the sharded layout returned by ShardPath,
+	// dir/name.ext/YYYY/MM/name.ext.timestamp, for files with so many versions that listing
+	// their directory is slow, and makes Read, Remove, Versions, and Find find the versions
+	// in both layouts, so that Reshard can move the existing versions while the instance is
+	// used. The other APIs scanning directories, such as WalkVersions, only see the flat
+	// versions. It doesn't support a Scheme other than FlatScheme (see ErrShardedScheme).
+	// It is off by default.
+	Sharded bool
+	// CopyThrough makes Read write the versions it reads from the fallback, set with
+	// SetFallback, to this root, so that the next reads of a version hit this root.
+	CopyThrough bool
+	// Tiering is the policy of ApplyTiering, which moves the versions older than its HotFor
+	// to the fallback root. Nothing is moved by default.
+	Tiering TierPolicy
+	// VersionsCacheTTL makes Versions and the methods built on it, such as LastVersion, reuse
+	// the versions they listed for a file for that long, for callers listing the same files
+	// over and over between writes. The writes and removals of the instance, such as Write,
+	// Remove, and Prune, drop the listings of their directory at once; the changes made by other
+	// instances or processes are only seen once a listing expires. Hits and misses are counted
+	// by the MetricCacheHits and MetricCacheMisses metrics. Zero, the default, disables the cache.
+	VersionsCacheTTL time.Duration
+	// ReadCacheBytes makes Read keep the contents it reads in memory, up to that many bytes,
+	// evicting the least recently used ones, for callers reading the same versions over and
+	// over, such as the latest version of a file on every request. The cache holds a single
+	// copy of each content and Read returns copies of it. The writes and removals of the
+	// instance drop the contents they replace; the versions replaced or removed by other
+	// instances or processes may still be returned. Hits and misses are counted by the
+	// MetricReadCacheHits and MetricReadCacheMisses metrics. Zero, the default, disables the cache.
+	ReadCacheBytes int64
+	// fallback is the secondary instance set with SetFallback, or nil.
+	fallback *VersionFS
+	// plan logs the operations planned in dry-run mode, it is shared with the views created by WithRoot.
+	plan *plan
+	// locks are the per-file locks, they are shared with the views created by WithRoot.
+	locks *fileLocks
+	// usage is the number of bytes stored under the root, it is not shared with other instances.
+	usage *usage
+	// cache holds the listings of VersionsCacheTTL, it is not shared with other instances.
+	cache *versionsCache
+	// readCache holds the contents of ReadCacheBytes, it is not shared with other instances.
+	readCache *readCache
+	// root holds the root resolved through symbolic links, it is not shared with other instances.
+	root *resolvedRoot
+	// mu guards the registry maps below, it is shared with the views created by WithRoot.
+	mu *sync.RWMutex
 	// constructors maps FileType to their constructor functions.
-	constructors map[FileType]Constructor
+	constructors map[FileType]ConstructorE
+	// names maps FileType to their optional human-readable names.
+	names map[FileType]string
+	// named maps the names registered with RegisterNamed to their constructor functions.
+	named map[string]ConstructorE
+	// types maps the Go type of the files created by New to their FileType.
+	types map[reflect.Type]FileType
+	// shared are the Go types of the files created by New for several FileTypes.
+	shared map[reflect.Type]bool
+	// aliases maps FileType to the legacy names its versions may be stored under.
+	aliases map[FileType][]Alias
+	// prototypes are the files registered with RegisterPrototype, matched by FileFor.
+	prototypes []File
 }
 
 // New creates a new VersionFS instance with the specified root path.
@@ -77,26 +322,191 @@ type VersionFS struct {
 //	vfs := versionfs.New("./data")
 func New(rootPath string) *VersionFS {
 	return &VersionFS{
-		RootPath:     rootPath,
-		constructors: make(map[FileType]Constructor),
+		RootPath:       rootPath,
+		Backend:        OSBackend{},
+		CreateDirs:     true,
+		IgnoreDotfiles: true,
+		Logger:         NopLogger{},
+		Metrics:        NopMetrics{},
+		plan:           &plan{},
+		locks:          &fileLocks{},
+		usage:          &usage{},
+		cache:          &versionsCache{},
+		readCache:      &readCache{},
+		root:           &resolvedRoot{},
+		mu:             &sync.RWMutex{},
+		constructors:   make(map[FileType]ConstructorE),
+		names:          make(map[FileType]string),
+		named:          make(map[string]ConstructorE),
+		types:          make(map[reflect.Type]FileType),
+		shared:         make(map[reflect.Type]bool),
+		aliases:        make(map[FileType][]Alias),
 	}
 }
 
 // RegisterFileType registers a constructor function for a file type.
 // The constructor will be called when creating new instances of this file type.
+// An optional human-readable name can be given, it is used by TypeName in errors and logs.
 //
 // Example:
 //
 //	vfs.RegisterFileType(LeagueFileType, func(args ...any) versionfs.File {
 //	    return LeagueFile{season: args[0].(int)}
+//	}, "league")
+func (v *VersionFS) RegisterFileType(ftype FileType, constructor Constructor, name ...string) {
+	v.RegisterFileTypeE(ftype, func(args ...any) (File, error) {
+		return constructor(args...), nil
+	}, name...)
+}
+
+// RegisterFileTypeE registers a constructor function that can fail for a file type.
+// Errors returned by the constructor are propagated by NewE, New panics with them.
+//
+// Example:
+//
+//	vfs.RegisterFileTypeE(LeagueFileType, func(args ...any) (versionfs.File, error) {
+//	    season := args[0].(int)
+//	    if season < 1900 {
+//	        return nil, fmt.Errorf("invalid season %d", season)
+//	    }
+//	    return LeagueFile{season: season}, nil
 //	})
-func (v *VersionFS) RegisterFileType(ftype FileType, constructor Constructor) {
+func (v *VersionFS) RegisterFileTypeE(ftype FileType, constructor ConstructorE, name ...string) {
+	v.mu.Lock()
 	v.constructors[ftype] = constructor
+	if len(name) > 0 {
+		v.names[ftype] = name[0]
+	}
+	v.mu.Unlock()
+	v.logger().Debugf("Registering file type %s", v.TypeName(ftype))
+}
+
+// TypeName returns the name a file type was registered with.
+// Unnamed file types fall back to their numeric form.
+//
+// Example:
+//
+//	fmt.Println(vfs.TypeName(LeagueFileType)) // "league"
+//	fmt.Println(vfs.TypeName(99))             // "99"
+func (v *VersionFS) TypeName(ftype FileType) string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if name, ok := v.names[ftype]; ok {
+		return name
+	}
+	return strconv.Itoa(int(ftype))
+}
+
+// Clone creates a new VersionFS rooted at newRoot with a copy of the registry and options.
+// Registering file types on the clone does not affect the original, and vice versa. The clone
+// has its own per-file locks, and the backends tied to the root, such as the CASBackend of
+// EnableCAS, are recreated for newRoot, so that the clone stores its blobs under it.
+//
+// Example:
+//
+//	tenant := vfs.Clone("./data/tenant-a")
+func (v *VersionFS) Clone(newRoot string) *VersionFS {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	c := *v
+	c.RootPath = newRoot
+	if rb, ok := v.Backend.(rootedBackend); ok {
+		c.Backend = rb.rebase(v.RootPath, newRoot)
+	}
+	c.fallback = nil
+	c.plan = &plan{}
+	c.locks = &fileLocks{}
+	c.usage = &usage{}
+	c.cache = &versionsCache{}
+	c.readCache = &readCache{}
+	c.root = &resolvedRoot{}
+	c.mu = &sync.RWMutex{}
+	c.constructors = make(map[FileType]ConstructorE, len(v.constructors))
+	for ftype, constructor := range v.constructors {
+		c.constructors[ftype] = constructor
+	}
+	c.named = make(map[string]ConstructorE, len(v.named))
+	for name, constructor := range v.named {
+		c.named[name] = constructor
+	}
+	c.names = make(map[FileType]string, len(v.names))
+	for ftype, name := range v.names {
+		c.names[ftype] = name
+	}
+	c.types = make(map[reflect.Type]FileType, len(v.types))
+	for rtype, ftype := range v.types {
+		c.types[rtype] = ftype
+	}
+	c.shared = make(map[reflect.Type]bool, len(v.shared))
+	for rtype := range v.shared {
+		c.shared[rtype] = true
+	}
+	c.aliases = make(map[FileType][]Alias, len(v.aliases))
+	for ftype, aliases := range v.aliases {
+		c.aliases[ftype] = append([]Alias(nil), aliases...)
+	}
+	c.prototypes = append([]File(nil), v.prototypes...)
+	return &c
+}
+
+// WithRoot returns a lightweight view of the VersionFS rooted at newRoot.
+// Unlike Clone, the registry is shared by reference: file types registered on the
+// view are visible to the original and vice versa.
+//
+// Example:
+//
+//	staging := vfs.WithRoot("./staging")
+func (v *VersionFS) WithRoot(newRoot string) *VersionFS {
+	c := *v
+	c.RootPath = newRoot
+	c.fallback = nil
+	c.usage = &usage{}
+	c.cache = &versionsCache{}
+	c.readCache = &readCache{}
+	c.root = &resolvedRoot{}
+	return &c
+}
+
+// Sub returns a view of the VersionFS rooted at dir, relative to the root, like fs.Sub: a
+// component given the view only reaches the files under dir, and its paths are relative to
+// it. The view shares the registry, the options, the locks, and the dry-run log with the
+// original, like WithRoot, and the files written through it have the same path as when
+// written through the original with dir prepended to their directory. Returns an error
+// wrapping fs.ErrInvalid if dir isn't a valid path, or ErrOutsideRoot if it escapes the root.
+//
+// Example:
+//
+//	rosters, err := vfs.Sub("2023/rosters")
+func (v *VersionFS) Sub(dir string) (*VersionFS, error) {
+	dir = slashed(dir)
+	if err := v.confine("sub", dir); err != nil {
+		return nil, err
+	}
+	if !fs.ValidPath(dir) {
+		return nil, &fs.PathError{Op: "sub", Path: dir, Err: fs.ErrInvalid}
+	}
+	root := v.RootPath
+	if dir != "." {
+		root = path_.Join(v.RootPath, dir)
+	}
+	sub := v.WithRoot(root)
+	if v.fallback != nil {
+		fallback, err := v.fallback.Sub(dir)
+		if err != nil {
+			return nil, err
+		}
+		sub.fallback = fallback
+	}
+	return sub, nil
 }
 
 // Write writes data to a file and returns the generated timestamp.
 // The file is created with the pattern: dir/name.ext.timestamp
-// The directory is created automatically if it doesn't exist.
+// Writes to the same file are serialized within the process. At the Second resolution, a write
+// finding a version with the same timestamp waits for the next second instead of overwriting it,
+// so that no version is dated in the future.
+// The directory is created automatically if it doesn't exist, unless CreateDirs is disabled.
+// When SyncOnWrite is set, the version is flushed to stable storage before returning.
 //
 // Example:
 //
@@ -106,155 +516,1109 @@ func (v *VersionFS) RegisterFileType(ftype FileType, constructor Constructor) {
 //	}
 //	fmt.Printf("Created version: %s\n", ts)
 func (v *VersionFS) Write(file File, data []byte) (Timestamp, error) {
-	log.Debug().Msgf("Writing file %s/%s.%s.?", file.Dir(), file.Name(), file.Ext())
-	if err := v.MkdirAll(file.Dir(), 0755); err != nil {
+	return v.WriteCtx(context.Background(), file, data)
+}
+
+// WriteCtx works like Write, but returns the context error without writing if ctx is done
+// before the directory is created or the file is written.
+func (v *VersionFS) WriteCtx(ctx context.Context, file File, data []byte) (Timestamp, error) {
+	info := newOpInfo(OpWrite, file)
+	info.Bytes = int64(len(data))
+	end := v.instrument(ctx, &info)
+	ts, err := v.write(ctx, file, data, nil)
+	info.Timestamp = ts
+	end(err)
+	return ts, err
+}
+
+// WriteWithPrevious works like Write, and also returns the timestamp of the latest version
+// before the write, to record which version the new one supersedes, or the zero Timestamp
+// if there was none (see IsZero). The latest version is found after the write is reserved,
+// so that no other Write of the process can slip in between.
+//
+// Example:
+//
+//	ts, prev, err := vfs.WriteWithPrevious(file, data)
+//	if err == nil && !prev.IsZero() {
+//	    fmt.Printf("%s supersedes %s\n", ts, prev)
+//	}
+func (v *VersionFS) WriteWithPrevious(file File, data []byte) (Timestamp, Timestamp, error) {
+	var prev Timestamp
+	info := newOpInfo(OpWrite, file)
+	info.Bytes = int64(len(data))
+	end := v.instrument(context.Background(), &info)
+	ts, err := v.write(context.Background(), file, data, &prev)
+	info.Timestamp = ts
+	end(err)
+	return ts, prev, err
+}
+
+// WriteInto works like Write, but stores the version in dir instead of the directory of
+// file, keeping its name, extension, and resolution, e.g. to redirect a backfill to a
+// staging directory without defining another file type. The version is found with
+// Find(dir, file), while Versions and the other methods taking file look in file.Dir().
+//
+// Example:
+//
+//	ts, err := vfs.WriteInto("staging/league", file, data)
+//	versions, err := vfs.Find("staging/league", file)
+func (v *VersionFS) WriteInto(dir string, file File, data []byte) (Timestamp, error) {
+	return v.Write(fileInDir{File: file, dir: dir, res: v.resolutionOf(file)}, data)
+}
+
+// fileInDir is a File moved to another directory by WriteInto.
+type fileInDir struct {
+	File
+	dir string
+	res Resolution
+}
+
+func (f fileInDir) Dir() string            { return f.dir }
+func (f fileInDir) Resolution() Resolution { return f.res }
+
+// write implements WriteCtx. If prev isn't nil, it is set to the latest version before the write.
+func (v *VersionFS) write(ctx context.Context, file File, data []byte, prev *Timestamp) (Timestamp, error) {
+	v.logger().Debugf("Writing file %s/%s.%s.?", file.Dir(), file.Name(), file.Ext())
+	ts := v.newTimestamp(file)
+	if err := v.confine("write", v.versionPath(file, ts)); err != nil {
+		return Timestamp{}, err
+	}
+	if err := v.checkWritable("write", v.versionPath(file, ts)); err != nil {
+		return Timestamp{}, err
+	}
+	if v.DryRun {
+		v.plan.record(PlannedOp{Op: OpWrite, Path: v.versionPath(file, ts), Size: int64(len(data))})
+		return ts, v.previous(ctx, file, prev)
+	}
+	if err := ctx.Err(); err != nil {
+		return Timestamp{}, err
+	}
+	if v.CreateDirs {
+		if err := v.MkdirAll(file.Dir(), 0755); err != nil {
+			return Timestamp{}, err
+		}
+		if v.Scheme != nil {
+			if err := v.MkdirAll(path_.Dir(v.formatPath(file, ts)), 0755); err != nil {
+				return Timestamp{}, err
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return Timestamp{}, err
+		}
+	}
+	ts, unlock, err := v.reserve(file, ts)
+	if err != nil {
+		return Timestamp{}, err
+	}
+	defer unlock()
+	if v.Sharded {
+		if err := v.mkShardDir(file, ts); err != nil {
+			return Timestamp{}, err
+		}
+	}
+	name, filepath := v.versionPaths(file, ts)
+	if err := v.restrict("write", name); err != nil {
+		return Timestamp{}, err
+	}
+	if err := v.previous(ctx, file, prev); err != nil {
+		return Timestamp{}, err
+	}
+	done, err := v.claim(name, int64(len(data)))
+	if err != nil {
+		return Timestamp{}, err
+	}
+	if sb, ok := v.Backend.(SyncBackend); ok && v.SyncOnWrite {
+		_, err = callBackend(v, "write", filepath, func() (struct{}, error) {
+			return struct{}{}, sb.WriteFileSync(filepath, data, 0644)
+		})
+	} else {
+		err = v.backend().WriteFile(filepath, data, 0644)
+	}
+	done(err)
+	v.invalidate(name)
+	if err != nil && !v.CreateDirs && errors.Is(err, fs.ErrNotExist) {
+		return ts, fmt.Errorf("directory %s doesn't exist and CreateDirs is disabled: %w", file.Dir(), err)
+	}
+	return ts, err
+}
+
+// previous sets prev, if it isn't nil, to the latest version of a file, or to the zero Timestamp.
+func (v *VersionFS) previous(ctx context.Context, file File, prev *Timestamp) error {
+	if prev == nil {
+		return nil
+	}
+	versions, err := v.allVersions(ctx, file)
+	if err != nil {
+		return err
+	}
+	if len(versions) > 0 {
+		*prev = versions[0]
+	}
+	return nil
+}
+
+// newTimestamp returns the timestamp of a new version of file, at its resolution.
+func (v *VersionFS) newTimestamp(file File) Timestamp {
+	ts := NewFromTime(time.Now())
+	if res := v.resolutionOf(file); res != Second {
+		ts = ts.Truncate(res)
+	}
+	return ts
+}
+
+// reserve locks the writes of file, in the process and in the directory with ProcessLocks,
+// and returns the timestamp of the new version, taken again in the next second at the Second
+// resolution if a version of the current one exists, with the function unlocking the writes
+// once the version is created.
+func (v *VersionFS) reserve(file File, ts Timestamp) (Timestamp, func(), error) {
+	unlock := v.lockWrite(file)
+	unlockDir, err := v.lockDir(file.Dir())
+	if err != nil {
+		unlock()
+		return Timestamp{}, nil, err
+	}
+	if ts.res == Second {
+		if ts, err = v.nextFree(file, ts); err != nil {
+			unlockDir()
+			unlock()
+			return Timestamp{}, nil, err
+		}
+	}
+	return ts, func() {
+		unlockDir()
+		unlock()
+	}, nil
+}
+
+// nextFree returns ts if no version of file exists for it, or else waits for the next
+// second and takes the timestamp again, so that the writes within the same second don't
+// overwrite each other and no version is dated in the future. When Sharded is set, the
+// versions are looked for in both layouts. The writes of file must be locked.
+func (v *VersionFS) nextFree(file File, ts Timestamp) (Timestamp, error) {
+	for {
+		exists, err := v.versionExists(file, ts)
+		if err != nil {
+			return Timestamp{}, err
+		} else if !exists {
+			return ts, nil
+		}
+		time.Sleep(time.Until(ts.time.Truncate(time.Second).Add(time.Second)))
+		ts = NewFromTime(time.Now())
+	}
+}
+
+// versionExists reports whether the version ts of file exists, flat or, when Sharded is set, in its shard.
+func (v *VersionFS) versionExists(file File, ts Timestamp) (bool, error) {
+	paths := []string{v.formatPath(file, ts)}
+	if v.Sharded {
+		paths = append(paths, ShardPath(file, ts))
+	}
+	for _, p := range paths {
+		_, err := v.backend().Stat(path_.Join(v.RootPath, p))
+		if err == nil {
+			return true, nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// resolutionOf returns the resolution of the timestamps generated for a file.
+func (v *VersionFS) resolutionOf(file File) Resolution {
+	if rf, ok := file.(ResolutionFile); ok {
+		return rf.Resolution()
+	}
+	return v.Resolution
+}
+
+// checkWritable returns an error wrapping ErrReadOnly if the instance is read-only,
+// reporting the attempted operation and the path relative to the root.
+func (v *VersionFS) checkWritable(op, path string) error {
+	if v.ReadOnly {
+		return &fs.PathError{Op: op, Path: path, Err: ErrReadOnly}
+	}
+	return nil
+}
+
+// ErrVersionNotFound is returned when a specific version of a file doesn't exist.
+// The underlying storage error stays wrapped.
+var ErrVersionNotFound = errors.New("version not found")
+
+// versionNotFound wraps a missing-file error with ErrVersionNotFound.
+// Other errors are returned as-is.
+func versionNotFound(err error) error {
+	if err != nil && errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %w", ErrVersionNotFound, err)
+	}
+	return err
+}
+
+// Read reads a specific version of a file identified by its timestamp.
+// Returns an error wrapping ErrVersionNotFound if the file doesn't exist.
+//
+// Example:
+//
+//	data, err := vfs.Read(file, timestamp)
+//	if errors.Is(err, versionfs.ErrVersionNotFound) {
+//	    fmt.Println("No such version")
+//	} else if err != nil {
+//	    log.Fatal(err)
+//	}
+func (v *VersionFS) Read(file File, ts Timestamp) ([]byte, error) {
+	return v.ReadCtx(context.Background(), file, ts)
+}
+
+// ReadCtx works like Read, but returns the context error if ctx is done before or after the file is read.
+func (v *VersionFS) ReadCtx(ctx context.Context, file File, ts Timestamp) ([]byte, error) {
+	info := newOpInfo(OpRead, file)
+	info.Timestamp = ts
+	end := v.instrument(ctx, &info)
+	data, err := v.read(ctx, file, ts)
+	if v.fallback != nil && errors.Is(err, ErrVersionNotFound) {
+		data, err = v.readFallback(ctx, file, ts)
+	}
+	info.Bytes = int64(len(data))
+	end(err)
+	return data, err
+}
+
+// read implements ReadCtx.
+func (v *VersionFS) read(ctx context.Context, file File, ts Timestamp) ([]byte, error) {
+	v.logger().Debugf("Reading file %s/%s.%s.%s", file.Dir(), file.Name(), file.Ext(), ts)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	name, filepath := v.resolvedPaths(file, ts)
+	if err := v.confine("read", name); err != nil {
+		return nil, err
+	}
+	if err := v.restrict("read", name); err != nil {
+		return nil, err
+	}
+	var gen uint64
+	if v.ReadCacheBytes > 0 && v.readCache != nil {
+		var data []byte
+		var ok bool
+		if data, gen, ok = v.cachedRead(name); ok {
+			return data, nil
+		}
+	}
+	data, err := v.backend().ReadFile(filepath)
+	if err != nil {
+		return nil, versionNotFound(err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if v.VerifyOnRead {
+		if err := v.verify(name, data); err != nil {
+			return nil, err
+		}
+	}
+	if v.ReadCacheBytes > 0 && v.readCache != nil {
+		v.storeRead(name, data, gen)
+	}
+	return data, nil
+}
+
+// ReadRange reads length bytes of a specific version of a file, starting at offset.
+// A negative length reads to the end of the file. Reading past the end of the file
+// returns the bytes that are available, possibly none.
+// Returns an error if offset is negative, or an error wrapping ErrVersionNotFound
+// if the file doesn't exist.
+//
+// Example:
+//
+//	data, err := vfs.ReadRange(file, timestamp, 1024, 512)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+func (v *VersionFS) ReadRange(file File, ts Timestamp, offset, length int64) ([]byte, error) {
+	info := newOpInfo(OpReadRange, file)
+	info.Timestamp = ts
+	end := v.instrument(context.Background(), &info)
+	data, err := v.readRange(file, ts, offset, length)
+	info.Bytes = int64(len(data))
+	end(err)
+	return data, err
+}
+
+// readRange implements ReadRange.
+func (v *VersionFS) readRange(file File, ts Timestamp, offset, length int64) ([]byte, error) {
+	v.logger().Debugf("Reading range %d+%d of file %s/%s.%s.%s", offset, length, file.Dir(), file.Name(), file.Ext(), ts)
+	if offset < 0 {
+		return nil, fmt.Errorf("invalid negative offset %d", offset)
+	}
+	if err := v.confine("read", v.resolvePath(file, ts)); err != nil {
+		return nil, err
+	}
+	f, err := v.backend().Open(path_.Join(v.RootPath, v.resolvePath(file, ts)))
+	if err != nil {
+		return nil, versionNotFound(err)
+	}
+	defer func() { _ = f.Close() }()
+	if seeker, ok := f.(io.Seeker); ok {
+		if _, err := seeker.Seek(offset, io.SeekStart); err != nil {
+			return nil, err
+		}
+	} else if _, err := io.CopyN(io.Discard, f, offset); err != nil && err != io.EOF {
+		return nil, err
+	}
+	var r io.Reader = f
+	if length >= 0 {
+		r = io.LimitReader(f, length)
+	}
+	return io.ReadAll(r)
+}
+
+// Remove deletes a specific version of a file identified by its timestamp.
+// Returns an error wrapping ErrVersionNotFound if the file doesn't exist,
+// or an error if it cannot be deleted.
+//
+// Example:
+//
+//	err := vfs.Remove(file, timestamp)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+func (v *VersionFS) Remove(file File, ts Timestamp) error {
+	info := newOpInfo(OpRemove, file)
+	info.Timestamp = ts
+	end := v.instrument(context.Background(), &info)
+	err := v.remove(file, ts)
+	end(err)
+	return err
+}
+
+// remove implements Remove.
+func (v *VersionFS) remove(file File, ts Timestamp) error {
+	v.logger().Debugf("remove file %s/%s.%s.%s", file.Dir(), file.Name(), file.Ext(), ts)
+	name, filepath := v.resolvedPaths(file, ts)
+	if err := v.confine("remove", name); err != nil {
+		return err
+	}
+	if err := v.checkWritable("remove", Path(file, ts)); err != nil {
+		return err
+	}
+	if v.DryRun {
+		if _, err := v.backend().Stat(filepath); err != nil {
+			return versionNotFound(err)
+		}
+		v.plan.record(PlannedOp{Op: OpRemove, Path: name})
+		return nil
+	}
+	done := v.unclaim(name)
+	err := v.backend().Remove(filepath)
+	done(err)
+	v.invalidate(name)
+	return versionNotFound(err)
+}
+
+// Reset replaces the whole history of a file with a single version holding data and
+// returns its timestamp. The new version is written before the older ones are removed,
+// so concurrent readers always see at least one version. If some older versions can't be
+// removed, Reset still returns the new timestamp, with the joined removal errors.
+//
+// Example:
+//
+//	ts, err := vfs.Reset(file, []byte("known good"))
+func (v *VersionFS) Reset(file File, data []byte) (Timestamp, error) {
+	ts, err := v.Write(file, data)
+	if err != nil {
+		return Timestamp{}, err
+	}
+	versions, err := v.localVersions(file)
+	if err != nil {
+		return ts, err
+	}
+	var errs []error
+	for _, version := range versions {
+		if version.String() == ts.String() {
+			continue
+		}
+		if err := v.Remove(file, version); err != nil {
+			errs = append(errs, fmt.Errorf("removing version %s: %w", version, err))
+		}
+	}
+	return ts, errors.Join(errs...)
+}
+
+// New creates a new File instance using a registered constructor.
+// Panics if the file type has not been registered or if its constructor fails.
+//
+// Example:
+//
+//	file := vfs.New(LeagueFileType, 2023)
+func (v *VersionFS) New(ftype FileType, args ...any) File {
+	file, err := v.NewE(ftype, args...)
+	if err != nil {
+		panic(err)
+	}
+	return file
+}
+
+// NewE creates a new File instance using a registered constructor.
+// Returns an error wrapping ErrNotRegistered if the file type has not been registered,
+// or the error returned by the constructor.
+//
+// Example:
+//
+//	file, err := vfs.NewE(LeagueFileType, 1850)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+func (v *VersionFS) NewE(ftype FileType, args ...any) (File, error) {
+	v.mu.RLock()
+	c, ok := v.constructors[ftype]
+	v.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("file type %s %w", v.TypeName(ftype), ErrNotRegistered)
+	}
+	file, err := c(args...)
+	if err != nil {
+		return nil, fmt.Errorf("file type %s: %w", v.TypeName(ftype), err)
+	}
+	v.recordType(file, ftype)
+	return file, nil
+}
+
+// recordType remembers the FileType of the Go type of a file created by New. A Go type
+// created for several file types is marked as shared instead, whatever the order of the calls.
+func (v *VersionFS) recordType(file File, ftype FileType) {
+	if _, ok := file.(TypedFile); ok {
+		return
+	}
+	rtype := reflect.TypeOf(file)
+	v.mu.RLock()
+	known, ok := v.types[rtype]
+	shared := v.shared[rtype]
+	v.mu.RUnlock()
+	if ok && known == ftype || shared {
+		return
+	}
+	v.mu.Lock()
+	known, ok = v.types[rtype]
+	conflict := ok && known != ftype
+	if conflict {
+		delete(v.types, rtype)
+		v.shared[rtype] = true
+	} else if !v.shared[rtype] {
+		v.types[rtype] = ftype
+	}
+	v.mu.Unlock()
+	if conflict {
+		v.logger().Warnf("file types %s and %s share the Go type %s, implement TypedFile to tell them apart", v.TypeName(known), v.TypeName(ftype), rtype)
+	}
+}
+
+// typeOf returns the FileType of a file, declared by a TypedFile or recorded by New for its
+// Go type. The Go types shared by several file types have none.
+func (v *VersionFS) typeOf(file File) (FileType, bool) {
+	if typed, ok := file.(TypedFile); ok {
+		return typed.FileType(), true
+	}
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	ftype, ok := v.types[reflect.TypeOf(file)]
+	return ftype, ok
+}
+
+// ErrNoVersions is returned when no versions of a file exist.
+var ErrNoVersions = errors.New("no version found")
+
+// ErrNoPreviousVersion is returned by PreviousVersion when a file has a single version.
+var ErrNoPreviousVersion = errors.New("no previous version found")
+
+// HasSome checks if any versions of a file exist.
+// Returns true if at least one version exists, false otherwise.
+// Like Find, it only matches the files with the extension of the file, and it returns as
+// soon as one is found, without listing the versions.
+//
+// Example:
+//
+//	exists, err := vfs.HasSome(file)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	if exists {
+//	    fmt.Println("File has versions")
+//	}
+func (v *VersionFS) HasSome(file File) (bool, error) {
+	info := newOpInfo(OpVersions, file)
+	end := v.instrument(context.Background(), &info)
+	found, err := v.hasSome(file)
+	end(err)
+	if err != nil || found || v.fallback == nil {
+		return found, err
+	}
+	return v.fallback.HasSome(file)
+}
+
+// hasSome implements HasSome for this root, stopping at the first version of file.
+func (v *VersionFS) hasSome(file File) (bool, error) {
+	if len(v.aliasesOf(file)) > 0 || v.Scheme != nil || v.Sharded {
+		versions, err := v.findAppendAll(context.Background(), nil, file.Dir(), file)
+		return len(versions) > 0, err
+	}
+	dir := slashed(file.Dir())
+	if err := v.confine("find", dir); err != nil {
+		return false, err
+	}
+	entries, err := v.backend().ReadDir(path_.Join(v.RootPath, dir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || v.hidden(entry.Name()) {
+			continue
+		}
+		if _, ok := v.matchVersion(dir, entry.Name(), file); ok && !v.empty(entry) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// LastVersion returns the most recent version (timestamp) of a file.
+// Returns ErrNoVersions if no versions exist.
+// Returns an empty slice if the directory doesn't exist.
+//
+// Example:
+//
+//	latest, err := vfs.LastVersion(file)
+//	if err == versionfs.ErrNoVersions {
+//	    fmt.Println("No versions found")
+//	} else if err != nil {
+//	    log.Fatal(err)
+//	}
+func (v *VersionFS) LastVersion(file File) (Timestamp, error) {
+	if v.listsThroughVersions(file) {
+		versions, err := v.Versions(file)
+		if err != nil {
+			return Timestamp{}, err
+		}
+		if len(versions) == 0 {
+			return Timestamp{}, ErrNoVersions
+		}
+		return versions[0], nil
+	}
+	info := newOpInfo(OpVersions, file)
+	end := v.instrument(context.Background(), &info)
+	latest, err := v.lastVersion(file)
+	end(err)
+	return latest, err
+}
+
+// listsThroughVersions reports whether the versions of file must be listed by Versions, rather
+// than by the single-pass scans of LastVersion, LastVersions, VersionsUnsorted, and VersionsMulti,
+// for them to return the same versions: with aliases, a fallback, a Scheme, the Sharded layout,
+// or the versions cache.
+func (v *VersionFS) listsThroughVersions(file File) bool {
+	return len(v.aliasesOf(file)) > 0 || v.fallback != nil || v.Scheme != nil || v.Sharded || v.VersionsCacheTTL > 0
+}
+
+// lastVersion implements LastVersion in a single pass over the directory of file, without
+// sorting nor collecting the versions. It returns the first version that Versions would.
+func (v *VersionFS) lastVersion(file File) (Timestamp, error) {
+	latest := make(map[File]Timestamp, 1)
+	if err := v.lastVersionsIn(slashed(file.Dir()), []File{file}, latest); err != nil {
+		return Timestamp{}, err
+	}
+	ts, ok := latest[file]
+	if !ok {
+		return Timestamp{}, ErrNoVersions
+	}
+	return ts, nil
+}
+
+// LastVersions returns the most recent version of each of files, like LastVersion, reading
+// each directory once for all the files it holds, e.g. to load the latest version of many
+// files sharing a directory at startup. Files without versions are absent from the map.
+// The files must be comparable, to be used as keys.
+//
+// Example:
+//
+//	latest, err := vfs.LastVersions(files)
+//	for file, ts := range latest {
+//	    fmt.Printf("%s: %s\n", versionfs.BasePath(file), ts)
+//	}
+func (v *VersionFS) LastVersions(files []File) (map[File]Timestamp, error) {
+	latest := make(map[File]Timestamp, len(files))
+	var dirs []string
+	byDir := make(map[string][]File)
+	seen := make(map[File]bool, len(files))
+	for _, file := range files {
+		if seen[file] {
+			continue
+		}
+		seen[file] = true
+		if v.listsThroughVersions(file) {
+			ts, err := v.LastVersion(file)
+			if errors.Is(err, ErrNoVersions) {
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			latest[file] = ts
+			continue
+		}
+		dir := slashed(file.Dir())
+		if _, ok := byDir[dir]; !ok {
+			dirs = append(dirs, dir)
+		}
+		byDir[dir] = append(byDir[dir], file)
+	}
+	for _, dir := range dirs {
+		info := OpInfo{Op: OpVersions, Dir: dir}
+		end := v.instrument(context.Background(), &info)
+		err := v.lastVersionsIn(dir, byDir[dir], latest)
+		end(err)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return latest, nil
+}
+
+// lastVersionsIn sets the latest version of each of files, all in dir, in latest, in a single
+// pass over dir. The files without versions are left out.
+func (v *VersionFS) lastVersionsIn(dir string, files []File, latest map[File]Timestamp) error {
+	if err := v.confine("versions", dir); err != nil {
+		return err
+	}
+	entries, err := v.backend().ReadDir(path_.Join(v.RootPath, dir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	latestNames := make([]string, len(files))
+	matchers := make([]Matcher, len(files))
+	for i, file := range files {
+		matchers[i] = v.matcher(file)
+	}
+	for _, entry := range entries {
+		if v.hidden(entry.Name()) {
+			continue
+		}
+		for i, file := range files {
+			ts, ok := v.matchName(matchers[i], dir, entry.Name())
+			if !ok || v.empty(entry) {
+				continue
+			}
+			// Versions breaks the ties between equal instants, such as timestamps of mixed
+			// resolutions, by the names in descending order
+			current := latest[file]
+			if latestNames[i] == "" || ts.time.After(current.time) || ts.time.Equal(current.time) && entry.Name() > latestNames[i] {
+				latest[file], latestNames[i] = ts, entry.Name()
+			}
+		}
+	}
+	return nil
+}
+
+// PreviousVersion returns the version just before the most recent one, e.g. to diff
+// the latest version against the prior one. Returns ErrNoVersions if no versions exist,
+// and ErrNoPreviousVersion if there is a single version.
+//
+// Example:
+//
+//	previous, err := vfs.PreviousVersion(file)
+//	if errors.Is(err, versionfs.ErrNoPreviousVersion) {
+//	    fmt.Println("First version, nothing to compare")
+//	}
+func (v *VersionFS) PreviousVersion(file File) (Timestamp, error) {
+	versions, err := v.Versions(file)
+	if err != nil {
+		return Timestamp{}, err
+	}
+	switch len(versions) {
+	case 0:
+		return Timestamp{}, ErrNoVersions
+	case 1:
+		return Timestamp{}, ErrNoPreviousVersion
+	}
+	return versions[1], nil
+}
+
+// VersionIndex returns the position of the version ts among the versions of a file, from 0
+// for the oldest, and the number of versions, e.g. to label it "version 3 of 7" with
+// index+1. Returns an error wrapping ErrVersionNotFound if ts isn't a version of the file.
+//
+// Example:
+//
+//	index, total, err := vfs.VersionIndex(file, ts)
+//	fmt.Printf("version %d of %d\n", index+1, total)
+func (v *VersionFS) VersionIndex(file File, ts Timestamp) (int, int, error) {
+	versions, err := v.Versions(file)
+	if err != nil {
+		return 0, 0, err
+	}
+	want := ts.String()
+	for i, version := range versions {
+		if version.String() == want {
+			return len(versions) - 1 - i, len(versions), nil
+		}
+	}
+	return 0, 0, fmt.Errorf("%w: %s", ErrVersionNotFound, Path(file, ts))
+}
+
+// VersionDates returns one timestamp per day having versions of a file, newest first,
+// for example to highlight the days a file was updated in a calendar. The timestamp of
+// each day is the newest version of that day, days are compared with SimpleDateString.
+//
+// Example:
+//
+//	dates, err := vfs.VersionDates(file)
+//	for _, ts := range dates {
+//	    fmt.Println(ts.SimpleDateString())
+//	}
+func (v *VersionFS) VersionDates(file File) ([]Timestamp, error) {
+	versions, err := v.Versions(file)
+	if err != nil {
+		return nil, err
+	}
+	dates := make([]Timestamp, 0, len(versions))
+	seen := make(map[string]bool)
+	for _, ts := range versions {
+		date := ts.SimpleDateString()
+		if !seen[date] {
+			seen[date] = true
+			dates = append(dates, ts)
+		}
+	}
+	return dates, nil
+}
+
+// VersionAt returns the version of a file effective at a given instant, the newest
+// version written at or before at. Returns ErrNoVersions if no version existed by then.
+// at may be any time.Time: the timestamps, interpreted in the local time zone like LatestAge,
+// are compared with the instant at, whatever its location. Since a timestamp is truncated to the second,
+// or to its coarser resolution, a version is effective from the start of its second, so an
+// at within that second, such as 14:05:23.9 for 14:05:23, returns it.
+//
+// Example:
+//
+//	ts, err := vfs.VersionAt(file, time.Date(2023, 10, 1, 0, 0, 0, 0, time.Local))
+func (v *VersionFS) VersionAt(file File, at time.Time) (Timestamp, error) {
+	versions, err := v.Versions(file)
+	if err != nil {
 		return Timestamp{}, err
 	}
-	ts := NewFromTime(time.Now())
-	filepath := Path(file, ts)
-	return ts, os.WriteFile(path_.Join(v.RootPath, filepath), data, 0644)
+	for _, ts := range versions {
+		if !ts.time.After(at) {
+			return ts, nil
+		}
+	}
+	return Timestamp{}, ErrNoVersions
 }
 
-// Read reads a specific version of a file identified by its timestamp.
-// Returns an error if the file doesn't exist.
+// LatestAge returns how long ago the most recent version of a file was written.
+// Returns ErrNoVersions if no versions exist.
+// The age is computed from the timestamp in the filename, which has no time zone and is
+// interpreted in the local time zone, the one Write names the versions in.
 //
 // Example:
 //
-//	data, err := vfs.Read(file, timestamp)
-//	if err != nil {
-//	    log.Fatal(err)
+//	age, err := vfs.LatestAge(file)
+//	if err == nil && age > 24*time.Hour {
+//	    fmt.Println("League file is stale")
 //	}
-func (v *VersionFS) Read(file File, ts Timestamp) ([]byte, error) {
-	log.Debug().Msgf("Reading file %s/%s.%s.%s", file.Dir(), file.Name(), file.Ext(), ts)
-	return os.ReadFile(path_.Join(v.RootPath, Path(file, ts)))
+func (v *VersionFS) LatestAge(file File) (time.Duration, error) {
+	latest, err := v.LastVersion(file)
+	if err != nil {
+		return 0, err
+	}
+	return time.Since(latest.Time()), nil
 }
 
-// Remove deletes a specific version of a file identified by its timestamp.
-// Returns an error if the file doesn't exist or cannot be deleted.
+// Versions returns all versions (timestamps) of a file, sorted newest first.
+// Returns an empty slice if the directory doesn't exist or contains no matching files.
+// Only returns versions for files that match the exact name and extension.
+//
+// The order always compares the parsed timestamps, not the filenames; there is no option
+// to turn it off. The filenames are sorted lexically first, which already gives the right
+// order for fixed-width timestamps. Every call then checks that order against the
+// timestamps, one comparison per version, and only sorts the timestamps again by value,
+// in O(n log n), when it is wrong, such as with timestamps of mixed resolutions or versions
+// stored under aliases with other extensions. Find, ListVersions, and FindAnyExt give the
+// same guarantee at the same cost.
 //
 // Example:
 //
-//	err := vfs.Remove(file, timestamp)
+//	versions, err := vfs.Versions(file)
 //	if err != nil {
 //	    log.Fatal(err)
 //	}
-func (v *VersionFS) Remove(file File, ts Timestamp) error {
-	log.Debug().Msgf("remove file %s/%s.%s.%s", file.Dir(), file.Name(), file.Ext(), ts)
-	return os.Remove(path_.Join(v.RootPath, Path(file, ts)))
+//	for _, ts := range versions {
+//	    fmt.Printf("Version: %s\n", ts)
+//	}
+func (v *VersionFS) Versions(file File) ([]Timestamp, error) {
+	return v.VersionsCtx(context.Background(), file)
 }
 
-// New creates a new File instance using a registered constructor.
-// Panics if the file type has not been registered.
-//
-// Example:
-//
-//	file := vfs.New(LeagueFileType, 2023)
-func (v *VersionFS) New(ftype FileType, args ...any) File {
-	c, ok := v.constructors[ftype]
-	if !ok {
-		panic(fmt.Errorf("file type %d not registered", ftype))
+// VersionsCtx works like Versions, but checks ctx between directory entries and returns
+// the context error as soon as it is done.
+func (v *VersionFS) VersionsCtx(ctx context.Context, file File) ([]Timestamp, error) {
+	return v.versionsCtx(ctx, file, true)
+}
+
+// localVersions works like Versions but ignores the fallback, for the operations
+// modifying this root. The directory is listed again rather than taken from the cache.
+func (v *VersionFS) localVersions(file File) ([]Timestamp, error) {
+	v.invalidate(file.Dir())
+	return v.versionsCtx(context.Background(), file, false)
+}
+
+// localLastVersion works like LastVersion but ignores the fallback.
+func (v *VersionFS) localLastVersion(file File) (Timestamp, error) {
+	versions, err := v.localVersions(file)
+	if err != nil {
+		return Timestamp{}, err
+	}
+	if len(versions) == 0 {
+		return Timestamp{}, ErrNoVersions
 	}
-	return c(args...)
+	return versions[0], nil
 }
 
-// ErrNoVersions is returned when no versions of a file exist.
-var ErrNoVersions = errors.New("no version found")
+// versionsCtx implements VersionsCtx, merging the versions of the fallback if withFallback is set.
+func (v *VersionFS) versionsCtx(ctx context.Context, file File, withFallback bool) ([]Timestamp, error) {
+	info := newOpInfo(OpVersions, file)
+	end := v.instrument(ctx, &info)
+	versions, err := v.allVersions(ctx, file)
+	if err == nil && withFallback && v.fallback != nil {
+		versions, err = v.withFallback(ctx, file, versions)
+	}
+	end(err)
+	return versions, err
+}
 
-// HasSome checks if any versions of a file exist.
-// Returns true if at least one version exists, false otherwise.
+// allVersions implements VersionsCtx, including the versions stored under aliases.
+func (v *VersionFS) allVersions(ctx context.Context, file File) ([]Timestamp, error) {
+	if len(v.aliasesOf(file)) == 0 {
+		return v.versions(ctx, file)
+	}
+	listed, err := v.listVersions(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]Timestamp, len(listed))
+	for i, lv := range listed {
+		versions[i] = lv.Timestamp
+	}
+	return versions, nil
+}
+
+// VersionsUnsorted works like Versions but doesn't sort the versions, for callers building
+// a set or a map from them, which saves the sorting in directories with many versions.
+// The order is unspecified: it is the order of the directory listing, and may change from
+// one call to the next. With aliases, a fallback, a Scheme, the Sharded layout, or the
+// versions cache, the versions are listed by Versions, and sorted.
 //
 // Example:
 //
-//	exists, err := vfs.HasSome(file)
-//	if err != nil {
-//	    log.Fatal(err)
-//	}
-//	if exists {
-//	    fmt.Println("File has versions")
+//	versions, err := vfs.VersionsUnsorted(file)
+//	seen := make(map[string]bool, len(versions))
+//	for _, ts := range versions {
+//	    seen[ts.String()] = true
 //	}
-func (v *VersionFS) HasSome(file File) (bool, error) {
-	versions, err := v.Versions(file)
-	if err != nil {
-		return false, err
+func (v *VersionFS) VersionsUnsorted(file File) ([]Timestamp, error) {
+	if v.listsThroughVersions(file) {
+		return v.Versions(file)
 	}
-	return len(versions) > 0, nil
+	info := newOpInfo(OpVersions, file)
+	end := v.instrument(context.Background(), &info)
+	versions, err := v.scanVersions(context.Background(), file, false)
+	end(err)
+	return versions, err
 }
 
-// LastVersion returns the most recent version (timestamp) of a file.
-// Returns ErrNoVersions if no versions exist.
-// Returns an empty slice if the directory doesn't exist.
+// VersionsPage returns at most limit versions of a file, newest first like Versions,
+// skipping the offset newest ones, and the total number of versions, e.g. to page through
+// the history of a file in an API. An offset past the end returns an empty page.
 //
 // Example:
 //
-//	latest, err := vfs.LastVersion(file)
-//	if err == versionfs.ErrNoVersions {
-//	    fmt.Println("No versions found")
-//	} else if err != nil {
-//	    log.Fatal(err)
-//	}
-func (v *VersionFS) LastVersion(file File) (Timestamp, error) {
+//	page, total, err := vfs.VersionsPage(file, 20*pageIndex, 20)
+//	pages := (total + 19) / 20
+func (v *VersionFS) VersionsPage(file File, offset, limit int) ([]Timestamp, int, error) {
+	if offset < 0 {
+		return nil, 0, fmt.Errorf("invalid negative offset %d", offset)
+	}
+	if limit < 0 {
+		return nil, 0, fmt.Errorf("invalid negative limit %d", limit)
+	}
 	versions, err := v.Versions(file)
 	if err != nil {
-		return Timestamp{}, err
+		return nil, 0, err
 	}
-	if len(versions) == 0 {
-		return Timestamp{}, ErrNoVersions
+	total := len(versions)
+	if offset >= total {
+		return []Timestamp{}, total, nil
 	}
-	return versions[0], nil
+	versions = versions[offset:]
+	if limit < len(versions) {
+		versions = versions[:limit]
+	}
+	return versions, total, nil
 }
 
-// Versions returns all versions (timestamps) of a file, sorted newest first.
-// Returns an empty slice if the directory doesn't exist or contains no matching files.
-// Only returns versions for files that match the exact name and extension.
+// VersionsMulti returns the versions of each of files, like Versions, reading each directory
+// once for all the files it holds, e.g. to list many files sharing a directory on every page
+// of a report. Every file is in the map, with the same versions in the same order as
+// Versions. The files must be comparable, to be used as keys.
 //
 // Example:
 //
-//	versions, err := vfs.Versions(file)
-//	if err != nil {
-//	    log.Fatal(err)
-//	}
-//	for _, ts := range versions {
-//	    fmt.Printf("Version: %s\n", ts)
+//	versions, err := vfs.VersionsMulti(rosters)
+//	for _, roster := range rosters {
+//	    fmt.Printf("%s: %d versions\n", versionfs.BasePath(roster), len(versions[roster]))
 //	}
-func (v *VersionFS) Versions(file File) ([]Timestamp, error) {
-	entries, err := os.ReadDir(path_.Join(v.RootPath, file.Dir()))
+func (v *VersionFS) VersionsMulti(files []File) (map[File][]Timestamp, error) {
+	listed := make(map[File][]Timestamp, len(files))
+	var dirs []string
+	byDir := make(map[string][]File)
+	for _, file := range files {
+		if _, ok := listed[file]; ok {
+			continue
+		}
+		listed[file] = nil
+		if v.listsThroughVersions(file) {
+			versions, err := v.Versions(file)
+			if err != nil {
+				return nil, err
+			}
+			listed[file] = versions
+			continue
+		}
+		dir := slashed(file.Dir())
+		if _, ok := byDir[dir]; !ok {
+			dirs = append(dirs, dir)
+		}
+		byDir[dir] = append(byDir[dir], file)
+	}
+	for _, dir := range dirs {
+		info := OpInfo{Op: OpVersions, Dir: dir}
+		end := v.instrument(context.Background(), &info)
+		versions, err := v.scanDir(context.Background(), dir, byDir[dir], true)
+		end(err)
+		if err != nil {
+			return nil, err
+		}
+		for i, file := range byDir[dir] {
+			listed[file] = versions[i]
+		}
+	}
+	return listed, nil
+}
+
+// versions lists the versions stored under the current name of a file, ignoring aliases,
+// through the cache when VersionsCacheTTL is set.
+func (v *VersionFS) versions(ctx context.Context, file File) ([]Timestamp, error) {
+	if v.VersionsCacheTTL > 0 && v.cache != nil {
+		return v.cachedVersions(ctx, file)
+	}
+	return v.readVersions(ctx, file)
+}
+
+// readVersions implements versions, listing the directory of file.
+func (v *VersionFS) readVersions(ctx context.Context, file File) ([]Timestamp, error) {
+	if v.Scheme != nil {
+		return v.findAppend(ctx, nil, file.Dir(), file)
+	}
+	return v.scanVersions(ctx, file, true)
+}
+
+// scanVersions implements versions for the default layout, sorting the versions newest
+// first if sorted is set.
+func (v *VersionFS) scanVersions(ctx context.Context, file File, sorted bool) ([]Timestamp, error) {
+	versions, err := v.scanDir(ctx, file.Dir(), []File{file}, sorted)
+	if err != nil {
+		return nil, err
+	}
+	return versions[0], nil
+}
+
+// scanDir lists the versions of files, all in dir, in a single pass over dir, and returns
+// them in the order of files, each like scanVersions.
+func (v *VersionFS) scanDir(ctx context.Context, dir string, files []File, sorted bool) ([][]Timestamp, error) {
+	if err := v.confine("versions", dir); err != nil {
+		return nil, err
+	}
+	versions := make([][]Timestamp, len(files))
+	entries, err := v.backend().ReadDir(path_.Join(v.RootPath, slashed(dir)))
 	if err != nil {
-		if os.IsNotExist(err) {
-			return []Timestamp{}, nil
+		if errors.Is(err, fs.ErrNotExist) {
+			for i := range versions {
+				versions[i] = []Timestamp{}
+			}
+			return versions, nil
 		}
 		return nil, err
 	}
-	var versions []Timestamp
-	fname := file.Name()
-	sort.SliceStable(entries, func(i, j int) bool {
-		return entries[i].Name() > entries[j].Name()
-	})
+	// filter before sorting, the directory may hold many entries of other files
+	names := make([][]string, len(files))
+	matchers := make([]Matcher, len(files))
+	for i, file := range files {
+		matchers[i] = v.matcher(file)
+	}
 	for _, entry := range entries {
-		if strings.HasPrefix(entry.Name(), fname) { // AND extension
-			rest := entry.Name()[len(fname):]
-			// next char has to be a dot
-			if len(rest) == 0 || !strings.HasPrefix(rest, ".") {
-				log.Warn().Msgf("unexpected file: %s/%s", file.Dir(), entry.Name())
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if v.hidden(entry.Name()) {
+			continue
+		}
+		for i, file := range files {
+			if entry.IsDir() && entry.Name() == path_.Base(shardDir(file)) {
 				continue
 			}
-			rest = rest[1:]
-			tokens := strings.Split(rest, ".")
-			ts, err := NewTimestamp(tokens[len(tokens)-1])
-			if err != nil {
-				log.Warn().Msgf("unexpected timestamp for file: %s/%s", file.Dir(), entry.Name())
-				continue
+			if ts, ok := v.matchName(matchers[i], dir, entry.Name()); ok && !v.empty(entry) {
+				versions[i] = append(versions[i], ts)
+				if sorted {
+					names[i] = append(names[i], entry.Name())
+				}
 			}
-			versions = append(versions, ts)
+		}
+	}
+	for i, file := range files {
+		if sorted {
+			sort.Sort(byNameDesc{versions[i], names[i]})
+		}
+		if versions[i], err = v.appendShards(ctx, versions[i], file.Dir(), file); err != nil {
+			return nil, err
+		}
+		if sorted {
+			// timestamps of mixed resolutions don't always sort lexically in timestamp order
+			sortNewestFirst(versions[i])
 		}
 	}
 	return versions, nil
 }
 
+// byNameDesc sorts versions by their filenames in descending order.
+type byNameDesc struct {
+	versions []Timestamp
+	names    []string
+}
+
+func (s byNameDesc) Len() int           { return len(s.names) }
+func (s byNameDesc) Less(i, j int) bool { return s.names[i] > s.names[j] }
+func (s byNameDesc) Swap(i, j int) {
+	s.versions[i], s.versions[j] = s.versions[j], s.versions[i]
+	s.names[i], s.names[j] = s.names[j], s.names[i]
+}
+
 // Detect checks if a filename matches the given file type pattern and extracts the timestamp.
 // Returns the timestamp if the filename matches, or an error describing why it doesn't match.
 // Validates that the filename has the correct name, extension, and timestamp format.
 //
 // Expected filename format: name.ext.timestamp or name.ext1.ext2.timestamp
+// A path can be given, such as one found by filepath.Walk, only its last element is checked.
 //
 // Example:
 //
@@ -265,43 +1629,174 @@ func (v *VersionFS) Versions(file File) ([]Timestamp, error) {
 //	    fmt.Printf("Found version: %s\n", ts)
 //	}
 func (v *VersionFS) Detect(filename string, file File) (Timestamp, error) {
-	fname := file.Name()
-	fext := file.Ext()
+	filename = path_.Base(slashed(filename))
+	if v.Scheme != nil {
+		return v.Scheme.Parse(filename, file)
+	}
+	return v.matcher(file).MatchError(filename)
+}
 
-	// Check if filename starts with the file name
-	if !strings.HasPrefix(filename, fname) {
-		return Timestamp{}, fmt.Errorf("filename %q does not match file name %q", filename, fname)
+// DetectDir runs Detect on every file of a directory, for reconciliation reports: it returns
+// the timestamps of the versions of file, newest first, and the reasons the other filenames
+// were rejected, in lexical order, each one naming its file. Versions stored under an alias
+// of the file type are matched. Subdirectories are ignored.
+// Returns empty results if the directory doesn't exist.
+//
+// Example:
+//
+//	matched, rejected, err := vfs.DetectDir("2023/league", file)
+//	for _, reason := range rejected {
+//	    fmt.Println("skipped:", reason)
+//	}
+func (v *VersionFS) DetectDir(dir string, file File) (matched []Timestamp, rejected []string, err error) {
+	dir = slashed(dir)
+	if err := v.confine("detect", dir); err != nil {
+		return nil, nil, err
+	}
+	entries, err := v.backend().ReadDir(path_.Join(v.RootPath, dir))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return []Timestamp{}, []string{}, nil
+		}
+		return nil, nil, err
+	}
+	aliases := v.aliasesOf(file)
+	matched, rejected = []Timestamp{}, []string{}
+	for _, entry := range entries {
+		if entry.IsDir() || v.hidden(entry.Name()) {
+			continue
+		}
+		ts, err := v.Detect(entry.Name(), file)
+		for i := 0; err != nil && i < len(aliases); i++ {
+			var aliasErr error
+			if ts, aliasErr = v.Detect(entry.Name(), aliasFile{dir: dir, alias: aliases[i]}); aliasErr == nil {
+				err = nil
+			}
+		}
+		if err != nil {
+			rejected = append(rejected, err.Error())
+			continue
+		}
+		matched = append(matched, ts)
 	}
+	sortNewestFirst(matched)
+	return matched, rejected, nil
+}
 
-	rest := filename[len(fname):]
+// VerifyDir lists the files of a directory named after the versions of file, name.ext.,
+// whose timestamp doesn't parse, in lexical order. Find and Versions silently skip them;
+// VerifyDir surfaces them, e.g. to check that a directory is clean before a migration.
+// Unrelated files are ignored, as well as the versions of a longer extension
+// (themes.csv.gz.20231019140523 for themes.csv) and the checksum sidecars of valid versions.
+// Subdirectories and aliases are ignored. Returns an empty list if the directory doesn't exist.
+//
+// Example:
+//
+//	bad, err := vfs.VerifyDir("2023/league", file)
+//	// bad: ["league.txt.2023-10-19", "league.txt.20231019140523.bak"]
+func (v *VersionFS) VerifyDir(dir string, file File) (bad []string, err error) {
+	dir = slashed(dir)
+	if err := v.confine("verify", dir); err != nil {
+		return nil, err
+	}
+	entries, err := v.backend().ReadDir(path_.Join(v.RootPath, dir))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+	bad = []string{}
+	for _, entry := range entries {
+		if entry.IsDir() || v.hidden(entry.Name()) {
+			continue
+		}
+		if token, ok := v.timestampToken(entry.Name(), file); ok && !validTimestampToken(token) {
+			bad = append(bad, entry.Name())
+		}
+	}
+	sort.Strings(bad)
+	return bad, nil
+}
 
-	// Next char must be a dot
-	if len(rest) == 0 || !strings.HasPrefix(rest, ".") {
-		return Timestamp{}, fmt.Errorf("filename %q has invalid format, expected dot after name", filename)
+// timestampToken returns what follows name.ext. in filename, if it starts with it.
+func (v *VersionFS) timestampToken(filename string, file File) (string, bool) {
+	rest, ok := strings.CutPrefix(filename, file.Name()+".")
+	ext := file.Ext()
+	if !ok || len(rest) <= len(ext) || rest[len(ext)] != '.' || !v.matchExt(rest[:len(ext)], ext) {
+		return "", false
 	}
+	return rest[len(ext)+1:], true
+}
+
+// validTimestampToken reports whether token, what follows name.ext. in a filename, is a
+// timestamp, the checksum sidecar of one, or ends with one, in which case the file is a
+// version of a longer extension.
+func validTimestampToken(token string) bool {
+	token = strings.TrimSuffix(token, ChecksumSuffix)
+	_, err := NewTimestamp(token[strings.LastIndexByte(token, '.')+1:])
+	return err == nil
+}
+
+// hidden reports whether a directory entry is a dotfile ignored with IgnoreDotfiles.
+func (v *VersionFS) hidden(name string) bool {
+	return v.IgnoreDotfiles && strings.HasPrefix(name, ".")
+}
 
-	rest = rest[1:] // Remove the dot
-	tokens := strings.Split(rest, ".")
+// empty reports whether entry is a zero-byte file, ignored by the listings with SkipEmpty.
+// The entries that can't be stat'ed, removed since the listing, are ignored as well.
+func (v *VersionFS) empty(entry fs.DirEntry) bool {
+	if !v.SkipEmpty || entry.IsDir() {
+		return false
+	}
+	info, err := entry.Info()
+	return err != nil || info.Size() == 0
+}
 
-	// Expected format: name.ext.timestamp or name.ext1.ext2.timestamp
-	// We need at least extension.timestamp
-	if len(tokens) < 2 {
-		return Timestamp{}, fmt.Errorf("filename %q has invalid format, expected ext.timestamp", filename)
+// matchExt reports whether an extension found in a filename matches the expected one,
+// honoring CaseInsensitiveExt.
+func (v *VersionFS) matchExt(actual, expected string) bool {
+	if v.CaseInsensitiveExt {
+		return strings.EqualFold(actual, expected)
 	}
+	return actual == expected
+}
 
-	// Check if extension matches (handle multi-part extensions like csv.gz)
-	// Join all tokens except the last one (which should be timestamp)
-	actualExt := strings.Join(tokens[:len(tokens)-1], ".")
-	if actualExt != fext {
-		return Timestamp{}, fmt.Errorf("filename %q has extension %q but expected %q", filename, actualExt, fext)
+// ParseFilename splits a versioned filename into its name, extension, and timestamp
+// without knowing its file type. The name is everything before the first dot, the
+// timestamp is the last token and the extension is everything in between, so that
+// multi-part extensions are supported but names can't contain dots.
+// Returns an error if the filename doesn't have the name.ext.timestamp format.
+//
+// Example:
+//
+//	name, ext, ts, err := versionfs.ParseFilename("themes.csv.gz.20231019140523")
+//	// name: "themes", ext: "csv.gz", ts: 2023-10-19 14:05:23
+func ParseFilename(filename string) (name, ext string, ts Timestamp, err error) {
+	first := strings.IndexByte(filename, '.')
+	last := strings.LastIndexByte(filename, '.')
+	if first <= 0 || first == last || last == first+1 {
+		return "", "", Timestamp{}, fmt.Errorf("filename %q has invalid format, expected name.ext.timestamp", filename)
+	}
+	ts, err = NewTimestamp(filename[last+1:])
+	if err != nil {
+		return "", "", Timestamp{}, fmt.Errorf("filename %q has invalid timestamp: %w", filename, err)
 	}
+	return filename[:first], filename[first+1 : last], ts, nil
+}
 
-	// Last token should be the timestamp
-	ts, err := NewTimestamp(tokens[len(tokens)-1])
+// TimestampFromFilename parses the timestamp of a versioned filename, the last dot-separated
+// token, ignoring the name and extension entirely. It is a lenient helper for filenames
+// from a trusted source; use Detect to also validate the name and extension.
+//
+// Example:
+//
+//	ts, err := versionfs.TimestampFromFilename("league.json.20231019140523")
+func TimestampFromFilename(filename string) (Timestamp, error) {
+	ts, err := NewTimestamp(filename[strings.LastIndexByte(filename, '.')+1:])
 	if err != nil {
 		return Timestamp{}, fmt.Errorf("filename %q has invalid timestamp: %w", filename, err)
 	}
-
 	return ts, nil
 }
 
@@ -322,67 +1817,204 @@ func (v *VersionFS) Detect(filename string, file File) (Timestamp, error) {
 //	    // process data...
 //	}
 func (v *VersionFS) Find(dir string, file File) ([]Timestamp, error) {
-	entries, err := os.ReadDir(path_.Join(v.RootPath, dir))
+	return v.FindCtx(context.Background(), dir, file)
+}
+
+// FindCtx works like Find, but checks ctx between directory entries and returns
+// the context error as soon as it is done.
+func (v *VersionFS) FindCtx(ctx context.Context, dir string, file File) ([]Timestamp, error) {
+	info := newOpInfo(OpFind, file)
+	info.Dir = dir
+	end := v.instrument(ctx, &info)
+	found, err := v.findAppendAll(ctx, nil, dir, file)
+	end(err)
+	return found, err
+}
+
+// FindAppend works like Find but appends the matching timestamps to dst, which is
+// truncated first, and returns the extended slice. Passing the previous result back
+// in lets hot polling loops recycle the backing array instead of allocating a new one.
+// The timestamps are sorted newest first.
+//
+// Example:
+//
+//	var buf []versionfs.Timestamp
+//	for range ticker.C {
+//	    buf, err = vfs.FindAppend(buf, "2023/league", file)
+//	    if err != nil {
+//	        log.Fatal(err)
+//	    }
+//	}
+func (v *VersionFS) FindAppend(dst []Timestamp, dir string, file File) ([]Timestamp, error) {
+	return v.findAppendAll(context.Background(), dst, dir, file)
+}
+
+// findAppendAll implements FindAppend, including the versions stored under aliases.
+func (v *VersionFS) findAppendAll(ctx context.Context, dst []Timestamp, dir string, file File) ([]Timestamp, error) {
+	dst, err := v.findAppend(ctx, dst, dir, file)
+	if err != nil || len(v.aliasesOf(file)) == 0 {
+		return dst, err
+	}
+	listed, err := v.withAliases(ctx, dir, file, dst)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return []Timestamp{}, nil
-		}
 		return nil, err
 	}
+	dst = dst[:0]
+	for _, lv := range listed {
+		dst = append(dst, lv.Timestamp)
+	}
+	return dst, nil
+}
 
-	var results []Timestamp
-	fname := file.Name()
-	fext := file.Ext()
-
-	// Sort by name descending (newest first)
-	sort.SliceStable(entries, func(i, j int) bool {
-		return entries[i].Name() > entries[j].Name()
-	})
+// findAppend implements FindAppend for the current name of a file, ignoring aliases.
+func (v *VersionFS) findAppend(ctx context.Context, dst []Timestamp, dir string, file File) ([]Timestamp, error) {
+	dst = dst[:0]
+	dir = slashed(dir)
+	if err := v.confine("find", dir); err != nil {
+		return nil, err
+	}
+	versionDir := v.versionDir(dir, file)
+	entries, err := v.backend().ReadDir(path_.Join(v.RootPath, versionDir))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			if dst == nil {
+				// keep the empty-not-nil convention for missing directories
+				return []Timestamp{}, nil
+			}
+			return dst, nil
+		}
+		return nil, err
+	}
 
+	// filter before sorting, like scanDir, the directory may hold many entries of other files
+	var names []string
 	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
+		if err := ctx.Err(); err != nil {
+			return nil, err
 		}
-
-		// Check if filename starts with the file name
-		if !strings.HasPrefix(entry.Name(), fname) {
+		if entry.IsDir() || v.hidden(entry.Name()) {
 			continue
 		}
+		if ts, ok := v.matchVersion(versionDir, entry.Name(), file); ok && !v.empty(entry) {
+			dst = append(dst, ts)
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Stable(byNameDesc{dst, names})
+	if dst, err = v.appendShards(ctx, dst, dir, file); err != nil {
+		return nil, err
+	}
 
-		rest := entry.Name()[len(fname):]
+	// mixed-case extensions and timestamps of mixed resolutions don't always sort
+	// lexically in timestamp order
+	sortNewestFirst(dst)
 
-		// Next char must be a dot
-		if len(rest) == 0 || !strings.HasPrefix(rest, ".") {
-			continue
+	return dst, nil
+}
+
+// matchVersion returns the timestamp of filename, an entry of dir, if it is a version of
+// file, parsed by the Scheme of the instance or matched by the Matcher of file.
+func (v *VersionFS) matchVersion(dir, filename string, file File) (Timestamp, bool) {
+	if v.Scheme != nil {
+		ts, err := v.Scheme.Parse(filename, file)
+		return ts, err == nil
+	}
+	return v.matchName(v.matcher(file), dir, filename)
+}
+
+// FindAnyExt searches a directory for the versions of a name with any extension, grouped
+// by extension and sorted newest first, to detect a file type whose Ext changed between
+// versions. The extension is everything between the name and the timestamp, so multi-part
+// extensions form their own group: "themes.csv.gz.<ts>" is grouped under "csv.gz", apart
+// from "themes.csv.<ts>". With CaseInsensitiveExt, extensions are grouped lower-cased.
+// Returns an empty map if the directory doesn't exist.
+//
+// Example:
+//
+//	groups, err := vfs.FindAnyExt("2023/league", "league")
+//	if len(groups) > 1 {
+//	    fmt.Println("league has versions with several extensions")
+//	}
+func (v *VersionFS) FindAnyExt(dir, name string) (map[string][]Timestamp, error) {
+	found, err := v.findAnyExt(slashed(dir), name)
+	if err != nil {
+		return nil, err
+	}
+	groups := make(map[string][]Timestamp, len(found))
+	for ext, versions := range found {
+		timestamps := make([]Timestamp, len(versions))
+		for i, version := range versions {
+			timestamps[i] = version.ts
 		}
+		groups[ext] = timestamps
+	}
+	return groups, nil
+}
 
-		rest = rest[1:] // Remove the dot
-		tokens := strings.Split(rest, ".")
+// extVersion is a version found by findAnyExt, with the extension it is stored with.
+type extVersion struct {
+	ts  Timestamp
+	ext string
+}
 
-		// Expected format: name.ext.timestamp or name.ext1.ext2.timestamp
-		// We need at least extension.timestamp
-		if len(tokens) < 2 {
+// findAnyExt implements FindAnyExt, keeping the extension of each version as stored, which
+// differs from the one of its group with CaseInsensitiveExt.
+func (v *VersionFS) findAnyExt(dir, name string) (map[string][]extVersion, error) {
+	if err := v.confine("find", dir); err != nil {
+		return nil, err
+	}
+	groups := make(map[string][]extVersion)
+	entries, err := v.backend().ReadDir(path_.Join(v.RootPath, dir))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return groups, nil
+		}
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || v.hidden(entry.Name()) || !strings.HasPrefix(entry.Name(), name+".") {
 			continue
 		}
-
-		// Check if extension matches (handle multi-part extensions like csv.gz)
-		// Join all tokens except the last one (which should be timestamp)
-		actualExt := strings.Join(tokens[:len(tokens)-1], ".")
-		if actualExt != fext {
+		rest := entry.Name()[len(name)+1:]
+		last := strings.LastIndexByte(rest, '.')
+		if last <= 0 {
 			continue
 		}
-
-		// Last token should be the timestamp
-		ts, err := NewTimestamp(tokens[len(tokens)-1])
+		ts, err := NewTimestamp(rest[last+1:])
 		if err != nil {
-			log.Warn().Msgf("unexpected timestamp for file: %s/%s", dir, entry.Name())
+			v.logger().Warnf("unexpected timestamp for file: %s/%s", dir, entry.Name())
 			continue
 		}
-
-		results = append(results, ts)
+		if v.empty(entry) {
+			continue
+		}
+		ext := rest[:last]
+		group := ext
+		if v.CaseInsensitiveExt {
+			group = strings.ToLower(ext)
+		}
+		groups[group] = append(groups[group], extVersion{ts: ts, ext: ext})
+	}
+	for _, versions := range groups {
+		newer := func(i, j int) bool {
+			return versions[i].ts.time.After(versions[j].ts.time)
+		}
+		if !sort.SliceIsSorted(versions, newer) {
+			sort.SliceStable(versions, newer)
+		}
 	}
+	return groups, nil
+}
 
-	return results, nil
+// sortNewestFirst sorts timestamps in place, newest first, comparing their time whatever
+// their resolution. Already sorted timestamps, the common case, are left untouched.
+func sortNewestFirst(timestamps []Timestamp) {
+	newer := func(i, j int) bool {
+		return timestamps[i].time.After(timestamps[j].time)
+	}
+	if !sort.SliceIsSorted(timestamps, newer) {
+		sort.SliceStable(timestamps, newer)
+	}
 }
 
 // PathExists checks if a path exists in the filesystem.
@@ -399,11 +2031,15 @@ func (v *VersionFS) Find(dir string, file File) ([]Timestamp, error) {
 //	    fmt.Println("Directory exists")
 //	}
 func (v *VersionFS) PathExists(path string) (bool, error) {
-	_, err := os.Stat(path_.Join(v.RootPath, path))
+	path = slashed(path)
+	if err := v.confine("stat", path); err != nil {
+		return false, err
+	}
+	_, err := v.backend().Stat(path_.Join(v.RootPath, path))
 	if err == nil {
 		return true, nil
 	}
-	if os.IsNotExist(err) {
+	if errors.Is(err, fs.ErrNotExist) {
 		return false, nil
 	}
 	return false, err
@@ -420,5 +2056,16 @@ func (v *VersionFS) PathExists(path string) (bool, error) {
 //	    log.Fatal(err)
 //	}
 func (v *VersionFS) MkdirAll(path string, perm os.FileMode) error {
-	return os.MkdirAll(path_.Join(v.RootPath, path), perm)
+	path = slashed(path)
+	if err := v.confine("mkdir", path); err != nil {
+		return err
+	}
+	if err := v.checkWritable("mkdir", path); err != nil {
+		return err
+	}
+	if v.DryRun {
+		v.plan.record(PlannedOp{Op: OpMkdir, Path: path})
+		return nil
+	}
+	return v.backend().MkdirAll(path_.Join(v.RootPath, path), perm)
 }
@@ -3,18 +3,17 @@ package versionfs
 import (
 	"errors"
 	"fmt"
-	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
+	"io/fs"
+	"math/rand"
 	"os"
 	"path"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 )
 
-func init() {
-	// Disable logging during tests
-	zerolog.SetGlobalLevel(zerolog.Disabled)
-}
-
 const (
 	LeagueFileType FileType = iota
 	RosterFileType
@@ -78,6 +77,66 @@ func newTmpVersionFS(tb testing.TB) (string, *VersionFS) {
 	return dir, vfs
 }
 
+func TestBasePath(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "2023/league/league.txt", BasePath(fileLeague{season: 2023}))
+	assert.Equal(t, "catalog/themes.csv.gz", BasePath(fileThemes{}))
+	assert.Equal(t, "2023/league/league.txt", BasePath(fileBackslash{}))
+
+	// the glob built from the base path matches the versions of the file only
+	vfs := New(t.TempDir())
+	file := fileLeague{season: 2023}
+	putVersion(t, vfs, file, "20231018140523", "first")
+	putVersion(t, vfs, file, "20231019140523", "second")
+	putRaw(t, vfs, "2023/league/league.json.20231019140523", "other extension")
+	matches, err := filepath.Glob(filepath.Join(vfs.RootPath, filepath.FromSlash(BasePath(file))+".*"))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(vfs.RootPath, "2023", "league", "league.txt.20231018140523"),
+		filepath.Join(vfs.RootPath, "2023", "league", "league.txt.20231019140523"),
+	}, matches)
+}
+
+// FuzzAppendPath checks that Path, AppendPath, and the paths joined to the root by the
+// instances are the ones formatted by fmt and path.Join, for any file and timestamp.
+func FuzzAppendPath(f *testing.F) {
+	f.Add("2023/league", "league", "txt", int64(1697724323), uint8(Second), "/tmp/data")
+	f.Add("", "league", "", int64(0), uint8(Day), ".")
+	f.Add(`2023\league\`, "themes", "csv.gz", int64(-62135596800), uint8(Minute), "./data/")
+	f.Add("../up", "", "json", int64(253402300799), uint8(Hour), "")
+	f.Fuzz(func(t *testing.T, dir, name, ext string, unix int64, res uint8, root string) {
+		file := aliasFile{dir: dir, alias: Alias{Name: name, Ext: ext}}
+		ts := NewFromTime(time.Unix(unix, 0).UTC()).Truncate(Resolution(res % 4))
+		want := fmt.Sprintf("%s/%s.%s.%s", slashed(dir), name, ext, ts)
+		if got := Path(file, ts); got != want {
+			t.Fatalf("Path = %q, want %q", got, want)
+		}
+		if got := string(AppendPath([]byte("prefix:"), file, ts)); got != "prefix:"+want {
+			t.Fatalf("AppendPath = %q, want %q", got, "prefix:"+want)
+		}
+		gotName, gotFull := joinPath(root, file, ts)
+		if gotName != want || gotFull != path.Join(root, want) {
+			t.Fatalf("joinPath = %q, %q, want %q, %q", gotName, gotFull, want, path.Join(root, want))
+		}
+	})
+}
+
+func TestAppendPath_Allocs(t *testing.T) {
+	var file File = fileThemes{}
+	ts, err := NewTimestamp("20231019140523")
+	assert.Nil(t, err)
+	buf := make([]byte, 0, 64)
+	assert.Zero(t, testing.AllocsPerRun(100, func() {
+		buf = AppendPath(buf[:0], file, ts)
+	}))
+	assert.Equal(t, 1.0, testing.AllocsPerRun(100, func() {
+		_ = Path(file, ts)
+	}))
+	assert.Equal(t, 1.0, testing.AllocsPerRun(100, func() {
+		_, _ = joinPath("/tmp/data", file, ts)
+	}))
+}
+
 // Test the new method - It has two registered types (league and roster), make sure the correct file object
 // is created. it should panic if we create a type that doesn't exists
 func TestVersionFS_New(t *testing.T) {
@@ -175,6 +234,173 @@ func TestVersionFS_LastVersion_MissingDir(t *testing.T) {
 	assert.Equal(t, ErrNoVersions, err)
 }
 
+// TestVersionFS_LastVersion_MatchesVersions checks LastVersion against the first of Versions
+// on random directories, mixing resolutions, extensions, names, and invalid timestamps.
+func TestVersionFS_LastVersion_MatchesVersions(t *testing.T) {
+	t.Parallel()
+	rng := rand.New(rand.NewSource(1))
+	file := fileLeague{season: 2023}
+	start := time.Date(2023, 10, 19, 0, 0, 0, 0, time.UTC)
+	formats := []string{"20060102150405", "200601021504", "2006010215", "20060102"}
+	names := []string{"league.txt", "league.json", "league.csv.gz", "other.txt", ".league.txt"}
+	for i := 0; i < 200; i++ {
+		vfs := NewMemory()
+		assert.Nil(t, vfs.Backend.MkdirAll(file.Dir(), 0755))
+		for n := rng.Intn(20); n > 0; n-- {
+			tm := start.Add(time.Duration(rng.Intn(72)) * time.Hour).Add(time.Duration(rng.Intn(3)) * time.Minute)
+			ts := tm.Format(formats[rng.Intn(len(formats))])
+			if rng.Intn(10) == 0 {
+				ts = "notatimestamp"
+			}
+			name := path.Join(file.Dir(), names[rng.Intn(len(names))]+"."+ts)
+			assert.Nil(t, vfs.Backend.WriteFile(name, []byte("data"), 0644))
+		}
+		versions, err := vfs.Versions(file)
+		assert.Nil(t, err)
+		latest, err := vfs.LastVersion(file)
+		if len(versions) == 0 {
+			assert.Equal(t, ErrNoVersions, err)
+			continue
+		}
+		assert.Nil(t, err)
+		assert.Equal(t, versions[0].String(), latest.String(), "directory %d", i)
+	}
+}
+
+// readDirCountingBackend counts the calls to ReadDir.
+type readDirCountingBackend struct {
+	Backend
+	readDirs int
+}
+
+func (b *readDirCountingBackend) ReadDir(name string) ([]fs.DirEntry, error) {
+	b.readDirs++
+	return b.Backend.ReadDir(name)
+}
+
+func TestVersionFS_LastVersions(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	backend := &readDirCountingBackend{Backend: vfs.Backend}
+	vfs.Backend = backend
+	var files []File
+	for i := 0; i < 5; i++ {
+		file := aliasFile{dir: "shared", alias: Alias{Name: fmt.Sprintf("file%d", i), Ext: "txt"}}
+		files = append(files, file)
+		if i == 4 {
+			continue // no versions
+		}
+		putVersion(t, vfs, file, "20231019140523", "old")
+		putVersion(t, vfs, file, fmt.Sprintf("2023102%d", i), "new")
+	}
+	// the same instant at two resolutions, like Versions the larger name wins
+	putRaw(t, vfs, "shared/file0.txt.202310200000", "minute")
+	league := fileLeague{season: 2023}
+	putVersion(t, vfs, league, "20231019140523", "league")
+	files = append(files, league, fileLeague{season: 2024}, league)
+
+	backend.readDirs = 0
+	latest, err := vfs.LastVersions(files)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, backend.readDirs)
+	assert.Len(t, latest, 5)
+	for _, file := range files {
+		expected, err := vfs.LastVersion(file)
+		if errors.Is(err, ErrNoVersions) {
+			assert.NotContains(t, latest, file)
+			continue
+		}
+		assert.Nil(t, err)
+		assert.Equal(t, expected.String(), latest[file].String(), BasePath(file))
+	}
+	assert.Equal(t, "202310200000", latest[files[0]].String())
+}
+
+// LastVersion, LastVersions, VersionsUnsorted, and VersionsMulti return the versions of
+// Versions with the options their single-pass scans don't support, such as a stale cache.
+func TestVersionFS_FastPaths_Options(t *testing.T) {
+	t.Parallel()
+	for name, configure := range map[string]func(t *testing.T, vfs *VersionFS, file File){
+		"Sharded": func(t *testing.T, vfs *VersionFS, file File) {
+			vfs.Sharded = true
+			ts, _ := NewTimestamp("20231020140523")
+			putRaw(t, vfs, ShardPath(file, ts), "sharded")
+		},
+		"VersionsCacheTTL": func(t *testing.T, vfs *VersionFS, file File) {
+			vfs.VersionsCacheTTL = time.Hour
+			_, err := vfs.Versions(file)
+			assert.Nil(t, err)
+			// added behind the cache, which isn't invalidated
+			putVersion(t, vfs, file, "20231020140523", "uncached")
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			vfs := NewMemory()
+			file := fileLeague{season: 2023}
+			putVersion(t, vfs, file, "20231019140523", "flat")
+			configure(t, vfs, file)
+			versions, err := vfs.Versions(file)
+			assert.Nil(t, err)
+
+			last, err := vfs.LastVersion(file)
+			assert.Nil(t, err)
+			assert.Equal(t, versions[0].String(), last.String())
+			latest, err := vfs.LastVersions([]File{file})
+			assert.Nil(t, err)
+			assert.Equal(t, versions[0].String(), latest[file].String())
+			unsorted, err := vfs.VersionsUnsorted(file)
+			assert.Nil(t, err)
+			assert.ElementsMatch(t, timestampStrings(versions), timestampStrings(unsorted))
+			listed, err := vfs.VersionsMulti([]File{file})
+			assert.Nil(t, err)
+			assert.Equal(t, timestampStrings(versions), timestampStrings(listed[file]))
+		})
+	}
+}
+
+func TestVersionFS_VersionsMulti(t *testing.T) {
+	t.Parallel()
+	rng := rand.New(rand.NewSource(1))
+	start := time.Date(2023, 10, 19, 0, 0, 0, 0, time.UTC)
+	formats := []string{"20060102150405", "200601021504", "2006010215", "20060102"}
+	names := []string{"league.txt", "league.json", "leagues.txt", "other.txt", ".league.txt"}
+	files := []File{
+		aliasFile{dir: "shared", alias: Alias{Name: "league", Ext: "txt"}},
+		aliasFile{dir: "shared", alias: Alias{Name: "leagues", Ext: "txt"}},
+		aliasFile{dir: "shared", alias: Alias{Name: "other", Ext: "txt"}},
+		aliasFile{dir: "shared", alias: Alias{Name: "missing", Ext: "txt"}},
+		fileLeague{season: 2023},
+		fileLeague{season: 2024},
+		aliasFile{dir: "shared", alias: Alias{Name: "league", Ext: "txt"}},
+	}
+	for i := 0; i < 50; i++ {
+		vfs := NewMemory()
+		vfs.Logger = NopLogger{}
+		backend := &readDirCountingBackend{Backend: vfs.Backend}
+		vfs.Backend = backend
+		assert.Nil(t, backend.MkdirAll("shared", 0755))
+		putVersion(t, vfs, fileLeague{season: 2023}, "20231019140523", "league")
+		for n := rng.Intn(30); n > 0; n-- {
+			tm := start.Add(time.Duration(rng.Intn(72)) * time.Hour).Add(time.Duration(rng.Intn(3)) * time.Minute)
+			ts := tm.Format(formats[rng.Intn(len(formats))])
+			if rng.Intn(10) == 0 {
+				ts = "notatimestamp"
+			}
+			putRaw(t, vfs, path.Join("shared", names[rng.Intn(len(names))]+"."+ts), "data")
+		}
+		backend.readDirs = 0
+		listed, err := vfs.VersionsMulti(files)
+		assert.Nil(t, err)
+		assert.Equal(t, 3, backend.readDirs)
+		assert.Len(t, listed, 6)
+		for _, file := range files {
+			versions, err := vfs.Versions(file)
+			assert.Nil(t, err)
+			assert.Equal(t, timestampStrings(versions), timestampStrings(listed[file]), "directory %d, %s", i, BasePath(file))
+		}
+	}
+}
+
 func TestVersionFS_Write(t *testing.T) {
 	t.Parallel()
 	dir, vfs := newTmpVersionFS(t)
@@ -188,6 +414,54 @@ func TestVersionFS_Write(t *testing.T) {
 	assert.Equal(t, "new hello world", string(data))
 }
 
+func TestVersionFS_Write_SyncOnWrite(t *testing.T) {
+	t.Parallel()
+	dir, vfs := newTmpVersionFS(t)
+	defer func() { _ = os.RemoveAll(dir) }()
+	vfs.SyncOnWrite = true
+	file := vfs.New(LeagueFileType, 2023)
+	ts, err := vfs.Write(file, []byte("synced"))
+	assert.Nil(t, err)
+	data, err := vfs.Read(file, ts)
+	assert.Nil(t, err)
+	assert.Equal(t, "synced", string(data))
+	// backends without SyncBackend write normally
+	mem := NewMemory()
+	mem.SyncOnWrite = true
+	ts, err = mem.Write(fileLeague{season: 2023}, []byte("memory"))
+	assert.Nil(t, err)
+	data, err = mem.Read(fileLeague{season: 2023}, ts)
+	assert.Nil(t, err)
+	assert.Equal(t, "memory", string(data))
+}
+
+func TestOSBackend_WriteFileSync_Error(t *testing.T) {
+	t.Parallel()
+	err := OSBackend{}.WriteFileSync("/dev/null/league.json.20231019140523", []byte("data"), 0644)
+	assert.NotNil(t, err)
+}
+
+func TestVersionFS_Write_CreateDirsDisabled(t *testing.T) {
+	t.Parallel()
+	dir, vfs := newTmpVersionFS(t)
+	defer func() { _ = os.RemoveAll(dir) }()
+	vfs.CreateDirs = false
+	file := vfs.New(LeagueFileType, 2023)
+	_, err := vfs.Write(file, []byte("missing dir"))
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+	assert.Contains(t, err.Error(), "CreateDirs is disabled")
+	exists, err := vfs.PathExists("2023")
+	assert.Nil(t, err)
+	assert.False(t, exists)
+	// the directory is provisioned ahead of time
+	assert.Nil(t, os.MkdirAll(path.Join(dir, "2023/league"), 0755))
+	ts, err := vfs.Write(file, []byte("provisioned"))
+	assert.Nil(t, err)
+	data, err := vfs.Read(file, ts)
+	assert.Nil(t, err)
+	assert.Equal(t, "provisioned", string(data))
+}
+
 // let's write on a path that is not writable
 func TestVersionFS_Write_Error(t *testing.T) {
 	t.Parallel()
@@ -199,6 +473,71 @@ func TestVersionFS_Write_Error(t *testing.T) {
 	assert.Equal(t, "mkdir /dev/null: not a directory", err.Error())
 }
 
+func TestVersionFS_WriteWithPrevious(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	file := fileLeague{season: 2023}
+	first, prev, err := vfs.WriteWithPrevious(file, []byte("first"))
+	assert.Nil(t, err)
+	assert.True(t, prev.IsZero())
+	second, prev, err := vfs.WriteWithPrevious(file, []byte("second"))
+	assert.Nil(t, err)
+	assert.False(t, prev.IsZero())
+	assert.Equal(t, first.String(), prev.String())
+	assert.NotEqual(t, first.String(), second.String())
+}
+
+func TestVersionFS_WriteInto(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	file := fileLeague{season: 2023}
+	ts, err := vfs.WriteInto("staging/league", file, []byte("staged"))
+	assert.Nil(t, err)
+	found, err := vfs.Find("staging/league", file)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{ts.String()}, timestampStrings(found))
+	exists, err := vfs.PathExists("staging/league/league.txt." + ts.String())
+	assert.Nil(t, err)
+	assert.True(t, exists)
+	has, err := vfs.HasSome(file)
+	assert.Nil(t, err)
+	assert.False(t, has)
+
+	// the resolution of the file is kept
+	ts, err = vfs.WriteInto("staging/league", dailyLeague{file}, []byte("daily"))
+	assert.Nil(t, err)
+	assert.Equal(t, Day, ts.Resolution())
+}
+
+func TestVersionFS_WriteWithPrevious_Concurrent(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	file := fileLeague{season: 2023}
+	var wg sync.WaitGroup
+	prevs := make([]Timestamp, 3)
+	for i := range prevs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, prev, err := vfs.WriteWithPrevious(file, []byte("data"))
+			assert.Nil(t, err)
+			prevs[i] = prev
+		}(i)
+	}
+	wg.Wait()
+	// every write supersedes a distinct version, the first one none
+	seen := make(map[string]bool)
+	zeros := 0
+	for _, prev := range prevs {
+		seen[prev.String()] = true
+		if prev.IsZero() {
+			zeros++
+		}
+	}
+	assert.Len(t, seen, len(prevs))
+	assert.Equal(t, 1, zeros)
+}
+
 func TestVersionFS_Remove(t *testing.T) {
 	t.Parallel()
 	dir, vfs := newTmpVersionFS(t)
@@ -230,6 +569,118 @@ func TestVersionFS_Remove_Err(t *testing.T) {
 	}
 }
 
+func TestVersionFS_Reset(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	file := fileLeague{season: 2023}
+	putVersion(t, vfs, file, "20231019140523", "first")
+	putVersion(t, vfs, file, "20231020140523", "second")
+	ts, err := vfs.Reset(file, []byte("known good"))
+	assert.Nil(t, err)
+	versions, err := vfs.Versions(file)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{ts.String()}, timestampStrings(versions))
+	data, err := vfs.Read(file, ts)
+	assert.Nil(t, err)
+	assert.Equal(t, "known good", string(data))
+}
+
+// removeFailingBackend is a MemoryBackend whose removals fail.
+type removeFailingBackend struct {
+	*MemoryBackend
+}
+
+func (removeFailingBackend) Remove(name string) error {
+	return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrPermission}
+}
+
+func TestVersionFS_Reset_RemoveErr(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	file := fileLeague{season: 2023}
+	putVersion(t, vfs, file, "20231019140523", "first")
+	putVersion(t, vfs, file, "20231020140523", "second")
+	vfs.Backend = removeFailingBackend{vfs.Backend.(*MemoryBackend)}
+	ts, err := vfs.Reset(file, []byte("known good"))
+	assert.NotZero(t, ts)
+	assert.True(t, errors.Is(err, fs.ErrPermission))
+	assert.Contains(t, err.Error(), "removing version 20231019140523")
+	assert.Contains(t, err.Error(), "removing version 20231020140523")
+	versions, err := vfs.Versions(file)
+	assert.Nil(t, err)
+	assert.Len(t, versions, 3)
+}
+
+// dailyLeague is a league file versioned at the Day resolution.
+type dailyLeague struct {
+	fileLeague
+}
+
+func (dailyLeague) Resolution() Resolution {
+	return Day
+}
+
+func TestVersionFS_Write_Resolution(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	vfs.Resolution = Hour
+	file := fileLeague{season: 2023}
+	ts, err := vfs.Write(file, []byte("hourly"))
+	assert.Nil(t, err)
+	assert.Equal(t, Hour, ts.Resolution())
+	assert.Len(t, ts.String(), 10)
+	versions, err := vfs.Versions(file)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{ts.String()}, timestampStrings(versions))
+
+	// a ResolutionFile overrides the resolution of the instance
+	ts, err = vfs.Write(dailyLeague{file}, []byte("daily"))
+	assert.Nil(t, err)
+	assert.Equal(t, Day, ts.Resolution())
+	data, err := vfs.Read(file, ts)
+	assert.Nil(t, err)
+	assert.Equal(t, "daily", string(data))
+}
+
+func TestVersionFS_Versions_MixedResolutions(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	file := fileLeague{season: 2023}
+	putVersion(t, vfs, file, "20231019140523", "second")
+	putVersion(t, vfs, file, "20231020", "day")
+	putVersion(t, vfs, file, "2023101915", "hour")
+	putVersion(t, vfs, file, "202310191406", "minute")
+	putVersion(t, vfs, file, "20231019000000", "midnight")
+	expected := []string{"20231020", "2023101915", "202310191406", "20231019140523", "20231019000000"}
+	versions, err := vfs.Versions(file)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, timestampStrings(versions))
+	found, err := vfs.Find("2023/league", file)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, timestampStrings(found))
+}
+
+func TestVersionFS_Versions_OrderByValue(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	vfs.RegisterFileType(LeagueFileType, func(args ...any) File {
+		return fileLeague{season: args[0].(int)}
+	})
+	vfs.RegisterAlias(LeagueFileType, "league", "csv.gz")
+	file := vfs.New(LeagueFileType, 2023)
+	// in lexical order, the versions of the longer extension come last whatever their timestamps
+	putRaw(t, vfs, "2023/league/league.txt.20231018140523", "older")
+	putRaw(t, vfs, "2023/league/league.csv.gz.20231019", "newer")
+	putRaw(t, vfs, "2023/league/league.csv.gz.20231017140523", "oldest")
+	expected := []string{"20231019", "20231018140523", "20231017140523"}
+	versions, err := vfs.Versions(file)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, timestampStrings(versions))
+	found, err := vfs.Find("2023/league", file)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, timestampStrings(found))
+}
+
 func TestVersionFS_PathExists(t *testing.T) {
 	t.Parallel()
 	vfs := newTestVersionFS()
@@ -270,6 +721,20 @@ func TestVersionFS_HasSome_MissingDir(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestVersionFS_HasSome_WrongExt(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	file := fileLeague{season: 2023}
+	putRaw(t, vfs, "2023/league/league.json.20231019140523", "other extension")
+	ok, err := vfs.HasSome(file)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	putVersion(t, vfs, file, "20231019140523", "data")
+	ok, err = vfs.HasSome(file)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+}
+
 func TestVersionFS_LastVersion_NoVersions(t *testing.T) {
 	t.Parallel()
 	dir, vfs := newTmpVersionFS(t)
@@ -294,6 +759,24 @@ func TestVersionFS_Find(t *testing.T) {
 	assert.Equal(t, "20211125011946", timestamps[2].String())
 }
 
+// TestVersionFS_Find_Allocs checks that matching a directory entry doesn't allocate,
+// whether it is a version of the file or not.
+func TestVersionFS_Find_Allocs(t *testing.T) {
+	vfs := NewMemory()
+	var file File = fileThemes{}
+	for _, filename := range []string{
+		"themes.csv.gz.20231019140523",
+		"themes.CSV.GZ.20231019140523",
+		"themes.json.20231019140523",
+		"roster.json.20231019140523",
+	} {
+		allocs := testing.AllocsPerRun(100, func() {
+			vfs.matchVersion("catalog", filename, file)
+		})
+		assert.Zero(t, allocs, filename)
+	}
+}
+
 func TestVersionFS_Find_NoMatches(t *testing.T) {
 	t.Parallel()
 	dir, vfs := newTmpVersionFS(t)
@@ -417,6 +900,28 @@ func TestVersionFS_Detect_MultiPartExtension(t *testing.T) {
 	assert.Equal(t, "20211125011947", ts.String())
 }
 
+func TestVersionFS_Find_CompressedExtension(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	snapshot := aliasFile{dir: "catalog", alias: Alias{Name: "themes", Ext: "json.zst"}}
+	ts := putVersion(t, vfs, snapshot, "20231019140523", "zstd frame")
+	putRaw(t, vfs, "catalog/themes.json.20231020140523", "plain")
+	putRaw(t, vfs, "catalog/themes.json.gz.20231021140523", "gzip member")
+
+	// the codec suffix is part of the extension, the content is stored as written
+	detected, err := vfs.Detect("themes.json.zst.20231019140523", snapshot)
+	assert.Nil(t, err)
+	assert.Equal(t, ts.String(), detected.String())
+	_, err = vfs.Detect("themes.json.20231020140523", snapshot)
+	assert.NotNil(t, err)
+	found, err := vfs.Find("catalog", snapshot)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"20231019140523"}, timestampStrings(found))
+	data, err := vfs.Read(snapshot, ts)
+	assert.Nil(t, err)
+	assert.Equal(t, "zstd frame", string(data))
+}
+
 func TestVersionFS_Detect_WrongName(t *testing.T) {
 	t.Parallel()
 	vfs := newTestVersionFS()
@@ -483,6 +988,83 @@ func TestVersionFS_Detect_EmptyAfterName(t *testing.T) {
 	assert.Contains(t, err.Error(), "expected dot after name")
 }
 
+func TestVersionFS_DetectDir(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	file := fileLeague{season: 2023}
+	putVersion(t, vfs, file, "20231019140523", "first")
+	putVersion(t, vfs, file, "20231020140523", "second")
+	putRaw(t, vfs, "2023/league/league.json.20231021140523", "json")
+	putRaw(t, vfs, "2023/league/league.txt.notatimestamp", "bad")
+	putRaw(t, vfs, "2023/league/notes.txt", "notes")
+	putRaw(t, vfs, "2023/league/archive/league.txt.20231019140523", "nested")
+	matched, rejected, err := vfs.DetectDir("2023/league", file)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"20231020140523", "20231019140523"}, timestampStrings(matched))
+	assert.Equal(t, []string{
+		`filename "league.json.20231021140523" has extension "json" but expected "txt"`,
+		`filename "league.txt.notatimestamp" has invalid timestamp: parsing time "notatimestamp" as "20060102150405": cannot parse "notatimestamp" as "2006"`,
+		`filename "notes.txt" does not match file name "league"`,
+	}, rejected)
+}
+
+func TestVersionFS_DetectDir_MissingDir(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	matched, rejected, err := vfs.DetectDir("2023/league", fileLeague{season: 2023})
+	assert.Nil(t, err)
+	assert.Equal(t, []Timestamp{}, matched)
+	assert.Equal(t, []string{}, rejected)
+}
+
+// fileSettings is a file type whose name starts with a dot.
+type fileSettings struct{}
+
+func (fileSettings) Dir() string  { return "config" }
+func (fileSettings) Name() string { return ".settings" }
+func (fileSettings) Ext() string  { return "json" }
+
+func TestVersionFS_IgnoreDotfiles(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	logger := &recordingLogger{}
+	vfs.Logger = logger
+	league := fileLeague{season: 2023}
+	settings := fileSettings{}
+	putVersion(t, vfs, league, "20231019140523", "league")
+	putRaw(t, vfs, "2023/league/.DS_Store", "finder")
+	putRaw(t, vfs, "2023/league/._league.txt.20231019140523", "resource fork")
+	putVersion(t, vfs, settings, "20231019140523", "settings")
+
+	assert.True(t, vfs.IgnoreDotfiles)
+	versions, err := vfs.Versions(league)
+	assert.Nil(t, err)
+	assert.Len(t, versions, 1)
+	_, rejected, err := vfs.DetectDir("2023/league", league)
+	assert.Nil(t, err)
+	assert.Empty(t, rejected)
+	var walked []string
+	err = vfs.WalkVersions("", func(dir, name, ext string, ts Timestamp, info os.FileInfo) error {
+		walked = append(walked, path.Join(dir, name))
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"2023/league/league"}, walked)
+	assert.Empty(t, logger.messages)
+	versions, err = vfs.Versions(settings)
+	assert.Nil(t, err)
+	assert.Empty(t, versions)
+
+	// legitimately dotted names are found when the dotfiles aren't ignored
+	vfs.IgnoreDotfiles = false
+	versions, err = vfs.Versions(settings)
+	assert.Nil(t, err)
+	assert.Len(t, versions, 1)
+	found, err := vfs.Find("config", settings)
+	assert.Nil(t, err)
+	assert.Len(t, found, 1)
+}
+
 // Helper type for multi-part extension testing
 type fileThemes struct{}
 
@@ -640,6 +1222,564 @@ func TestVersionFS_Find_NoPrefix(t *testing.T) {
 	assert.Equal(t, ts.String(), timestamps[0].String())
 }
 
+// the clone has its own registry, registering on it must not leak into the original
+func TestVersionFS_Clone(t *testing.T) {
+	t.Parallel()
+	vfs := newTestVersionFS()
+	clone := vfs.Clone("./test-data/missing")
+	assert.Equal(t, "./test-data/missing", clone.RootPath)
+	assert.Equal(t, "./test-data/", vfs.RootPath)
+	file := clone.New(LeagueFileType, 2023)
+	assert.Equal(t, "2023/league", file.Dir())
+	const otherFileType FileType = 42
+	clone.RegisterFileType(otherFileType, func(args ...any) File {
+		return fileThemes{}
+	})
+	assert.NotPanics(t, func() { clone.New(otherFileType) })
+	assert.Panics(t, func() { vfs.New(otherFileType) })
+	vfs.RegisterFileType(RosterFileType, func(args ...any) File {
+		return fileThemes{}
+	})
+	assert.Equal(t, "catalog", vfs.New(RosterFileType).Dir())
+	assert.Equal(t, "2023/roster/team-3", clone.New(RosterFileType, 2023, 3, "2023-10-19").Dir())
+
+	// the clone has its own locks, while a view shares them
+	assert.NotSame(t, vfs.locks, clone.locks)
+	assert.Same(t, vfs.locks, vfs.WithRoot("./test-data/missing").locks)
+}
+
+// a view shares the registry with the original, but reads from its own root
+func TestVersionFS_WithRoot(t *testing.T) {
+	t.Parallel()
+	vfs := newTestVersionFS()
+	view := vfs.WithRoot("./test-data/missing")
+	file := view.New(LeagueFileType, 2023)
+	versions, err := view.Versions(file)
+	assert.Nil(t, err)
+	assert.Equal(t, []Timestamp{}, versions)
+	versions, err = vfs.Versions(file)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(versions))
+	const otherFileType FileType = 42
+	view.RegisterFileType(otherFileType, func(args ...any) File {
+		return fileThemes{}
+	})
+	assert.NotPanics(t, func() { vfs.New(otherFileType) })
+}
+
+func TestVersionFS_Sub(t *testing.T) {
+	t.Parallel()
+	for name, vfs := range map[string]*VersionFS{"memory": NewMemory(), "os": New(t.TempDir())} {
+		sub, err := vfs.Sub("2023")
+		assert.Nil(t, err, name)
+		inSub := aliasFile{dir: "league", alias: Alias{Name: "league", Ext: "txt"}}
+		inParent := fileLeague{season: 2023}
+		ts, err := sub.Write(inSub, []byte("through sub"))
+		assert.Nil(t, err, name)
+		parentTs, err := vfs.Write(inParent, []byte("through parent"))
+		assert.Nil(t, err, name)
+
+		// both instances see the same files at the same paths
+		expected := timestampStrings([]Timestamp{parentTs, ts})
+		versions, err := vfs.Versions(inParent)
+		assert.Nil(t, err, name)
+		assert.Equal(t, expected, timestampStrings(versions), name)
+		versions, err = sub.Versions(inSub)
+		assert.Nil(t, err, name)
+		assert.Equal(t, expected, timestampStrings(versions), name)
+		found, err := sub.Find("league", inSub)
+		assert.Nil(t, err, name)
+		assert.Equal(t, expected, timestampStrings(found), name)
+		data, err := vfs.Read(inParent, ts)
+		assert.Nil(t, err, name)
+		assert.Equal(t, "through sub", string(data), name)
+		data, err = sub.Read(inSub, parentTs)
+		assert.Nil(t, err, name)
+		assert.Equal(t, "through parent", string(data), name)
+		exists, err := vfs.PathExists(path.Join("2023", Path(inSub, ts)))
+		assert.Nil(t, err, name)
+		assert.True(t, exists, name)
+
+		// the sub-instance can't escape its root
+		_, err = sub.Versions(aliasFile{dir: "../2024/league", alias: Alias{Name: "league", Ext: "txt"}})
+		assert.ErrorIs(t, err, ErrOutsideRoot, name)
+		_, err = sub.PathExists("..")
+		assert.ErrorIs(t, err, ErrOutsideRoot, name)
+	}
+}
+
+func TestVersionFS_Sub_Invalid(t *testing.T) {
+	t.Parallel()
+	vfs := newTestVersionFS()
+	sub, err := vfs.Sub(".")
+	assert.Nil(t, err)
+	assert.Equal(t, vfs.RootPath, sub.RootPath)
+	sub, err = vfs.Sub("2023/league")
+	assert.Nil(t, err)
+	assert.Equal(t, "test-data/2023/league", sub.RootPath)
+	_, err = vfs.Sub("../other")
+	assert.ErrorIs(t, err, ErrOutsideRoot)
+	_, err = vfs.Sub("/etc")
+	assert.ErrorIs(t, err, ErrOutsideRoot)
+	_, err = vfs.Sub("2023/")
+	assert.ErrorIs(t, err, fs.ErrInvalid)
+	_, err = vfs.Sub("")
+	assert.ErrorIs(t, err, fs.ErrInvalid)
+}
+
+// the returned slice must reuse the backing array of dst and drop its previous content
+func TestVersionFS_FindAppend(t *testing.T) {
+	t.Parallel()
+	vfs := newTestVersionFS()
+	file := vfs.New(LeagueFileType, 2023)
+	stale, _ := NewTimestamp("20000101000000")
+	dst := make([]Timestamp, 1, 10)
+	dst[0] = stale
+	timestamps, err := vfs.FindAppend(dst, "2023/league", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 3, len(timestamps))
+	assert.Equal(t, 10, cap(timestamps))
+	assert.Same(t, &dst[0], &timestamps[0])
+	assert.Equal(t, "20211218030527", timestamps[0].String())
+	assert.Equal(t, "20211125011947", timestamps[1].String())
+	assert.Equal(t, "20211125011946", timestamps[2].String())
+	// a second call with the result yields the same content
+	timestamps, err = vfs.FindAppend(timestamps, "2023/league", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 3, len(timestamps))
+	assert.Equal(t, "20211218030527", timestamps[0].String())
+}
+
+func TestVersionFS_FindAppend_MissingDir(t *testing.T) {
+	t.Parallel()
+	vfs := newTestVersionFS()
+	file := vfs.New(LeagueFileType, 2023)
+	dst := make([]Timestamp, 2, 10)
+	timestamps, err := vfs.FindAppend(dst, "2023/missing", file)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(timestamps))
+	assert.Equal(t, 10, cap(timestamps))
+}
+
+func TestVersionFS_CaseInsensitiveExt(t *testing.T) {
+	t.Parallel()
+	vfs := newTestVersionFS()
+	file := vfs.New(LeagueFileType, 2023)
+	_, err := vfs.Detect("league.TXT.20211125011947", file)
+	assert.NotNil(t, err)
+	vfs.CaseInsensitiveExt = true
+	ts, err := vfs.Detect("league.TXT.20211125011947", file)
+	assert.Nil(t, err)
+	assert.Equal(t, "20211125011947", ts.String())
+	// the name is still compared exactly
+	_, err = vfs.Detect("LEAGUE.TXT.20211125011947", file)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "does not match file name")
+}
+
+func TestVersionFS_Find_CaseInsensitiveExt(t *testing.T) {
+	t.Parallel()
+	dir, vfs := newTmpVersionFS(t)
+	defer func() { _ = os.RemoveAll(dir) }()
+	if err := vfs.MkdirAll("2023/league", 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{
+		"league.txt.20211125011946",
+		"league.TXT.20211125011947",
+		"LEAGUE.TXT.20211218030527",
+	} {
+		if err := os.WriteFile(path.Join(dir, "2023/league", name), []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	file := vfs.New(LeagueFileType, 2023)
+	timestamps, err := vfs.Find("2023/league", file)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(timestamps))
+	vfs.CaseInsensitiveExt = true
+	timestamps, err = vfs.Find("2023/league", file)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(timestamps))
+	assert.Equal(t, "20211125011947", timestamps[0].String())
+	assert.Equal(t, "20211125011946", timestamps[1].String())
+}
+
+func TestVersionFS_TypeName(t *testing.T) {
+	t.Parallel()
+	vfs := newTestVersionFS()
+	vfs.RegisterFileType(RosterFileType, func(args ...any) File {
+		return fileRoster{season: args[0].(int), teamID: args[1].(int), date: args[2].(string)}
+	}, "roster")
+	assert.Equal(t, "roster", vfs.TypeName(RosterFileType))
+	assert.Equal(t, "0", vfs.TypeName(LeagueFileType))
+	assert.Equal(t, "99", vfs.TypeName(99))
+	assert.Equal(t, "roster", vfs.Clone("./other").TypeName(RosterFileType))
+}
+
+// the panic message uses the name of the type when there is one
+func TestVersionFS_New_PanicMessage(t *testing.T) {
+	t.Parallel()
+	vfs := newTestVersionFS()
+	assert.PanicsWithError(t, "file type 99 not registered", func() { vfs.New(99) })
+	vfs.names[99] = "themes"
+	assert.PanicsWithError(t, "file type themes not registered", func() { vfs.New(99) })
+}
+
+func TestVersionFS_Read_NotFound(t *testing.T) {
+	t.Parallel()
+	vfs := newTestVersionFS()
+	file := vfs.New(LeagueFileType, 2023)
+	ts, _ := NewTimestamp("20000101000000")
+	data, err := vfs.Read(file, ts)
+	assert.Nil(t, data)
+	assert.True(t, errors.Is(err, ErrVersionNotFound))
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+	err = vfs.Remove(file, ts)
+	assert.True(t, errors.Is(err, ErrVersionNotFound))
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+}
+
+// other errors are not reported as missing versions
+func TestVersionFS_Read_OtherError(t *testing.T) {
+	t.Parallel()
+	vfs := newTestVersionFS()
+	file := vfs.New(LeagueFileType, 2023)
+	ts, _ := NewTimestamp("20000101000000")
+	vfs.RootPath = "./test-data/2023/league/league.txt.20211125011946"
+	_, err := vfs.Read(file, ts)
+	assert.NotNil(t, err)
+	assert.False(t, errors.Is(err, ErrVersionNotFound))
+}
+
+func newSeasonVersionFS() *VersionFS {
+	vfs := newTestVersionFS()
+	vfs.RegisterFileTypeE(LeagueFileType, func(args ...any) (File, error) {
+		season := args[0].(int)
+		if season < 1900 {
+			return nil, fmt.Errorf("invalid season %d", season)
+		}
+		return fileLeague{season: season}, nil
+	})
+	return vfs
+}
+
+func TestVersionFS_NewE(t *testing.T) {
+	t.Parallel()
+	vfs := newSeasonVersionFS()
+	file, err := vfs.NewE(LeagueFileType, 2023)
+	assert.Nil(t, err)
+	assert.Equal(t, "2023/league", file.Dir())
+	file, err = vfs.NewE(LeagueFileType, 1850)
+	assert.Nil(t, file)
+	assert.Equal(t, "file type 0: invalid season 1850", err.Error())
+	// legacy constructors never fail
+	file, err = vfs.NewE(RosterFileType, 2023, 3, "2023-10-19")
+	assert.Nil(t, err)
+	assert.Equal(t, "2023/roster/team-3", file.Dir())
+	file, err = vfs.NewE(99)
+	assert.Nil(t, file)
+	assert.True(t, errors.Is(err, ErrNotRegistered))
+	assert.Equal(t, "file type 99 not registered", err.Error())
+}
+
+func TestVersionFS_New_ConstructorError(t *testing.T) {
+	t.Parallel()
+	vfs := newSeasonVersionFS()
+	assert.NotPanics(t, func() { vfs.New(LeagueFileType, 1900) })
+	assert.PanicsWithError(t, "file type 0: invalid season 1850", func() { vfs.New(LeagueFileType, 1850) })
+}
+
+func TestVersionFS_PreviousVersion(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	file := fileLeague{season: 2023}
+	_, err := vfs.PreviousVersion(file)
+	assert.Equal(t, ErrNoVersions, err)
+
+	putVersion(t, vfs, file, "20231019140523", "first")
+	_, err = vfs.PreviousVersion(file)
+	assert.Equal(t, ErrNoPreviousVersion, err)
+
+	putVersion(t, vfs, file, "20231020140523", "second")
+	putVersion(t, vfs, file, "20231021140523", "third")
+	previous, err := vfs.PreviousVersion(file)
+	assert.Nil(t, err)
+	assert.Equal(t, "20231020140523", previous.String())
+}
+
+func TestVersionFS_VersionIndex(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	file := fileLeague{season: 2023}
+	first := putVersion(t, vfs, file, "20231019140523", "first")
+	second := putVersion(t, vfs, file, "20231020", "second")
+	third := putVersion(t, vfs, file, "20231021140523", "third")
+
+	for want, ts := range []Timestamp{first, second, third} {
+		index, total, err := vfs.VersionIndex(file, ts)
+		assert.Nil(t, err)
+		assert.Equal(t, want, index, ts.String())
+		assert.Equal(t, 3, total)
+	}
+
+	// the same time at another resolution is another version
+	midnight, err := NewTimestamp("20231020000000")
+	assert.Nil(t, err)
+	_, _, err = vfs.VersionIndex(file, midnight)
+	assert.ErrorIs(t, err, ErrVersionNotFound)
+	_, _, err = vfs.VersionIndex(fileLeague{season: 2024}, first)
+	assert.ErrorIs(t, err, ErrVersionNotFound)
+}
+
+func TestVersionFS_VersionDates(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	file := fileLeague{season: 2023}
+	dates, err := vfs.VersionDates(file)
+	assert.Nil(t, err)
+	assert.Empty(t, dates)
+
+	putVersion(t, vfs, file, "20231019080000", "morning")
+	putVersion(t, vfs, file, "20231019140523", "afternoon")
+	putVersion(t, vfs, file, "20231021090000", "saturday")
+	putVersion(t, vfs, file, "20231021", "daily")
+	dates, err = vfs.VersionDates(file)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"20231021090000", "20231019140523"}, timestampStrings(dates))
+}
+
+func TestVersionFS_LatestAge(t *testing.T) {
+	t.Parallel()
+	vfs := newTestVersionFS()
+	file := vfs.New(LeagueFileType, 2023)
+	latest, _ := NewTimestamp("20211218030527")
+	before := time.Since(latest.Time())
+	age, err := vfs.LatestAge(file)
+	after := time.Since(latest.Time())
+	assert.Nil(t, err)
+	assert.True(t, age >= before && age <= after)
+}
+
+// The versions are named in local time, the age must not be off by the offset of the zone.
+func TestVersionFS_LatestAge_LocalZone(t *testing.T) {
+	for _, zone := range []struct {
+		name   string
+		offset int
+	}{{"JST", 9 * 3600}, {"EDT", -4 * 3600}} {
+		t.Run(zone.name, func(t *testing.T) {
+			setLocal(t, zone.name, zone.offset)
+			vfs := New(t.TempDir())
+			file := fileLeague{season: 2023}
+			_, err := vfs.Write(file, []byte("data"))
+			assert.Nil(t, err)
+			age, err := vfs.LatestAge(file)
+			assert.Nil(t, err)
+			assert.True(t, age >= 0 && age < 2*time.Second, "age %s", age)
+		})
+	}
+}
+
+func TestVersionFS_LatestAge_NoVersions(t *testing.T) {
+	t.Parallel()
+	vfs := newTestVersionFS()
+	vfs.RootPath = "./test-data/missing"
+	file := vfs.New(LeagueFileType, 2023)
+	age, err := vfs.LatestAge(file)
+	assert.Zero(t, age)
+	assert.Equal(t, ErrNoVersions, err)
+}
+
+func TestVersionFS_ReadRange(t *testing.T) {
+	t.Parallel()
+	vfs := newTestVersionFS()
+	file := vfs.New(LeagueFileType, 2023)
+	ts, _ := NewTimestamp("20211125011947")
+	for _, tc := range []struct {
+		offset, length int64
+		expected       string
+	}{
+		{0, 5, "hello"},
+		{6, 5, "world"},
+		{6, -1, "world 2\n"},
+		{12, 10, "2\n"},
+		{14, 10, ""},
+		{100, 10, ""},
+		{0, 0, ""},
+	} {
+		data, err := vfs.ReadRange(file, ts, tc.offset, tc.length)
+		assert.Nil(t, err)
+		assert.Equal(t, tc.expected, string(data), "offset %d, length %d", tc.offset, tc.length)
+	}
+}
+
+func TestVersionFS_ReadRange_Errors(t *testing.T) {
+	t.Parallel()
+	vfs := newTestVersionFS()
+	file := vfs.New(LeagueFileType, 2023)
+	ts, _ := NewTimestamp("20211125011947")
+	_, err := vfs.ReadRange(file, ts, -1, 10)
+	assert.Equal(t, "invalid negative offset -1", err.Error())
+	ts, _ = NewTimestamp("20000101000000")
+	_, err = vfs.ReadRange(file, ts, 0, 10)
+	assert.True(t, errors.Is(err, ErrVersionNotFound))
+}
+
+func TestVersionFS_VersionAt(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	file := fileLeague{season: 2023}
+	ts1 := putVersion(t, vfs, file, "20211125011947", "1")
+	ts2 := putVersion(t, vfs, file, "20231019140523", "2")
+	_, err := vfs.VersionAt(file, time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local))
+	assert.Equal(t, ErrNoVersions, err)
+	ts, err := vfs.VersionAt(file, time.Date(2022, 1, 1, 0, 0, 0, 0, time.Local))
+	assert.Nil(t, err)
+	assert.Equal(t, ts1, ts)
+	ts, err = vfs.VersionAt(file, ts2.Time())
+	assert.Nil(t, err)
+	assert.Equal(t, ts2, ts)
+
+	// a version is effective from the start of its second, in the local time zone
+	ts, err = vfs.VersionAt(file, time.Date(2023, 10, 19, 14, 5, 23, 900_000_000, time.Local))
+	assert.Nil(t, err)
+	assert.Equal(t, ts2.String(), ts.String())
+	ts, err = vfs.VersionAt(file, time.Date(2023, 10, 19, 14, 5, 22, 999_999_999, time.Local))
+	assert.Nil(t, err)
+	assert.Equal(t, ts1.String(), ts.String())
+
+	// the same instants in another location
+	edt := time.FixedZone("EDT", -4*3600)
+	ts, err = vfs.VersionAt(file, time.Date(2023, 10, 19, 14, 5, 23, 0, time.Local).In(edt))
+	assert.Nil(t, err)
+	assert.Equal(t, ts2.String(), ts.String())
+	ts, err = vfs.VersionAt(file, time.Date(2023, 10, 19, 14, 5, 22, 0, time.Local).In(edt))
+	assert.Nil(t, err)
+	assert.Equal(t, ts1.String(), ts.String())
+}
+
+// A version just written is effective now, whatever the offset of the local time zone.
+func TestVersionFS_VersionAt_Now(t *testing.T) {
+	for _, zone := range []struct {
+		name   string
+		offset int
+	}{{"JST", 9 * 3600}, {"EDT", -4 * 3600}} {
+		t.Run(zone.name, func(t *testing.T) {
+			setLocal(t, zone.name, zone.offset)
+			vfs := New(t.TempDir())
+			file := fileLeague{season: 2023}
+			written, err := vfs.Write(file, []byte("now"))
+			assert.Nil(t, err)
+			ts, err := vfs.VersionAt(file, time.Now())
+			assert.Nil(t, err)
+			assert.Equal(t, written.String(), ts.String())
+		})
+	}
+}
+
+func TestVersionFS_VersionsUnsorted(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	file := fileLeague{season: 2023}
+	putVersion(t, vfs, file, "20211125011947", "1")
+	putVersion(t, vfs, file, "20231019140523", "2")
+	putVersion(t, vfs, file, "2022111501", "3")
+	putRaw(t, vfs, "2023/league/other.txt.20211125011949", "other name")
+	versions, err := vfs.Versions(file)
+	assert.Nil(t, err)
+	unsorted, err := vfs.VersionsUnsorted(file)
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, timestampStrings(versions), timestampStrings(unsorted))
+
+	unsorted, err = vfs.VersionsUnsorted(fileLeague{season: 2024})
+	assert.Nil(t, err)
+	assert.Empty(t, unsorted)
+}
+
+func TestTimestampFromFilename(t *testing.T) {
+	t.Parallel()
+	ts, err := TimestampFromFilename("league.json.20231019140523")
+	assert.Nil(t, err)
+	assert.Equal(t, "20231019140523", ts.String())
+	ts, err = TimestampFromFilename("anything.with.dots.csv.gz.20231019140523")
+	assert.Nil(t, err)
+	assert.Equal(t, "20231019140523", ts.String())
+	ts, err = TimestampFromFilename("20231019140523")
+	assert.Nil(t, err)
+	assert.Equal(t, "20231019140523", ts.String())
+	_, err = TimestampFromFilename("league.json")
+	assert.NotNil(t, err)
+	_, err = TimestampFromFilename("league.json.20231019140523.")
+	assert.NotNil(t, err)
+}
+
+func TestVersionFS_ReadOnly(t *testing.T) {
+	t.Parallel()
+	dir, vfs := newTmpVersionFS(t)
+	defer func() { _ = os.RemoveAll(dir) }()
+	file := vfs.New(LeagueFileType, 2023)
+	ts, err := vfs.Write(file, []byte("before"))
+	assert.Nil(t, err)
+	vfs.ReadOnly = true
+
+	_, err = vfs.Write(vfs.New(LeagueFileType, 2024), []byte("after"))
+	assert.True(t, errors.Is(err, ErrReadOnly))
+	var pathErr *fs.PathError
+	assert.True(t, errors.As(err, &pathErr))
+	assert.Equal(t, "write", pathErr.Op)
+	assert.Contains(t, pathErr.Path, "2024/league/league.txt.")
+	err = vfs.Remove(file, ts)
+	assert.True(t, errors.Is(err, ErrReadOnly))
+	assert.Equal(t, "remove 2023/league/league.txt."+ts.String()+": read-only file system", err.Error())
+	err = vfs.MkdirAll("2025/league", 0755)
+	assert.True(t, errors.Is(err, ErrReadOnly))
+
+	// nothing was created or removed
+	entries, err := os.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "2023", entries[0].Name())
+	versions, err := vfs.Versions(file)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{ts.String()}, timestampStrings(versions))
+	data, err := vfs.Read(file, ts)
+	assert.Nil(t, err)
+	assert.Equal(t, "before", string(data))
+}
+
+func TestVersionFS_FindAnyExt(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	putRaw(t, vfs, "catalog/themes.csv.20211125011947", "1")
+	putRaw(t, vfs, "catalog/themes.csv.gz.20221125011947", "2")
+	putRaw(t, vfs, "catalog/themes.csv.gz.20231125011947", "3")
+	putRaw(t, vfs, "catalog/themes.CSV.20241125011947", "4")
+	putRaw(t, vfs, "catalog/themes.csv.notatimestamp", "bad")
+	putRaw(t, vfs, "catalog/themes.20241125011947", "no extension")
+	putRaw(t, vfs, "catalog/themes-old.csv.20241125011947", "other name")
+	putRaw(t, vfs, "catalog/themes.csv.20251125011947/nested", "directory")
+	groups, err := vfs.FindAnyExt("catalog", "themes")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(groups))
+	assert.Equal(t, []string{"20211125011947"}, timestampStrings(groups["csv"]))
+	assert.Equal(t, []string{"20241125011947"}, timestampStrings(groups["CSV"]))
+	assert.Equal(t, []string{"20231125011947", "20221125011947"}, timestampStrings(groups["csv.gz"]))
+
+	vfs.CaseInsensitiveExt = true
+	groups, err = vfs.FindAnyExt("catalog", "themes")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(groups))
+	assert.Equal(t, []string{"20241125011947", "20211125011947"}, timestampStrings(groups["csv"]))
+
+	groups, err = vfs.FindAnyExt("missing", "themes")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string][]Timestamp{}, groups)
+}
+
 // Benchmarks
 
 func BenchmarkWrite(b *testing.B) {
@@ -700,6 +1840,103 @@ func BenchmarkVersions(b *testing.B) {
 	}
 }
 
+// BenchmarkVersions_Mixed lists the versions of a file in a directory shared with other
+// file types: 10k versions among 100k entries.
+func BenchmarkVersions_Mixed(b *testing.B) {
+	vfs := NewMemory()
+	vfs.Logger = NopLogger{}
+	file := fileLeague{season: 2023}
+	if err := vfs.Backend.MkdirAll(file.Dir(), 0755); err != nil {
+		b.Fatal(err)
+	}
+	start := time.Date(2021, 11, 25, 1, 19, 47, 0, time.UTC)
+	for i := 0; i < 100000; i++ {
+		ts := NewFromTime(start.Add(time.Duration(i) * time.Second))
+		name := Path(file, ts)
+		if i%10 != 0 {
+			name = Path(aliasFile{dir: file.Dir(), alias: Alias{Name: fmt.Sprintf("roster%d", i%9), Ext: "json"}}, ts)
+		}
+		if err := vfs.Backend.WriteFile(name, []byte("data"), 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := vfs.Versions(file); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkVersionsUnsorted compares Versions and VersionsUnsorted on a directory with many versions.
+func BenchmarkVersionsUnsorted(b *testing.B) {
+	vfs := NewMemory()
+	file := fileLeague{season: 2023}
+	if err := vfs.Backend.MkdirAll(file.Dir(), 0755); err != nil {
+		b.Fatal(err)
+	}
+	start := time.Date(2021, 11, 25, 1, 19, 47, 0, time.UTC)
+	for i := 0; i < 10000; i++ {
+		// written in a shuffled order, like the listings of most filesystems
+		ts := NewFromTime(start.Add(time.Duration(i*7919%10000) * time.Second))
+		if err := vfs.Backend.WriteFile(Path(file, ts), []byte("data"), 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+	for _, bench := range []struct {
+		name     string
+		versions func(File) ([]Timestamp, error)
+	}{
+		{"Versions", vfs.Versions},
+		{"VersionsUnsorted", vfs.VersionsUnsorted},
+	} {
+		versions := bench.versions
+		b.Run(bench.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := versions(file); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkLastVersions compares LastVersions and a loop of LastVersion on 50 files sharing
+// a directory.
+func BenchmarkLastVersions(b *testing.B) {
+	vfs := NewMemory()
+	if err := vfs.Backend.MkdirAll("shared", 0755); err != nil {
+		b.Fatal(err)
+	}
+	start := time.Date(2021, 11, 25, 1, 19, 47, 0, time.UTC)
+	files := make([]File, 50)
+	for i := range files {
+		files[i] = aliasFile{dir: "shared", alias: Alias{Name: fmt.Sprintf("file%d", i), Ext: "txt"}}
+		for j := 0; j < 20; j++ {
+			ts := NewFromTime(start.Add(time.Duration(j) * time.Hour))
+			if err := vfs.Backend.WriteFile(Path(files[i], ts), []byte("data"), 0644); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	b.Run("LastVersion", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, file := range files {
+				if _, err := vfs.LastVersion(file); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("LastVersions", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := vfs.LastVersions(files); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
 func BenchmarkLastVersion(b *testing.B) {
 	dir, vfs := newTmpVersionFS(b)
 	defer func() { _ = os.RemoveAll(dir) }()
@@ -758,6 +1995,34 @@ func BenchmarkFind(b *testing.B) {
 	}
 }
 
+// BenchmarkFind_Mixed finds the versions of a file in a directory shared with other file
+// types: 10k versions among 100k entries.
+func BenchmarkFind_Mixed(b *testing.B) {
+	vfs := NewMemory()
+	vfs.Logger = NopLogger{}
+	file := fileLeague{season: 2023}
+	if err := vfs.Backend.MkdirAll(file.Dir(), 0755); err != nil {
+		b.Fatal(err)
+	}
+	start := time.Date(2021, 11, 25, 1, 19, 47, 0, time.UTC)
+	for i := 0; i < 100000; i++ {
+		ts := NewFromTime(start.Add(time.Duration(i) * time.Second))
+		name := Path(file, ts)
+		if i%10 != 0 {
+			name = Path(aliasFile{dir: file.Dir(), alias: Alias{Name: fmt.Sprintf("roster%d", i%9), Ext: "json"}}, ts)
+		}
+		if err := vfs.Backend.WriteFile(name, []byte("data"), 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := vfs.Find(file.Dir(), file); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 func BenchmarkHasSome(b *testing.B) {
 	dir, vfs := newTmpVersionFS(b)
 	defer func() { _ = os.RemoveAll(dir) }()
@@ -776,3 +2041,169 @@ func BenchmarkHasSome(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkHasSome_LargeDir(b *testing.B) {
+	vfs := NewMemory()
+	file := fileLeague{season: 2023}
+	if err := vfs.Backend.MkdirAll(file.Dir(), 0755); err != nil {
+		b.Fatal(err)
+	}
+	start := time.Date(2021, 11, 25, 1, 19, 47, 0, time.UTC)
+	for i := 0; i < 10000; i++ {
+		if err := vfs.Backend.WriteFile(Path(file, NewFromTime(start.Add(time.Duration(i)*time.Second))), []byte("data"), 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.Run("Versions", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := vfs.Versions(file); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("HasSome", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := vfs.HasSome(file); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkFindAppend(b *testing.B) {
+	dir, vfs := newTmpVersionFS(b)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	file := vfs.New(LeagueFileType, 2023)
+	// Create 10 versions
+	for i := 0; i < 10; i++ {
+		_, err := vfs.Write(file, []byte(fmt.Sprintf("version %d", i)))
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	var timestamps []Timestamp
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var err error
+		timestamps, err = vfs.FindAppend(timestamps, "2023/league", file)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestVersionFS_VerifyDir(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	file := fileLeague{season: 2023}
+	putVersion(t, vfs, file, "20231019140523", "valid")
+	putRaw(t, vfs, "2023/league/league.txt.20231019140523"+ChecksumSuffix, "sidecar")
+	putRaw(t, vfs, "2023/league/league.txt.2023-10-19", "dashes")
+	putRaw(t, vfs, "2023/league/league.txt.20231019140523.bak", "backup")
+	putRaw(t, vfs, "2023/league/league.txt.", "empty")
+	putRaw(t, vfs, "2023/league/league.txt.gz.20231019140523", "longer extension")
+	putRaw(t, vfs, "2023/league/league.json.notatimestamp", "other extension")
+	putRaw(t, vfs, "2023/league/notes.txt", "unrelated")
+	putRaw(t, vfs, "2023/league/archive/league.txt.bad", "nested")
+	bad, err := vfs.VerifyDir("2023/league", file)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"league.txt.", "league.txt.2023-10-19", "league.txt.20231019140523.bak"}, bad)
+
+	vfs.CaseInsensitiveExt = true
+	putRaw(t, vfs, "2023/league/league.TXT.yesterday", "mixed case")
+	bad, err = vfs.VerifyDir("2023/league", file)
+	assert.Nil(t, err)
+	assert.Contains(t, bad, "league.TXT.yesterday")
+
+	bad, err = vfs.VerifyDir("2024/league", file)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{}, bad)
+}
+
+func TestVersionFS_SkipEmpty(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	file := fileLeague{season: 2023}
+	other := fileLeague{season: 2024}
+	putVersion(t, vfs, file, "20231019140523", "valid")
+	crashed := putVersion(t, vfs, file, "20231020140523", "")
+	putVersion(t, vfs, other, "20241020140523", "")
+
+	// empty versions are listed by default
+	versions, err := vfs.Versions(file)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"20231020140523", "20231019140523"}, timestampStrings(versions))
+
+	vfs.SkipEmpty = true
+	versions, err = vfs.Versions(file)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"20231019140523"}, timestampStrings(versions))
+	found, err := vfs.Find(file.Dir(), file)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"20231019140523"}, timestampStrings(found))
+	groups, err := vfs.FindAnyExt(file.Dir(), file.Name())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"20231019140523"}, timestampStrings(groups["txt"]))
+	latest, err := vfs.LastVersion(file)
+	assert.Nil(t, err)
+	assert.Equal(t, "20231019140523", latest.String())
+	latests, err := vfs.LastVersions([]File{file, other})
+	assert.Nil(t, err)
+	assert.Equal(t, "20231019140523", latests[file].String())
+	assert.NotContains(t, latests, File(other))
+	infos, err := vfs.VersionsWithInfo(file)
+	assert.Nil(t, err)
+	assert.Len(t, infos, 1)
+	has, err := vfs.HasSome(other)
+	assert.Nil(t, err)
+	assert.False(t, has)
+
+	// the empty versions are still read by timestamp, but not pruned
+	data, err := vfs.Read(file, crashed)
+	assert.Nil(t, err)
+	assert.Empty(t, data)
+	removed, err := vfs.Prune(file, RetentionPolicy{KeepLast: 1})
+	assert.Nil(t, err)
+	assert.Empty(t, removed)
+	exists, err := vfs.PathExists(Path(file, crashed))
+	assert.Nil(t, err)
+	assert.True(t, exists)
+}
+
+func TestVersionFS_VersionsPage(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	file := fileLeague{season: 2023}
+	for _, ts := range []string{"20231015140523", "20231016140523", "20231017140523", "20231018140523", "20231019140523"} {
+		putVersion(t, vfs, file, ts, ts)
+	}
+
+	page, total, err := vfs.VersionsPage(file, 0, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, total)
+	assert.Equal(t, []string{"20231019140523", "20231018140523"}, timestampStrings(page))
+	page, total, err = vfs.VersionsPage(file, 4, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, total)
+	assert.Equal(t, []string{"20231015140523"}, timestampStrings(page))
+	page, total, err = vfs.VersionsPage(file, 5, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, total)
+	assert.NotNil(t, page)
+	assert.Empty(t, page)
+	page, total, err = vfs.VersionsPage(file, 1, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, total)
+	assert.Empty(t, page)
+	page, total, err = vfs.VersionsPage(fileLeague{season: 2024}, 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, total)
+	assert.Empty(t, page)
+
+	_, _, err = vfs.VersionsPage(file, -1, 2)
+	assert.NotNil(t, err)
+	_, _, err = vfs.VersionsPage(file, 0, -1)
+	assert.NotNil(t, err)
+}
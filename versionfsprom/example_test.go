@@ -0,0 +1,24 @@
+package versionfsprom_test
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sperano/versionfs"
+	"github.com/sperano/versionfs/versionfsprom"
+	"log"
+	"net/http"
+	"time"
+)
+
+func Example() {
+	vfs := versionfs.New("./data")
+	collector := versionfsprom.New()
+	vfs.Metrics = collector
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collector)
+	go collector.ScanEvery(context.Background(), vfs, time.Minute)
+
+	http.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	log.Fatal(http.ListenAndServe(":9090", nil))
+}
@@ -0,0 +1,139 @@
+// Package versionfsprom implements a versionfs.Metrics exported as a prometheus.Collector.
+// It is a separate module so that the core versionfs module doesn't depend on client_golang.
+package versionfsprom
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sperano/versionfs"
+	"os"
+	"time"
+)
+
+// MetricVersions is the gauge of the number of versions per directory, set by Scan.
+const MetricVersions = "versionfs_versions"
+
+// Collector is a versionfs.Metrics and a prometheus.Collector exporting the metrics of
+// versionfs instances: a histogram of the operation latencies in seconds, counters of the
+// operations, errors, bytes written and read, and pruned versions, and a gauge of the number
+// of versions per directory, populated by Scan or ScanEvery.
+//
+// Example:
+//
+//	collector := versionfsprom.New()
+//	prometheus.MustRegister(collector)
+//	vfs.Metrics = collector
+type Collector struct {
+	counters map[string]*prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	versions *prometheus.GaugeVec
+}
+
+// New creates a Collector, the latencies are observed in the default histogram buckets.
+func New() *Collector {
+	counter := func(name, help string, labels ...string) *prometheus.CounterVec {
+		return prometheus.NewCounterVec(prometheus.CounterOpts{Name: name, Help: help}, labels)
+	}
+	return &Collector{
+		counters: map[string]*prometheus.CounterVec{
+			versionfs.MetricOperations:      counter(versionfs.MetricOperations, "Number of versionfs operations.", "op", "root"),
+			versionfs.MetricErrors:          counter(versionfs.MetricErrors, "Number of failed versionfs operations.", "op", "root"),
+			versionfs.MetricBytesWritten:    counter(versionfs.MetricBytesWritten, "Number of bytes written by versionfs.", "root"),
+			versionfs.MetricBytesRead:       counter(versionfs.MetricBytesRead, "Number of bytes read by versionfs.", "root"),
+			versionfs.MetricPrunedVersions:  counter(versionfs.MetricPrunedVersions, "Number of versions removed by the versionfs pruning.", "root"),
+			versionfs.MetricCacheHits:       counter(versionfs.MetricCacheHits, "Number of versionfs listings served by the cache.", "root"),
+			versionfs.MetricCacheMisses:     counter(versionfs.MetricCacheMisses, "Number of versionfs listings missing the cache.", "root"),
+			versionfs.MetricReadCacheHits:   counter(versionfs.MetricReadCacheHits, "Number of versionfs contents served by the read cache.", "root"),
+			versionfs.MetricReadCacheMisses: counter(versionfs.MetricReadCacheMisses, "Number of versionfs contents missing the read cache.", "root"),
+		},
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    versionfs.MetricOperationDuration + "_seconds",
+			Help:    "Latency of the versionfs operations.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"op", "root"}),
+		versions: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: MetricVersions,
+			Help: "Number of versions per directory, as of the last scan.",
+		}, []string{"root", "dir"}),
+	}
+}
+
+// IncCounter implements versionfs.Metrics, unknown counters are ignored.
+func (c *Collector) IncCounter(name string, delta int64, labels ...string) {
+	if vec, ok := c.counters[name]; ok {
+		vec.With(promLabels(labels)).Add(float64(delta))
+	}
+}
+
+// ObserveDuration implements versionfs.Metrics, unknown durations are ignored.
+func (c *Collector) ObserveDuration(name string, d time.Duration, labels ...string) {
+	if name == versionfs.MetricOperationDuration {
+		c.duration.With(promLabels(labels)).Observe(d.Seconds())
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, vec := range c.counters {
+		vec.Describe(ch)
+	}
+	c.duration.Describe(ch)
+	c.versions.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, vec := range c.counters {
+		vec.Collect(ch)
+	}
+	c.duration.Collect(ch)
+	c.versions.Collect(ch)
+}
+
+// Scan walks the tree of vfs and sets the number of versions of every directory,
+// replacing the previous counts of its root.
+func (c *Collector) Scan(vfs *versionfs.VersionFS) error {
+	counts := make(map[string]int)
+	err := vfs.WalkVersions("", func(dir, name, ext string, ts versionfs.Timestamp, info os.FileInfo) error {
+		counts[dir]++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	c.versions.DeletePartialMatch(prometheus.Labels{"root": vfs.RootPath})
+	for dir, count := range counts {
+		c.versions.WithLabelValues(vfs.RootPath, dir).Set(float64(count))
+	}
+	return nil
+}
+
+// ScanEvery runs Scan immediately, then every interval until ctx is done.
+// Scan errors are reported to the Logger of vfs and don't stop the scans.
+//
+// Example:
+//
+//	go collector.ScanEvery(ctx, vfs, time.Minute)
+func (c *Collector) ScanEvery(ctx context.Context, vfs *versionfs.VersionFS, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := c.Scan(vfs); err != nil && vfs.Logger != nil {
+			vfs.Logger.Warnf("scanning %s: %s", vfs.RootPath, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// promLabels converts key-value pairs to prometheus.Labels.
+func promLabels(pairs []string) prometheus.Labels {
+	labels := make(prometheus.Labels, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		labels[pairs[i]] = pairs[i+1]
+	}
+	return labels
+}
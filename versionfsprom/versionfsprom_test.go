@@ -0,0 +1,70 @@
+package versionfsprom
+
+import (
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sperano/versionfs"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+type fileLeague struct{}
+
+func (fileLeague) Dir() string  { return "2023/league" }
+func (fileLeague) Name() string { return "league" }
+func (fileLeague) Ext() string  { return "json" }
+
+func TestCollector(t *testing.T) {
+	collector := New()
+	vfs := versionfs.NewMemory()
+	vfs.RootPath = "data"
+	vfs.Metrics = collector
+	ts, err := vfs.Write(fileLeague{}, []byte("hello"))
+	assert.Nil(t, err)
+	_, err = vfs.Read(fileLeague{}, ts)
+	assert.Nil(t, err)
+	assert.Nil(t, vfs.Remove(fileLeague{}, ts))
+	_, err = vfs.Read(fileLeague{}, ts)
+	assert.NotNil(t, err)
+
+	expected := `
+# HELP versionfs_errors_total Number of failed versionfs operations.
+# TYPE versionfs_errors_total counter
+versionfs_errors_total{op="read",root="data"} 1
+# HELP versionfs_operations_total Number of versionfs operations.
+# TYPE versionfs_operations_total counter
+versionfs_operations_total{op="read",root="data"} 2
+versionfs_operations_total{op="remove",root="data"} 1
+versionfs_operations_total{op="write",root="data"} 1
+# HELP versionfs_read_bytes_total Number of bytes read by versionfs.
+# TYPE versionfs_read_bytes_total counter
+versionfs_read_bytes_total{root="data"} 5
+# HELP versionfs_written_bytes_total Number of bytes written by versionfs.
+# TYPE versionfs_written_bytes_total counter
+versionfs_written_bytes_total{root="data"} 5
+`
+	err = testutil.CollectAndCompare(collector, strings.NewReader(expected),
+		"versionfs_errors_total", "versionfs_operations_total", "versionfs_read_bytes_total", "versionfs_written_bytes_total")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, testutil.CollectAndCount(collector, "versionfs_operation_duration_seconds"))
+}
+
+func TestCollector_Scan(t *testing.T) {
+	collector := New()
+	vfs := versionfs.NewMemory()
+	vfs.RootPath = "data"
+	_, err := vfs.Write(fileLeague{}, []byte("hello"))
+	assert.Nil(t, err)
+	vfs.Resolution = versionfs.Day
+	_, err = vfs.Write(fileLeague{}, []byte("hello"))
+	assert.Nil(t, err)
+	assert.Nil(t, collector.Scan(vfs))
+
+	expected := `
+# HELP versionfs_versions Number of versions per directory, as of the last scan.
+# TYPE versionfs_versions gauge
+versionfs_versions{dir="2023/league",root="data"} 2
+`
+	err = testutil.CollectAndCompare(collector, strings.NewReader(expected), "versionfs_versions")
+	assert.Nil(t, err)
+}
@@ -0,0 +1,209 @@
+// Package versionfstest implements support for testing implementations of versionfs.Backend.
+//
+// The conformance tests guarantee that a backend has the same semantics as the local filesystem.
+// They are run against every backend of this repository, and can be run against third-party ones:
+//
+//	func TestConformance(t *testing.T) {
+//	    versionfstest.TestConformance(t, func(t *testing.T) *versionfs.VersionFS {
+//	        vfs := versionfs.New("")
+//	        vfs.Backend = newMyBackend(t)
+//	        return vfs
+//	    })
+//	}
+package versionfstest
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sperano/versionfs"
+	"github.com/stretchr/testify/assert"
+	"path"
+	"strings"
+	"testing"
+)
+
+// leagueFileType is the file type registered by TestConformance on every instance.
+const leagueFileType versionfs.FileType = 0
+
+type fileLeague struct {
+	season int
+}
+
+func (f fileLeague) Dir() string  { return fmt.Sprintf("%d/league", f.season) }
+func (f fileLeague) Name() string { return "league" }
+func (f fileLeague) Ext() string  { return "txt" }
+
+// rawFile is a file with any name, to store entries next to the versions of fileLeague.
+type rawFile struct {
+	dir, name, ext string
+}
+
+func (f rawFile) Dir() string  { return f.dir }
+func (f rawFile) Name() string { return f.name }
+func (f rawFile) Ext() string  { return f.ext }
+
+// TestConformance runs the conformance tests against the instances created by newVFS,
+// which must be rooted on an empty tree. Each subtest creates its own instance.
+func TestConformance(t *testing.T, newVFS func(t *testing.T) *versionfs.VersionFS) {
+	create := func(t *testing.T) *versionfs.VersionFS {
+		vfs := newVFS(t)
+		vfs.RegisterFileType(leagueFileType, func(args ...any) versionfs.File {
+			return fileLeague{season: args[0].(int)}
+		})
+		return vfs
+	}
+
+	t.Run("WriteRead", func(t *testing.T) {
+		vfs := create(t)
+		file := vfs.New(leagueFileType, 2023)
+		ts, err := vfs.Write(file, []byte("new hello world"))
+		assert.Nil(t, err)
+		data, err := vfs.Read(file, ts)
+		assert.Nil(t, err)
+		assert.Equal(t, "new hello world", string(data))
+		exists, err := vfs.PathExists(pathOf(vfs, file, ts))
+		assert.Nil(t, err)
+		assert.True(t, exists)
+		exists, err = vfs.PathExists("2023")
+		assert.Nil(t, err)
+		assert.True(t, exists)
+	})
+
+	t.Run("ReadRange", func(t *testing.T) {
+		vfs := create(t)
+		file := vfs.New(leagueFileType, 2023)
+		ts := putVersion(t, vfs, file, "20211125011947", "hello world 2\n")
+		data, err := vfs.ReadRange(file, ts, 6, 5)
+		assert.Nil(t, err)
+		assert.Equal(t, "world", string(data))
+		data, err = vfs.ReadRange(file, ts, 6, -1)
+		assert.Nil(t, err)
+		assert.Equal(t, "world 2\n", string(data))
+		data, err = vfs.ReadRange(file, ts, 100, 5)
+		assert.Nil(t, err)
+		assert.Equal(t, "", string(data))
+	})
+
+	t.Run("Versions", func(t *testing.T) {
+		vfs := create(t)
+		file := vfs.New(leagueFileType, 2023)
+		putVersion(t, vfs, file, "20211125011946", "1")
+		putVersion(t, vfs, file, "20211218030527", "3")
+		putVersion(t, vfs, file, "20211125011947", "2")
+		putVersion(t, vfs, rawFile{"2023/league", "other", "txt"}, "20211125011949", "other name")
+		versions, err := vfs.Versions(file)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"20211218030527", "20211125011947", "20211125011946"}, timestampStrings(versions))
+	})
+
+	t.Run("Find", func(t *testing.T) {
+		vfs := create(t)
+		file := vfs.New(leagueFileType, 2023)
+		putVersion(t, vfs, file, "20211125011946", "1")
+		putVersion(t, vfs, file, "20211218030527", "3")
+		putVersion(t, vfs, file, "20211125011947", "2")
+		putVersion(t, vfs, rawFile{"2023/league", "league", "json"}, "20211125011948", "wrong extension")
+		putRaw(t, vfs, strings.Replace(pathOf(vfs, file, mustTimestamp(t, "20211125011951")), "20211125011951", "notatimestamp", 1), "wrong timestamp")
+		putVersion(t, vfs, rawFile{"2023/league", "other", "txt"}, "20211125011949", "other name")
+		putRaw(t, vfs, pathOf(vfs, file, mustTimestamp(t, "20211125011950"))+"/nested", "directory")
+		expected := []string{"20211218030527", "20211125011947", "20211125011946"}
+
+		timestamps, err := vfs.Find("2023/league", file)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, timestampStrings(timestamps))
+
+		last, err := vfs.LastVersion(file)
+		assert.Nil(t, err)
+		assert.Equal(t, "20211218030527", last.String())
+
+		ok, err := vfs.HasSome(file)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("MissingDir", func(t *testing.T) {
+		vfs := create(t)
+		file := vfs.New(leagueFileType, 2023)
+		versions, err := vfs.Versions(file)
+		assert.Nil(t, err)
+		assert.Equal(t, []versionfs.Timestamp{}, versions)
+		timestamps, err := vfs.Find("2023/league", file)
+		assert.Nil(t, err)
+		assert.Equal(t, []versionfs.Timestamp{}, timestamps)
+		_, err = vfs.LastVersion(file)
+		assert.Equal(t, versionfs.ErrNoVersions, err)
+		exists, err := vfs.PathExists("2023/league")
+		assert.Nil(t, err)
+		assert.False(t, exists)
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		vfs := create(t)
+		file := vfs.New(leagueFileType, 2023)
+		ts := putVersion(t, vfs, file, "20211125011947", "data")
+		assert.Nil(t, vfs.Remove(file, ts))
+		_, err := vfs.Read(file, ts)
+		assert.True(t, errors.Is(err, versionfs.ErrVersionNotFound))
+		err = vfs.Remove(file, ts)
+		assert.True(t, errors.Is(err, versionfs.ErrVersionNotFound))
+		exists, err := vfs.PathExists(pathOf(vfs, file, ts))
+		assert.Nil(t, err)
+		assert.False(t, exists)
+	})
+
+	t.Run("Detect", func(t *testing.T) {
+		vfs := create(t)
+		file := vfs.New(leagueFileType, 2023)
+		ts, err := vfs.Detect(path.Base(pathOf(vfs, file, mustTimestamp(t, "20211125011947"))), file)
+		assert.Nil(t, err)
+		assert.Equal(t, "20211125011947", ts.String())
+		_, err = vfs.Detect(path.Base(pathOf(vfs, rawFile{"2023/league", "league", "json"}, ts)), file)
+		assert.NotNil(t, err)
+	})
+}
+
+// pathOf returns the path of the version ts of file with the Scheme of vfs, relative to the root.
+func pathOf(vfs *versionfs.VersionFS, file versionfs.File, ts versionfs.Timestamp) string {
+	if vfs.Scheme == nil {
+		return versionfs.Path(file, ts)
+	}
+	return vfs.Scheme.Format(file, ts)
+}
+
+// mustTimestamp parses a timestamp, failing the test if it is invalid.
+func mustTimestamp(t *testing.T, ts string) versionfs.Timestamp {
+	t.Helper()
+	timestamp, err := versionfs.NewTimestamp(ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return timestamp
+}
+
+// putVersion stores a version with a known timestamp through the backend of vfs, at its
+// path in the Scheme of vfs.
+func putVersion(t *testing.T, vfs *versionfs.VersionFS, file versionfs.File, ts string, data string) versionfs.Timestamp {
+	t.Helper()
+	timestamp := mustTimestamp(t, ts)
+	putRaw(t, vfs, pathOf(vfs, file, timestamp), data)
+	return timestamp
+}
+
+// putRaw stores a file at a path relative to the root through the backend of vfs.
+func putRaw(t *testing.T, vfs *versionfs.VersionFS, name string, data string) {
+	t.Helper()
+	if err := vfs.Backend.MkdirAll(path.Join(vfs.RootPath, path.Dir(name)), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := vfs.Backend.WriteFile(path.Join(vfs.RootPath, name), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func timestampStrings(timestamps []versionfs.Timestamp) []string {
+	strs := make([]string, len(timestamps))
+	for i, ts := range timestamps {
+		strs[i] = ts.String()
+	}
+	return strs
+}
@@ -0,0 +1,65 @@
+package versionfstest
+
+import (
+	"github.com/sperano/versionfs"
+	"testing"
+)
+
+func TestConformance_OS(t *testing.T) {
+	t.Parallel()
+	TestConformance(t, func(t *testing.T) *versionfs.VersionFS {
+		return versionfs.New(t.TempDir())
+	})
+}
+
+func TestConformance_Memory(t *testing.T) {
+	t.Parallel()
+	TestConformance(t, func(t *testing.T) *versionfs.VersionFS {
+		return versionfs.NewMemory()
+	})
+}
+
+func TestConformance_CAS(t *testing.T) {
+	t.Parallel()
+	TestConformance(t, func(t *testing.T) *versionfs.VersionFS {
+		vfs := versionfs.New(t.TempDir())
+		vfs.EnableCAS()
+		return vfs
+	})
+}
+
+func TestConformance_MemoryCAS(t *testing.T) {
+	t.Parallel()
+	TestConformance(t, func(t *testing.T) *versionfs.VersionFS {
+		vfs := versionfs.NewMemory()
+		vfs.EnableCAS()
+		return vfs
+	})
+}
+
+func TestConformance_FlatScheme(t *testing.T) {
+	t.Parallel()
+	TestConformance(t, func(t *testing.T) *versionfs.VersionFS {
+		vfs := versionfs.NewMemory()
+		vfs.Scheme = versionfs.FlatScheme{}
+		return vfs
+	})
+}
+
+func TestConformance_DirScheme(t *testing.T) {
+	t.Parallel()
+	TestConformance(t, func(t *testing.T) *versionfs.VersionFS {
+		vfs := versionfs.NewMemory()
+		vfs.Scheme = versionfs.DirScheme{}
+		return vfs
+	})
+}
+
+func TestConformance_DirSchemeOS(t *testing.T) {
+	t.Parallel()
+	TestConformance(t, func(t *testing.T) *versionfs.VersionFS {
+		vfs := versionfs.New(t.TempDir())
+		vfs.Scheme = versionfs.DirScheme{}
+		return vfs
+	})
+}
@@ -0,0 +1,18 @@
+module github.com/sperano/versionfs/versionfswatch
+
+go 1.24
+
+replace github.com/sperano/versionfs => ../
+
+require (
+	github.com/fsnotify/fsnotify v1.8.0
+	github.com/sperano/versionfs v0.0.0
+	github.com/stretchr/testify v1.9.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/sys v0.13.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -0,0 +1,166 @@
+// Package versionfswatch notifies the new versions of files stored on the local filesystem
+// by a versionfs instance, or by other processes, with fsnotify. It is a separate module so
+// that the core versionfs module doesn't depend on fsnotify.
+package versionfswatch
+
+import (
+	"context"
+	"errors"
+	"github.com/fsnotify/fsnotify"
+	"github.com/sperano/versionfs"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Watch delivers the timestamps of the versions of file created after it returns, by any
+// process, until ctx is done, when the channel is closed. The filenames are matched with
+// Detect, like Find does, and each version is delivered once, whether it is created in place,
+// written in several steps, or staged and renamed. If the directory of the file doesn't
+// exist, its nearest existing parent is watched, and the versions are delivered as soon as
+// the directory appears. The root of vfs must be a directory of the local filesystem.
+//
+// Example:
+//
+//	versions, err := versionfswatch.Watch(ctx, vfs, file)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	for ts := range versions {
+//	    fmt.Printf("new version %s\n", ts)
+//	}
+func Watch(ctx context.Context, vfs *versionfs.VersionFS, file versionfs.File) (<-chan versionfs.Timestamp, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &watch{
+		vfs:     vfs,
+		file:    file,
+		dir:     filepath.Join(vfs.RootPath, file.Dir()),
+		watcher: watcher,
+		seen:    make(map[string]bool),
+	}
+	versions, err := w.attach()
+	if err != nil {
+		_ = watcher.Close()
+		return nil, err
+	}
+	for _, ts := range versions {
+		w.seen[ts.String()] = true
+	}
+	ch := make(chan versionfs.Timestamp)
+	go w.run(ctx, ch)
+	return ch, nil
+}
+
+// watch is the state of a Watch.
+type watch struct {
+	vfs  *versionfs.VersionFS
+	file versionfs.File
+	// dir is the directory of the file on the local filesystem.
+	dir     string
+	watcher *fsnotify.Watcher
+	// attached is set once dir is watched, before that its nearest existing parents are.
+	attached bool
+	// parents are the parents of dir watched while it doesn't exist.
+	parents []string
+	// seen are the timestamps of the versions already delivered, or existing when Watch was called.
+	seen map[string]bool
+}
+
+// attach watches dir, or its nearest existing parent if it doesn't exist. When dir is watched,
+// it returns its versions, since some may have been written before the watch started.
+func (w *watch) attach() ([]versionfs.Timestamp, error) {
+	if w.attached {
+		return nil, nil
+	}
+	dir := w.dir
+	for {
+		err := w.watcher.Add(dir)
+		if err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if !errors.Is(err, fs.ErrNotExist) || parent == dir {
+			return nil, err
+		}
+		dir = parent
+	}
+	if dir != w.dir {
+		w.parents = append(w.parents, dir)
+		// the next directory may have been created before its parent was watched
+		if _, err := os.Stat(filepath.Join(dir, w.next(dir))); err == nil {
+			return w.attach()
+		}
+		return nil, nil
+	}
+	w.attached = true
+	for _, parent := range w.parents {
+		_ = w.watcher.Remove(parent)
+	}
+	w.parents = nil
+	return w.vfs.Find(w.file.Dir(), w.file)
+}
+
+// next returns the name of the child of parent on the path to dir.
+func (w *watch) next(parent string) string {
+	dir := w.dir
+	for filepath.Dir(dir) != parent {
+		dir = filepath.Dir(dir)
+	}
+	return filepath.Base(dir)
+}
+
+// run delivers the new versions to ch until ctx is done or the watcher fails.
+func (w *watch) run(ctx context.Context, ch chan<- versionfs.Timestamp) {
+	defer close(ch)
+	defer func() { _ = w.watcher.Close() }()
+	for {
+		var versions []versionfs.Timestamp
+		select {
+		case <-ctx.Done():
+			return
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			w.warnf("watching %s: %s", w.dir, err)
+			continue
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) {
+				continue
+			}
+			if w.attached && filepath.Dir(event.Name) == w.dir {
+				if ts, err := w.vfs.Detect(filepath.Base(event.Name), w.file); err == nil {
+					versions = append(versions, ts)
+				}
+			} else {
+				var err error
+				if versions, err = w.attach(); err != nil {
+					w.warnf("watching %s: %s", w.dir, err)
+				}
+			}
+		}
+		for _, ts := range versions {
+			if w.seen[ts.String()] {
+				continue
+			}
+			w.seen[ts.String()] = true
+			select {
+			case ch <- ts:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+func (w *watch) warnf(format string, args ...any) {
+	if w.vfs.Logger != nil {
+		w.vfs.Logger.Warnf(format, args...)
+	}
+}
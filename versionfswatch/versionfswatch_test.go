@@ -0,0 +1,84 @@
+package versionfswatch
+
+import (
+	"context"
+	"github.com/sperano/versionfs"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type fileLeague struct{}
+
+func (fileLeague) Dir() string  { return "2023/league" }
+func (fileLeague) Name() string { return "league" }
+func (fileLeague) Ext() string  { return "json" }
+
+// receive returns the next timestamp delivered on ch, failing the test after a timeout.
+func receive(t *testing.T, ch <-chan versionfs.Timestamp) versionfs.Timestamp {
+	t.Helper()
+	select {
+	case ts, ok := <-ch:
+		if !ok {
+			t.Fatal("channel closed")
+		}
+		return ts
+	case <-time.After(5 * time.Second):
+		t.Fatal("no version delivered")
+	}
+	return versionfs.Timestamp{}
+}
+
+func TestWatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	vfs := versionfs.New(t.TempDir())
+	_, err := vfs.Write(fileLeague{}, []byte("existing"))
+	assert.Nil(t, err)
+	ch, err := Watch(ctx, vfs, fileLeague{})
+	assert.Nil(t, err)
+
+	// written in several steps, like scp does
+	f, err := os.Create(filepath.Join(vfs.RootPath, "2023/league/league.json.20231019140523"))
+	assert.Nil(t, err)
+	_, _ = f.WriteString("first ")
+	_ = f.Sync()
+	_, _ = f.WriteString("second")
+	assert.Nil(t, f.Close())
+	assert.Nil(t, os.WriteFile(filepath.Join(vfs.RootPath, "2023/league/other.json.20231019140523"), nil, 0644))
+	assert.Equal(t, "20231019140523", receive(t, ch).String())
+
+	ts, err := vfs.Write(fileLeague{}, []byte("new"))
+	assert.Nil(t, err)
+	assert.Equal(t, ts.String(), receive(t, ch).String())
+
+	cancel()
+	for range ch {
+	}
+}
+
+func TestWatch_MissingDir(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	vfs := versionfs.New(t.TempDir())
+	ch, err := Watch(ctx, vfs, fileLeague{})
+	assert.Nil(t, err)
+	ts, err := vfs.Write(fileLeague{}, []byte("first"))
+	assert.Nil(t, err)
+	assert.Equal(t, ts.String(), receive(t, ch).String())
+}
+
+func TestWatch_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := Watch(ctx, versionfs.New(t.TempDir()), fileLeague{})
+	assert.Nil(t, err)
+	cancel()
+	select {
+	case _, ok := <-ch:
+		assert.False(t, ok)
+	case <-time.After(5 * time.Second):
+		t.Fatal("channel not closed")
+	}
+}
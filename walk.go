@@ -0,0 +1,256 @@
+package versionfs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	path_ "path"
+	"sync"
+)
+
+// WalkVersionsFunc is the function called by WalkVersions for each versioned file.
+// dir is relative to the VersionFS root, name, ext, and ts are parsed from the filename
+// with ParseFilename, and info describes the file. Returning an error stops the walk.
+type WalkVersionsFunc func(dir, name, ext string, ts Timestamp, info os.FileInfo) error
+
+// WalkVersions recursively walks the directory root and calls fn for every versioned
+// file found, whatever its file type, in lexical order, or in no particular order with
+// WalkParallelism. Files that don't have the name.ext.timestamp format are skipped and
+// reported in the debug log.
+// Returns nil if root doesn't exist, or the first error returned by fn.
+//
+// Example:
+//
+//	err := vfs.WalkVersions("2023", func(dir, name, ext string, ts versionfs.Timestamp, info os.FileInfo) error {
+//	    fmt.Printf("%s/%s.%s %s %d bytes\n", dir, name, ext, ts.LongString(), info.Size())
+//	    return nil
+//	})
+func (v *VersionFS) WalkVersions(root string, fn WalkVersionsFunc) error {
+	return v.WalkVersionsCtx(context.Background(), root, fn)
+}
+
+// WalkVersionsCtx works like WalkVersions, but checks ctx between directory entries
+// and stops the walk with the context error as soon as it is done.
+func (v *VersionFS) WalkVersionsCtx(ctx context.Context, root string, fn WalkVersionsFunc) error {
+	root = slashed(root)
+	info := OpInfo{Op: OpWalk, Dir: root}
+	end := v.instrument(ctx, &info)
+	err := v.confine("walk", root)
+	if err == nil && v.WalkParallelism > 1 {
+		err = v.walkParallel(ctx, root, true, func(dir string, version walkedVersion) error {
+			return fn(dir, version.name, version.ext, version.ts, version.info)
+		})
+	} else if err == nil {
+		err = v.walk(ctx, root, fn)
+	}
+	end(err)
+	return err
+}
+
+// walk implements WalkVersionsCtx.
+func (v *VersionFS) walk(ctx context.Context, root string, fn WalkVersionsFunc) error {
+	entries, err := v.backend().ReadDir(path_.Join(v.RootPath, root))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if v.hidden(entry.Name()) {
+			continue
+		}
+		if entry.IsDir() {
+			if err := v.walk(ctx, path_.Join(root, entry.Name()), fn); err != nil {
+				return err
+			}
+			continue
+		}
+		name, ext, ts, err := ParseFilename(entry.Name())
+		if err != nil {
+			v.logger().Debugf("skipping unversioned file %s/%s: %s", root, entry.Name(), err)
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if err := fn(root, name, ext, ts, info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CountVersionsRecursive returns the number of versioned files under the directory root,
+// whatever their file type: the files whose names have the name.ext.timestamp format, as
+// walked by WalkVersions. Other files and directories aren't counted, and unlike
+// WalkVersions the files aren't stat'ed. Returns 0 if root doesn't exist.
+//
+// Example:
+//
+//	count, err := vfs.CountVersionsRecursive("2023")
+func (v *VersionFS) CountVersionsRecursive(root string) (int, error) {
+	root = slashed(root)
+	info := OpInfo{Op: OpWalk, Dir: root}
+	end := v.instrument(context.Background(), &info)
+	var count int
+	err := v.confine("walk", root)
+	if err == nil && v.WalkParallelism > 1 {
+		err = v.walkParallel(context.Background(), root, false, func(string, walkedVersion) error {
+			count++
+			return nil
+		})
+	} else if err == nil {
+		count, err = v.count(root)
+	}
+	end(err)
+	return count, err
+}
+
+// count implements CountVersionsRecursive.
+func (v *VersionFS) count(root string) (int, error) {
+	entries, err := v.backend().ReadDir(path_.Join(v.RootPath, root))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	count := 0
+	for _, entry := range entries {
+		if v.hidden(entry.Name()) {
+			continue
+		}
+		if entry.IsDir() {
+			n, err := v.count(path_.Join(root, entry.Name()))
+			if err != nil {
+				return 0, err
+			}
+			count += n
+		} else if _, _, _, err := ParseFilename(entry.Name()); err == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// walkedDir is a directory read by the workers of walkParallel, with its subdirectories
+// relative to the root.
+type walkedDir struct {
+	dir      string
+	subdirs  []string
+	versions []walkedVersion
+	err      error
+}
+
+// walkedVersion is a versioned file of a walkedDir, with its information if it was stat'ed.
+type walkedVersion struct {
+	name, ext string
+	ts        Timestamp
+	info      fs.FileInfo
+}
+
+// walkParallel walks the directory root like walk, reading WalkParallelism directories at
+// once, and stat'ing their versions if stat is set. visit is called for every version from
+// the calling goroutine, in no particular order. The workers are stopped before it returns.
+func (v *VersionFS) walkParallel(ctx context.Context, root string, stat bool, visit func(dir string, version walkedVersion) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	dirs := make(chan string)
+	walked := make(chan walkedDir)
+	var wg sync.WaitGroup
+	for i := 0; i < v.WalkParallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for dir := range dirs {
+				select {
+				case walked <- v.readWalkedDir(ctx, dir, stat):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	defer func() {
+		cancel()
+		close(dirs)
+		wg.Wait()
+	}()
+
+	pending := []string{root}
+	reading := 0
+	for len(pending) > 0 || reading > 0 {
+		var send chan string
+		var next string
+		if len(pending) > 0 {
+			send, next = dirs, pending[len(pending)-1]
+		}
+		select {
+		case send <- next:
+			pending = pending[:len(pending)-1]
+			reading++
+		case d := <-walked:
+			reading--
+			if d.err != nil {
+				return d.err
+			}
+			for _, version := range d.versions {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				if err := visit(d.dir, version); err != nil {
+					return err
+				}
+			}
+			pending = append(pending, d.subdirs...)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// readWalkedDir reads a directory for walkParallel, stat'ing its versions if stat is set.
+// A directory that doesn't exist, removed during the walk, is empty.
+func (v *VersionFS) readWalkedDir(ctx context.Context, dir string, stat bool) walkedDir {
+	walked := walkedDir{dir: dir}
+	entries, err := v.backend().ReadDir(path_.Join(v.RootPath, dir))
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			walked.err = err
+		}
+		return walked
+	}
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			walked.err = err
+			return walked
+		}
+		if v.hidden(entry.Name()) {
+			continue
+		}
+		if entry.IsDir() {
+			walked.subdirs = append(walked.subdirs, path_.Join(dir, entry.Name()))
+			continue
+		}
+		name, ext, ts, err := ParseFilename(entry.Name())
+		if err != nil {
+			v.logger().Debugf("skipping unversioned file %s/%s: %s", dir, entry.Name(), err)
+			continue
+		}
+		version := walkedVersion{name: name, ext: ext, ts: ts}
+		if stat {
+			if version.info, err = entry.Info(); err != nil {
+				walked.err = err
+				return walked
+			}
+		}
+		walked.versions = append(walked.versions, version)
+	}
+	return walked
+}
@@ -0,0 +1,211 @@
+package versionfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io/fs"
+	"math/rand"
+	"os"
+	"path"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseFilename(t *testing.T) {
+	t.Parallel()
+	name, ext, ts, err := ParseFilename("themes.csv.gz.20231019140523")
+	assert.Nil(t, err)
+	assert.Equal(t, "themes", name)
+	assert.Equal(t, "csv.gz", ext)
+	assert.Equal(t, "20231019140523", ts.String())
+	name, ext, ts, err = ParseFilename("roster-12-2023-10-19.json.20231019140523")
+	assert.Nil(t, err)
+	assert.Equal(t, "roster-12-2023-10-19", name)
+	assert.Equal(t, "json", ext)
+	for _, filename := range []string{
+		"league",
+		"league.txt",
+		".txt.20231019140523",
+		"league..20231019140523",
+		"league.20231019140523",
+		"league.txt.",
+		"league.txt.2023",
+	} {
+		_, _, _, err := ParseFilename(filename)
+		assert.NotNil(t, err, filename)
+	}
+}
+
+func TestVersionFS_WalkVersions(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	putRaw(t, vfs, "2023/league/league.txt.20211125011946", "1")
+	putRaw(t, vfs, "2023/league/league.txt.20211218030527", "333")
+	putRaw(t, vfs, "2023/league/league.foo", "unversioned")
+	putRaw(t, vfs, "2023/roster/team-3/roster-3-2023-10-19.json.20231019140523", "22")
+	putRaw(t, vfs, "catalog/themes.csv.gz.20231019140523", "outside")
+	var walked []string
+	err := vfs.WalkVersions("2023", func(dir, name, ext string, ts Timestamp, info os.FileInfo) error {
+		walked = append(walked, fmt.Sprintf("%s %s %s %s %d", dir, name, ext, ts, info.Size()))
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"2023/league league txt 20211125011946 1",
+		"2023/league league txt 20211218030527 3",
+		"2023/roster/team-3 roster-3-2023-10-19 json 20231019140523 2",
+	}, walked)
+}
+
+func TestVersionFS_WalkVersions_Error(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	putRaw(t, vfs, "2023/league/league.txt.20211125011946", "1")
+	putRaw(t, vfs, "2023/league/league.txt.20211218030527", "2")
+	stop := errors.New("stop")
+	calls := 0
+	err := vfs.WalkVersions("", func(dir, name, ext string, ts Timestamp, info os.FileInfo) error {
+		calls++
+		return stop
+	})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, 1, calls)
+	// a missing root is empty
+	err = vfs.WalkVersions("2024", func(dir, name, ext string, ts Timestamp, info os.FileInfo) error {
+		calls++
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestVersionFS_CountVersionsRecursive(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	putRaw(t, vfs, "2023/league/league.txt.20211125011946", "1")
+	putRaw(t, vfs, "2023/league/league.txt.20211218030527", "2")
+	putRaw(t, vfs, "2023/league/league.txt.20211218030527"+ChecksumSuffix, "sidecar")
+	putRaw(t, vfs, "2023/league/league.foo", "unversioned")
+	putRaw(t, vfs, "2023/league/.league.txt.20211218030527.tmp", "hidden")
+	putRaw(t, vfs, "2023/roster/team-3/roster-3-2023-10-19.json.20231019140523", "3")
+	putRaw(t, vfs, "catalog/themes.csv.gz.20231019140523", "outside")
+	count, err := vfs.CountVersionsRecursive("2023")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, count)
+	count, err = vfs.CountVersionsRecursive("")
+	assert.Nil(t, err)
+	assert.Equal(t, 4, count)
+	count, err = vfs.CountVersionsRecursive("2024")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+}
+
+// slowReadDirBackend is a Backend taking delay to read a directory, counting the reads.
+type slowReadDirBackend struct {
+	Backend
+	delay    time.Duration
+	readDirs atomic.Int64
+}
+
+func (b *slowReadDirBackend) ReadDir(name string) ([]fs.DirEntry, error) {
+	b.readDirs.Add(1)
+	time.Sleep(b.delay)
+	return b.Backend.ReadDir(name)
+}
+
+func TestVersionFS_WalkVersions_Parallel(t *testing.T) {
+	t.Parallel()
+	rng := rand.New(rand.NewSource(1))
+	start := time.Date(2023, 10, 19, 14, 0, 0, 0, time.UTC)
+	vfs := NewMemory()
+	vfs.Logger = NopLogger{}
+	dirs := []string{"2023"}
+	for i := 0; i < 40; i++ {
+		dirs = append(dirs, path.Join(dirs[rng.Intn(len(dirs))], fmt.Sprintf("dir%d", i)))
+	}
+	for i := 0; i < 400; i++ {
+		name := fmt.Sprintf("file%d.txt.%s", i%7, start.Add(time.Duration(i)*time.Second).Format("20060102150405"))
+		switch rng.Intn(10) {
+		case 0:
+			name = fmt.Sprintf("file%d.txt", i)
+		case 1:
+			name = "." + name
+		}
+		putRaw(t, vfs, path.Join(dirs[rng.Intn(len(dirs))], name), string(make([]byte, rng.Intn(100))))
+	}
+	vfs.Backend = &slowReadDirBackend{Backend: vfs.Backend, delay: time.Millisecond}
+
+	walk := func(parallelism int) ([]string, int64) {
+		vfs := vfs.WithRoot(vfs.RootPath)
+		vfs.WalkParallelism = parallelism
+		var walked []string
+		var size int64
+		var running atomic.Int32
+		err := vfs.WalkVersions("2023", func(dir, name, ext string, ts Timestamp, info os.FileInfo) error {
+			assert.Equal(t, int32(1), running.Add(1), "concurrent calls")
+			defer running.Add(-1)
+			walked = append(walked, fmt.Sprintf("%s %s %s %s %d", dir, name, ext, ts, info.Size()))
+			size += info.Size()
+			return nil
+		})
+		assert.Nil(t, err)
+		count, err := vfs.CountVersionsRecursive("2023")
+		assert.Nil(t, err)
+		assert.Equal(t, len(walked), count)
+		return walked, size
+	}
+	serial, serialSize := walk(0)
+	assert.Greater(t, len(serial), 300)
+	sort.Strings(serial)
+	for _, parallelism := range []int{1, 2, 8} {
+		walked, size := walk(parallelism)
+		sort.Strings(walked)
+		assert.Equal(t, serial, walked, parallelism)
+		assert.Equal(t, serialSize, size, parallelism)
+	}
+}
+
+func TestVersionFS_WalkVersions_ParallelStop(t *testing.T) {
+	t.Parallel()
+	vfs := NewMemory()
+	for i := 0; i < 100; i++ {
+		putRaw(t, vfs, fmt.Sprintf("2023/dir%02d/league.txt.20231019140523", i), "data")
+	}
+	backend := &slowReadDirBackend{Backend: vfs.Backend, delay: 5 * time.Millisecond}
+	vfs.Backend = backend
+	vfs.WalkParallelism = 4
+
+	// a cancellation stops the workers before the walk returns
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	err := vfs.WalkVersionsCtx(ctx, "", func(dir, name, ext string, ts Timestamp, info os.FileInfo) error {
+		calls++
+		cancel()
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, calls)
+	readDirs := backend.readDirs.Load()
+	assert.Less(t, readDirs, int64(20))
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, readDirs, backend.readDirs.Load())
+
+	// and so does an error of the walk function
+	stop := errors.New("stop")
+	calls = 0
+	err = vfs.WalkVersions("", func(dir, name, ext string, ts Timestamp, info os.FileInfo) error {
+		calls++
+		return stop
+	})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, 1, calls)
+
+	// a missing root is empty
+	count, err := vfs.CountVersionsRecursive("2024")
+	assert.Nil(t, err)
+	assert.Zero(t, count)
+}
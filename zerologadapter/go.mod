@@ -0,0 +1,20 @@
+module github.com/sperano/versionfs/zerologadapter
+
+go 1.24
+
+replace github.com/sperano/versionfs => ../
+
+require (
+	github.com/rs/zerolog v1.35.1
+	github.com/sperano/versionfs v0.0.0
+	github.com/stretchr/testify v1.8.4
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/mattn/go-colorable v0.1.14 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/sys v0.29.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -0,0 +1,29 @@
+// Package zerologadapter implements a versionfs.Logger writing to a zerolog.Logger.
+// It is a separate module so that the core versionfs module doesn't depend on zerolog.
+package zerologadapter
+
+import (
+	"github.com/rs/zerolog"
+	"github.com/sperano/versionfs"
+)
+
+// New returns a versionfs.Logger writing to logger.
+//
+// Example:
+//
+//	vfs.Logger = zerologadapter.New(log.Logger)
+func New(logger zerolog.Logger) versionfs.Logger {
+	return adapter{logger: logger}
+}
+
+type adapter struct {
+	logger zerolog.Logger
+}
+
+func (a adapter) Debugf(format string, args ...any) {
+	a.logger.Debug().Msgf(format, args...)
+}
+
+func (a adapter) Warnf(format string, args ...any) {
+	a.logger.Warn().Msgf(format, args...)
+}
@@ -0,0 +1,17 @@
+package zerologadapter
+
+import (
+	"bytes"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(zerolog.New(&buf).Level(zerolog.DebugLevel))
+	logger.Debugf("Reading file %s", "2023/league/league.json.20231019140523")
+	logger.Warnf("unexpected file: %s", "2023/league/league")
+	assert.Equal(t, `{"level":"debug","message":"Reading file 2023/league/league.json.20231019140523"}`+"\n"+
+		`{"level":"warn","message":"unexpected file: 2023/league/league"}`+"\n", buf.String())
+}